@@ -5,11 +5,13 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -20,24 +22,35 @@ import (
 	httpSwagger "github.com/swaggo/http-swagger"
 	"google.golang.org/api/option"
 
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/apiversion"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/client/email"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/config"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/cors"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/database"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/handler/auth"
+	blockhandler "github.com/bulatminnakhmetov/brigadka-backend/internal/handler/block"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/handler/media"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/handler/messaging"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/handler/profile"
+	reporthandler "github.com/bulatminnakhmetov/brigadka-backend/internal/handler/report"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/logging"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/metrics"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/presence"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/realip"
+	blockrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/block"
 	mediarepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/media"
 	messagingrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/messaging"
 	profilerepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
+	reportrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/report"
 	userrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/user"
 	verificationrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/verification"
 
 	authservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/auth"
+	blockservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/block"
 	mediaservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/media"
 	messagingservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/messaging"
 	profileservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/profile"
+	reportservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/report"
 	verificationservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/verification"
 
 	mediastorage "github.com/bulatminnakhmetov/brigadka-backend/internal/storage/media"
@@ -61,7 +74,7 @@ import (
 // @license.url   http://www.apache.org/licenses/LICENSE-2.0.html
 
 // @host      localhost:8080
-// @BasePath  /api
+// @BasePath  /api/v1
 
 // @securityDefinitions.apikey BearerAuth
 // @in header
@@ -75,6 +88,70 @@ type HealthResponse struct {
 	Timestamp string `json:"timestamp"`
 }
 
+// requestTimeout bounds regular requests. Long-lived routes such as the WebSocket and
+// streaming responses are registered outside of timeoutGroup and are not bounded.
+const requestTimeout = 60 * time.Second
+
+// Значения по умолчанию для ограничений HTTP-сервера
+const (
+	defaultReadHeaderTimeout = 10 * time.Second // Защита от slowloris: заголовки должны прийти быстро
+	defaultReadTimeout       = 5 * time.Minute  // Загрузка медиа идет через сервер, тело может быть большим
+	defaultIdleTimeout       = 2 * time.Minute
+	defaultMaxHeaderBytes    = 64 << 10
+)
+
+// serverConfig содержит ограничения HTTP-сервера
+type serverConfig struct {
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+}
+
+// newServer создает HTTP-сервер с заданными ограничениями. Таймауты чтения не мешают
+// WebSocket: после апгрейда дедлайны соединения сбрасываются.
+func newServer(addr string, handler http.Handler, config serverConfig) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: config.ReadHeaderTimeout,
+		ReadTimeout:       config.ReadTimeout,
+		IdleTimeout:       config.IdleTimeout,
+		MaxHeaderBytes:    config.MaxHeaderBytes,
+	}
+}
+
+// minJWTSecretLength - минимальная длина JWT_SECRET в байтах вне development-окружения
+const minJWTSecretLength = 32
+
+// Значения APP_ENV. Незаданное окружение считается production, чтобы забытая
+// переменная не отключала проверки конфигурации.
+const (
+	appEnvDevelopment = "development"
+	appEnvProduction  = "production"
+)
+
+// appEnvironment возвращает окружение из APP_ENV, по умолчанию production
+func appEnvironment() string {
+	return getEnv("APP_ENV", ptr(appEnvProduction))
+}
+
+// validateJWTSecret проверяет секрет для подписи токенов. Короткий секрет можно подобрать
+// перебором, поэтому вне development он должен быть не короче minJWTSecretLength байт.
+func validateJWTSecret(secret string, appEnv string) error {
+	if secret == "" {
+		return errors.New("JWT_SECRET must not be empty")
+	}
+	if appEnv != appEnvDevelopment && len(secret) < minJWTSecretLength {
+		return fmt.Errorf("JWT_SECRET must be at least %d bytes in the %q environment, got %d; set APP_ENV=%s to allow a shorter secret locally",
+			minJWTSecretLength, appEnv, len(secret), appEnvDevelopment)
+	}
+	return nil
+}
+
+// storageHealthTimeout ограничивает время проверки доступности хранилища в health check
+const storageHealthTimeout = 3 * time.Second
+
 // Объявление startTime в глобальной области видимости
 var startTime time.Time
 
@@ -124,9 +201,20 @@ func main() {
 		Password: getEnv("DB_PASSWORD", nil),
 		DBName:   getEnv("DB_NAME", nil),
 		SSLMode:  getEnv("DB_SSL_MODE", ptr("disable")),
+
+		MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", ptr(database.DefaultMaxOpenConns)),
+		MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", ptr(database.DefaultMaxIdleConns)),
+		ConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", ptr(database.DefaultConnMaxLifetime)),
+		ConnectTimeout:  getEnvAsDuration("DB_CONNECT_TIMEOUT", ptr(database.DefaultConnectTimeout)),
+		ConnectAttempts: getEnvAsInt("DB_CONNECT_ATTEMPTS", ptr(database.DefaultConnectAttempts)),
+		ConnectBackoff:  getEnvAsDuration("DB_CONNECT_BACKOFF", ptr(database.DefaultConnectBackoff)),
 	}
 
 	jwtSecret := getEnv("JWT_SECRET", nil)
+	appEnv := appEnvironment()
+	if err := validateJWTSecret(jwtSecret, appEnv); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
 	serverPort := getEnv("SERVER_PORT", ptr("8080"))
 	appVersion := getEnv("APP_VERSION", ptr("dev"))
 	frontendURL := getEnv("FRONTEND_URL", ptr("http://localhost:8080"))
@@ -139,6 +227,13 @@ func main() {
 	}
 	defer db.Close()
 
+	// Применение миграций; окружения, где схема мигрируется отдельно, отключают это через AUTO_MIGRATE=false
+	if getEnvAsBool("AUTO_MIGRATE", ptr(true)) {
+		if err := database.RunMigrations(db); err != nil {
+			log.Fatalf("Failed to run database migrations: %v", err)
+		}
+	}
+
 	// Инициализация S3-совместимого хранилища для Backblaze B2
 	s3Storage, err := mediastorage.NewS3StorageProvider(
 		getEnv("B2_ACCESS_KEY_ID", nil),
@@ -156,7 +251,18 @@ func main() {
 	mediaRepo := mediarepo.NewRepository(db)
 
 	// Инициализация сервиса медиа
-	mediaService := mediaservice.NewMediaService(mediaRepo, s3Storage)
+	var allowedContentTypes []string
+	if contentTypes := getEnv("MEDIA_ALLOWED_CONTENT_TYPES", ptr("")); contentTypes != "" {
+		allowedContentTypes = strings.Split(contentTypes, ",")
+	}
+	maxProfileVideos := getEnvAsInt("PROFILE_MAX_VIDEOS", ptr(profileservice.DefaultMaxVideos))
+	mediaService := mediaservice.NewMediaService(mediaRepo, s3Storage, mediaservice.Config{
+		MaxBytes:            int64(getEnvAsInt("MEDIA_MAX_BYTES", ptr(mediaservice.DefaultMaxBytes))),
+		AllowedContentTypes: allowedContentTypes,
+		MaxProfileMedia:     maxProfileVideos,
+		MinProfileMedia:     getEnvAsInt("PROFILE_MIN_MEDIA", ptr(0)),
+		PendingUploadTTL:    getEnvAsDuration("MEDIA_PENDING_UPLOAD_TTL", ptr(mediaservice.DefaultPendingUploadTTL)),
+	})
 
 	// Инициализация репозитория пользователей
 	userRepo := userrepo.NewPostgresUserRepository(db)
@@ -174,21 +280,44 @@ func main() {
 	)
 
 	// Инициализация сервиса аутентификации
-	authService := authservice.NewAuthService(userRepo, verificationService, jwtSecret)
+	authService := authservice.NewAuthService(userRepo, verificationService, jwtSecret, authservice.Config{
+		AccessTokenTTL:  getEnvAsDuration("ACCESS_TOKEN_TTL", ptr(authservice.DefaultAccessTokenTTL)),
+		RefreshTokenTTL: getEnvAsDuration("REFRESH_TOKEN_TTL", ptr(authservice.DefaultRefreshTokenTTL)),
+		// При ALLOW_REGISTRATION=false зарегистрироваться можно только по коду приглашения
+		InviteOnly: !getEnvAsBool("ALLOW_REGISTRATION", ptr(true)),
+	})
 
 	// Initialize auth handler with verification support
-	authHandler := auth.NewAuthHandler(authService)
+	loginLimiter := auth.NewLoginLimiter(
+		getEnvAsInt("LOGIN_MAX_FAILED_ATTEMPTS", ptr(5)),
+		getEnvAsDuration("LOGIN_LOCKOUT_DURATION", ptr(15*time.Minute)),
+		nil,
+	)
+	authHandler := auth.NewAuthHandler(authService, loginLimiter)
+	// Администраторы определяются списком ADMIN_EMAILS
+	adminMiddleware := authHandler.AdminMiddleware(strings.Split(getEnv("ADMIN_EMAILS", ptr("")), ","))
 
 	// Инициализация сервиса и хендлера профилей
 	profileRepo := profilerepo.NewPostgresRepository(db)
-	profileService := profileservice.NewProfileService(profileRepo, mediaRepo)
+	profileService := profileservice.NewProfileService(profileRepo, mediaRepo, profileservice.Config{
+		MaxImprovStyles:  getEnvAsInt("PROFILE_MAX_IMPROV_STYLES", ptr(profileservice.DefaultMaxImprovStyles)),
+		MaxVideos:        maxProfileVideos,
+		FeedCacheTTL:     getEnvAsDuration("PROFILE_FEED_CACHE_TTL", ptr(profileservice.DefaultFeedCacheTTL)),
+		TrendingCacheTTL: getEnvAsDuration("PROFILE_TRENDING_CACHE_TTL", ptr(profileservice.DefaultTrendingCacheTTL)),
+
+		RestoreGracePeriod: getEnvAsDuration("PROFILE_RESTORE_GRACE_PERIOD", ptr(profileservice.DefaultRestoreGracePeriod)),
+	})
 	profileHandler := profile.NewProfileHandler(profileService)
 
+	// Время последней активности пользователей
+	presenceTracker := presence.NewTracker(profileRepo, getEnvAsDuration("PRESENCE_TOUCH_INTERVAL", ptr(presence.DefaultTouchInterval)))
+
 	// Инициализация хендлера медиа
 	mediaHandler := media.NewMediaHandler(
 		mediaService,
 		getEnvAsInt("MAX_CONCURRENT_UPLOADS", nil),
 		int64(getEnvAsInt("MAX_UPLOAD_SIZE_MB", nil)),
+		int64(getEnvAsInt("MULTIPART_MAX_MEMORY_MB", ptr(10))),
 	)
 
 	// Load APNS private key
@@ -209,7 +338,7 @@ func main() {
 		// In a real implementation, load private key from file or environment
 		APNSPrivateKey:  apnsPrivateKey,
 		APNSBundleID:    getEnv("APNS_BUNDLE_ID", ptr("")),
-		APNSDevelopment: getEnv("APP_ENV", ptr("development")) != "production",
+		APNSDevelopment: appEnv != appEnvProduction,
 	}
 
 	// Initialize Firebase app
@@ -230,152 +359,334 @@ func main() {
 	pushHandler := pushhandler.NewHandler(pushService)
 
 	// Инициализация сервиса и хендлера сообщений
-	messagingRepo := messagingrepo.NewRepository(db)
-	messagingService := messagingservice.NewService(messagingRepo, profileRepo)
-	messagingHandler := messaging.NewHandler(messagingService, profileService, pushService)
+	// Origins the web frontend is served from, used for CORS and WebSocket upgrades
+	var allowedOrigins []string
+	if origins := getEnv("CORS_ALLOWED_ORIGINS", ptr("")); origins != "" {
+		allowedOrigins = strings.Split(origins, ",")
+	}
+	corsAllowlist := cors.NewAllowlist(allowedOrigins)
 
-	// Создание роутера
-	r := chi.NewRouter()
+	// Заголовки X-Forwarded-For и X-Real-IP учитываются только от доверенных прокси
+	var trustedProxyCIDRs []string
+	if cidrs := getEnv("TRUSTED_PROXIES", ptr("")); cidrs != "" {
+		trustedProxyCIDRs = strings.Split(cidrs, ",")
+	}
+	trustedProxies, err := realip.NewTrustedProxies(trustedProxyCIDRs)
+	if err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
-	// Базовые middleware
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Timeout(60 * time.Second))
-	r.Use(logging.ErrorLogger)
+	// Блокировки пользователей
+	blockService := blockservice.NewService(blockrepo.NewPostgresRepository(db))
+	blockHandler := blockhandler.NewHandler(blockService)
 
-	// Подключение Swagger UI
-	r.Get("/swagger/*", httpSwagger.Handler(
-		httpSwagger.URL("/swagger/doc.json"), // URL для доступа к API документации
-	))
+	reportService := reportservice.NewService(reportrepo.NewPostgresRepository(db))
+	reportHandler := reporthandler.NewHandler(reportService)
 
-	// Health endpoint для проверки работоспособности сервиса
-	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-		healthHandler(w, r, db, appVersion)
+	messagingRepo := messagingrepo.NewRepository(db)
+	messagingService := messagingservice.NewService(messagingRepo, profileRepo)
+	messagingConfig := messaging.Config{
+		IdleTimeout:            getEnvAsDuration("WS_IDLE_TIMEOUT", ptr(5*time.Minute)),
+		ReactionCoalesceWindow: time.Duration(getEnvAsInt("WS_REACTION_COALESCE_MS", ptr(300))) * time.Millisecond,
+		EphemeralEventInterval: time.Duration(getEnvAsInt("WS_EPHEMERAL_INTERVAL_MS", ptr(1000))) * time.Millisecond,
+		TypingTimeout:          time.Duration(getEnvAsInt("WS_TYPING_TIMEOUT_MS", ptr(5000))) * time.Millisecond,
+		MaxMalformedFrames:     getEnvAsInt("WS_MAX_MALFORMED_FRAMES", ptr(5)),
+		PingInterval:           getEnvAsDuration("WS_PING_INTERVAL", ptr(30*time.Second)),
+		SendBufferSize:         getEnvAsInt("WS_SEND_BUFFER_SIZE", ptr(messaging.DefaultSendBufferSize)),
+		MaxFrameBytes:          int64(getEnvAsInt("WS_MAX_FRAME_BYTES", ptr(messaging.DefaultMaxFrameBytes))),
+		ExpirySweepInterval:    time.Duration(getEnvAsInt("MESSAGE_EXPIRY_SWEEP_SECONDS", ptr(10))) * time.Second,
+		AllowedOrigins:         allowedOrigins,
+		AllowAnyOrigin:         getEnvAsBool("WS_ALLOW_ANY_ORIGIN", ptr(false)),
+		Presence:               presenceTracker,
+	}
+	messagingHandler := messaging.NewHandler(messagingService, profileService, pushService, messagingConfig)
+	// Статус "в сети" в результатах поиска берется из WebSocket-подключений
+	profileHandler.SetPresence(messagingHandler)
+
+	// Удаление исчезающих сообщений в фоне
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	go messagingHandler.RunExpirySweeper(sweeperCtx)
+	// Удаление незавершенных загрузок медиа и повторная очистка хранилища в фоне
+	go mediaService.RunPendingUploadSweeper(sweeperCtx, getEnvAsDuration("MEDIA_PENDING_SWEEP_INTERVAL", ptr(time.Hour)))
+
+	// Метрики Prometheus
+	appMetrics := metrics.New()
+	appMetrics.RegisterGauge("websocket_clients_active", "Number of connected WebSocket clients.", func() float64 {
+		return float64(messagingHandler.ActiveClients())
 	})
 
 	// Расширенный health check с дополнительной информацией
-	r.Get("/health/details", func(w http.ResponseWriter, r *http.Request) {
+	healthDetails := func(w http.ResponseWriter, r *http.Request) {
 		details := map[string]interface{}{
 			"status":      "healthy",
 			"version":     appVersion,
 			"timestamp":   time.Now().Format(time.RFC3339),
-			"environment": getEnv("APP_ENV", ptr("development")),
+			"environment": appEnv,
 			"services": map[string]interface{}{
 				"database": map[string]interface{}{
 					"status": "connected",
 					"host":   dbConfig.Host,
 					"name":   dbConfig.DBName,
 				},
+				"storage": map[string]interface{}{
+					"status": "connected",
+					"bucket": getEnv("B2_BUCKET_NAME", nil),
+				},
 			},
 			"uptime": time.Since(startTime).String(),
 		}
+		services := details["services"].(map[string]interface{})
 
 		// Проверка соединения с базой данных
 		if err := db.Ping(); err != nil {
 			details["status"] = "error"
-			details["services"].(map[string]interface{})["database"].(map[string]interface{})["status"] = "error"
+			services["database"].(map[string]interface{})["status"] = "error"
+		}
+
+		// Проверка доступности бакета, зависший эндпоинт не должен задерживать ответ
+		ctx, cancel := context.WithTimeout(r.Context(), storageHealthTimeout)
+		defer cancel()
+		if err := s3Storage.Ping(ctx); err != nil {
+			log.Printf("Storage health check failed: %v", err)
+			details["status"] = "error"
+			services["storage"].(map[string]interface{})["status"] = "error"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if details["status"] == "error" {
 			w.WriteHeader(http.StatusServiceUnavailable)
 		} else {
 			w.WriteHeader(http.StatusOK)
 		}
-
-		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(details)
+	}
+
+	// Создание роутера
+	r := newRouter(routerDeps{
+		authHandler:      authHandler,
+		adminMiddleware:  adminMiddleware,
+		profileHandler:   profileHandler,
+		mediaHandler:     mediaHandler,
+		messagingHandler: messagingHandler,
+		pushHandler:      pushHandler,
+		blockHandler:     blockHandler,
+		reportHandler:    reportHandler,
+		presenceTracker:  presenceTracker,
+		trustedProxies:   trustedProxies,
+		corsAllowlist:    corsAllowlist,
+		metrics:          appMetrics,
+		health: func(w http.ResponseWriter, r *http.Request) {
+			healthHandler(w, r, db, appVersion)
+		},
+		healthDetails: healthDetails,
 	})
 
-	r.Group(func(r chi.Router) {
+	// Запуск сервера с корректной обработкой graceful shutdown
+	server := newServer(":"+serverPort, r, serverConfig{
+		ReadHeaderTimeout: getEnvAsDuration("SERVER_READ_HEADER_TIMEOUT", ptr(defaultReadHeaderTimeout)),
+		ReadTimeout:       getEnvAsDuration("SERVER_READ_TIMEOUT", ptr(defaultReadTimeout)),
+		IdleTimeout:       getEnvAsDuration("SERVER_IDLE_TIMEOUT", ptr(defaultIdleTimeout)),
+		MaxHeaderBytes:    getEnvAsInt("SERVER_MAX_HEADER_BYTES", ptr(defaultMaxHeaderBytes)),
+	})
+
+	// Запуск сервера в горутине
+	go func() {
+		log.Printf("Server is starting on port %s", serverPort)
+		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("Could not listen on port %s: %v\n", serverPort, err)
+		}
+	}()
+
+	// Канал для обработки сигналов завершения
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	// Ожидание сигнала
+	<-stop
+
+	// Корректное завершение работы сервера
+	log.Println("Shutting down server...")
+	stopSweeper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(ctx); err != nil {
+		log.Fatalf("Server forced to shutdown: %v", err)
+	}
+
+	log.Println("Server gracefully stopped")
+}
+
+// routerDeps содержит хендлеры и middleware, из которых собирается роутер
+type routerDeps struct {
+	authHandler      *auth.AuthHandler
+	adminMiddleware  func(http.Handler) http.Handler
+	profileHandler   *profile.ProfileHandler
+	mediaHandler     *media.MediaHandler
+	messagingHandler *messaging.Handler
+	pushHandler      *pushhandler.Handler
+	blockHandler     *blockhandler.Handler
+	reportHandler    *reporthandler.Handler
+	presenceTracker  *presence.Tracker
+	trustedProxies   *realip.TrustedProxies
+	corsAllowlist    *cors.Allowlist
+	metrics          *metrics.Metrics
+	health           http.HandlerFunc
+	healthDetails    http.HandlerFunc
+}
+
+// newRouter регистрирует все маршруты сервиса
+func newRouter(d routerDeps) *chi.Mux {
+	r := chi.NewRouter()
+
+	// Базовые middleware
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+	r.Use(d.trustedProxies.Middleware)
+	r.Use(d.metrics.Middleware)
+	r.Use(d.corsAllowlist.Middleware)
+	r.Use(logging.ErrorLogger)
+
+	timeoutGroup(r, func(r chi.Router) {
+		// Подключение Swagger UI
+		r.Get("/swagger/*", httpSwagger.Handler(
+			httpSwagger.URL("/swagger/doc.json"), // URL для доступа к API документации
+		))
+
+		// Метрики для Prometheus
+		r.Method(http.MethodGet, "/metrics", d.metrics.Handler())
+
+		// Health endpoint для проверки работоспособности сервиса
+		r.Get("/health", d.health)
 
-		r.Route("/api", func(r chi.Router) {
+		// Расширенный health check с дополнительной информацией
+		r.Get("/health/details", d.healthDetails)
+	})
 
+	// Маршруты API, версия указывается в префиксе /api/v1
+	apiversion.Mount(r, apiversion.Current, func(r chi.Router) {
+		timeoutGroup(r, func(r chi.Router) {
 			r.Route("/auth", func(r chi.Router) {
-				r.Post("/login", authHandler.Login)
-				r.Post("/register", authHandler.Register)
-				r.Post("/refresh", authHandler.RefreshToken)
-				r.Get("/verify-email", authHandler.VerifyEmail)
+				r.Post("/login", d.authHandler.Login)
+				r.Post("/register", d.authHandler.Register)
+				r.Post("/refresh", d.authHandler.Refresh)
+				r.Post("/logout", d.authHandler.Logout)
+				r.Get("/validate", d.authHandler.ValidateToken)
+				r.Post("/password-reset/request", d.authHandler.RequestPasswordReset)
+				r.Post("/password-reset/confirm", d.authHandler.ConfirmPasswordReset)
+				r.Get("/verify-email", d.authHandler.VerifyEmail)
+				r.Get("/confirm-email", d.authHandler.VerifyEmail)
 
 				r.Group(func(r chi.Router) {
-					r.Use(authHandler.AuthMiddleware(false))
-					r.Post("/resend-verification", authHandler.ResendVerification)
-					r.Get("/verification-status", authHandler.GetVerificationStatus)
+					r.Use(d.authHandler.AuthMiddleware(false))
+					r.Post("/resend-verification", d.authHandler.ResendVerification)
+					r.Post("/send-verification", d.authHandler.ResendVerification)
+					r.Get("/verification-status", d.authHandler.GetVerificationStatus)
+					r.Delete("/account", d.authHandler.DeleteAccount)
 				})
 			})
 
 			r.Group(func(r chi.Router) {
-				r.Use(authHandler.AuthMiddleware(true))
+				r.Use(d.authHandler.AuthMiddleware(true))
+				r.Use(d.presenceTracker.Middleware)
 
 				r.Route("/profiles", func(r chi.Router) {
 
-					r.Post("/", profileHandler.CreateProfile)
-					r.Get("/{userID}", profileHandler.GetProfile)
-					r.Patch("/{userID}", profileHandler.UpdateProfile)
+					r.Post("/", d.profileHandler.CreateProfile)
+					r.Get("/me/search-preview", d.profileHandler.GetSearchPreview)
+					r.Get("/me/edit", d.profileHandler.GetProfileEditForm)
+					r.Get("/{userID}", d.profileHandler.GetProfile)
+					r.Patch("/{userID}", d.profileHandler.UpdateProfile)
+					r.Delete("/{userID}", d.profileHandler.DeleteProfile)
+					r.Post("/{userID}/restore", d.profileHandler.RestoreProfile)
+					r.Patch("/{userID}/improv", d.profileHandler.PatchImprovProfile)
+					r.Put("/{userID}/improv/looking-for-team", d.profileHandler.SetLookingForTeam)
+					r.Put("/{userID}/media/order", d.profileHandler.ReorderMedia)
 
 					// Регистрация обработчиков для справочников
 					r.Route("/catalog", func(r chi.Router) {
-						r.Get("/improv-styles", profileHandler.GetImprovStyles)
-						r.Get("/improv-goals", profileHandler.GetImprovGoals)
-						r.Get("/genders", profileHandler.GetGenders)
-						r.Get("/cities", profileHandler.GetCities)
+						r.Get("/", d.profileHandler.GetAllCatalogs)
+						r.Get("/languages", d.profileHandler.GetCatalogLanguages)
+						r.Get("/improv-styles", d.profileHandler.GetImprovStyles)
+						r.Get("/improv-goals", d.profileHandler.GetImprovGoals)
+						r.Get("/genders", d.profileHandler.GetGenders)
+						r.Get("/cities", d.profileHandler.GetCities)
 					})
 
-					r.Post("/search", profileHandler.SearchProfiles)
+					r.Post("/search", d.profileHandler.SearchProfiles)
 				})
 
+				r.Get("/meta/enums", d.profileHandler.GetEnums)
+				r.Get("/feed/new", d.profileHandler.GetNewProfilesFeed)
+				r.Get("/stats/trending", d.profileHandler.GetTrending)
+
 				// Маршруты для работы с медиа (требуют аутентификации)
 				r.Route("/media", func(r chi.Router) {
-					r.Post("/", mediaHandler.UploadMedia)
+					r.Post("/", d.mediaHandler.UploadMedia)
+					r.Post("/presign", d.mediaHandler.PresignUpload)
+					r.Get("/constraints", d.mediaHandler.GetConstraints)
+					r.Post("/{mediaID}/complete", d.mediaHandler.CompleteUpload)
+					r.Get("/{mediaID}", d.mediaHandler.GetMedia)
+					r.Delete("/{mediaID}", d.mediaHandler.DeleteMedia)
 				})
 
-				// Маршруты для работы с сообщениями (требуют аутентификации)
-				r.Post("/chats", messagingHandler.CreateChat)
-				r.Get("/chats", messagingHandler.GetUserChats)
-				r.Post("/chats/direct", messagingHandler.GetOrCreateDirectChat)
-				r.Get("/chats/{chatID}", messagingHandler.GetChat)
-				r.Get("/chats/{chatID}/messages", messagingHandler.GetChatMessages)
-				r.Post("/chats/{chatID}/messages", messagingHandler.SendMessage)
-				r.Post("/chats/{chatID}/participants", messagingHandler.AddParticipant)
-				r.Delete("/chats/{chatID}/participants/{userID}", messagingHandler.RemoveParticipant)
-				r.Post("/messages/{messageID}/reactions", messagingHandler.AddReaction)
-				r.Delete("/messages/{messageID}/reactions/{reactionCode}", messagingHandler.RemoveReaction)
-				r.HandleFunc("/ws/chat", messagingHandler.HandleWebSocket)
-
-				r.Post("/push/register", pushHandler.RegisterToken)
-				r.Delete("/push/unregister", pushHandler.UnregisterToken)
-			})
-		})
-	})
-
-	// Запуск сервера с корректной обработкой graceful shutdown
-	server := &http.Server{
-		Addr:    ":" + serverPort,
-		Handler: r,
-	}
+				r.Get("/users/{userID}/media", d.mediaHandler.GetUserMedia)
 
-	// Запуск сервера в горутине
-	go func() {
-		log.Printf("Server is starting on port %s", serverPort)
-		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Could not listen on port %s: %v\n", serverPort, err)
-		}
-	}()
+				// Блокировки пользователей
+				r.Get("/users/blocks", d.blockHandler.GetBlockedUsers)
+				r.Post("/users/{userID}/block", d.blockHandler.BlockUser)
+				r.Delete("/users/{userID}/block", d.blockHandler.UnblockUser)
 
-	// Канал для обработки сигналов завершения
-	stop := make(chan os.Signal, 1)
-	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+				// Жалобы на пользователей и контент; читать их могут только администраторы (users.is_admin)
+				r.Post("/reports", d.reportHandler.CreateReport)
+				r.With(d.reportHandler.AdminMiddleware).Get("/reports", d.reportHandler.GetReports)
 
-	// Ожидание сигнала
-	<-stop
+				// Маршруты администратора
+				r.Route("/admin", func(r chi.Router) {
+					r.Use(d.adminMiddleware)
+					r.Get("/catalog/{type}/translations", d.profileHandler.GetCatalogTranslations)
+					r.Post("/invite-codes", d.authHandler.CreateInviteCode)
+				})
 
-	// Корректное завершение работы сервера
-	log.Println("Shutting down server...")
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+				// Маршруты для работы с сообщениями (требуют аутентификации)
+				r.Post("/chats", d.messagingHandler.CreateChat)
+				r.Get("/chats", d.messagingHandler.GetUserChats)
+				r.Post("/chats/direct", d.messagingHandler.GetOrCreateDirectChat)
+				r.Get("/chats/{chatID}", d.messagingHandler.GetChat)
+				r.Patch("/chats/{chatID}", d.messagingHandler.RenameChat)
+				r.Get("/chats/{chatID}/messages", d.messagingHandler.GetChatMessages)
+				r.Get("/chats/{chatID}/messages/search", d.messagingHandler.SearchMessages)
+				r.Post("/chats/{chatID}/messages", d.messagingHandler.SendMessage)
+				r.Get("/chats/{chatID}/messages/{messageID}", d.messagingHandler.GetMessage)
+				r.Put("/chats/{chatID}/messages/{messageID}", d.messagingHandler.EditMessage)
+				r.Delete("/chats/{chatID}/messages/{messageID}", d.messagingHandler.DeleteMessage)
+				r.Get("/chats/{chatID}/read-positions", d.messagingHandler.GetReadPositions)
+				r.Post("/chats/{chatID}/participants", d.messagingHandler.AddParticipant)
+				r.Delete("/chats/{chatID}/participants/{userID}", d.messagingHandler.RemoveParticipant)
+				r.Post("/chats/{chatID}/participants/{userID}/role", d.messagingHandler.SetParticipantRole)
+				r.Get("/messages/{messageID}/reactions", d.messagingHandler.GetReactions)
+				r.Post("/messages/{messageID}/reactions", d.messagingHandler.AddReaction)
+				r.Delete("/messages/{messageID}/reactions", d.messagingHandler.RemoveReaction)
+				r.Delete("/messages/{messageID}/reactions/{reactionCode}", d.messagingHandler.RemoveReaction) // Устаревший путь
+				r.Get("/messaging/overview", d.messagingHandler.GetChatOverviews)
+				r.Get("/messaging/sent", d.messagingHandler.GetSentMessages)
+				r.Get("/messaging/reactions", d.messagingHandler.GetReactionCatalog)
+				r.Get("/users/me/reactions", d.messagingHandler.GetUserReactions)
+				r.Get("/users/me/teams", d.messagingHandler.GetUserTeams)
+
+				r.Post("/push/register", d.pushHandler.RegisterToken)
+				r.Delete("/push/unregister", d.pushHandler.UnregisterToken)
+			})
+		})
 
-	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
-	}
+		// Долгоживущие соединения не ограничиваются таймаутом запроса
+		r.Group(func(r chi.Router) {
+			r.Use(d.authHandler.AuthMiddleware(true))
+			r.HandleFunc("/ws/chat", d.messagingHandler.HandleWebSocket)
+		})
+	})
 
-	log.Println("Server gracefully stopped")
+	return r
 }
 
 // Вспомогательные функции для работы с переменными окружения
@@ -389,12 +700,46 @@ func getEnv(key string, fallback *string) string {
 	return *fallback
 }
 
+// getEnvAsInt parses an integer, a value that is set but not an integer is a config error
 func getEnvAsInt(key string, fallback *int) int {
 	if value, exists := os.LookupEnv(key); exists {
-		var intVal int
-		if _, err := fmt.Sscanf(value, "%d", &intVal); err == nil {
-			return intVal
+		intVal, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			panic(fmt.Sprintf("Environment variable %s is not a valid integer: %q", key, value))
+		}
+		return intVal
+	}
+	if fallback == nil {
+		panic(fmt.Sprintf("Environment variable %s is not set and no fallback provided", key))
+	}
+	return *fallback
+}
+
+// getEnvAsDuration parses a duration such as "15m" or "720h", a value that is set but
+// not a duration is a config error
+func getEnvAsDuration(key string, fallback *time.Duration) time.Duration {
+	if value, exists := os.LookupEnv(key); exists {
+		duration, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			panic(fmt.Sprintf("Environment variable %s is not a valid duration: %q", key, value))
+		}
+		return duration
+	}
+	if fallback == nil {
+		panic(fmt.Sprintf("Environment variable %s is not set and no fallback provided", key))
+	}
+	return *fallback
+}
+
+// getEnvAsBool parses a boolean such as "true" or "0", a value that is set but not a
+// boolean is a config error
+func getEnvAsBool(key string, fallback *bool) bool {
+	if value, exists := os.LookupEnv(key); exists {
+		boolVal, err := strconv.ParseBool(strings.TrimSpace(value))
+		if err != nil {
+			panic(fmt.Sprintf("Environment variable %s is not a valid boolean: %q", key, value))
 		}
+		return boolVal
 	}
 	if fallback == nil {
 		panic(fmt.Sprintf("Environment variable %s is not set and no fallback provided", key))
@@ -402,6 +747,16 @@ func getEnvAsInt(key string, fallback *int) int {
 	return *fallback
 }
 
+// timeoutGroup registers routes that must finish within requestTimeout. The timeout
+// middleware cancels the request context and answers 504, which would cut off
+// WebSocket connections and streamed responses, so those are registered outside.
+func timeoutGroup(r chi.Router, routes func(r chi.Router)) {
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.Timeout(requestTimeout))
+		routes(r)
+	})
+}
+
 // LoadAPNSPrivateKey loads an APNS private key from a file path or from base64-encoded environment variable
 func LoadAPNSPrivateKey(source string) ([]byte, error) {
 	// Check if the source is a file path
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/apiversion"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/cors"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/handler/auth"
+	blockhandler "github.com/bulatminnakhmetov/brigadka-backend/internal/handler/block"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/handler/media"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/handler/messaging"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/handler/profile"
+	pushhandler "github.com/bulatminnakhmetov/brigadka-backend/internal/handler/push"
+	reporthandler "github.com/bulatminnakhmetov/brigadka-backend/internal/handler/report"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/metrics"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/presence"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/realip"
+)
+
+func TestGetEnvAsInt_InvalidValueFailsStartup(t *testing.T) {
+	t.Setenv("DB_PORT", "54x32")
+
+	assert.PanicsWithValue(t, `Environment variable DB_PORT is not a valid integer: "54x32"`, func() {
+		getEnvAsInt("DB_PORT", ptr(5432))
+	})
+}
+
+func TestGetEnvAsInt_UsesValueOrFallback(t *testing.T) {
+	t.Setenv("DB_PORT", "6543")
+	assert.Equal(t, 6543, getEnvAsInt("DB_PORT", ptr(5432)))
+
+	assert.Equal(t, 5432, getEnvAsInt("UNSET_TEST_PORT", ptr(5432)))
+}
+
+func TestGetEnvAsDuration_InvalidValueFailsStartup(t *testing.T) {
+	t.Setenv("ACCESS_TOKEN_TTL", "15 minutes")
+
+	assert.Panics(t, func() {
+		getEnvAsDuration("ACCESS_TOKEN_TTL", ptr(15*time.Minute))
+	})
+}
+
+func TestGetEnvAsBool_InvalidValueFailsStartup(t *testing.T) {
+	t.Setenv("WS_ALLOW_ANY_ORIGIN", "yes please")
+
+	assert.PanicsWithValue(t, `Environment variable WS_ALLOW_ANY_ORIGIN is not a valid boolean: "yes please"`, func() {
+		getEnvAsBool("WS_ALLOW_ANY_ORIGIN", ptr(false))
+	})
+}
+
+func TestValidateJWTSecret_ShortSecretFailsInProduction(t *testing.T) {
+	err := validateJWTSecret("too-short", "production")
+
+	assert.ErrorContains(t, err, "at least 32 bytes")
+}
+
+func TestValidateJWTSecret(t *testing.T) {
+	long := strings.Repeat("s", minJWTSecretLength)
+
+	assert.NoError(t, validateJWTSecret(long, "production"))
+	assert.NoError(t, validateJWTSecret(long, "staging"))
+	// Local setups may use a short secret
+	assert.NoError(t, validateJWTSecret("dev-secret", appEnvDevelopment))
+	assert.Error(t, validateJWTSecret("", appEnvDevelopment))
+	assert.Error(t, validateJWTSecret(long[1:], "staging"))
+}
+
+func TestAppEnvironment_DefaultsToProduction(t *testing.T) {
+	t.Setenv("APP_ENV", "")
+	os.Unsetenv("APP_ENV")
+
+	// A deployment that forgets APP_ENV still gets the secret check
+	assert.Equal(t, appEnvProduction, appEnvironment())
+	assert.Error(t, validateJWTSecret("too-short", appEnvironment()))
+
+	t.Setenv("APP_ENV", appEnvDevelopment)
+	assert.Equal(t, appEnvDevelopment, appEnvironment())
+}
+
+// testRouterDeps wires newRouter with handlers that are never invoked
+func testRouterDeps(t *testing.T) routerDeps {
+	trustedProxies, err := realip.NewTrustedProxies(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authHandler := &auth.AuthHandler{}
+	noop := func(w http.ResponseWriter, r *http.Request) {}
+
+	return routerDeps{
+		authHandler:      authHandler,
+		adminMiddleware:  authHandler.AdminMiddleware(nil),
+		profileHandler:   &profile.ProfileHandler{},
+		mediaHandler:     &media.MediaHandler{},
+		messagingHandler: &messaging.Handler{},
+		pushHandler:      &pushhandler.Handler{},
+		blockHandler:     &blockhandler.Handler{},
+		reportHandler:    &reporthandler.Handler{},
+		presenceTracker:  presence.NewTracker(nil, 0),
+		trustedProxies:   trustedProxies,
+		corsAllowlist:    cors.NewAllowlist(nil),
+		metrics:          metrics.New(),
+		health:           noop,
+		healthDetails:    noop,
+	}
+}
+
+// walkRoutes calls fn for every route with the middlewares it runs through.
+// Unlike chi.Walk it keeps the middlewares of the group a subrouter is mounted in.
+func walkRoutes(routes chi.Routes, prefix string, parent chi.Middlewares, fn func(method, route string, middlewares chi.Middlewares)) {
+	for _, route := range routes.Routes() {
+		middlewares := append(append(chi.Middlewares{}, parent...), routes.Middlewares()...)
+
+		if route.SubRoutes != nil {
+			for _, handler := range route.Handlers {
+				if chain, ok := handler.(*chi.ChainHandler); ok {
+					middlewares = append(middlewares, chain.Middlewares...)
+					break
+				}
+			}
+			walkRoutes(route.SubRoutes, prefix+strings.TrimSuffix(route.Pattern, "/*"), middlewares, fn)
+			continue
+		}
+
+		for method, handler := range route.Handlers {
+			if method == "*" {
+				continue
+			}
+			routeMiddlewares := middlewares
+			if chain, ok := handler.(*chi.ChainHandler); ok {
+				routeMiddlewares = append(append(chi.Middlewares{}, middlewares...), chain.Middlewares...)
+			}
+			fn(method, prefix+route.Pattern, routeMiddlewares)
+		}
+	}
+}
+
+func TestNewRouter_LongLivedRoutesAreNotBounded(t *testing.T) {
+	router := newRouter(testRouterDeps(t))
+
+	longLived := map[string]bool{
+		"/api/" + apiversion.Current + "/ws/chat": true,
+		"/api/ws/chat": true,
+	}
+
+	seen := 0
+	walkRoutes(router, "", nil, func(method, route string, middlewares chi.Middlewares) {
+		// Probe each middleware on the route for the one that sets a deadline
+		bounded := false
+		probe := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := r.Context().Deadline(); ok {
+				bounded = true
+			}
+		})
+		for _, mw := range middlewares {
+			mw(probe).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, "/", nil))
+		}
+
+		if longLived[route] {
+			seen++
+			assert.False(t, bounded, "%s %s must not be timed out", method, route)
+		} else {
+			assert.True(t, bounded, "%s %s is not bounded by the request timeout", method, route)
+		}
+	})
+
+	assert.Positive(t, seen, "the WebSocket route is registered")
+}
+
+func TestNewRouter_ServesSentMessageLocation(t *testing.T) {
+	router := newRouter(testRouterDeps(t))
+
+	// SendMessage points Location at the single message route of the current version
+	location := "/api/" + apiversion.Current + "/chats/chat-1/messages/msg-1"
+	assert.True(t, router.Match(chi.NewRouteContext(), http.MethodGet, location))
+}
+
+func TestNewServer_AppliesConfiguredLimits(t *testing.T) {
+	handler := http.NewServeMux()
+	server := newServer(":9090", handler, serverConfig{
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		IdleTimeout:       90 * time.Second,
+		MaxHeaderBytes:    8 << 10,
+	})
+
+	assert.Equal(t, ":9090", server.Addr)
+	assert.Equal(t, handler, server.Handler)
+	assert.Equal(t, 5*time.Second, server.ReadHeaderTimeout)
+	assert.Equal(t, 30*time.Second, server.ReadTimeout)
+	assert.Equal(t, 90*time.Second, server.IdleTimeout)
+	assert.Equal(t, 8<<10, server.MaxHeaderBytes)
+}
+
+func TestNewServer_DefaultsBoundHeaders(t *testing.T) {
+	server := newServer(":8080", http.NewServeMux(), serverConfig{
+		ReadHeaderTimeout: getEnvAsDuration("SERVER_READ_HEADER_TIMEOUT_UNSET", ptr(defaultReadHeaderTimeout)),
+		MaxHeaderBytes:    getEnvAsInt("SERVER_MAX_HEADER_BYTES_UNSET", ptr(defaultMaxHeaderBytes)),
+	})
+
+	// A zero value would leave the server open to slowloris and oversized headers
+	assert.Positive(t, server.ReadHeaderTimeout)
+	assert.Positive(t, server.MaxHeaderBytes)
+	assert.Less(t, server.MaxHeaderBytes, http.DefaultMaxHeaderBytes)
+}
@@ -0,0 +1,12 @@
+-- Restore globally unique message IDs
+ALTER TABLE message_reactions DROP CONSTRAINT message_reactions_chat_id_message_id_user_id_reaction_code_key;
+ALTER TABLE message_reactions DROP CONSTRAINT message_reactions_message_fkey;
+ALTER TABLE message_reactions DROP COLUMN chat_id;
+
+ALTER TABLE messages DROP CONSTRAINT messages_pkey;
+ALTER TABLE messages ADD CONSTRAINT messages_pkey PRIMARY KEY (id);
+
+ALTER TABLE message_reactions ADD CONSTRAINT message_reactions_message_id_fkey
+	FOREIGN KEY (message_id) REFERENCES messages(id) ON DELETE CASCADE;
+ALTER TABLE message_reactions ADD CONSTRAINT message_reactions_message_id_user_id_reaction_code_key
+	UNIQUE (message_id, user_id, reaction_code);
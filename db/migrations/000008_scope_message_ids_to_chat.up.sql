@@ -0,0 +1,22 @@
+-- Message IDs are client-generated and only need to be unique within a chat
+ALTER TABLE message_reactions DROP CONSTRAINT message_reactions_message_id_fkey;
+ALTER TABLE message_reactions DROP CONSTRAINT message_reactions_message_id_user_id_reaction_code_key;
+
+ALTER TABLE messages DROP CONSTRAINT messages_pkey;
+ALTER TABLE messages ADD CONSTRAINT messages_pkey PRIMARY KEY (chat_id, id);
+
+-- Reactions reference messages by chat and message ID
+ALTER TABLE message_reactions ADD COLUMN chat_id UUID;
+
+UPDATE message_reactions mr
+SET chat_id = m.chat_id
+FROM messages m
+WHERE m.id = mr.message_id;
+
+DELETE FROM message_reactions WHERE chat_id IS NULL;
+
+ALTER TABLE message_reactions ALTER COLUMN chat_id SET NOT NULL;
+ALTER TABLE message_reactions ADD CONSTRAINT message_reactions_message_fkey
+	FOREIGN KEY (chat_id, message_id) REFERENCES messages(chat_id, id) ON DELETE CASCADE;
+ALTER TABLE message_reactions ADD CONSTRAINT message_reactions_chat_id_message_id_user_id_reaction_code_key
+	UNIQUE (chat_id, message_id, user_id, reaction_code);
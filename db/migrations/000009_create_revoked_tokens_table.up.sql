@@ -0,0 +1,9 @@
+-- Create revoked tokens table
+CREATE TABLE revoked_tokens (
+    jti VARCHAR(64) PRIMARY KEY,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create index on expiry for cleaning up expired entries
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
@@ -0,0 +1,2 @@
+-- Drop message edit timestamps
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
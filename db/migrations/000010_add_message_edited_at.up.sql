@@ -0,0 +1,2 @@
+-- Record when a message was last edited by its sender
+ALTER TABLE messages ADD COLUMN edited_at TIMESTAMPTZ;
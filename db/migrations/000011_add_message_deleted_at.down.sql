@@ -0,0 +1,8 @@
+-- Tombstones have no content left, drop them with the column
+DELETE FROM messages WHERE deleted_at IS NOT NULL;
+
+ALTER TABLE messages DROP CONSTRAINT messages_content_check;
+ALTER TABLE messages ADD CONSTRAINT messages_content_check
+	CHECK (LENGTH(TRIM(content)) > 0);
+
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,6 @@
+-- Deleted messages keep their row as a tombstone with the content cleared
+ALTER TABLE messages ADD COLUMN deleted_at TIMESTAMPTZ;
+
+ALTER TABLE messages DROP CONSTRAINT messages_content_check;
+ALTER TABLE messages ADD CONSTRAINT messages_content_check
+	CHECK (deleted_at IS NOT NULL OR LENGTH(TRIM(content)) > 0);
@@ -0,0 +1,5 @@
+-- Pending media never received its file
+DELETE FROM media WHERE status = 'pending';
+
+ALTER TABLE media DROP COLUMN IF EXISTS object_key;
+ALTER TABLE media DROP COLUMN IF EXISTS status;
@@ -0,0 +1,6 @@
+-- Media uploaded directly to storage stays pending until the client reports completion
+ALTER TABLE media ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'ready'
+	CHECK (status IN ('pending', 'ready'));
+
+-- Storage key of the uploaded object, used to check that a direct upload landed
+ALTER TABLE media ADD COLUMN object_key TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,4 @@
+-- Disappearing messages are removed by the expiry sweeper once expires_at has passed
+ALTER TABLE messages ADD COLUMN expires_at TIMESTAMPTZ;
+
+CREATE INDEX idx_messages_expires_at ON messages(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,8 @@
+-- Storage objects of deleted media that could not be removed and are retried later
+CREATE TABLE media_cleanup_queue (
+	id SERIAL PRIMARY KEY,
+	object_key TEXT NOT NULL CHECK (LENGTH(TRIM(object_key)) > 0),
+	queued_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_media_cleanup_queue_queued_at ON media_cleanup_queue(queued_at);
@@ -0,0 +1 @@
+ALTER TABLE profile_media DROP COLUMN IF EXISTS position;
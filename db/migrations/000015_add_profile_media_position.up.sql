@@ -0,0 +1,11 @@
+-- Profile videos form an ordered gallery
+ALTER TABLE profile_media ADD COLUMN position INT NOT NULL DEFAULT 0;
+
+-- Keep the previous order of existing media
+UPDATE profile_media pm
+SET position = ranked.position
+FROM (
+	SELECT user_id, media_id, ROW_NUMBER() OVER (PARTITION BY user_id, role ORDER BY media_id) - 1 AS position
+	FROM profile_media
+) ranked
+WHERE pm.user_id = ranked.user_id AND pm.media_id = ranked.media_id;
@@ -0,0 +1,2 @@
+ALTER TABLE cities DROP COLUMN IF EXISTS longitude;
+ALTER TABLE cities DROP COLUMN IF EXISTS latitude;
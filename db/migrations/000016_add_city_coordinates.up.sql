@@ -0,0 +1,6 @@
+-- City centre coordinates for proximity search
+ALTER TABLE cities ADD COLUMN latitude DOUBLE PRECISION;
+ALTER TABLE cities ADD COLUMN longitude DOUBLE PRECISION;
+
+UPDATE cities SET latitude = 55.7558, longitude = 37.6173 WHERE name = 'Москва';
+UPDATE cities SET latitude = 59.9343, longitude = 30.3351 WHERE name = 'Санкт-Петербург';
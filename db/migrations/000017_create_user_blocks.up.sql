@@ -0,0 +1,11 @@
+-- Users a user has blocked. A block hides both users from each other's searches
+-- and keeps them out of the same chats.
+CREATE TABLE user_blocks (
+	blocker_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	blocked_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (blocker_id, blocked_id),
+	CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX idx_user_blocks_blocked_id ON user_blocks(blocked_id);
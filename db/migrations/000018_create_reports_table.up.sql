@@ -0,0 +1,17 @@
+-- Moderators can read reports; everyone else can only file them
+ALTER TABLE users ADD COLUMN is_admin BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Reports of abusive profiles, messages and users. A reporter has at most one
+-- report per target; reporting again replaces the reason.
+CREATE TABLE reports (
+	id SERIAL PRIMARY KEY,
+	reporter_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	target_type TEXT NOT NULL CHECK (target_type IN ('profile', 'message', 'user')),
+	target_id TEXT NOT NULL,
+	reason TEXT NOT NULL CHECK (LENGTH(TRIM(reason)) > 0),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (reporter_id, target_type, target_id)
+);
+
+CREATE INDEX idx_reports_updated_at ON reports(updated_at DESC);
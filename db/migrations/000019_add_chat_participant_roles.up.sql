@@ -0,0 +1,15 @@
+-- Admins manage group chat membership and the chat name
+ALTER TABLE chat_participants ADD COLUMN role TEXT NOT NULL DEFAULT 'member'
+	CHECK (role IN ('admin', 'member'));
+
+-- Creators were not recorded, so the first participant of each group chat becomes its admin
+UPDATE chat_participants cp
+SET role = 'admin'
+FROM (
+	SELECT DISTINCT ON (p.chat_id) p.chat_id, p.user_id
+	FROM chat_participants p
+	JOIN chats c ON c.id = p.chat_id
+	WHERE c.is_group
+	ORDER BY p.chat_id, p.joined_at, p.user_id
+) first_participant
+WHERE cp.chat_id = first_participant.chat_id AND cp.user_id = first_participant.user_id;
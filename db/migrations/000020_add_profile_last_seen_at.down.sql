@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_profiles_last_seen_at;
+ALTER TABLE profiles DROP COLUMN IF EXISTS last_seen_at;
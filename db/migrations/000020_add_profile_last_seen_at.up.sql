@@ -0,0 +1,4 @@
+-- When the user was last active, updated on authenticated requests and WebSocket connections
+ALTER TABLE profiles ADD COLUMN last_seen_at TIMESTAMPTZ;
+
+CREATE INDEX idx_profiles_last_seen_at ON profiles(last_seen_at DESC NULLS LAST);
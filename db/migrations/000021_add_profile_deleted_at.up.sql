@@ -0,0 +1,2 @@
+-- Soft-deleted profiles are hidden from other users and can be restored by the owner
+ALTER TABLE profiles ADD COLUMN deleted_at TIMESTAMPTZ;
@@ -0,0 +1,5 @@
+-- When the profile last changed, used by clients for caching
+ALTER TABLE profiles ADD COLUMN updated_at TIMESTAMPTZ;
+UPDATE profiles SET updated_at = COALESCE(created_at, CURRENT_TIMESTAMP);
+ALTER TABLE profiles ALTER COLUMN updated_at SET NOT NULL;
+ALTER TABLE profiles ALTER COLUMN updated_at SET DEFAULT CURRENT_TIMESTAMP;
@@ -0,0 +1,2 @@
+-- Drop invite codes table
+DROP TABLE IF EXISTS invite_codes;
@@ -0,0 +1,7 @@
+-- Single-use invite codes for registration in invite-only mode
+CREATE TABLE invite_codes (
+    code VARCHAR(64) PRIMARY KEY,
+    created_by INT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    used_at TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Drop password resets table
+DROP TABLE IF EXISTS password_resets;
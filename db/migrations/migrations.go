@@ -0,0 +1,10 @@
+// Package migrations embeds the SQL schema migrations so the service can apply
+// them without the files being present on disk
+package migrations
+
+import "embed"
+
+// FS holds the numbered up and down migrations
+//
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,43 @@
+package migrations
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFS_EveryVersionHasUpAndDown(t *testing.T) {
+	source, err := iofs.New(FS, ".")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer source.Close()
+
+	version, err := source.First()
+	assert.NoError(t, err)
+
+	count := 0
+	for {
+		count++
+		up, _, err := source.ReadUp(version)
+		assert.NoError(t, err, "version %d has no up migration", version)
+		up.Close()
+		down, _, err := source.ReadDown(version)
+		assert.NoError(t, err, "version %d has no down migration", version)
+		down.Close()
+
+		next, err := source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		assert.NoError(t, err)
+		// Versions are numbered without gaps
+		assert.Equal(t, version+1, next)
+		version = next
+	}
+
+	assert.Greater(t, count, 1)
+}
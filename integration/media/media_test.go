@@ -298,8 +298,8 @@ func (s *MediaIntegrationTestSuite) TestUploadInvalidFile() {
 	assert.NoError(t, err)
 	defer resp.Body.Close()
 
-	// Should return bad request for invalid file type
-	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Should return status 400 Bad Request")
+	// Should reject the detected file type
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode, "Should return status 415 Unsupported Media Type")
 }
 
 // TestUploadNoFile tests submitting a request without a file
@@ -40,6 +40,7 @@ type MessagingIntegrationTestSuite struct {
 type messagingRequest struct {
 	MessageID string `json:"message_id"`
 	Content   string `json:"content"`
+	ExpiresIn int    `json:"expires_in,omitempty"`
 }
 
 // Chat creation request
@@ -79,8 +80,8 @@ func (s *MessagingIntegrationTestSuite) createTestUser() (int, string, error) {
 	return user.UserID, user.Token, err
 }
 
-// Helper function to create a chat
-func (s *MessagingIntegrationTestSuite) createChat(token string, chatID string, chatName string, participants []int) error {
+// Helper function to create a chat, returns the ID of the chat
+func (s *MessagingIntegrationTestSuite) createChat(token string, chatID string, chatName string, participants []int) (string, error) {
 	createChatReq := createChatRequest{
 		ChatID:       chatID,
 		ChatName:     chatName,
@@ -95,23 +96,28 @@ func (s *MessagingIntegrationTestSuite) createChat(token string, chatID string,
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to create chat: %v", err)
+		return "", fmt.Errorf("failed to create chat: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("failed to create chat. Status: %d, Body: %s", resp.StatusCode, string(body))
+		return "", fmt.Errorf("failed to create chat. Status: %d, Body: %s", resp.StatusCode, string(body))
 	}
 
-	return nil
+	var response map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		return "", fmt.Errorf("failed to decode chat: %v", err)
+	}
+	return response["chat_id"], nil
 }
 
-// Helper to create users and a chat for testing
+// Helper to create users and a group chat for testing. A chat of two is a direct
+// chat with a server-assigned ID, so three users are created.
 func (s *MessagingIntegrationTestSuite) setupUsersAndChat() ([]testUser, string, error) {
 	// Create test users
-	testUsers := make([]testUser, 2)
-	for i := 0; i < 2; i++ {
+	testUsers := make([]testUser, 3)
+	for i := range testUsers {
 		userID, token, err := s.createTestUser()
 		if err != nil {
 			return nil, "", fmt.Errorf("failed to create test user: %v", err)
@@ -121,7 +127,7 @@ func (s *MessagingIntegrationTestSuite) setupUsersAndChat() ([]testUser, string,
 
 	// Create a chat between the test users
 	chatID := uuid.NewString()
-	err := s.createChat(testUsers[0].Token, chatID, "Test Chat", []int{testUsers[0].UserID, testUsers[1].UserID})
+	_, err := s.createChat(testUsers[0].Token, chatID, "Test Chat", []int{testUsers[0].UserID, testUsers[1].UserID, testUsers[2].UserID})
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to create test chat: %v", err)
 	}
@@ -146,6 +152,8 @@ func (s *MessagingIntegrationTestSuite) TestCreateChat() {
 	assert.NoError(t, err, "Failed to create first test user")
 	user2ID, _, err := s.createTestUser()
 	assert.NoError(t, err, "Failed to create second test user")
+	user3ID, _, err := s.createTestUser()
+	assert.NoError(t, err, "Failed to create third test user")
 
 	// Create a unique chat ID
 	chatID := uuid.NewString()
@@ -155,7 +163,7 @@ func (s *MessagingIntegrationTestSuite) TestCreateChat() {
 	createChatReq := createChatRequest{
 		ChatID:       chatID,
 		ChatName:     chatName,
-		Participants: []int{user1ID, user2ID},
+		Participants: []int{user1ID, user2ID, user3ID},
 	}
 
 	createChatJSON, _ := json.Marshal(createChatReq)
@@ -178,6 +186,24 @@ func (s *MessagingIntegrationTestSuite) TestCreateChat() {
 	assert.Equal(t, chatID, response["chat_id"], "Returned chat ID should match")
 }
 
+// TestCreateChatOfTwoReusesDirectChat tests that a chat of two people is their single direct chat
+func (s *MessagingIntegrationTestSuite) TestCreateChatOfTwoReusesDirectChat() {
+	t := s.T()
+
+	user1ID, user1Token, err := s.createTestUser()
+	assert.NoError(t, err, "Failed to create first test user")
+	user2ID, _, err := s.createTestUser()
+	assert.NoError(t, err, "Failed to create second test user")
+
+	firstID, err := s.createChat(user1Token, uuid.NewString(), "Pair", []int{user2ID})
+	assert.NoError(t, err)
+	secondID, err := s.createChat(user1Token, uuid.NewString(), "Pair again", []int{user1ID, user2ID})
+	assert.NoError(t, err)
+
+	assert.NotEmpty(t, firstID)
+	assert.Equal(t, firstID, secondID, "The pair's direct chat should be reused")
+}
+
 // TestCreateChatUnauthorized tests creating a chat without authentication
 func (s *MessagingIntegrationTestSuite) TestCreateChatUnauthorized() {
 	t := s.T()
@@ -237,7 +263,7 @@ func (s *MessagingIntegrationTestSuite) TestSendAndGetMessages() {
 	defer sendResp.Body.Close()
 
 	// Check send response
-	assert.Equal(t, http.StatusOK, sendResp.StatusCode, "Should return status 200 OK")
+	assert.Equal(t, http.StatusCreated, sendResp.StatusCode, "Should return status 201 Created")
 
 	// Get messages from the chat
 	getReq, _ := http.NewRequest("GET", fmt.Sprintf("%s/api/chats/%s/messages", s.appUrl, chatID), nil)
@@ -268,6 +294,51 @@ func (s *MessagingIntegrationTestSuite) TestSendAndGetMessages() {
 	assert.True(t, messageFound, "The sent message should be retrieved")
 }
 
+// TestDisappearingMessageExcludedAfterExpiry tests that an expired message is no longer listed
+func (s *MessagingIntegrationTestSuite) TestDisappearingMessageExcludedAfterExpiry() {
+	t := s.T()
+
+	testUsers, chatID, err := s.setupUsersAndChat()
+	assert.NoError(t, err, "Failed to setup users and chat")
+
+	messageID := generateMessageID()
+	sendMsgJSON, _ := json.Marshal(messagingRequest{
+		MessageID: messageID,
+		Content:   "This message will disappear",
+		ExpiresIn: 1,
+	})
+	sendReq, _ := http.NewRequest("POST", fmt.Sprintf("%s/api/chats/%s/messages", s.appUrl, chatID), bytes.NewBuffer(sendMsgJSON))
+	sendReq.Header.Set("Content-Type", "application/json")
+	sendReq.Header.Set("Authorization", "Bearer "+testUsers[0].Token)
+
+	client := &http.Client{}
+	sendResp, err := client.Do(sendReq)
+	assert.NoError(t, err)
+	defer sendResp.Body.Close()
+	assert.Equal(t, http.StatusCreated, sendResp.StatusCode, "Should return status 201 Created")
+
+	var sent map[string]interface{}
+	assert.NoError(t, json.NewDecoder(sendResp.Body).Decode(&sent))
+	assert.NotEmpty(t, sent["expires_at"], "A disappearing message should report its expiry")
+
+	// Wait for the message to expire
+	time.Sleep(1500 * time.Millisecond)
+
+	getReq, _ := http.NewRequest("GET", fmt.Sprintf("%s/api/chats/%s/messages", s.appUrl, chatID), nil)
+	getReq.Header.Set("Authorization", "Bearer "+testUsers[0].Token)
+
+	getResp, err := client.Do(getReq)
+	assert.NoError(t, err)
+	defer getResp.Body.Close()
+	assert.Equal(t, http.StatusOK, getResp.StatusCode, "Should return status 200 OK")
+
+	var messages []map[string]interface{}
+	assert.NoError(t, json.NewDecoder(getResp.Body).Decode(&messages))
+	for _, msg := range messages {
+		assert.NotEqual(t, messageID, msg["message_id"], "An expired message should not be listed")
+	}
+}
+
 // TestGetUserChats tests retrieving a user's chats
 func (s *MessagingIntegrationTestSuite) TestGetUserChats() {
 	t := s.T()
@@ -376,7 +447,7 @@ func (s *MessagingIntegrationTestSuite) sendTestMessage(token string, chatID str
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
+	if resp.StatusCode != http.StatusCreated {
 		body, _ := io.ReadAll(resp.Body)
 		return "", fmt.Errorf("failed to send message. Status: %d, Body: %s", resp.StatusCode, string(body))
 	}
@@ -568,6 +568,50 @@ func (s *ProfileSearchTestSuite) TestSearchByVideo() {
 	}
 }
 
+// TestSearchByMedia tests searching profiles by the presence of any media
+func (s *ProfileSearchTestSuite) TestSearchByMedia() {
+	t := s.T()
+
+	templates := []ProfileTemplate{
+		{
+			FullName:     "Media Owner",
+			BirthYear:    1995,
+			Gender:       "female",
+			CityID:       1,
+			Goal:         "hobby",
+			ImprovStyles: []string{"shortform"},
+			HasAvatar:    true,
+		},
+		{
+			FullName:     "No Media",
+			BirthYear:    1995,
+			Gender:       "male",
+			CityID:       1,
+			Goal:         "hobby",
+			ImprovStyles: []string{"shortform"},
+		},
+	}
+	_, createdAfter := s.createTestProfiles(t, templates)
+
+	// Without the filter both profiles are found
+	filter := map[string]interface{}{
+		"created_after": createdAfter,
+		"page":          1,
+		"page_size":     10,
+	}
+
+	result, err := s.executeSearch(filter)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(result.Profiles))
+
+	filter["has_media"] = true
+	result, err = s.executeSearch(filter)
+	assert.NoError(t, err)
+	if assert.Equal(t, 1, len(result.Profiles)) {
+		assert.Equal(t, "Media Owner", result.Profiles[0].FullName)
+	}
+}
+
 // TestCombinedFilters tests searching with multiple filters combined
 func (s *ProfileSearchTestSuite) TestCombinedFilters() {
 	t := s.T()
@@ -173,6 +173,7 @@ func (s *StyleMatchOrderingTestSuite) TestStyleMatchOrdering() {
 	// Execute search with no additional filters
 	filter := map[string]interface{}{
 		"created_after": s.createdAt,
+		"sort_by":       "relevance",
 		"page":          1,
 		"page_size":     10,
 	}
@@ -243,6 +244,7 @@ func (s *StyleMatchOrderingTestSuite) TestStyleMatchOrderingWithFilters() {
 	filter := map[string]interface{}{
 		"genders":       []string{"female"},
 		"created_after": s.createdAt,
+		"sort_by":       "relevance",
 		"page":          1,
 		"page_size":     10,
 	}
@@ -300,6 +302,7 @@ func (s *StyleMatchOrderingTestSuite) TestCreationDateSecondaryOrdering() {
 	// Execute search
 	filter := map[string]interface{}{
 		"created_after": s.createdAt,
+		"sort_by":       "relevance",
 		"page":          1,
 		"page_size":     10,
 	}
@@ -329,6 +332,45 @@ func (s *StyleMatchOrderingTestSuite) TestCreationDateSecondaryOrdering() {
 	assert.Less(t, pos2, pos1, "Middle profile should come before oldest profile")
 }
 
+// TestDefaultOrderingIsNewestFirst tests that without sort_by style matches don't affect the order
+func (s *StyleMatchOrderingTestSuite) TestDefaultOrderingIsNewestFirst() {
+	t := s.T()
+
+	// Created from most to least matching, so newest first is the reverse of relevance
+	threeMatchesID := s.createProfileWithStyles(t, "Three Matches Default", []string{"shortform", "longform", "musical"}, "male")
+	time.Sleep(1 * time.Second)
+	oneMatchID := s.createProfileWithStyles(t, "One Match Default", []string{"shortform", "absurd", "realistic"}, "male")
+	time.Sleep(1 * time.Second)
+	noMatchesID := s.createProfileWithStyles(t, "No Matches Default", []string{"absurd", "realistic", "playback"}, "male")
+
+	filter := map[string]interface{}{
+		"created_after": s.createdAt,
+		"page":          1,
+		"page_size":     10,
+	}
+
+	result, err := s.executeSearch(filter)
+	assert.NoError(t, err)
+
+	orderedUserIDs := make([]int, 0, len(result.Profiles))
+	for _, p := range result.Profiles {
+		if p.UserID != s.userID { // Exclude the searcher's profile
+			orderedUserIDs = append(orderedUserIDs, p.UserID)
+		}
+	}
+
+	threeMatchesPos := indexOf(threeMatchesID, orderedUserIDs)
+	oneMatchPos := indexOf(oneMatchID, orderedUserIDs)
+	noMatchesPos := indexOf(noMatchesID, orderedUserIDs)
+
+	assert.NotEqual(t, -1, threeMatchesPos)
+	assert.NotEqual(t, -1, oneMatchPos)
+	assert.NotEqual(t, -1, noMatchesPos)
+
+	assert.Less(t, noMatchesPos, oneMatchPos, "Newest profile should come first")
+	assert.Less(t, oneMatchPos, threeMatchesPos, "Oldest profile should come last")
+}
+
 // Helper function to update a profile's gender
 func (s *StyleMatchOrderingTestSuite) updateProfileGender(t *testing.T, userID int, gender string) {
 	// Register a user for this profile
@@ -0,0 +1,61 @@
+package apiversion
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// Current is the API version served under the unversioned /api alias
+const Current = "v1"
+
+// contextKey is unexported so keys never collide with other packages
+type contextKey string
+
+const versionKey contextKey = "api_version"
+
+// WithVersion returns a copy of ctx carrying the requested API version
+func WithVersion(ctx context.Context, version string) context.Context {
+	return context.WithValue(ctx, versionKey, version)
+}
+
+// FromContext returns the requested API version, if any
+func FromContext(ctx context.Context) (string, bool) {
+	version, ok := ctx.Value(versionKey).(string)
+	return version, ok
+}
+
+// Middleware records the API version of the request and echoes it in the API-Version header
+func Middleware(version string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("API-Version", version)
+			next.ServeHTTP(w, r.WithContext(WithVersion(r.Context(), version)))
+		})
+	}
+}
+
+// deprecatedAlias marks responses served through the unversioned alias
+func deprecatedAlias(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Mount registers the routes of a version under /api/{version}. Routes of the current
+// version are also served under /api until clients move to the versioned prefix.
+func Mount(r chi.Router, version string, routes func(r chi.Router)) {
+	r.Route("/api/"+version, func(r chi.Router) {
+		r.Use(Middleware(version))
+		routes(r)
+	})
+
+	if version == Current {
+		r.Route("/api", func(r chi.Router) {
+			r.Use(deprecatedAlias, Middleware(version))
+			routes(r)
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package apiversion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRouter() *chi.Mux {
+	r := chi.NewRouter()
+	Mount(r, Current, func(r chi.Router) {
+		r.Get("/profiles/{userID}", func(w http.ResponseWriter, r *http.Request) {
+			version, _ := FromContext(r.Context())
+			w.Write([]byte(version + ":" + chi.URLParam(r, "userID")))
+		})
+	})
+	return r
+}
+
+func TestMount_VersionedAndAliasResolveToSameHandler(t *testing.T) {
+	r := newTestRouter()
+
+	for _, path := range []string{"/api/v1/profiles/42", "/api/profiles/42"} {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, path, nil))
+
+		assert.Equal(t, http.StatusOK, rr.Code, path)
+		assert.Equal(t, "v1:42", rr.Body.String(), path)
+		assert.Equal(t, "v1", rr.Header().Get("API-Version"), path)
+	}
+}
+
+func TestMount_OnlyAliasIsDeprecated(t *testing.T) {
+	r := newTestRouter()
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/profiles/42", nil))
+	assert.Empty(t, rr.Header().Get("Deprecation"))
+
+	rr = httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/profiles/42", nil))
+	assert.Equal(t, "true", rr.Header().Get("Deprecation"))
+}
+
+func TestMount_UnknownVersionIsNotFound(t *testing.T) {
+	r := newTestRouter()
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v2/profiles/42", nil))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
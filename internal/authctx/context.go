@@ -0,0 +1,47 @@
+package authctx
+
+import (
+	"context"
+	"net/http"
+
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+)
+
+// contextKey is unexported so keys never collide with other packages
+type contextKey string
+
+const (
+	userIDKey contextKey = "user_id"
+	emailKey  contextKey = "email"
+)
+
+// WithUserID returns a copy of ctx carrying the authenticated user ID
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userIDKey, userID)
+}
+
+// UserIDFromContext returns the authenticated user ID, if any
+func UserIDFromContext(ctx context.Context) (int, bool) {
+	userID, ok := ctx.Value(userIDKey).(int)
+	return userID, ok
+}
+
+// WithEmail returns a copy of ctx carrying the authenticated user's email
+func WithEmail(ctx context.Context, email string) context.Context {
+	return context.WithValue(ctx, emailKey, email)
+}
+
+// EmailFromContext returns the authenticated user's email, if any
+func EmailFromContext(ctx context.Context) (string, bool) {
+	email, ok := ctx.Value(emailKey).(string)
+	return email, ok
+}
+
+// RequireUserID returns the authenticated user ID or responds with 401 if it is missing
+func RequireUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	userID, ok := UserIDFromContext(r.Context())
+	if !ok {
+		apierrors.RespondError(w, http.StatusUnauthorized, "Unauthorized", apierrors.CodeUnauthorized)
+	}
+	return userID, ok
+}
@@ -0,0 +1,55 @@
+package authctx
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserIDFromContext(t *testing.T) {
+	userID, ok := UserIDFromContext(WithUserID(context.Background(), 42))
+	assert.True(t, ok)
+	assert.Equal(t, 42, userID)
+}
+
+func TestUserIDFromContext_Absent(t *testing.T) {
+	_, ok := UserIDFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestUserIDFromContext_IgnoresStringKey(t *testing.T) {
+	// A raw string key must not be mistaken for ours
+	ctx := context.WithValue(context.Background(), "user_id", 42)
+
+	_, ok := UserIDFromContext(ctx)
+	assert.False(t, ok)
+}
+
+func TestEmailFromContext(t *testing.T) {
+	email, ok := EmailFromContext(WithEmail(context.Background(), "user@example.com"))
+	assert.True(t, ok)
+	assert.Equal(t, "user@example.com", email)
+
+	_, ok = EmailFromContext(context.Background())
+	assert.False(t, ok)
+}
+
+func TestRequireUserID(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	rr := httptest.NewRecorder()
+
+	_, ok := RequireUserID(rr, req)
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+
+	req = req.WithContext(WithUserID(req.Context(), 7))
+	rr = httptest.NewRecorder()
+
+	userID, ok := RequireUserID(rr, req)
+	assert.True(t, ok)
+	assert.Equal(t, 7, userID)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
@@ -0,0 +1,78 @@
+package cors
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// allowedMethods are the methods browsers may use in cross-origin requests
+const allowedMethods = "GET, POST, PUT, PATCH, DELETE, OPTIONS"
+
+// allowedHeaders are the request headers browsers may send in cross-origin requests
+const allowedHeaders = "Authorization, Content-Type"
+
+// preflightMaxAge is how long, in seconds, browsers may cache a preflight response
+const preflightMaxAge = 600
+
+// Allowlist is the set of origins allowed to call the API from a browser
+type Allowlist struct {
+	origins map[string]struct{}
+}
+
+// NewAllowlist creates an allowlist from origins such as "https://app.example.com".
+// Empty entries and trailing slashes are ignored.
+func NewAllowlist(origins []string) *Allowlist {
+	allowlist := &Allowlist{origins: make(map[string]struct{}, len(origins))}
+	for _, origin := range origins {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			allowlist.origins[strings.ToLower(origin)] = struct{}{}
+		}
+	}
+	return allowlist
+}
+
+// Allowed reports whether the origin is on the allowlist
+func (a *Allowlist) Allowed(origin string) bool {
+	_, ok := a.origins[strings.ToLower(origin)]
+	return ok
+}
+
+// CheckOrigin reports whether a WebSocket upgrade request may proceed. Requests
+// without an Origin header don't come from a browser and are allowed.
+func (a *Allowlist) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || a.Allowed(origin)
+}
+
+// Middleware sets the CORS headers for allowed origins and answers preflight requests.
+// Responses to other origins carry no CORS headers, so browsers block them.
+func (a *Allowlist) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if origin == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Responses differ by origin, caches must not share them
+		w.Header().Add("Vary", "Origin")
+		allowed := a.Allowed(origin)
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+		}
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", allowedMethods)
+				w.Header().Set("Access-Control-Allow-Headers", allowedHeaders)
+				w.Header().Set("Access-Control-Max-Age", strconv.Itoa(preflightMaxAge))
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,82 @@
+package cors
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func serve(allowlist *Allowlist, req *http.Request) (*httptest.ResponseRecorder, bool) {
+	called := false
+	handler := allowlist.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr, called
+}
+
+func TestMiddleware_AllowedOriginGetsCredentialedHeaders(t *testing.T) {
+	allowlist := NewAllowlist([]string{"https://app.example.com/", " https://admin.example.com"})
+
+	req := httptest.NewRequest("GET", "/api/profiles/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	rr, called := serve(allowlist, req)
+
+	assert.True(t, called)
+	assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", rr.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", rr.Header().Get("Vary"))
+}
+
+func TestMiddleware_UnknownOriginGetsNoHeaders(t *testing.T) {
+	allowlist := NewAllowlist([]string{"https://app.example.com"})
+
+	req := httptest.NewRequest("GET", "/api/profiles/1", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rr, called := serve(allowlist, req)
+
+	assert.True(t, called)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Credentials"))
+}
+
+func TestMiddleware_AnswersPreflight(t *testing.T) {
+	allowlist := NewAllowlist([]string{"https://app.example.com"})
+
+	req := httptest.NewRequest("OPTIONS", "/api/profiles/1", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "PATCH")
+	req.Header.Set("Access-Control-Request-Headers", "Authorization, Content-Type")
+	rr, called := serve(allowlist, req)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Equal(t, "https://app.example.com", rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, rr.Header().Get("Access-Control-Allow-Methods"), "PATCH")
+	assert.Equal(t, "Authorization, Content-Type", rr.Header().Get("Access-Control-Allow-Headers"))
+
+	// A preflight from an unknown origin is answered without granting anything
+	req.Header.Set("Origin", "https://evil.example.com")
+	rr, called = serve(allowlist, req)
+
+	assert.False(t, called)
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, rr.Header().Get("Access-Control-Allow-Methods"))
+}
+
+func TestCheckOrigin(t *testing.T) {
+	allowlist := NewAllowlist([]string{"https://app.example.com"})
+
+	req := httptest.NewRequest("GET", "/api/ws/chat", nil)
+	assert.True(t, allowlist.CheckOrigin(req), "non-browser clients send no Origin")
+
+	req.Header.Set("Origin", "https://APP.example.com")
+	assert.True(t, allowlist.CheckOrigin(req))
+
+	req.Header.Set("Origin", "https://evil.example.com")
+	assert.False(t, allowlist.CheckOrigin(req))
+}
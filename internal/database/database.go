@@ -1,13 +1,28 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
+	"time"
 
 	_ "github.com/lib/pq"
 )
 
+// Значения по умолчанию для параметров, не заданных в Config
+const (
+	DefaultMaxOpenConns    = 25
+	DefaultMaxIdleConns    = 25
+	DefaultConnMaxLifetime = 30 * time.Minute
+	DefaultConnectTimeout  = 5 * time.Second
+	DefaultConnectAttempts = 5
+	DefaultConnectBackoff  = time.Second
+)
+
+// maxConnectBackoff ограничивает паузу между попытками подключения
+const maxConnectBackoff = 30 * time.Second
+
 // Config содержит настройки подключения к базе данных
 type Config struct {
 	Host     string
@@ -16,13 +31,55 @@ type Config struct {
 	Password string
 	DBName   string
 	SSLMode  string
+
+	// Настройки пула соединений; нулевые значения заменяются значениями по умолчанию
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+
+	// ConnectTimeout ограничивает одну попытку подключения
+	ConnectTimeout time.Duration
+	// ConnectAttempts - сколько раз пробовать подключиться при старте
+	ConnectAttempts int
+	// ConnectBackoff - пауза перед второй попыткой, дальше она удваивается
+	ConnectBackoff time.Duration
+}
+
+// withDefaults возвращает копию конфигурации с заполненными значениями по умолчанию
+func (c Config) withDefaults() Config {
+	if c.MaxOpenConns <= 0 {
+		c.MaxOpenConns = DefaultMaxOpenConns
+	}
+	if c.MaxIdleConns <= 0 {
+		c.MaxIdleConns = DefaultMaxIdleConns
+	}
+	// Простаивающих соединений не может быть больше, чем открытых
+	if c.MaxIdleConns > c.MaxOpenConns {
+		c.MaxIdleConns = c.MaxOpenConns
+	}
+	if c.ConnMaxLifetime <= 0 {
+		c.ConnMaxLifetime = DefaultConnMaxLifetime
+	}
+	if c.ConnectTimeout <= 0 {
+		c.ConnectTimeout = DefaultConnectTimeout
+	}
+	if c.ConnectAttempts <= 0 {
+		c.ConnectAttempts = DefaultConnectAttempts
+	}
+	if c.ConnectBackoff <= 0 {
+		c.ConnectBackoff = DefaultConnectBackoff
+	}
+	return c
 }
 
-// NewConnection устанавливает соединение с базой данных
+// NewConnection устанавливает соединение с базой данных. База может быть еще не готова
+// в момент старта контейнера, поэтому подключение повторяется с растущей паузой.
 func NewConnection(config *Config) (*sql.DB, error) {
+	cfg := config.withDefaults()
+
 	connStr := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		config.Host, config.Port, config.User, config.Password, config.DBName, config.SSLMode,
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s connect_timeout=%d",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode, connectTimeoutSeconds(cfg.ConnectTimeout),
 	)
 
 	db, err := sql.Open("postgres", connStr)
@@ -30,11 +87,45 @@ func NewConnection(config *Config) (*sql.DB, error) {
 		return nil, err
 	}
 
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+
 	// Проверяем соединение
-	if err := db.Ping(); err != nil {
+	ping := func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout)
+		defer cancel()
+		return db.PingContext(ctx)
+	}
+	if err := retry(ping, cfg.ConnectAttempts, cfg.ConnectBackoff, time.Sleep); err != nil {
+		db.Close()
 		return nil, err
 	}
 
-	log.Println("Успешное подключение к базе данных")
+	log.Printf("Успешное подключение к базе данных (max_open_conns=%d, max_idle_conns=%d, conn_max_lifetime=%s)",
+		cfg.MaxOpenConns, cfg.MaxIdleConns, cfg.ConnMaxLifetime)
 	return db, nil
 }
+
+// retry вызывает fn до attempts раз, удваивая паузу между попытками, и возвращает последнюю ошибку
+func retry(fn func() error, attempts int, backoff time.Duration, sleep func(time.Duration)) error {
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		if attempt == attempts {
+			break
+		}
+		log.Printf("Не удалось подключиться к базе данных (попытка %d из %d): %v; повтор через %s", attempt, attempts, err, backoff)
+		sleep(backoff)
+		backoff = min(backoff*2, maxConnectBackoff)
+	}
+	return fmt.Errorf("failed to connect after %d attempts: %w", attempts, err)
+}
+
+// connectTimeoutSeconds переводит таймаут в секунды для connect_timeout; lib/pq принимает только целые секунды
+func connectTimeoutSeconds(timeout time.Duration) int {
+	seconds := int((timeout + time.Second - 1) / time.Second)
+	return max(seconds, 1)
+}
@@ -0,0 +1,57 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry_BacksOffUntilSuccess(t *testing.T) {
+	calls := 0
+	var sleeps []time.Duration
+
+	err := retry(func() error {
+		calls++
+		if calls < 3 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}, 5, time.Second, func(d time.Duration) { sleeps = append(sleeps, d) })
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, sleeps)
+}
+
+func TestRetry_GivesUpAfterAttempts(t *testing.T) {
+	cause := errors.New("connection refused")
+	calls := 0
+	sleeps := 0
+
+	err := retry(func() error {
+		calls++
+		return cause
+	}, 3, 20*time.Second, func(d time.Duration) {
+		sleeps++
+		assert.LessOrEqual(t, d, maxConnectBackoff)
+	})
+
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, 3, calls)
+	// No pause after the last attempt
+	assert.Equal(t, 2, sleeps)
+}
+
+func TestConfigWithDefaults(t *testing.T) {
+	cfg := Config{MaxOpenConns: 10, MaxIdleConns: 50}.withDefaults()
+
+	assert.Equal(t, 10, cfg.MaxOpenConns)
+	assert.Equal(t, 10, cfg.MaxIdleConns)
+	assert.Equal(t, DefaultConnMaxLifetime, cfg.ConnMaxLifetime)
+	assert.Equal(t, DefaultConnectTimeout, cfg.ConnectTimeout)
+	assert.Equal(t, DefaultConnectAttempts, cfg.ConnectAttempts)
+	assert.Equal(t, 1, connectTimeoutSeconds(1500*time.Millisecond/2))
+	assert.Equal(t, 2, connectTimeoutSeconds(1500*time.Millisecond))
+}
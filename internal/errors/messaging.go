@@ -9,4 +9,17 @@ const (
 	ErrorChatAlreadyExistsWithThisID = "chat already exists with this ID"
 	ErrorMessageAlreadyExists        = "message with this ID already exists"
 	ErrorReactionAlreadyExists       = "reaction already exists with this ID"
+	ErrorInvalidMessageCursor        = "invalid message cursor"
+	ErrorMessageNotFound             = "message not found"
+	ErrorNotMessageSender            = "only the sender can change this message"
+	ErrorInvalidMessageExpiry        = "invalid message expiry"
+	ErrorUsersBlocked                = "users have blocked each other"
+	ErrorNotChatAdmin                = "only chat admins can do this"
+	ErrorLastChatAdmin               = "chat must keep at least one admin"
+	ErrorInvalidChatRole             = "invalid chat role"
+	ErrorInvalidChatName             = "invalid chat name"
+	ErrorNotGroupChat                = "only group chats can be renamed"
+	ErrorDirectChatParticipants      = "participants can't be added to a direct chat"
+	ErrorInvalidSearchQuery          = "invalid search query"
+	ErrorAmbiguousMessageID          = "message id is used in several chats"
 )
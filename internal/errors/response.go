@@ -0,0 +1,118 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Machine-readable error codes returned to clients alongside the message
+const (
+	// Generic codes
+	CodeInvalidRequest  = "invalid_request"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodePayloadTooLarge = "payload_too_large"
+	CodeTooManyRequests = "too_many_requests"
+	CodeInternal        = "internal_error"
+
+	// Auth
+	CodeInvalidCredentials        = "invalid_credentials"
+	CodeInvalidToken              = "invalid_token"
+	CodeTokenRequired             = "token_required"
+	CodeEmailAlreadyRegistered    = "email_already_registered"
+	CodeEmailNotVerified          = "email_not_verified"
+	CodeEmailAlreadyVerified      = "email_already_verified"
+	CodeInvalidVerificationToken  = "invalid_verification_token"
+	CodeVerificationEmailCooldown = "verification_email_cooldown"
+	CodeInvalidPasswordReset      = "invalid_password_reset"
+	CodeIncorrectPassword         = "incorrect_password"
+	CodePasswordRequired          = "password_required"
+	CodeTooManyLoginAttempts      = "too_many_login_attempts"
+	CodeUserNotFound              = "user_not_found"
+	CodeInvalidUserID             = "invalid_user_id"
+	CodeRegistrationClosed        = "registration_closed"
+	CodeInvalidInviteCode         = "invalid_invite_code"
+
+	// Blocks
+	CodeCannotBlockSelf = "cannot_block_self"
+	CodeUserBlocked     = "user_blocked"
+
+	// Reports
+	CodeInvalidReportTarget  = "invalid_report_target"
+	CodeInvalidReportReason  = "invalid_report_reason"
+	CodeReportTargetNotFound = "report_target_not_found"
+
+	// Profiles
+	CodeProfileNotFound         = "profile_not_found"
+	CodeProfileAlreadyExists    = "profile_already_exists"
+	CodeUnsupportedActivityType = "unsupported_activity_type"
+	CodeInvalidImprovGoal       = "invalid_improv_goal"
+	CodeInvalidImprovStyle      = "invalid_improv_style"
+	CodeTooManyImprovStyles     = "too_many_improv_styles"
+	CodeTooManyVideos           = "too_many_videos"
+	CodeInvalidGender           = "invalid_gender"
+	CodeInvalidCity             = "invalid_city"
+	CodeCatalogNotFound         = "catalog_not_found"
+	CodeUnsupportedLanguage     = "unsupported_language"
+	CodeInvalidCursor           = "invalid_cursor"
+	CodeInvalidLimit            = "invalid_limit"
+	CodeInvalidMedia            = "invalid_media"
+	CodeInvalidSort             = "invalid_sort"
+	CodeInvalidMediaOrder       = "invalid_media_order"
+	CodeInvalidProximity        = "invalid_proximity"
+	CodeProfileNotDeleted       = "profile_not_deleted"
+	CodeProfileRestoreExpired   = "profile_restore_expired"
+	CodeImprovStylesRequired    = "improv_styles_required"
+
+	// Media
+	CodeFileTooLarge         = "file_too_large"
+	CodeInvalidFileType      = "invalid_file_type"
+	CodeInvalidFile          = "invalid_file"
+	CodeMissingFile          = "missing_file"
+	CodeEmptyFile            = "empty_file"
+	CodeMalformedMultipart   = "malformed_multipart"
+	CodeMediaNotFound        = "media_not_found"
+	CodeNotMediaOwner        = "not_media_owner"
+	CodeUploadNotFound       = "upload_not_found"
+	CodeProfileMediaRequired = "profile_media_required"
+
+	// Messaging
+	CodeChatNotFound           = "chat_not_found"
+	CodeChatAlreadyExists      = "chat_already_exists"
+	CodeCannotChatWithSelf     = "cannot_chat_with_self"
+	CodeParticipantsRequired   = "participants_required"
+	CodeMessageNotFound        = "message_not_found"
+	CodeMessageAlreadyExists   = "message_already_exists"
+	CodeNotMessageSender       = "not_message_sender"
+	CodeReactionAlreadyExists  = "reaction_already_exists"
+	CodeInvalidReactionCode    = "invalid_reaction_code"
+	CodeInvalidMessageExpiry   = "invalid_message_expiry"
+	CodeUnsupportedSubprotocol = "unsupported_subprotocol"
+	CodeNotChatAdmin           = "not_chat_admin"
+	CodeLastChatAdmin          = "last_chat_admin"
+	CodeInvalidChatRole        = "invalid_chat_role"
+	CodeInvalidChatName        = "invalid_chat_name"
+	CodeNotGroupChat           = "not_group_chat"
+	CodeInvalidSearchQuery     = "invalid_search_query"
+	CodeAmbiguousMessageID     = "ambiguous_message_id"
+
+	// Push
+	CodePlatformRequired  = "platform_required"
+	CodePushTokenNotFound = "push_token_not_found"
+)
+
+// ErrorResponse is the body of every error response
+type ErrorResponse struct {
+	Error string `json:"error"`
+	Code  string `json:"code"`
+}
+
+// RespondError writes a JSON error response with the given status, message and code
+func RespondError(w http.ResponseWriter, status int, message string, code string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(ErrorResponse{Error: message, Code: code})
+}
@@ -0,0 +1,23 @@
+package errors
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondError_WritesJSONBody(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	RespondError(rr, http.StatusNotFound, "Profile not found", CodeProfileNotFound)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var body ErrorResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Equal(t, ErrorResponse{Error: "Profile not found", Code: "profile_not_found"}, body)
+}
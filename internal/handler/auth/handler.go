@@ -1,23 +1,33 @@
 package auth
 
 import (
-	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"math"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	userrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/user"
 	authservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/auth"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/verification"
 )
 
 type AuthHandler struct {
-	authService *authservice.AuthService
+	authService  *authservice.AuthService
+	loginLimiter *LoginLimiter
 }
 
-func NewAuthHandler(authService *authservice.AuthService) *AuthHandler {
+// NewAuthHandler creates an auth handler. A nil loginLimiter disables login throttling.
+func NewAuthHandler(authService *authservice.AuthService, loginLimiter *LoginLimiter) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
+		authService:  authService,
+		loginLimiter: loginLimiter,
 	}
 }
 
@@ -28,27 +38,46 @@ func NewAuthHandler(authService *authservice.AuthService) *AuthHandler {
 // @Produce      json
 // @Param        request  body  LoginRequest  true  "Login data"
 // @Success      200      {object}  AuthResponse
-// @Failure      400      {string}  string  "Invalid data"
-// @Failure      401      {string}  string  "Invalid credentials"
-// @Failure      500      {string}  string  "Internal server error"
+// @Failure      400      {object}  apierrors.ErrorResponse  "Invalid data"
+// @Failure      401      {object}  apierrors.ErrorResponse  "Invalid credentials"
+// @Failure      429      {object}  apierrors.ErrorResponse  "Too many failed attempts"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Internal server error"
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
 		return
 	}
 
+	limiterKey := loginLimiterKey(req.Email, r)
+	if h.loginLimiter != nil {
+		if retryAfter, ok := h.loginLimiter.Allow(limiterKey); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			apierrors.RespondError(w, http.StatusTooManyRequests, "Too many failed login attempts", apierrors.CodeTooManyLoginAttempts)
+			return
+		}
+	}
+
 	serviceResponse, err := h.authService.Login(req.Email, req.Password)
 	if err != nil {
+		if err.Error() == "invalid credentials" || err.Error() == "user not found" {
+			if h.loginLimiter != nil {
+				h.loginLimiter.RecordFailure(limiterKey)
+			}
+		}
 		if err.Error() == "invalid credentials" {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			apierrors.RespondError(w, http.StatusUnauthorized, err.Error(), apierrors.CodeInvalidCredentials)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, err.Error(), apierrors.CodeInternal)
 		return
 	}
 
+	if h.loginLimiter != nil {
+		h.loginLimiter.Reset(limiterKey)
+	}
+
 	// Convert service response to API response
 	response := ToAuthResponse(serviceResponse)
 
@@ -63,28 +92,37 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        request  body  RegisterRequest  true  "Registration data"
 // @Success      201      {object}  AuthResponse
-// @Failure      400      {string}  string  "Invalid data"
-// @Failure      409      {string}  string  "Email already registered"
-// @Failure      500      {string}  string  "Internal server error"
+// @Failure      400      {object}  apierrors.ErrorResponse  "Invalid data"
+// @Failure      403      {object}  apierrors.ErrorResponse  "Registration is invite-only and the invite code is missing or invalid"
+// @Failure      409      {object}  apierrors.ErrorResponse  "Email already registered"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Internal server error"
 // @Router       /auth/register [post]
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req RegisterRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
 		return
 	}
 
-	serviceResponse, err := h.authService.Register(req.Email, req.Password)
+	serviceResponse, err := h.authService.Register(req.Email, req.Password, req.InviteCode)
 	if err != nil {
+		if errors.Is(err, authservice.ErrRegistrationClosed) {
+			apierrors.RespondError(w, http.StatusForbidden, err.Error(), apierrors.CodeRegistrationClosed)
+			return
+		}
+		if errors.Is(err, authservice.ErrInvalidInviteCode) {
+			apierrors.RespondError(w, http.StatusForbidden, err.Error(), apierrors.CodeInvalidInviteCode)
+			return
+		}
 		if err.Error() == "email already registered" {
-			http.Error(w, err.Error(), http.StatusConflict)
+			apierrors.RespondError(w, http.StatusConflict, err.Error(), apierrors.CodeEmailAlreadyRegistered)
 			return
 		}
 		if strings.Contains(err.Error(), "email already registered but not verified") {
-			http.Error(w, err.Error(), http.StatusConflict)
+			apierrors.RespondError(w, http.StatusConflict, err.Error(), apierrors.CodeEmailAlreadyRegistered)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, err.Error(), apierrors.CodeInternal)
 		return
 	}
 
@@ -103,20 +141,25 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        request  body  RefreshRequest  true  "Token refresh data"
 // @Success      200      {object}  AuthResponse
-// @Failure      400      {string}  string  "Invalid data"
-// @Failure      401      {string}  string  "Invalid refresh token"
-// @Failure      500      {string}  string  "Internal server error"
+// @Failure      400      {object}  apierrors.ErrorResponse  "Invalid data"
+// @Failure      401      {object}  apierrors.ErrorResponse  "Invalid refresh token"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Internal server error"
 // @Router       /auth/refresh [post]
-func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 	var req RefreshRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
 		return
 	}
 
 	serviceResponse, err := h.authService.RefreshToken(req.RefreshToken)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusUnauthorized)
+		switch err {
+		case authservice.ErrInvalidRefreshToken, authservice.ErrInvalidTokenType, authservice.ErrTokenRevoked, userrepo.ErrUserNotFound:
+			apierrors.RespondError(w, http.StatusUnauthorized, err.Error(), apierrors.CodeInvalidToken)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		}
 		return
 	}
 
@@ -127,6 +170,206 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(response)
 }
 
+// @Summary      Create invite code
+// @Description  Mint a single-use invite code for registration in invite-only mode. Admins only
+// @Tags         admin
+// @Produce      json
+// @Security     BearerAuth
+// @Success      201      {object}  InviteCodeResponse
+// @Failure      401      {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403      {object}  apierrors.ErrorResponse  "Forbidden"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /admin/invite-codes [post]
+func (h *AuthHandler) CreateInviteCode(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	code, err := h.authService.CreateInviteCode(userID)
+	if err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(InviteCodeResponse{Code: code})
+}
+
+// @Summary      Validate access token
+// @Description  Check the access token from the Authorization header and return when it expires, so that it can be refreshed in time
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200      {object}  TokenValidationResponse
+// @Failure      401      {object}  apierrors.ErrorResponse  "Missing or invalid token"
+// @Router       /auth/validate [get]
+func (h *AuthHandler) ValidateToken(w http.ResponseWriter, r *http.Request) {
+	tokenString := extractToken(r)
+	if tokenString == "" {
+		apierrors.RespondError(w, http.StatusUnauthorized, "Authorization header required", apierrors.CodeUnauthorized)
+		return
+	}
+
+	info, err := h.authService.ValidateToken(tokenString)
+	if err != nil {
+		apierrors.RespondError(w, http.StatusUnauthorized, err.Error(), apierrors.CodeInvalidToken)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(TokenValidationResponse{
+		Valid:            true,
+		ExpiresAt:        info.ExpiresAt,
+		ExpiresInSeconds: int64(info.ExpiresIn / time.Second),
+	})
+}
+
+// @Summary      Logout
+// @Description  Revoke the refresh token and the access token of the current session
+// @Tags         auth
+// @Accept       json
+// @Param        request  body  LogoutRequest  true  "Logout data"
+// @Success      204      "Logged out"
+// @Failure      400      {object}  apierrors.ErrorResponse  "Invalid data"
+// @Failure      401      {object}  apierrors.ErrorResponse  "Invalid refresh token"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	var req LogoutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	err := h.authService.Logout(req.RefreshToken, extractToken(r))
+	if err != nil {
+		switch err {
+		case authservice.ErrInvalidRefreshToken, authservice.ErrInvalidTokenType:
+			apierrors.RespondError(w, http.StatusUnauthorized, err.Error(), apierrors.CodeInvalidToken)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Delete account
+// @Description  Permanently delete the current user with their profile, media and messages
+// @Tags         auth
+// @Accept       json
+// @Security     BearerAuth
+// @Param        request  body  DeleteAccountRequest  true  "Current password"
+// @Success      204      "Account deleted"
+// @Failure      400      {object}  apierrors.ErrorResponse  "Invalid data"
+// @Failure      401      {object}  apierrors.ErrorResponse  "Incorrect password"
+// @Failure      404      {object}  apierrors.ErrorResponse  "User not found"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /auth/account [delete]
+func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req DeleteAccountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	if req.Password == "" {
+		apierrors.RespondError(w, http.StatusBadRequest, "Password is required", apierrors.CodePasswordRequired)
+		return
+	}
+
+	err := h.authService.DeleteAccount(userID, req.Password, extractToken(r), req.RefreshToken)
+	if err != nil {
+		switch {
+		case errors.Is(err, authservice.ErrIncorrectPassword):
+			apierrors.RespondError(w, http.StatusUnauthorized, err.Error(), apierrors.CodeIncorrectPassword)
+		case errors.Is(err, userrepo.ErrUserNotFound):
+			apierrors.RespondError(w, http.StatusNotFound, "User not found", apierrors.CodeUserNotFound)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Request password reset
+// @Description  Send a password reset link to the email if an account exists
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  PasswordResetRequest  true  "Password reset data"
+// @Success      200      {object}  VerificationResponse
+// @Failure      400      {object}  apierrors.ErrorResponse  "Invalid data"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /auth/password-reset/request [post]
+func (h *AuthHandler) RequestPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	if err := h.authService.RequestPasswordReset(req.Email); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		return
+	}
+
+	// The same response is returned whether or not the account exists
+	response := VerificationResponse{
+		Success: true,
+		Message: "If the account exists, a password reset link has been sent",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// @Summary      Confirm password reset
+// @Description  Set a new password using a password reset token
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request  body  PasswordResetConfirmRequest  true  "New password data"
+// @Success      200      {object}  VerificationResponse
+// @Failure      400      {object}  apierrors.ErrorResponse  "Invalid or expired token"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /auth/password-reset/confirm [post]
+func (h *AuthHandler) ConfirmPasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req PasswordResetConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	err := h.authService.ConfirmPasswordReset(req.Token, req.NewPassword)
+	if err != nil {
+		if errors.Is(err, verification.ErrInvalidResetToken) || errors.Is(err, authservice.ErrInvalidPassword) {
+			apierrors.RespondError(w, http.StatusBadRequest, err.Error(), apierrors.CodeInvalidPasswordReset)
+			return
+		}
+		apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		return
+	}
+
+	response := VerificationResponse{
+		Success: true,
+		Message: "Password has been reset",
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
 // @Summary      Verify user email
 // @Description  Verify user email with token
 // @Tags         auth
@@ -134,14 +377,15 @@ func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        token  query  string  true  "Verification token"
 // @Success      200    {object}  VerificationResponse
-// @Failure      401    {string}  string  "Invalid or expired token"
-// @Failure      500    {string}  string  "Internal server error"
+// @Failure      401    {object}  apierrors.ErrorResponse  "Invalid or expired token"
+// @Failure      500    {object}  apierrors.ErrorResponse  "Internal server error"
 // @Router       /auth/verify-email [get]
+// @Router       /auth/confirm-email [get]
 func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 	// Get token from query parameters
 	token := r.URL.Query().Get("token")
 	if token == "" {
-		http.Error(w, "Missing verification token", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Missing verification token", apierrors.CodeTokenRequired)
 		return
 	}
 
@@ -149,10 +393,10 @@ func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if strings.Contains(err.Error(), "invalid verification token") ||
 			strings.Contains(err.Error(), "verification token has expired") {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
+			apierrors.RespondError(w, http.StatusUnauthorized, err.Error(), apierrors.CodeInvalidVerificationToken)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, err.Error(), apierrors.CodeInternal)
 		return
 	}
 
@@ -170,33 +414,38 @@ func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
 // @Tags         auth
 // @Accept       json
 // @Produce      json
-// @Param        request  body  ResendVerificationRequest  true  "Email for verification"
+// @Param        request  body  ResendVerificationRequest  false  "Email for verification"
 // @Success      200      {object}  VerificationResponse
-// @Failure      400      {string}  string  "Invalid data"
-// @Failure      404      {string}  string  "User not found"
-// @Failure      500      {string}  string  "Internal server error"
+// @Failure      400      {object}  apierrors.ErrorResponse  "Invalid data"
+// @Failure      404      {object}  apierrors.ErrorResponse  "User not found"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Internal server error"
 // @Router       /auth/resend-verification [post]
+// @Router       /auth/send-verification [post]
 func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	// The body is optional, an empty one sends the email respecting the cooldown
 	var req ResendVerificationRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
 		return
 	}
 
-	userID := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
 
 	// Resend verification
 	err := h.authService.ResendVerificationEmail(userID, req.IgnoreCooldown)
 	if err != nil {
 		if errors.Is(err, verification.ErrEmailRecentlySent) {
-			http.Error(w, "Verification email was sent recently", http.StatusTooManyRequests)
+			apierrors.RespondError(w, http.StatusTooManyRequests, "Verification email was sent recently", apierrors.CodeVerificationEmailCooldown)
 			return
 		}
 		if errors.Is(err, verification.ErrEmailAlreadyVerified) {
-			http.Error(w, "Email is already verified", http.StatusConflict)
+			apierrors.RespondError(w, http.StatusConflict, "Email is already verified", apierrors.CodeEmailAlreadyVerified)
 			return
 		}
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, err.Error(), apierrors.CodeInternal)
 		return
 	}
 
@@ -216,17 +465,20 @@ func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request)
 // @Produce      json
 // @Security     BearerAuth
 // @Success      200  {object}  VerificationStatusResponse
-// @Failure      401  {string}  string  "Unauthorized"
-// @Failure      500  {string}  string  "Internal server error"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
 // @Router       /auth/verification-status [get]
 func (h *AuthHandler) GetVerificationStatus(w http.ResponseWriter, r *http.Request) {
 	// Get userID from context set by the modified AuthMiddleware
-	userID := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
 
 	// Get verification status from auth service
 	isVerified, err := h.authService.IsUserVerified(userID)
 	if err != nil {
-		http.Error(w, "Error checking verification status", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Error checking verification status", apierrors.CodeInternal)
 		return
 	}
 
@@ -236,7 +488,7 @@ func (h *AuthHandler) GetVerificationStatus(w http.ResponseWriter, r *http.Reque
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Error encoding response", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Error encoding response", apierrors.CodeInternal)
 	}
 }
 
@@ -246,30 +498,68 @@ func (h *AuthHandler) AuthMiddleware(requireEmailVerification bool) func(http.Ha
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			tokenString := extractToken(r)
 			if tokenString == "" {
-				http.Error(w, "Authorization header required", http.StatusUnauthorized)
+				apierrors.RespondError(w, http.StatusUnauthorized, "Authorization header required", apierrors.CodeUnauthorized)
 				return
 			}
 
 			user, err := h.authService.GetUserInfoFromToken(tokenString)
 			if err != nil {
-				http.Error(w, err.Error(), http.StatusUnauthorized)
+				apierrors.RespondError(w, http.StatusUnauthorized, err.Error(), apierrors.CodeInvalidToken)
 				return
 			}
 
 			if requireEmailVerification && !user.EmailVerified {
-				http.Error(w, "Email not verified", http.StatusForbidden)
+				apierrors.RespondError(w, http.StatusForbidden, "Email not verified", apierrors.CodeEmailNotVerified)
 				return
 			}
 
 			// Add user data to request context
 			ctx := r.Context()
-			ctx = context.WithValue(ctx, "user_id", user.ID)
+			ctx = authctx.WithUserID(ctx, user.ID)
+			ctx = authctx.WithEmail(ctx, user.Email)
 
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
+// AdminMiddleware restricts access to users whose email is in the admin list.
+// It must run after AuthMiddleware.
+func (h *AuthHandler) AdminMiddleware(adminEmails []string) func(http.Handler) http.Handler {
+	admins := make(map[string]struct{}, len(adminEmails))
+	for _, email := range adminEmails {
+		if email = strings.ToLower(strings.TrimSpace(email)); email != "" {
+			admins[email] = struct{}{}
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			email, ok := authctx.EmailFromContext(r.Context())
+			if !ok {
+				apierrors.RespondError(w, http.StatusUnauthorized, "Unauthorized", apierrors.CodeUnauthorized)
+				return
+			}
+
+			if _, isAdmin := admins[strings.ToLower(email)]; !isAdmin {
+				apierrors.RespondError(w, http.StatusForbidden, "Forbidden", apierrors.CodeForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// loginLimiterKey identifies login attempts for an email from a client address
+func loginLimiterKey(email string, r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return strings.ToLower(strings.TrimSpace(email)) + "|" + host
+}
+
 // Helper function to extract token from request
 func extractToken(r *http.Request) string {
 	authHeader := r.Header.Get("Authorization")
@@ -0,0 +1,413 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	userrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/user"
+	authservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/auth"
+)
+
+// MockUserRepository is a mock implementation of authservice.UserRepository
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) GetUserByEmail(email string) (*userrepo.User, error) {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userrepo.User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetUserByID(id int) (*userrepo.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*userrepo.User), args.Error(1)
+}
+
+func (m *MockUserRepository) CreateUser(user *userrepo.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateEmailVerificationStatus(userID int, verified bool) error {
+	args := m.Called(userID, verified)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateUser(user *userrepo.User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(userID int, passwordHash string) error {
+	args := m.Called(userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RevokeToken(jti string, expiresAt time.Time) error {
+	args := m.Called(jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) IsTokenRevoked(jti string) (bool, error) {
+	args := m.Called(jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) DeleteUser(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CreateInviteCode(code string, createdBy int) error {
+	args := m.Called(code, createdBy)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ClaimInviteCode(code string) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ReleaseInviteCode(code string) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+// MockEmailService is a mock implementation of authservice.EmailVerificationService
+type MockEmailService struct {
+	mock.Mock
+}
+
+func (m *MockEmailService) SendVerificationEmail(userID int, userEmail string) error {
+	args := m.Called(userID, userEmail)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) VerifyEmail(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) ResendVerificationEmail(userID int, ignoreCooldown bool) error {
+	args := m.Called(userID, ignoreCooldown)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) IsTokenExpiredForEmail(email string) (bool, error) {
+	args := m.Called(email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockEmailService) SendPasswordResetEmail(userID int, userEmail string) error {
+	args := m.Called(userID, userEmail)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) ConsumePasswordResetToken(token string) (int, error) {
+	args := m.Called(token)
+	return args.Int(0), args.Error(1)
+}
+
+func setupLoginHandler(t *testing.T, limiter *LoginLimiter) *AuthHandler {
+	t.Helper()
+	return setupHandlerWithConfig(t, limiter, authservice.Config{})
+}
+
+func setupHandlerWithConfig(t *testing.T, limiter *LoginLimiter, config authservice.Config) *AuthHandler {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct-password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByEmail", "test@example.com").Return(&userrepo.User{
+		ID:            1,
+		Email:         "test@example.com",
+		PasswordHash:  string(hash),
+		EmailVerified: true,
+	}, nil)
+	userRepo.On("IsTokenRevoked", mock.Anything).Return(false, nil)
+
+	return NewAuthHandler(authservice.NewAuthService(userRepo, nil, "test-secret", config), limiter)
+}
+
+func login(h *AuthHandler, password string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(LoginRequest{Email: "test@example.com", Password: password})
+	req := httptest.NewRequest("POST", "/api/auth/login", bytes.NewReader(body))
+	req.RemoteAddr = "203.0.113.1:1234"
+	rr := httptest.NewRecorder()
+	h.Login(rr, req)
+	return rr
+}
+
+func TestLogin_LocksOutAfterRepeatedFailures(t *testing.T) {
+	clock := newFakeClock()
+	h := setupLoginHandler(t, NewLoginLimiter(3, time.Minute, clock.Now))
+
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, http.StatusUnauthorized, login(h, "wrong-password").Code)
+	}
+
+	// Even the correct password is rejected while locked
+	rr := login(h, "correct-password")
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, http.StatusOK, login(h, "correct-password").Code)
+}
+
+func TestLogin_SuccessResetsFailures(t *testing.T) {
+	clock := newFakeClock()
+	h := setupLoginHandler(t, NewLoginLimiter(3, time.Minute, clock.Now))
+
+	assert.Equal(t, http.StatusUnauthorized, login(h, "wrong-password").Code)
+	assert.Equal(t, http.StatusUnauthorized, login(h, "wrong-password").Code)
+	assert.Equal(t, http.StatusOK, login(h, "correct-password").Code)
+	assert.Equal(t, http.StatusUnauthorized, login(h, "wrong-password").Code)
+	assert.Equal(t, http.StatusUnauthorized, login(h, "wrong-password").Code)
+
+	assert.Equal(t, http.StatusOK, login(h, "correct-password").Code)
+}
+
+func TestAuthMiddleware_RejectsTokenAfterAccessTTL(t *testing.T) {
+	h := setupHandlerWithConfig(t, nil, authservice.Config{AccessTokenTTL: time.Second})
+
+	rr := login(h, "correct-password")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp AuthResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	protected := h.AuthMiddleware(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func() int {
+		req := httptest.NewRequest("GET", "/api/auth/verify", nil)
+		req.Header.Set("Authorization", "Bearer "+resp.Token)
+		rr := httptest.NewRecorder()
+		protected.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request())
+
+	time.Sleep(1100 * time.Millisecond)
+	assert.Equal(t, http.StatusUnauthorized, request())
+}
+
+func TestValidateToken_ReturnsExpiry(t *testing.T) {
+	h := setupHandlerWithConfig(t, nil, authservice.Config{AccessTokenTTL: time.Hour})
+
+	rr := login(h, "correct-password")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp AuthResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	req := httptest.NewRequest("GET", "/api/auth/validate", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.Token)
+	rr = httptest.NewRecorder()
+	h.ValidateToken(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body TokenValidationResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.True(t, body.Valid)
+	assert.InDelta(t, time.Hour.Seconds(), body.ExpiresInSeconds, 5)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), body.ExpiresAt, 5*time.Second)
+
+	// An invalid token is rejected
+	req = httptest.NewRequest("GET", "/api/auth/validate", nil)
+	req.Header.Set("Authorization", "Bearer not-a-token")
+	rr = httptest.NewRecorder()
+	h.ValidateToken(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Body.String(), apierrors.CodeInvalidToken)
+}
+
+func TestAuthMiddleware_RequiresVerifiedEmail(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct-password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+
+	userRepo := new(MockUserRepository)
+	userRepo.On("GetUserByEmail", "test@example.com").Return(&userrepo.User{
+		ID:            1,
+		Email:         "test@example.com",
+		PasswordHash:  string(hash),
+		EmailVerified: false,
+	}, nil)
+	userRepo.On("IsTokenRevoked", mock.Anything).Return(false, nil)
+	h := NewAuthHandler(authservice.NewAuthService(userRepo, nil, "test-secret", authservice.Config{}), nil)
+
+	rr := login(h, "correct-password")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp AuthResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.False(t, resp.EmailVerified)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	request := func(requireVerification bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/profiles", nil)
+		req.Header.Set("Authorization", "Bearer "+resp.Token)
+		rr := httptest.NewRecorder()
+		h.AuthMiddleware(requireVerification)(ok).ServeHTTP(rr, req)
+		return rr
+	}
+
+	// Profile routes require a confirmed email
+	forbidden := request(true)
+	assert.Equal(t, http.StatusForbidden, forbidden.Code)
+	assert.Contains(t, forbidden.Body.String(), "Email not verified")
+	assert.Equal(t, apierrors.CodeEmailNotVerified, decodeJSONKeys(t, forbidden)["code"])
+
+	// Verification routes stay reachable
+	assert.Equal(t, http.StatusOK, request(false).Code)
+}
+
+func TestResendVerification_AcceptsEmptyBody(t *testing.T) {
+	emailService := new(MockEmailService)
+	emailService.On("ResendVerificationEmail", 1, false).Return(nil)
+	h := NewAuthHandler(authservice.NewAuthService(new(MockUserRepository), emailService, "test-secret", authservice.Config{}), nil)
+
+	req := httptest.NewRequest("POST", "/api/auth/send-verification", nil)
+	req = req.WithContext(authctx.WithUserID(req.Context(), 1))
+	rr := httptest.NewRecorder()
+
+	h.ResendVerification(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	emailService.AssertExpectations(t)
+}
+
+func TestAuthMiddleware_StoresUserInContext(t *testing.T) {
+	h := setupLoginHandler(t, nil)
+
+	rr := login(h, "correct-password")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var resp AuthResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+
+	var userID int
+	var email string
+	var userIDOK, emailOK bool
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, userIDOK = authctx.UserIDFromContext(r.Context())
+		email, emailOK = authctx.EmailFromContext(r.Context())
+	})
+
+	req := httptest.NewRequest("GET", "/api/profiles/1", nil)
+	req.Header.Set("Authorization", "Bearer "+resp.Token)
+	h.AuthMiddleware(true)(next).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.True(t, userIDOK)
+	assert.Equal(t, 1, userID)
+	assert.True(t, emailOK)
+	assert.Equal(t, "test@example.com", email)
+}
+
+// decodeJSONKeys decodes a JSON object body into a generic map
+func decodeJSONKeys(t *testing.T, rr *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+	var body map[string]interface{}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	return body
+}
+
+func TestAuthResponses_UseConsistentUserIDKey(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	emailService := new(MockEmailService)
+	userRepo.On("GetUserByEmail", "new@example.com").Return(nil, userrepo.ErrUserNotFound)
+	userRepo.On("CreateUser", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*userrepo.User).ID = 42
+	}).Return(nil)
+	emailService.On("SendVerificationEmail", 42, "new@example.com").Return(nil)
+	h := NewAuthHandler(authservice.NewAuthService(userRepo, emailService, "test-secret", authservice.Config{}), nil)
+
+	body, _ := json.Marshal(RegisterRequest{Email: "new@example.com", Password: "password"})
+	rr := httptest.NewRecorder()
+	h.Register(rr, httptest.NewRequest("POST", "/api/auth/register", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	registered := decodeJSONKeys(t, rr)
+	assert.Equal(t, float64(42), registered["user_id"])
+	assert.NotContains(t, registered, "id")
+	assert.NotContains(t, registered, "user")
+
+	rr = login(setupLoginHandler(t, nil), "correct-password")
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	loggedIn := decodeJSONKeys(t, rr)
+	assert.Equal(t, float64(1), loggedIn["user_id"])
+	assert.NotContains(t, loggedIn, "id")
+	assert.NotContains(t, loggedIn, "user")
+}
+
+func TestRegister_InviteOnly(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	emailService := new(MockEmailService)
+	userRepo.On("GetUserByEmail", mock.Anything).Return(nil, userrepo.ErrUserNotFound)
+	userRepo.On("CreateUser", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*userrepo.User).ID = 42
+	}).Return(nil)
+	userRepo.On("ClaimInviteCode", "invite-1").Return(nil).Once()
+	userRepo.On("ClaimInviteCode", "invite-1").Return(userrepo.ErrInviteCodeNotFound)
+	emailService.On("SendVerificationEmail", 42, mock.Anything).Return(nil)
+	h := NewAuthHandler(authservice.NewAuthService(userRepo, emailService, "test-secret", authservice.Config{InviteOnly: true}), nil)
+
+	register := func(email, inviteCode string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(RegisterRequest{Email: email, Password: "password", InviteCode: inviteCode})
+		rr := httptest.NewRecorder()
+		h.Register(rr, httptest.NewRequest("POST", "/api/auth/register", bytes.NewReader(body)))
+		return rr
+	}
+
+	// Without a code registration is closed
+	rr := register("first@example.com", "")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), apierrors.CodeRegistrationClosed)
+	userRepo.AssertNotCalled(t, "CreateUser", mock.Anything)
+
+	// A valid code lets the user in
+	rr = register("first@example.com", "invite-1")
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	// The code can't be used twice
+	rr = register("second@example.com", "invite-1")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), apierrors.CodeInvalidInviteCode)
+	userRepo.AssertNumberOfCalls(t, "CreateUser", 1)
+}
+
+func TestCreateInviteCode_StoresCodeOfAdmin(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	userRepo.On("CreateInviteCode", mock.Anything, 7).Return(nil)
+	h := NewAuthHandler(authservice.NewAuthService(userRepo, nil, "test-secret", authservice.Config{}), nil)
+
+	req := httptest.NewRequest("POST", "/api/admin/invite-codes", nil)
+	req = req.WithContext(authctx.WithUserID(req.Context(), 7))
+	rr := httptest.NewRecorder()
+	h.CreateInviteCode(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var resp InviteCodeResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Len(t, resp.Code, 16)
+	userRepo.AssertCalled(t, "CreateInviteCode", resp.Code, 7)
+}
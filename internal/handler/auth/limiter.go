@@ -0,0 +1,107 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// maxTrackedLoginKeys bounds the number of keys kept before stale entries are pruned
+const maxTrackedLoginKeys = 10000
+
+// LoginLimiter locks out further login attempts for a key after too many
+// consecutive failures
+type LoginLimiter struct {
+	maxFailures int
+	window      time.Duration
+	now         func() time.Time
+
+	mu       sync.Mutex
+	attempts map[string]*loginAttempts
+}
+
+type loginAttempts struct {
+	failures    int
+	lastFailure time.Time
+	lockedUntil time.Time
+}
+
+// NewLoginLimiter creates a limiter that locks a key for the window after maxFailures
+// consecutive failures. A nil clock defaults to time.Now.
+func NewLoginLimiter(maxFailures int, window time.Duration, now func() time.Time) *LoginLimiter {
+	if now == nil {
+		now = time.Now
+	}
+	return &LoginLimiter{
+		maxFailures: maxFailures,
+		window:      window,
+		now:         now,
+		attempts:    make(map[string]*loginAttempts),
+	}
+}
+
+// Allow reports whether an attempt is allowed for the key and, if not, how long
+// the caller has to wait
+func (l *LoginLimiter) Allow(key string) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	attempts, ok := l.attempts[key]
+	if !ok {
+		return 0, true
+	}
+
+	now := l.now()
+	if now.Before(attempts.lockedUntil) {
+		return attempts.lockedUntil.Sub(now), false
+	}
+
+	// The lockout or the failure streak has expired
+	if l.isStale(attempts, now) {
+		delete(l.attempts, key)
+	}
+	return 0, true
+}
+
+// RecordFailure counts a failed attempt and locks the key once the threshold is reached
+func (l *LoginLimiter) RecordFailure(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	attempts, ok := l.attempts[key]
+	if !ok || l.isStale(attempts, now) {
+		if len(l.attempts) >= maxTrackedLoginKeys {
+			l.prune(now)
+		}
+		attempts = &loginAttempts{}
+		l.attempts[key] = attempts
+	}
+
+	attempts.failures++
+	attempts.lastFailure = now
+	if attempts.failures >= l.maxFailures {
+		attempts.lockedUntil = now.Add(l.window)
+		attempts.failures = 0
+	}
+}
+
+// Reset clears the failures of the key, e.g. after a successful login
+func (l *LoginLimiter) Reset(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.attempts, key)
+}
+
+// isStale reports whether the entry no longer affects future attempts
+func (l *LoginLimiter) isStale(attempts *loginAttempts, now time.Time) bool {
+	return !now.Before(attempts.lockedUntil) && now.Sub(attempts.lastFailure) >= l.window
+}
+
+// prune removes stale entries
+func (l *LoginLimiter) prune(now time.Time) {
+	for key, attempts := range l.attempts {
+		if l.isStale(attempts, now) {
+			delete(l.attempts, key)
+		}
+	}
+}
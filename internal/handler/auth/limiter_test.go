@@ -0,0 +1,80 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a manually advanced clock for tests
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func TestLoginLimiter_LocksAfterMaxFailures(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewLoginLimiter(3, time.Minute, clock.Now)
+
+	for i := 0; i < 3; i++ {
+		_, ok := limiter.Allow("key")
+		assert.True(t, ok)
+		limiter.RecordFailure("key")
+	}
+
+	retryAfter, ok := limiter.Allow("key")
+	assert.False(t, ok)
+	assert.Equal(t, time.Minute, retryAfter)
+
+	// Other keys are not affected
+	_, ok = limiter.Allow("other")
+	assert.True(t, ok)
+
+	clock.Advance(45 * time.Second)
+	retryAfter, ok = limiter.Allow("key")
+	assert.False(t, ok)
+	assert.Equal(t, 15*time.Second, retryAfter)
+
+	clock.Advance(15 * time.Second)
+	_, ok = limiter.Allow("key")
+	assert.True(t, ok)
+}
+
+func TestLoginLimiter_ResetClearsFailures(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewLoginLimiter(3, time.Minute, clock.Now)
+
+	limiter.RecordFailure("key")
+	limiter.RecordFailure("key")
+	limiter.Reset("key")
+	limiter.RecordFailure("key")
+	limiter.RecordFailure("key")
+
+	_, ok := limiter.Allow("key")
+	assert.True(t, ok)
+}
+
+func TestLoginLimiter_FailuresExpireAfterWindow(t *testing.T) {
+	clock := newFakeClock()
+	limiter := NewLoginLimiter(3, time.Minute, clock.Now)
+
+	limiter.RecordFailure("key")
+	limiter.RecordFailure("key")
+	clock.Advance(time.Minute)
+	limiter.RecordFailure("key")
+
+	_, ok := limiter.Allow("key")
+	assert.True(t, ok)
+}
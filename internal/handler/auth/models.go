@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"time"
+
 	serviceAuth "github.com/bulatminnakhmetov/brigadka-backend/internal/service/auth"
 )
 
@@ -11,14 +13,33 @@ type LoginRequest struct {
 }
 
 type RegisterRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+	Email      string `json:"email"`
+	Password   string `json:"password"`
+	InviteCode string `json:"invite_code,omitempty"` // Required when registration is invite-only
 }
 
 type RefreshRequest struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type DeleteAccountRequest struct {
+	Password     string `json:"password"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
 type VerifyEmailRequest struct {
 	Token string `json:"token"`
 }
@@ -45,6 +66,18 @@ type VerificationStatusResponse struct {
 	Verified bool `json:"verified"`
 }
 
+// InviteCodeResponse holds a newly minted invite code
+type InviteCodeResponse struct {
+	Code string `json:"code"`
+}
+
+// TokenValidationResponse reports when a valid access token expires
+type TokenValidationResponse struct {
+	Valid            bool      `json:"valid"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	ExpiresInSeconds int64     `json:"expires_in_seconds"`
+}
+
 func ToAuthResponse(serviceResponse *serviceAuth.AuthResponse) AuthResponse {
 	return AuthResponse{
 		UserID:        serviceResponse.User.ID,
@@ -0,0 +1,134 @@
+package block
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	blockservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/block"
+)
+
+// BlockedUsersResponse lists the users blocked by the caller
+type BlockedUsersResponse struct {
+	UserIDs []int `json:"user_ids"`
+}
+
+// Handler handles user block endpoints
+type Handler struct {
+	service blockservice.Service
+}
+
+// NewHandler creates a new block handler
+func NewHandler(service blockservice.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// @Summary      Block a user
+// @Description  Blocks the user. Blocked users and the caller don't see each other in search and can't be put in the same chat
+// @Tags         users
+// @Param        userID  path  int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid user ID or the caller's own ID"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  apierrors.ErrorResponse  "User not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/users/{userID}/block [post]
+// @Security     BearerAuth
+func (h *Handler) BlockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	blockedID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.BlockUser(userID, blockedID); err != nil {
+		switch {
+		case errors.Is(err, blockservice.ErrCannotBlockSelf):
+			apierrors.RespondError(w, http.StatusBadRequest, "Cannot block yourself", apierrors.CodeCannotBlockSelf)
+		case errors.Is(err, blockservice.ErrUserNotFound):
+			apierrors.RespondError(w, http.StatusNotFound, "User not found", apierrors.CodeUserNotFound)
+		default:
+			log.Printf("Error blocking user: %v", err)
+			apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		}
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Unblock a user
+// @Description  Removes a block made by the caller. Unblocking a user who isn't blocked succeeds
+// @Tags         users
+// @Param        userID  path  int  true  "User ID"
+// @Success      204
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid user ID"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/users/{userID}/block [delete]
+// @Security     BearerAuth
+func (h *Handler) UnblockUser(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	blockedID, ok := targetUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.UnblockUser(userID, blockedID); err != nil {
+		log.Printf("Error unblocking user: %v", err)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      List blocked users
+// @Description  Returns the IDs of the users blocked by the caller, most recently blocked first
+// @Tags         users
+// @Produce      json
+// @Success      200  {object}  BlockedUsersResponse
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/users/blocks [get]
+// @Security     BearerAuth
+func (h *Handler) GetBlockedUsers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	userIDs, err := h.service.GetBlockedUsers(userID)
+	if err != nil {
+		log.Printf("Error fetching blocked users: %v", err)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(BlockedUsersResponse{UserIDs: userIDs})
+}
+
+// targetUserID parses the user ID from the URL path, responding with 400 if it is invalid
+func targetUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
+		return 0, false
+	}
+	return id, true
+}
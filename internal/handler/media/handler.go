@@ -2,15 +2,27 @@ package media
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"strconv"
 
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/media"
+	"github.com/go-chi/chi/v5"
 )
 
 // MediaService определяет интерфейс для работы с медиа
 type MediaService interface {
 	UploadMedia(userID int, fileHeader, thumbnailHeader media.UploadedFile) (*media.Media, error)
+	GetUserMedia(ownerID int, includePrivate bool, limit, offset int) ([]media.Media, int, error)
+	GetMedia(mediaID, userID int) (*media.Media, error)
+	DeleteMedia(mediaID, userID int) error
+	PresignUpload(userID int, fileName, contentType string) (*media.PresignedUpload, error)
+	CompleteUpload(mediaID, userID int) (*media.Media, error)
+	Constraints() media.Constraints
 }
 
 // MediaHandler handles requests for media operations
@@ -18,17 +30,31 @@ type MediaHandler struct {
 	service         MediaService
 	uploadSemaphore chan struct{}
 	maxFileSizeMB   int64
+	maxMemoryMB     int64 // Multipart data above this is spooled to temporary files
 }
 
 // NewMediaHandler creates a new instance of MediaHandler
-func NewMediaHandler(service MediaService, maxConcurrentUploads int, maxFileSizeMB int64) *MediaHandler {
+func NewMediaHandler(service MediaService, maxConcurrentUploads int, maxFileSizeMB int64, maxMemoryMB int64) *MediaHandler {
 	return &MediaHandler{
 		service:         service,
 		uploadSemaphore: make(chan struct{}, maxConcurrentUploads),
 		maxFileSizeMB:   maxFileSizeMB,
+		maxMemoryMB:     maxMemoryMB,
 	}
 }
 
+// Pagination defaults for media listing
+const (
+	defaultMediaPageSize = 20
+	maxMediaPageSize     = 100
+)
+
+// PresignRequest describes a file the client is going to upload directly to storage
+type PresignRequest struct {
+	FileName    string `json:"file_name"`
+	ContentType string `json:"content_type"`
+}
+
 // Response for media operations
 type MediaResponse struct {
 	ID           int    `json:"id"`
@@ -44,10 +70,11 @@ type MediaResponse struct {
 // @Param        file       formData  file  true  "File to upload"
 // @Param        thumbnail  formData  file  true  "Thumbnail file"
 // @Success      200   {object}  MediaResponse
-// @Failure      400   {string}  string  "Invalid file"
-// @Failure      401   {string}  string  "Unauthorized"
-// @Failure      413   {string}  string  "File too large"
-// @Failure      500   {string}  string  "Internal server error"
+// @Failure      400   {object}  apierrors.ErrorResponse  "Missing, empty or invalid file, or malformed form"
+// @Failure      401   {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      413   {object}  apierrors.ErrorResponse  "File too large"
+// @Failure      415   {object}  apierrors.ErrorResponse  "File type not allowed"
+// @Failure      500   {object}  apierrors.ErrorResponse  "Internal server error"
 // @Router       /api/media [post]
 // @Security     BearerAuth
 func (h *MediaHandler) UploadMedia(w http.ResponseWriter, r *http.Request) {
@@ -55,45 +82,43 @@ func (h *MediaHandler) UploadMedia(w http.ResponseWriter, r *http.Request) {
 	h.uploadSemaphore <- struct{}{}
 	defer func() { <-h.uploadSemaphore }()
 	// Get user ID from context (assuming it's set by auth middleware)
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Parse multipart form
-	err := r.ParseMultipartForm(10 << 20) // 10 MB
+	err := r.ParseMultipartForm(h.maxMemoryMB << 20)
 	if err != nil {
-		if err.(*http.MaxBytesError) != nil {
-			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
-			return
+		var maxBytesErr *http.MaxBytesError
+		switch {
+		case errors.As(err, &maxBytesErr):
+			apierrors.RespondError(w, http.StatusRequestEntityTooLarge, "File too large", apierrors.CodeFileTooLarge)
+		case errors.Is(err, http.ErrNotMultipart):
+			apierrors.RespondError(w, http.StatusBadRequest, "Request must be multipart/form-data", apierrors.CodeMalformedMultipart)
+		default:
+			apierrors.RespondError(w, http.StatusBadRequest, "Could not parse multipart form", apierrors.CodeMalformedMultipart)
 		}
-		http.Error(w, "Could not parse form", http.StatusBadRequest)
 		return
 	}
+	defer r.MultipartForm.RemoveAll()
 
 	// Get main file from request
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		http.Error(w, "Could not get file", http.StatusBadRequest)
+	file, header, ok := formFile(w, r, "file")
+	if !ok {
 		return
 	}
 	defer file.Close()
 
-	// Create wrapper for the main file header
-	fileWrapper := &media.FileHeaderWrapper{FileHeader: header}
-
-	// Check for thumbnail file
-	var thumbnailWrapper *media.FileHeaderWrapper
-	thumbnailFile, thumbnailHeader, err := r.FormFile("thumbnail")
-	if err != nil {
-		http.Error(w, "Could not get thumbnail", http.StatusBadRequest)
+	// Get thumbnail file from request
+	thumbnailFile, thumbnailHeader, ok := formFile(w, r, "thumbnail")
+	if !ok {
 		return
 	}
-
 	defer thumbnailFile.Close()
 
-	thumbnailWrapper = &media.FileHeaderWrapper{FileHeader: thumbnailHeader}
+	fileWrapper := &media.FileHeaderWrapper{FileHeader: header}
+	thumbnailWrapper := &media.FileHeaderWrapper{FileHeader: thumbnailHeader}
 
 	// Upload media
 	uploaded, err := h.service.UploadMedia(userID, fileWrapper, thumbnailWrapper)
@@ -101,11 +126,11 @@ func (h *MediaHandler) UploadMedia(w http.ResponseWriter, r *http.Request) {
 		log.Printf("Error uploading media: %v", err)
 		switch err {
 		case media.ErrInvalidFileType:
-			http.Error(w, "Invalid file type", http.StatusBadRequest)
+			apierrors.RespondError(w, http.StatusUnsupportedMediaType, "Invalid file type", apierrors.CodeInvalidFileType)
 		case media.ErrFileTooBig:
-			http.Error(w, "File too large", http.StatusRequestEntityTooLarge)
+			apierrors.RespondError(w, http.StatusRequestEntityTooLarge, "File too large", apierrors.CodeFileTooLarge)
 		default:
-			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
 		}
 		return
 	}
@@ -118,3 +143,284 @@ func (h *MediaHandler) UploadMedia(w http.ResponseWriter, r *http.Request) {
 		ThumbnailURL: uploaded.ThumbnailURL,
 	})
 }
+
+// formFile returns the file of a parsed multipart form field, responding with 400 if
+// the field is missing, empty or unreadable
+func formFile(w http.ResponseWriter, r *http.Request, field string) (multipart.File, *multipart.FileHeader, bool) {
+	file, header, err := r.FormFile(field)
+	if err != nil {
+		if errors.Is(err, http.ErrMissingFile) {
+			apierrors.RespondError(w, http.StatusBadRequest, "Could not get "+field+": missing \""+field+"\" form field", apierrors.CodeMissingFile)
+			return nil, nil, false
+		}
+		apierrors.RespondError(w, http.StatusBadRequest, "Could not get "+field+": unreadable upload", apierrors.CodeInvalidFile)
+		return nil, nil, false
+	}
+
+	if header.Size == 0 {
+		file.Close()
+		apierrors.RespondError(w, http.StatusBadRequest, "Could not get "+field+": file is empty", apierrors.CodeEmptyFile)
+		return nil, nil, false
+	}
+
+	return file, header, true
+}
+
+// @Summary      Get user media
+// @Description  Returns media uploaded by the user. Other users only see media attached to the owner's profile.
+// @Description  Profile media comes first in gallery order, then the remaining uploads newest first
+// @Description  The total number of media across all pages is returned in the X-Total-Count header
+// @Tags         media
+// @Produce      json
+// @Param        userID  path   int  true   "User ID"
+// @Param        limit   query  int  false  "Page size (default 20, max 100)"
+// @Param        offset  query  int  false  "Offset (default 0)"
+// @Success      200  {array}   MediaResponse
+// @Header       200  {int}     X-Total-Count  "Total number of media"
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid user ID"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/users/{userID}/media [get]
+// @Security     BearerAuth
+func (h *MediaHandler) GetUserMedia(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ownerID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
+		return
+	}
+
+	limit := defaultMediaPageSize
+	offset := 0
+
+	if val, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && val > 0 {
+		limit = val
+	}
+	if limit > maxMediaPageSize {
+		limit = maxMediaPageSize
+	}
+	if val, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && val >= 0 {
+		offset = val
+	}
+
+	// Only the owner can see media that isn't attached to their profile
+	items, total, err := h.service.GetUserMedia(ownerID, userID == ownerID, limit, offset)
+	if err != nil {
+		log.Printf("Error fetching user media: %v", err)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		return
+	}
+
+	response := make([]MediaResponse, 0, len(items))
+	for _, item := range items {
+		response = append(response, MediaResponse{
+			ID:           item.ID,
+			URL:          item.URL,
+			ThumbnailURL: item.ThumbnailURL,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
+	json.NewEncoder(w).Encode(response)
+}
+
+// mediaID parses the media ID from the URL path, responding with 400 if it is invalid
+func mediaID(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(chi.URLParam(r, "mediaID"))
+	if err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid media ID", apierrors.CodeInvalidRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+// respondOwnershipError responds to a failed single media operation
+func respondOwnershipError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, media.ErrMediaNotFound):
+		apierrors.RespondError(w, http.StatusNotFound, "Media not found", apierrors.CodeMediaNotFound)
+	case errors.Is(err, media.ErrNotMediaOwner):
+		apierrors.RespondError(w, http.StatusForbidden, "Media belongs to another user", apierrors.CodeNotMediaOwner)
+	case errors.Is(err, media.ErrUploadNotFound):
+		apierrors.RespondError(w, http.StatusConflict, "File has not been uploaded yet", apierrors.CodeUploadNotFound)
+	case errors.Is(err, media.ErrProfileMediaRequired):
+		apierrors.RespondError(w, http.StatusConflict, "Profile must keep at least the minimum number of media items", apierrors.CodeProfileMediaRequired)
+	case errors.Is(err, media.ErrFileTooBig):
+		apierrors.RespondError(w, http.StatusRequestEntityTooLarge, "File too large", apierrors.CodeFileTooLarge)
+	case errors.Is(err, media.ErrInvalidFileType):
+		apierrors.RespondError(w, http.StatusUnsupportedMediaType, "Invalid file type", apierrors.CodeInvalidFileType)
+	default:
+		log.Printf("Error accessing media: %v", err)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Get media
+// @Description  Returns a media item of the current user
+// @Tags         media
+// @Produce      json
+// @Param        mediaID  path  int  true  "Media ID"
+// @Success      200  {object}  MediaResponse
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid media ID"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  apierrors.ErrorResponse  "Media belongs to another user"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Media not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/media/{mediaID} [get]
+// @Security     BearerAuth
+func (h *MediaHandler) GetMedia(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	item, err := h.service.GetMedia(id, userID)
+	if err != nil {
+		respondOwnershipError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MediaResponse{
+		ID:           item.ID,
+		URL:          item.URL,
+		ThumbnailURL: item.ThumbnailURL,
+	})
+}
+
+// @Summary      Delete media
+// @Description  Deletes a media item of the current user
+// @Tags         media
+// @Param        mediaID  path  int  true  "Media ID"
+// @Success      204
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid media ID"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  apierrors.ErrorResponse  "Media belongs to another user"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Media not found"
+// @Failure      409  {object}  apierrors.ErrorResponse  "Profile must keep at least the minimum number of media items"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/media/{mediaID} [delete]
+// @Security     BearerAuth
+func (h *MediaHandler) DeleteMedia(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteMedia(id, userID); err != nil {
+		respondOwnershipError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Get upload constraints
+// @Description  Returns the largest accepted file, the accepted content types and how many media items a profile may show and must keep
+// @Tags         media
+// @Produce      json
+// @Success      200  {object}  media.Constraints
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Router       /api/media/constraints [get]
+// @Security     BearerAuth
+func (h *MediaHandler) GetConstraints(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.service.Constraints())
+}
+
+// @Summary      Presign upload
+// @Description  Creates a pending media item and returns a URL to PUT the file to directly. Call complete once the upload has finished.
+// @Tags         media
+// @Accept       json
+// @Produce      json
+// @Param        request  body  PresignRequest  true  "File to upload"
+// @Success      201  {object}  media.PresignedUpload
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid request body"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      415  {object}  apierrors.ErrorResponse  "File type not allowed"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/media/presign [post]
+// @Security     BearerAuth
+func (h *MediaHandler) PresignUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req PresignRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FileName == "" || req.ContentType == "" {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	upload, err := h.service.PresignUpload(userID, req.FileName, req.ContentType)
+	if err != nil {
+		if errors.Is(err, media.ErrInvalidFileType) {
+			apierrors.RespondError(w, http.StatusUnsupportedMediaType, "Invalid file type", apierrors.CodeInvalidFileType)
+			return
+		}
+		log.Printf("Error presigning upload: %v", err)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(upload)
+}
+
+// @Summary      Complete upload
+// @Description  Marks a presigned upload as ready once its file is in storage. A file that is too large or of a type other than declared is removed, and the upload may be retried while its URL is valid.
+// @Tags         media
+// @Produce      json
+// @Param        mediaID  path  int  true  "Media ID"
+// @Success      200  {object}  MediaResponse
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid media ID"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  apierrors.ErrorResponse  "Media belongs to another user"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Media not found"
+// @Failure      409  {object}  apierrors.ErrorResponse  "File has not been uploaded yet"
+// @Failure      413  {object}  apierrors.ErrorResponse  "File too large"
+// @Failure      415  {object}  apierrors.ErrorResponse  "Invalid file type"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/media/{mediaID}/complete [post]
+// @Security     BearerAuth
+func (h *MediaHandler) CompleteUpload(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	id, ok := mediaID(w, r)
+	if !ok {
+		return
+	}
+
+	item, err := h.service.CompleteUpload(id, userID)
+	if err != nil {
+		respondOwnershipError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(MediaResponse{
+		ID:           item.ID,
+		URL:          item.URL,
+		ThumbnailURL: item.ThumbnailURL,
+	})
+}
@@ -13,7 +13,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/media"
+	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -32,6 +35,74 @@ func (m *MockMediaService) UploadMedia(userID int, fileHeader, thumbnailHeader m
 	return args.Get(0).(*media.Media), args.Error(1)
 }
 
+// GetUserMedia implements MediaService interface
+func (m *MockMediaService) GetUserMedia(ownerID int, includePrivate bool, limit, offset int) ([]media.Media, int, error) {
+	args := m.Called(ownerID, includePrivate, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]media.Media), args.Int(1), args.Error(2)
+}
+
+// GetMedia implements MediaService interface
+func (m *MockMediaService) GetMedia(mediaID, userID int) (*media.Media, error) {
+	args := m.Called(mediaID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*media.Media), args.Error(1)
+}
+
+// DeleteMedia implements MediaService interface
+func (m *MockMediaService) DeleteMedia(mediaID, userID int) error {
+	args := m.Called(mediaID, userID)
+	return args.Error(0)
+}
+
+// PresignUpload implements MediaService interface
+func (m *MockMediaService) PresignUpload(userID int, fileName, contentType string) (*media.PresignedUpload, error) {
+	args := m.Called(userID, fileName, contentType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*media.PresignedUpload), args.Error(1)
+}
+
+// CompleteUpload implements MediaService interface
+func (m *MockMediaService) CompleteUpload(mediaID, userID int) (*media.Media, error) {
+	args := m.Called(mediaID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*media.Media), args.Error(1)
+}
+
+// Constraints implements MediaService interface
+func (m *MockMediaService) Constraints() media.Constraints {
+	args := m.Called()
+	return args.Get(0).(media.Constraints)
+}
+
+// Helper function to create a request for a single media item
+func createMediaItemRequest(method string, requesterID int, mediaID string) *http.Request {
+	req := httptest.NewRequest(method, "/api/media/"+mediaID, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("mediaID", mediaID)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = authctx.WithUserID(ctx, requesterID)
+	return req.WithContext(ctx)
+}
+
+// Helper function to create a request for a user's media list
+func createUserMediaRequest(requesterID int, ownerID string, query string) *http.Request {
+	req := httptest.NewRequest("GET", "/api/users/"+ownerID+"/media"+query, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("userID", ownerID)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = authctx.WithUserID(ctx, requesterID)
+	return req.WithContext(ctx)
+}
+
 // Helper function to create a multipart request with file uploads
 func createMultipartRequest(t *testing.T, fileContent, thumbnailContent []byte) (*http.Request, error) {
 	body := new(bytes.Buffer)
@@ -69,7 +140,7 @@ func createMultipartRequest(t *testing.T, fileContent, thumbnailContent []byte)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 
 	// Add user_id to the context
-	ctx := context.WithValue(req.Context(), "user_id", 123)
+	ctx := authctx.WithUserID(req.Context(), 123)
 	req = req.WithContext(ctx)
 
 	return req, nil
@@ -88,7 +159,7 @@ func TestMediaHandler_UploadMedia_Success(t *testing.T) {
 		}, nil)
 
 	// Create handler with mock service
-	handler := NewMediaHandler(mockService, 10, 100)
+	handler := NewMediaHandler(mockService, 10, 100, 10)
 
 	// Create test request
 	fileContent := []byte("fake image content")
@@ -124,7 +195,7 @@ func TestMediaHandler_UploadMedia_Unauthorized(t *testing.T) {
 	mockService := new(MockMediaService)
 
 	// Create handler with mock service
-	handler := NewMediaHandler(mockService, 10, 100)
+	handler := NewMediaHandler(mockService, 10, 100, 10)
 
 	// Create test request without user_id in context
 	fileContent := []byte("fake image content")
@@ -159,7 +230,7 @@ func TestMediaHandler_UploadMedia_ServiceErrors(t *testing.T) {
 		{
 			name:          "Invalid file type error",
 			serviceErr:    media.ErrInvalidFileType,
-			expectedCode:  http.StatusBadRequest,
+			expectedCode:  http.StatusUnsupportedMediaType,
 			expectedError: "Invalid file type",
 		},
 		{
@@ -186,7 +257,7 @@ func TestMediaHandler_UploadMedia_ServiceErrors(t *testing.T) {
 				Return(nil, tc.serviceErr)
 
 			// Create handler with mock service
-			handler := NewMediaHandler(mockService, 10, 100)
+			handler := NewMediaHandler(mockService, 10, 100, 10)
 
 			// Create test request
 			fileContent := []byte("fake image content")
@@ -244,7 +315,7 @@ func TestMediaHandler_ConcurrentUploads(t *testing.T) {
 
 	// Create handler with a limit of 3 concurrent uploads
 	maxConcurrent := 3
-	handler := NewMediaHandler(mockService, maxConcurrent, 100)
+	handler := NewMediaHandler(mockService, maxConcurrent, 100, 10)
 
 	// Create sample content
 	fileContent := []byte("fake image content")
@@ -297,7 +368,7 @@ func TestMediaHandler_MissingFiles(t *testing.T) {
 	mockService := new(MockMediaService)
 
 	// Create handler with mock service
-	handler := NewMediaHandler(mockService, 10, 100)
+	handler := NewMediaHandler(mockService, 10, 100, 10)
 
 	// Test case: missing file
 	t.Run("Missing main file", func(t *testing.T) {
@@ -317,7 +388,7 @@ func TestMediaHandler_MissingFiles(t *testing.T) {
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 
 		// Add user_id to context
-		ctx := context.WithValue(req.Context(), "user_id", 123)
+		ctx := authctx.WithUserID(req.Context(), 123)
 		req = req.WithContext(ctx)
 
 		rr := httptest.NewRecorder()
@@ -345,7 +416,7 @@ func TestMediaHandler_MissingFiles(t *testing.T) {
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 
 		// Add user_id to context
-		ctx := context.WithValue(req.Context(), "user_id", 123)
+		ctx := authctx.WithUserID(req.Context(), 123)
 		req = req.WithContext(ctx)
 
 		rr := httptest.NewRecorder()
@@ -355,3 +426,266 @@ func TestMediaHandler_MissingFiles(t *testing.T) {
 		assert.Contains(t, rr.Body.String(), "Could not get thumbnail")
 	})
 }
+
+func TestMediaHandler_GetUserMedia_NonOwnerSeesPublicOnly(t *testing.T) {
+	mockService := new(MockMediaService)
+	handler := NewMediaHandler(mockService, 1, 10, 10)
+
+	publicMedia := []media.Media{
+		{ID: 2, URL: "https://example.com/2.jpg", ThumbnailURL: "https://example.com/2_thumb.jpg"},
+	}
+	mockService.On("GetUserMedia", 42, false, defaultMediaPageSize, 0).Return(publicMedia, 1, nil)
+
+	req := createUserMediaRequest(123, "42", "")
+	rr := httptest.NewRecorder()
+
+	handler.GetUserMedia(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response []MediaResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response, 1)
+	assert.Equal(t, 2, response[0].ID)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestMediaHandler_GetUserMedia_OwnerSeesPrivate(t *testing.T) {
+	mockService := new(MockMediaService)
+	handler := NewMediaHandler(mockService, 1, 10, 10)
+
+	allMedia := []media.Media{
+		{ID: 1, URL: "https://example.com/1.jpg", ThumbnailURL: "https://example.com/1_thumb.jpg"},
+		{ID: 2, URL: "https://example.com/2.jpg", ThumbnailURL: "https://example.com/2_thumb.jpg"},
+	}
+	mockService.On("GetUserMedia", 42, true, 5, 10).Return(allMedia, 12, nil)
+
+	req := createUserMediaRequest(42, "42", "?limit=5&offset=10")
+	rr := httptest.NewRecorder()
+
+	handler.GetUserMedia(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response []MediaResponse
+	err := json.Unmarshal(rr.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Len(t, response, 2)
+	assert.Equal(t, "12", rr.Header().Get("X-Total-Count"))
+
+	mockService.AssertExpectations(t)
+}
+
+func TestMediaHandler_GetUserMedia_InvalidUserID(t *testing.T) {
+	mockService := new(MockMediaService)
+	handler := NewMediaHandler(mockService, 1, 10, 10)
+
+	req := createUserMediaRequest(123, "abc", "")
+	rr := httptest.NewRecorder()
+
+	handler.GetUserMedia(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	mockService.AssertNotCalled(t, "GetUserMedia")
+}
+
+func TestMediaHandler_UploadMedia_InvalidForm(t *testing.T) {
+	// Build a multipart body with the given fields mapped to their contents
+	multipartRequest := func(files map[string][]byte) *http.Request {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		for field, content := range files {
+			part, err := writer.CreateFormFile(field, field+".jpg")
+			assert.NoError(t, err)
+			_, err = part.Write(content)
+			assert.NoError(t, err)
+		}
+		writer.Close()
+
+		req := httptest.NewRequest("POST", "/api/media", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		return req
+	}
+
+	tests := []struct {
+		name         string
+		request      func() *http.Request
+		expectedCode string
+	}{
+		{
+			name: "Wrong file field name",
+			request: func() *http.Request {
+				return multipartRequest(map[string][]byte{"upload": []byte("content"), "thumbnail": []byte("thumbnail")})
+			},
+			expectedCode: apierrors.CodeMissingFile,
+		},
+		{
+			name: "Empty file",
+			request: func() *http.Request {
+				return multipartRequest(map[string][]byte{"file": {}, "thumbnail": []byte("thumbnail")})
+			},
+			expectedCode: apierrors.CodeEmptyFile,
+		},
+		{
+			name: "Not multipart",
+			request: func() *http.Request {
+				req := httptest.NewRequest("POST", "/api/media", bytes.NewBufferString(`{"file":"content"}`))
+				req.Header.Set("Content-Type", "application/json")
+				return req
+			},
+			expectedCode: apierrors.CodeMalformedMultipart,
+		},
+		{
+			name: "Truncated multipart body",
+			request: func() *http.Request {
+				req := httptest.NewRequest("POST", "/api/media", bytes.NewBufferString("--boundary\r\nContent-Disposition: form-data; name=\"file\"; filename=\"a.jpg\"\r\n\r\ncontent"))
+				req.Header.Set("Content-Type", "multipart/form-data; boundary=boundary")
+				return req
+			},
+			expectedCode: apierrors.CodeMalformedMultipart,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockMediaService)
+			handler := NewMediaHandler(mockService, 1, 10, 10)
+
+			req := tc.request()
+			req = req.WithContext(authctx.WithUserID(req.Context(), 123))
+			rr := httptest.NewRecorder()
+
+			handler.UploadMedia(rr, req)
+
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+			var body apierrors.ErrorResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tc.expectedCode, body.Code)
+			mockService.AssertNotCalled(t, "UploadMedia", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestMediaHandler_DeleteMedia_Owner(t *testing.T) {
+	mockService := new(MockMediaService)
+	handler := NewMediaHandler(mockService, 1, 10, 10)
+
+	mockService.On("DeleteMedia", 7, 42).Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.DeleteMedia(rr, createMediaItemRequest("DELETE", 42, "7"))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestMediaHandler_SingleMediaOwnershipErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"non-owner", media.ErrNotMediaOwner, http.StatusForbidden, apierrors.CodeNotMediaOwner},
+		{"missing", media.ErrMediaNotFound, http.StatusNotFound, apierrors.CodeMediaNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockMediaService)
+			handler := NewMediaHandler(mockService, 1, 10, 10)
+			mockService.On("GetMedia", 7, 123).Return(nil, tt.err)
+			mockService.On("DeleteMedia", 7, 123).Return(tt.err)
+
+			for method, serve := range map[string]http.HandlerFunc{"GET": handler.GetMedia, "DELETE": handler.DeleteMedia} {
+				rr := httptest.NewRecorder()
+				serve(rr, createMediaItemRequest(method, 123, "7"))
+
+				assert.Equal(t, tt.status, rr.Code, method)
+				var body apierrors.ErrorResponse
+				assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+				assert.Equal(t, tt.code, body.Code, method)
+			}
+		})
+	}
+}
+
+func TestMediaHandler_DeleteMedia_ProfileMinimum(t *testing.T) {
+	mockService := new(MockMediaService)
+	handler := NewMediaHandler(mockService, 1, 10, 10)
+	mockService.On("DeleteMedia", 7, 42).Return(media.ErrProfileMediaRequired)
+
+	rr := httptest.NewRecorder()
+	handler.DeleteMedia(rr, createMediaItemRequest("DELETE", 42, "7"))
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	var body apierrors.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, apierrors.CodeProfileMediaRequired, body.Code)
+}
+
+func TestMediaHandler_PresignUpload(t *testing.T) {
+	mockService := new(MockMediaService)
+	handler := NewMediaHandler(mockService, 1, 10, 10)
+
+	expiresAt := time.Now().Add(time.Minute).UTC().Truncate(time.Second)
+	mockService.On("PresignUpload", 42, "clip.mp4", "video/mp4").
+		Return(&media.PresignedUpload{MediaID: 9, UploadURL: "https://s3.example.com/put", ExpiresAt: expiresAt}, nil)
+	mockService.On("PresignUpload", 42, "notes.txt", "text/plain").Return(nil, media.ErrInvalidFileType)
+
+	req := httptest.NewRequest("POST", "/api/media/presign", bytes.NewBufferString(`{"file_name":"clip.mp4","content_type":"video/mp4"}`))
+	req = req.WithContext(authctx.WithUserID(req.Context(), 42))
+	rr := httptest.NewRecorder()
+	handler.PresignUpload(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var upload media.PresignedUpload
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&upload))
+	assert.Equal(t, 9, upload.MediaID)
+	assert.Equal(t, "https://s3.example.com/put", upload.UploadURL)
+	assert.True(t, expiresAt.Equal(upload.ExpiresAt))
+
+	req = httptest.NewRequest("POST", "/api/media/presign", bytes.NewBufferString(`{"file_name":"notes.txt","content_type":"text/plain"}`))
+	req = req.WithContext(authctx.WithUserID(req.Context(), 42))
+	rr = httptest.NewRecorder()
+	handler.PresignUpload(rr, req)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+}
+
+func TestMediaHandler_CompleteUpload_NotUploadedYet(t *testing.T) {
+	mockService := new(MockMediaService)
+	handler := NewMediaHandler(mockService, 1, 10, 10)
+
+	mockService.On("CompleteUpload", 9, 42).Return(nil, media.ErrUploadNotFound)
+
+	rr := httptest.NewRecorder()
+	handler.CompleteUpload(rr, createMediaItemRequest("POST", 42, "9"))
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	var body apierrors.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, apierrors.CodeUploadNotFound, body.Code)
+}
+
+func TestMediaHandler_GetConstraints(t *testing.T) {
+	mockService := new(MockMediaService)
+	handler := NewMediaHandler(mockService, 1, 10, 10)
+
+	mockService.On("Constraints").Return(media.Constraints{
+		MaxBytes:            50 << 20,
+		AllowedContentTypes: []string{"image/jpeg", "video/mp4"},
+		MaxProfileMedia:     10,
+		MinProfileMedia:     1,
+	})
+
+	req := httptest.NewRequest("GET", "/api/media/constraints", nil)
+	req = req.WithContext(authctx.WithUserID(req.Context(), 42))
+	rr := httptest.NewRecorder()
+	handler.GetConstraints(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"max_bytes":52428800,"allowed_content_types":["image/jpeg","video/mp4"],"max_profile_media":10,"min_profile_media":1}`, rr.Body.String())
+}
@@ -2,16 +2,26 @@ package messaging
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/lib/pq"
 
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/apiversion"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/cors"
 	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/messaging"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/profile"
@@ -33,6 +43,99 @@ type Handler struct {
 	upgrader         websocket.Upgrader
 	clients          map[int]*Client // Map of userID to client connection
 	clientsMutex     sync.RWMutex
+	idleTimeout      time.Duration
+
+	reactionWindow   time.Duration
+	pendingReactions map[reactionKey][]byte // Latest reaction event per key awaiting broadcast
+	reactionsMutex   sync.Mutex
+	reactionLocks    [reactionLockStripes]sync.Mutex // Serialize changes to the same reaction, see lockReaction
+
+	ephemeralInterval time.Duration
+	ephemeralEvents   map[ephemeralKey]*ephemeralState // Throttle state of typing and read receipt events
+	ephemeralMutex    sync.Mutex
+
+	typingTimeout time.Duration
+	typingTimers  map[ephemeralKey]*typingExpiry // Pending expiry of typing indicators still on
+	typingMutex   sync.Mutex
+
+	maxMalformedFrames int
+	pingInterval       time.Duration
+	sendBufferSize     int
+	maxFrameBytes      int64
+
+	expirySweepInterval time.Duration
+
+	presence PresenceRecorder
+}
+
+// PresenceRecorder records when a user was last active
+type PresenceRecorder interface {
+	Record(userID int)
+}
+
+// DefaultSendBufferSize is the number of outbound frames queued per client when not configured
+const DefaultSendBufferSize = 256
+
+// DefaultMaxFrameBytes is the largest inbound WebSocket frame accepted when not configured
+const DefaultMaxFrameBytes = 64 << 10
+
+// Page sizes of paginated endpoints
+const (
+	defaultPageSize       = 50
+	maxPageSize           = 100
+	defaultSearchPageSize = 20
+	maxSearchPageSize     = maxPageSize
+)
+
+// Config holds the configuration for the messaging handler
+type Config struct {
+	// IdleTimeout closes WebSocket connections with no inbound messages within the window.
+	// Zero disables the timeout.
+	IdleTimeout time.Duration
+
+	// ReactionCoalesceWindow collapses reaction changes by the same user on the same
+	// message within the window into a single broadcast. Zero broadcasts every change.
+	ReactionCoalesceWindow time.Duration
+
+	// EphemeralEventInterval limits typing and read receipt broadcasts to one per user
+	// per chat within the interval, the latest suppressed event is sent when it ends.
+	// Zero broadcasts every event.
+	EphemeralEventInterval time.Duration
+
+	// TypingTimeout broadcasts is_typing false for a user who sent no typing event
+	// within the timeout, so indicators of clients that never clear them don't stick.
+	// Zero disables the expiry.
+	TypingTimeout time.Duration
+
+	// MaxMalformedFrames closes WebSocket connections after this many consecutive frames
+	// that could not be parsed. Zero keeps such connections open.
+	MaxMalformedFrames int
+
+	// PingInterval pings WebSocket clients at this interval. A connection that has not
+	// answered a ping or sent anything for two intervals is closed. Zero disables pings.
+	PingInterval time.Duration
+
+	// SendBufferSize is the number of outbound frames queued per client. A client whose
+	// queue overflows is disconnected. Zero uses DefaultSendBufferSize.
+	SendBufferSize int
+
+	// MaxFrameBytes is the largest inbound WebSocket frame accepted. A larger frame closes
+	// the connection with close code 1009 (message too big). Zero uses DefaultMaxFrameBytes.
+	MaxFrameBytes int64
+
+	// ExpirySweepInterval is how often expired disappearing messages are removed and
+	// their removal broadcast. Zero disables the sweeper.
+	ExpirySweepInterval time.Duration
+
+	// AllowedOrigins lists the browser origins allowed to open WebSocket connections.
+	// Upgrades without an Origin header come from non-browser clients and are allowed.
+	AllowedOrigins []string
+
+	// AllowAnyOrigin accepts upgrades from every origin. Only meant for local development.
+	AllowAnyOrigin bool
+
+	// Presence is told when a user connects and disconnects. Nil records nothing.
+	Presence PresenceRecorder
 }
 
 // CreateChatRequest представляет запрос на создание чата
@@ -47,16 +150,37 @@ type AddParticipantRequest struct {
 	UserID int `json:"user_id"`
 }
 
+// SetParticipantRoleRequest представляет запрос на изменение роли участника чата
+type SetParticipantRoleRequest struct {
+	Role string `json:"role" enums:"admin,member"`
+}
+
+// RenameChatRequest представляет запрос на переименование чата
+type RenameChatRequest struct {
+	ChatName string `json:"chat_name"`
+}
+
 // AddReactionRequest представляет запрос на добавление реакции к сообщению
 type AddReactionRequest struct {
-	ReactionID   string `json:"reaction_id"`
+	ReactionID   string `json:"reaction_id"` // Если не указан, ID генерирует сервер
+	ReactionCode string `json:"reaction_code"`
+}
+
+// RemoveReactionRequest представляет запрос на удаление реакции с сообщения
+type RemoveReactionRequest struct {
 	ReactionCode string `json:"reaction_code"`
 }
 
 // SendMessageRequest представляет запрос на отправку сообщения
 type SendMessageRequest struct {
-	MessageID string `json:"message_id"`
+	MessageID string `json:"message_id"` // Если не указан, ID генерирует сервер
 	Content   string `json:"content"`
+	ExpiresIn int    `json:"expires_in,omitempty"` // Секунды до исчезновения сообщения, 0 - бессрочно
+}
+
+// EditMessageRequest представляет запрос на редактирование сообщения
+type EditMessageRequest struct {
+	Content string `json:"content"`
 }
 
 type GetOrCreateDirectChatRequest struct {
@@ -71,19 +195,73 @@ type AddReactionResponse struct {
 	ReactionID string `json:"reaction_id"`
 }
 
+// WSSubprotocol is the WebSocket subprotocol of the current frame format.
+// Clients that don't request a subprotocol get this format too.
+const WSSubprotocol = "brigadka.v1"
+
+// supportedSubprotocols lists the frame formats the server speaks, newest first
+var supportedSubprotocols = []string{WSSubprotocol}
+
 // WSConn is an interface for websocket.Conn to allow mocking in tests.
 type WSConn interface {
 	ReadMessage() (messageType int, p []byte, err error)
 	WriteMessage(messageType int, data []byte) error
+	WriteControl(messageType int, data []byte, deadline time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetReadLimit(limit int64)
+	SetPongHandler(h func(appData string) error)
 	Close() error
 }
 
 type Client struct {
-	conn   WSConn
-	userID int
+	conn       WSConn
+	userID     int
+	chatRooms  map[string]struct{} // Chats the client receives realtime events for
+	roomsMutex sync.RWMutex
+
+	send     chan []byte   // Outbound frames, written by the client's writer goroutine only
+	done     chan struct{} // Closed when the client disconnects
+	doneOnce sync.Once
+}
+
+// joinRoom activates realtime delivery of a chat for the client
+func (c *Client) joinRoom(chatID string) {
+	c.roomsMutex.Lock()
+	defer c.roomsMutex.Unlock()
+	c.chatRooms[chatID] = struct{}{}
 }
 
-func NewHandler(messagineService messaging.Service, profileService ProfileService, pushService PushService) *Handler {
+// leaveRoom deactivates realtime delivery of a chat for the client
+func (c *Client) leaveRoom(chatID string) {
+	c.roomsMutex.Lock()
+	defer c.roomsMutex.Unlock()
+	delete(c.chatRooms, chatID)
+}
+
+// inRoom reports whether the client receives realtime events for a chat
+func (c *Client) inRoom(chatID string) bool {
+	c.roomsMutex.RLock()
+	defer c.roomsMutex.RUnlock()
+	_, ok := c.chatRooms[chatID]
+	return ok
+}
+
+func NewHandler(messagineService messaging.Service, profileService ProfileService, pushService PushService, config Config) *Handler {
+	sendBufferSize := config.SendBufferSize
+	if sendBufferSize <= 0 {
+		sendBufferSize = DefaultSendBufferSize
+	}
+	maxFrameBytes := config.MaxFrameBytes
+	if maxFrameBytes <= 0 {
+		maxFrameBytes = DefaultMaxFrameBytes
+	}
+
+	// Browsers send cookies with cross-site upgrades, so unknown origins must be rejected
+	checkOrigin := cors.NewAllowlist(config.AllowedOrigins).CheckOrigin
+	if config.AllowAnyOrigin {
+		checkOrigin = func(r *http.Request) bool { return true }
+	}
+
 	return &Handler{
 		messagineService: messagineService,
 		profileService:   profileService,
@@ -91,11 +269,28 @@ func NewHandler(messagineService messaging.Service, profileService ProfileServic
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  1024,
 			WriteBufferSize: 1024,
-			CheckOrigin: func(r *http.Request) bool {
-				return true // In production, implement proper origin check
-			},
+			Subprotocols:    supportedSubprotocols,
+			CheckOrigin:     checkOrigin,
 		},
-		clients: make(map[int]*Client),
+		clients:          make(map[int]*Client),
+		idleTimeout:      config.IdleTimeout,
+		reactionWindow:   config.ReactionCoalesceWindow,
+		pendingReactions: make(map[reactionKey][]byte),
+
+		ephemeralInterval: config.EphemeralEventInterval,
+		ephemeralEvents:   make(map[ephemeralKey]*ephemeralState),
+
+		typingTimeout: config.TypingTimeout,
+		typingTimers:  make(map[ephemeralKey]*typingExpiry),
+
+		maxMalformedFrames: config.MaxMalformedFrames,
+		pingInterval:       config.PingInterval,
+		sendBufferSize:     sendBufferSize,
+		maxFrameBytes:      maxFrameBytes,
+
+		expirySweepInterval: config.ExpirySweepInterval,
+
+		presence: config.Presence,
 	}
 }
 
@@ -114,18 +309,25 @@ type Reaction struct {
 // @Accept       json
 // @Produce      json
 // @Security     BearerAuth
+// @Param        Sec-WebSocket-Protocol header string false "Формат сообщений, например brigadka.v1"
 // @Success      101 {object} string "WebSocket connection established"
-// @Failure      401 {string} string "Unauthorized"
+// @Failure      400 {object} apierrors.ErrorResponse "Unsupported subprotocol"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
 // @Router       /ws/chat [get]
 func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from context (assuming auth middleware sets this)
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Upgrade connection to WebSocket
+	// A client asking only for formats we don't speak would misread every frame
+	if requested := websocket.Subprotocols(r); len(requested) > 0 && !supportsAnySubprotocol(requested) {
+		apierrors.RespondError(w, http.StatusBadRequest, "Unsupported WebSocket subprotocol", apierrors.CodeUnsupportedSubprotocol)
+		return
+	}
+
+	// Upgrade connection to WebSocket, the upgrader echoes the negotiated subprotocol
 	conn, err := h.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Error upgrading to WebSocket: %v", err)
@@ -135,55 +337,77 @@ func (h *Handler) HandleWebSocket(w http.ResponseWriter, r *http.Request) {
 	h.handleWSConnection(conn, userID)
 }
 
+// supportsAnySubprotocol reports whether one of the requested subprotocols is supported
+func supportsAnySubprotocol(requested []string) bool {
+	for _, protocol := range requested {
+		for _, supported := range supportedSubprotocols {
+			if protocol == supported {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // @Summary      Создать новый чат
-// @Description  Создает новый чат с указанными участниками
+// @Description  Создает новый чат с указанными участниками. Чат на двоих - это личный чат: возвращается существующий личный чат пары или создается новый, chat_id из запроса при этом не используется
 // @Tags         messaging
 // @Accept       json
 // @Produce      json
 // @Param        request body CreateChatRequest true "Данные для создания чата"
 // @Security     BearerAuth
 // @Success      201 {object} ChatIDResponse "Чат успешно создан"
-// @Failure      400 {string} string "Некорректный запрос"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      409 {string} string "Чат с таким ID уже существует"
-// @Failure      500 {string} string "Ошибка сервера"
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректный запрос или чат с самим собой"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      403 {object} apierrors.ErrorResponse "Пользователи заблокировали друг друга"
+// @Failure      409 {object} apierrors.ErrorResponse "Чат с таким ID уже существует"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
 // @Router       /chats [post]
 func (h *Handler) CreateChat(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Parse request body
 	var req CreateChatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request", apierrors.CodeInvalidRequest)
 		return
 	}
 
 	// Validate request
 	if len(req.Participants) == 0 {
-		http.Error(w, "At least one participant is required", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "At least one participant is required", apierrors.CodeParticipantsRequired)
 		return
 	}
 
 	// Create chat using the service
-	err := h.messagineService.CreateChat(r.Context(), req.ChatID, userID, req.ChatName, req.Participants)
+	chatID, err := h.messagineService.CreateChat(r.Context(), req.ChatID, userID, req.ChatName, req.Participants)
 	if err != nil {
 		// Check if it's a duplicate chat (UUID constraint violation)
 		if isPrimaryKeyViolation(err) {
-			http.Error(w, apierrors.ErrorChatAlreadyExistsWithThisID, http.StatusConflict)
+			apierrors.RespondError(w, http.StatusConflict, apierrors.ErrorChatAlreadyExistsWithThisID, apierrors.CodeChatAlreadyExists)
 			return
 		}
-		http.Error(w, "Server error", http.StatusInternalServerError)
+		if err.Error() == apierrors.ErrorUsersBlocked {
+			apierrors.RespondError(w, http.StatusForbidden, apierrors.ErrorUsersBlocked, apierrors.CodeUserBlocked)
+			return
+		}
+		if err.Error() == apierrors.ErrorCannotCreateChatWithSelf {
+			apierrors.RespondError(w, http.StatusBadRequest, apierrors.ErrorCannotCreateChatWithSelf, apierrors.CodeCannotChatWithSelf)
+			return
+		}
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
 		log.Printf("Error creating chat: %v", err)
 		return
 	}
 
+	h.activateChatRoom(chatID, append([]int{userID}, req.Participants...)...)
+
 	response := ChatIDResponse{
-		ChatID: req.ChatID,
+		ChatID: chatID,
 	}
 
 	// Return created chat
@@ -200,23 +424,23 @@ func (h *Handler) CreateChat(w http.ResponseWriter, r *http.Request) {
 // @Param        request body GetOrCreateDirectChatRequest true "ID второго пользователя"
 // @Security     BearerAuth
 // @Success      200 {object} ChatIDResponse "ID чата"
-// @Failure      400 {string} string "Некорректный запрос или попытка создать чат с самим собой"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      500 {string} string "Ошибка сервера"
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректный запрос или попытка создать чат с самим собой"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      403 {object} apierrors.ErrorResponse "Пользователи заблокировали друг друга"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
 // @Router       /chats/direct [post]
 // GetOrCreateDirectChat finds an existing direct chat or creates a new one
 func (h *Handler) GetOrCreateDirectChat(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context (current user)
-	currentUserID, ok := r.Context().Value("user_id").(int)
+	currentUserID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Parse request to get the other user's ID
 	var req GetOrCreateDirectChatRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request", apierrors.CodeInvalidRequest)
 		return
 	}
 
@@ -224,15 +448,21 @@ func (h *Handler) GetOrCreateDirectChat(w http.ResponseWriter, r *http.Request)
 	chatID, err := h.messagineService.GetOrCreateDirectChat(r.Context(), currentUserID, req.UserID)
 	if err != nil {
 		if err.Error() == apierrors.ErrorCannotCreateChatWithSelf {
-			http.Error(w, apierrors.ErrorCannotCreateChatWithSelf, http.StatusBadRequest)
+			apierrors.RespondError(w, http.StatusBadRequest, apierrors.ErrorCannotCreateChatWithSelf, apierrors.CodeCannotChatWithSelf)
+			return
+		}
+		if err.Error() == apierrors.ErrorUsersBlocked {
+			apierrors.RespondError(w, http.StatusForbidden, apierrors.ErrorUsersBlocked, apierrors.CodeUserBlocked)
 			return
 		}
 
-		http.Error(w, "Server error", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
 		log.Printf("Error getting/creating direct chat: %v", err)
 		return
 	}
 
+	h.activateChatRoom(chatID, currentUserID, req.UserID)
+
 	response := ChatIDResponse{
 		ChatID: chatID,
 	}
@@ -248,21 +478,20 @@ func (h *Handler) GetOrCreateDirectChat(w http.ResponseWriter, r *http.Request)
 // @Produce      json
 // @Security     BearerAuth
 // @Success      200 {array} messaging.Chat "Список чатов пользователя"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      500 {string} string "Ошибка сервера"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
 // @Router       /chats [get]
 func (h *Handler) GetUserChats(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Get user's chats using the service
 	chats, err := h.messagineService.GetUserChats(userID)
 	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
 		log.Printf("Error fetching chats: %v", err)
 		return
 	}
@@ -272,6 +501,32 @@ func (h *Handler) GetUserChats(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(chats)
 }
 
+// @Summary      Получить команды пользователя
+// @Description  Возвращает групповые чаты пользователя: групповые чаты служат импровизационными командами
+// @Tags         messaging
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {array} messaging.Chat "Команды пользователя"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /users/me/teams [get]
+func (h *Handler) GetUserTeams(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	teams, err := h.messagineService.GetUserTeams(userID)
+	if err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+		log.Printf("Error fetching teams: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(teams)
+}
+
 // @Summary      Получить детали чата
 // @Description  Возвращает информацию о чате и его участниках
 // @Tags         messaging
@@ -279,15 +534,14 @@ func (h *Handler) GetUserChats(w http.ResponseWriter, r *http.Request) {
 // @Param        chatID path string true "ID чата"
 // @Security     BearerAuth
 // @Success      200 {object} messaging.Chat "Детали чата"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      404 {string} string "Чат не найден"
-// @Failure      500 {string} string "Ошибка сервера"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат не найден"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
 // @Router       /chats/{chatID} [get]
 func (h *Handler) GetChat(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -298,9 +552,9 @@ func (h *Handler) GetChat(w http.ResponseWriter, r *http.Request) {
 	chat, err := h.messagineService.GetChat(chatID, userID)
 	if err != nil {
 		if err.Error() == apierrors.ErrorUserNotInChat {
-			http.Error(w, "Chat not found", http.StatusNotFound)
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
 		} else {
-			http.Error(w, "Server error", http.StatusInternalServerError)
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
 			log.Printf("Error fetching chat details: %v", err)
 		}
 		return
@@ -312,68 +566,184 @@ func (h *Handler) GetChat(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary      Получить сообщения чата
-// @Description  Возвращает сообщения чата с поддержкой пагинации
+// @Description  Возвращает сообщения чата с поддержкой пагинации. С параметром before возвращает страницу
+// @Description  messaging.MessagePage с курсором следующей страницы, иначе массив сообщений по limit/offset
 // @Tags         messaging
 // @Produce      json
 // @Param        chatID path string true "ID чата"
-// @Param        limit query int false "Максимальное количество сообщений (по умолчанию 50)"
-// @Param        offset query int false "Смещение (по умолчанию 0)"
+// @Param        limit query int false "Максимальное количество сообщений (по умолчанию 50, не больше 100)"
+// @Param        offset query int false "Смещение (по умолчанию 0), игнорируется вместе с before"
+// @Param        before query string false "Курсор: ID сообщения, до которого вернуть страницу. Пустое значение — с последнего сообщения"
+// @Param        include query string false "Дополнительные данные через запятую: reactions - счетчики реакций, reads - кто прочитал сообщение"
 // @Security     BearerAuth
 // @Success      200 {array} messaging.ChatMessage "Сообщения чата"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      404 {string} string "Чат не найден"
-// @Failure      500 {string} string "Ошибка сервера"
+// @Failure      400 {object} apierrors.ErrorResponse "Неверный курсор или параметр include"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат не найден"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
 // @Router       /chats/{chatID}/messages [get]
 func (h *Handler) GetChatMessages(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Get chat ID from URL
 	chatID := chi.URLParam(r, "chatID")
 
-	// Get pagination parameters
-	limitStr := r.URL.Query().Get("limit")
-	offsetStr := r.URL.Query().Get("offset")
+	limit, offset := parsePagination(r, defaultPageSize, maxPageSize)
 
-	limit := 50 // Default
-	offset := 0 // Default
-
-	// Parse limit and offset
-	if limitStr != "" {
-		if val, err := parseInt(limitStr); err == nil && val > 0 {
-			limit = val
-		}
+	include, err := parseMessageInclude(r.URL.Query().Get("include"))
+	if err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid include", apierrors.CodeInvalidRequest)
+		return
 	}
 
-	if offsetStr != "" {
-		if val, err := parseInt(offsetStr); err == nil && val >= 0 {
-			offset = val
+	// Prefer the cursor when present, offsets shift as new messages arrive
+	if r.URL.Query().Has("before") {
+		page, err := h.messagineService.GetChatMessagesBefore(chatID, userID, r.URL.Query().Get("before"), limit)
+		if err != nil {
+			switch err.Error() {
+			case apierrors.ErrorUserNotInChat:
+				apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+			case apierrors.ErrorInvalidMessageCursor:
+				apierrors.RespondError(w, http.StatusBadRequest, "Invalid cursor", apierrors.CodeInvalidCursor)
+			default:
+				apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+				log.Printf("Error fetching messages: %v", err)
+			}
+			return
+		}
+		if !h.attachMessageDetails(w, chatID, userID, page.Messages, include) {
+			return
 		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(page)
+		return
 	}
 
 	// Get messages
 	messages, err := h.messagineService.GetChatMessages(chatID, userID, limit, offset)
 	if err != nil {
 		if err.Error() == apierrors.ErrorUserNotInChat {
-			http.Error(w, "Chat not found", http.StatusNotFound)
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
 		} else {
-			http.Error(w, "Server error", http.StatusInternalServerError)
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
 			log.Printf("Error fetching messages: %v", err)
 		}
 		return
 	}
+	if !h.attachMessageDetails(w, chatID, userID, messages, include) {
+		return
+	}
 
 	// Return messages
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(messages)
 }
 
+// attachMessageDetails fills in the requested details of the messages and writes an
+// error response if that fails
+func (h *Handler) attachMessageDetails(w http.ResponseWriter, chatID string, userID int, messages []messaging.ChatMessage, include messaging.MessageInclude) bool {
+	// The default response stays lean
+	if include == (messaging.MessageInclude{}) {
+		return true
+	}
+	if err := h.messagineService.AttachMessageDetails(chatID, userID, messages, include); err != nil {
+		if err.Error() == apierrors.ErrorUserNotInChat {
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		} else {
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error fetching message details: %v", err)
+		}
+		return false
+	}
+	return true
+}
+
+// @Summary      Поиск сообщений в чате
+// @Description  Ищет сообщения чата, содержащие текст запроса без учета регистра. Удаленные сообщения не возвращаются,
+// @Description  результаты отсортированы от новых к старым
+// @Tags         messaging
+// @Produce      json
+// @Param        chatID path string true "ID чата"
+// @Param        q query string true "Текст для поиска (до 200 символов)"
+// @Param        limit query int false "Максимальное количество сообщений (по умолчанию 20, не больше 100)"
+// @Param        offset query int false "Смещение (по умолчанию 0)"
+// @Security     BearerAuth
+// @Success      200 {array} messaging.ChatMessage "Найденные сообщения"
+// @Failure      400 {object} apierrors.ErrorResponse "Пустой или слишком длинный запрос"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат не найден"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /chats/{chatID}/messages/search [get]
+func (h *Handler) SearchMessages(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID := chi.URLParam(r, "chatID")
+
+	limit, offset := parsePagination(r, defaultSearchPageSize, maxSearchPageSize)
+
+	messages, err := h.messagineService.SearchMessages(chatID, userID, r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		switch err.Error() {
+		case apierrors.ErrorInvalidSearchQuery:
+			apierrors.RespondError(w, http.StatusBadRequest, "Search query must be 1 to 200 characters", apierrors.CodeInvalidSearchQuery)
+		case apierrors.ErrorUserNotInChat:
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error searching messages: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+// @Summary      Получить позиции прочтения чата
+// @Description  Возвращает для каждого участника чата последнее прочитанное сообщение и время прочтения.
+// @Description  У участников, которые еще ничего не прочитали, поля пустые
+// @Tags         messaging
+// @Produce      json
+// @Param        chatID path string true "ID чата"
+// @Security     BearerAuth
+// @Success      200 {array} messaging.ReadPosition "Позиции прочтения участников"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат не найден"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /chats/{chatID}/read-positions [get]
+func (h *Handler) GetReadPositions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID := chi.URLParam(r, "chatID")
+
+	positions, err := h.messagineService.GetReadPositions(chatID, userID)
+	if err != nil {
+		if err.Error() == apierrors.ErrorUserNotInChat {
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		} else {
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error fetching read positions: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(positions)
+}
+
 // @Summary      Добавить участника в чат
-// @Description  Добавляет нового участника в существующий чат
+// @Description  Добавляет нового участника в существующий чат. Доступно только администраторам чата
 // @Tags         messaging
 // @Accept       json
 // @Produce      json
@@ -381,16 +751,16 @@ func (h *Handler) GetChatMessages(w http.ResponseWriter, r *http.Request) {
 // @Param        request body AddParticipantRequest true "Данные пользователя для добавления"
 // @Security     BearerAuth
 // @Success      201 {string} string "Участник успешно добавлен"
-// @Failure      400 {string} string "Некорректный запрос"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      404 {string} string "Чат не найден"
-// @Failure      500 {string} string "Ошибка сервера"
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректный запрос или чат личный"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      403 {object} apierrors.ErrorResponse "Пользователь не администратор чата или пользователи заблокировали друг друга"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат не найден"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
 // @Router       /chats/{chatID}/participants [post]
 func (h *Handler) AddParticipant(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -400,52 +770,52 @@ func (h *Handler) AddParticipant(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req AddParticipantRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request", apierrors.CodeInvalidRequest)
 		return
 	}
 
-	// Check if the current user is in the chat (only participants can add others)
-	inChat, err := h.messagineService.IsUserInChat(userID, chatID)
-	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		log.Printf("Error checking chat participation: %v", err)
-		return
-	}
-	if !inChat {
-		http.Error(w, "Chat not found", http.StatusNotFound)
+	// Add new participant (only chat admins can add others)
+	if err := h.messagineService.AddParticipant(chatID, userID, req.UserID); err != nil {
+		switch err.Error() {
+		case apierrors.ErrorUserNotInChat:
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		case apierrors.ErrorNotChatAdmin:
+			apierrors.RespondError(w, http.StatusForbidden, apierrors.ErrorNotChatAdmin, apierrors.CodeNotChatAdmin)
+		case apierrors.ErrorUsersBlocked:
+			apierrors.RespondError(w, http.StatusForbidden, apierrors.ErrorUsersBlocked, apierrors.CodeUserBlocked)
+		case apierrors.ErrorDirectChatParticipants:
+			apierrors.RespondError(w, http.StatusBadRequest, apierrors.ErrorDirectChatParticipants, apierrors.CodeNotGroupChat)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error adding participant: %v", err)
+		}
 		return
 	}
 
-	// Add new participant
-	if err := h.messagineService.AddParticipant(chatID, req.UserID); err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		log.Printf("Error adding participant: %v", err)
-		return
-	}
+	h.activateChatRoom(chatID, req.UserID)
 
 	// Return success
 	w.WriteHeader(http.StatusCreated)
 }
 
 // @Summary      Удалить участника из чата
-// @Description  Удаляет участника из чата (пользователь может удалить только себя)
+// @Description  Удаляет участника из чата. Любой участник может выйти из чата сам, удалять других могут только администраторы
 // @Tags         messaging
 // @Produce      json
 // @Param        chatID path string true "ID чата"
 // @Param        userID path int true "ID пользователя для удаления"
 // @Security     BearerAuth
 // @Success      200 {string} string "Участник успешно удален"
-// @Failure      400 {string} string "Некорректный запрос"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      403 {string} string "Нет прав на удаление этого пользователя"
-// @Failure      404 {string} string "Чат не найден"
-// @Failure      500 {string} string "Ошибка сервера"
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректный запрос"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      403 {object} apierrors.ErrorResponse "Нет прав на удаление этого пользователя"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат не найден"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
 // @Router       /chats/{chatID}/participants/{userID} [delete]
 func (h *Handler) RemoveParticipant(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -454,39 +824,133 @@ func (h *Handler) RemoveParticipant(w http.ResponseWriter, r *http.Request) {
 	targetUserID, err := parseInt(chi.URLParam(r, "userID"))
 
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
 		return
 	}
 
-	// Check if the current user is in the chat
-	inChat, err := h.messagineService.IsUserInChat(userID, chatID)
+	// Remove participant (users can leave on their own, admins can remove others)
+	if err := h.messagineService.RemoveParticipant(chatID, userID, targetUserID); err != nil {
+		switch err.Error() {
+		case apierrors.ErrorUserNotInChat:
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		case apierrors.ErrorNotChatAdmin:
+			apierrors.RespondError(w, http.StatusForbidden, apierrors.ErrorNotChatAdmin, apierrors.CodeNotChatAdmin)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error removing participant: %v", err)
+		}
+		return
+	}
+
+	h.deactivateChatRoom(chatID, targetUserID)
+
+	// Return success
+	w.WriteHeader(http.StatusOK)
+}
+
+// @Summary      Изменить роль участника чата
+// @Description  Назначает участника администратором или снимает с него права администратора. Доступно только администраторам чата
+// @Tags         messaging
+// @Accept       json
+// @Param        chatID path string true "ID чата"
+// @Param        userID path int true "ID участника"
+// @Param        request body SetParticipantRoleRequest true "Новая роль"
+// @Security     BearerAuth
+// @Success      204
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректная роль"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      403 {object} apierrors.ErrorResponse "Пользователь не администратор чата"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат или участник не найден"
+// @Failure      409 {object} apierrors.ErrorResponse "В чате должен остаться хотя бы один администратор"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /chats/{chatID}/participants/{userID}/role [post]
+func (h *Handler) SetParticipantRole(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID := chi.URLParam(r, "chatID")
+	targetUserID, err := parseInt(chi.URLParam(r, "userID"))
 	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		log.Printf("Error checking chat participation: %v", err)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
+		return
+	}
+
+	var req SetParticipantRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	if err := h.messagineService.SetParticipantRole(chatID, userID, targetUserID, req.Role); err != nil {
+		switch err.Error() {
+		case apierrors.ErrorInvalidChatRole:
+			apierrors.RespondError(w, http.StatusBadRequest, "Role must be admin or member", apierrors.CodeInvalidChatRole)
+		case apierrors.ErrorUserNotInChat:
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		case apierrors.ErrorNotChatAdmin:
+			apierrors.RespondError(w, http.StatusForbidden, apierrors.ErrorNotChatAdmin, apierrors.CodeNotChatAdmin)
+		case apierrors.ErrorLastChatAdmin:
+			apierrors.RespondError(w, http.StatusConflict, apierrors.ErrorLastChatAdmin, apierrors.CodeLastChatAdmin)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error setting participant role: %v", err)
+		}
 		return
 	}
-	if !inChat {
-		http.Error(w, "Chat not found", http.StatusNotFound)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Переименовать чат
+// @Description  Меняет название группового чата и рассылает участникам событие chat_renamed. Доступно только администраторам чата
+// @Tags         messaging
+// @Accept       json
+// @Param        chatID path string true "ID чата"
+// @Param        request body RenameChatRequest true "Новое название"
+// @Security     BearerAuth
+// @Success      204
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректное название или чат не групповой"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      403 {object} apierrors.ErrorResponse "Пользователь не администратор чата"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат не найден"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /chats/{chatID} [patch]
+func (h *Handler) RenameChat(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
 		return
 	}
 
-	// Allow users to remove themselves, or check if target is the current user
-	if userID != targetUserID {
-		// In a real app, check if user has permission to remove others (admin/creator)
-		// For simplicity, we'll allow any participant to remove others
-		http.Error(w, "Not authorized to remove this user", http.StatusForbidden)
+	chatID := chi.URLParam(r, "chatID")
+
+	var req RenameChatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request", apierrors.CodeInvalidRequest)
 		return
 	}
 
-	// Remove participant
-	if err := h.messagineService.RemoveParticipant(chatID, targetUserID); err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		log.Printf("Error removing participant: %v", err)
+	if err := h.messagineService.RenameChat(chatID, userID, req.ChatName); err != nil {
+		switch err.Error() {
+		case apierrors.ErrorInvalidChatName:
+			apierrors.RespondError(w, http.StatusBadRequest, "Chat name must be 1 to 255 characters", apierrors.CodeInvalidChatName)
+		case apierrors.ErrorNotGroupChat:
+			apierrors.RespondError(w, http.StatusBadRequest, apierrors.ErrorNotGroupChat, apierrors.CodeNotGroupChat)
+		case apierrors.ErrorUserNotInChat:
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		case apierrors.ErrorNotChatAdmin:
+			apierrors.RespondError(w, http.StatusForbidden, apierrors.ErrorNotChatAdmin, apierrors.CodeNotChatAdmin)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error renaming chat: %v", err)
+		}
 		return
 	}
 
-	// Return success
-	w.WriteHeader(http.StatusOK)
+	h.broadcastChatRenamed(chatID, strings.TrimSpace(req.ChatName), userID)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // @Summary      Добавить реакцию к сообщению
@@ -498,17 +962,16 @@ func (h *Handler) RemoveParticipant(w http.ResponseWriter, r *http.Request) {
 // @Param        request body AddReactionRequest true "Данные реакции"
 // @Security     BearerAuth
 // @Success      200 {object} AddReactionResponse "Реакция успешно добавлена"
-// @Failure      400 {string} string "Некорректный запрос"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      404 {string} string "Сообщение не найдено или нет прав для реакции"
-// @Failure      409 {string} string "Реакция с таким ID уже существует"
-// @Failure      500 {string} string "Ошибка сервера"
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректный запрос"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      404 {object} apierrors.ErrorResponse "Сообщение не найдено или нет прав для реакции"
+// @Failure      409 {object} apierrors.ErrorResponse "Реакция с таким ID уже существует или ID сообщения используется в нескольких чатах"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
 // @Router       /messages/{messageID}/reactions [post]
 func (h *Handler) AddReaction(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -518,52 +981,55 @@ func (h *Handler) AddReaction(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req AddReactionRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request", apierrors.CodeInvalidRequest)
+		return
+	}
+	req.ReactionID = idOrNew(req.ReactionID)
+
+	chatID, err := h.messagineService.GetChatIDForMessage(messageID)
+	if err != nil {
+		respondMessageChatError(w, err)
 		return
 	}
 
+	unlock := h.lockReaction(chatID, messageID, userID, req.ReactionCode)
+	defer unlock()
+
 	// Add reaction using service
-	err := h.messagineService.AddReaction(req.ReactionID, messageID, userID, req.ReactionCode)
+	err = h.messagineService.AddReaction(req.ReactionID, messageID, userID, req.ReactionCode)
 	if err != nil {
 		// Check if it's a duplicate reaction (UUID constraint violation)
 		if isPrimaryKeyViolation(err) {
-			http.Error(w, apierrors.ErrorReactionAlreadyExists, http.StatusConflict)
+			apierrors.RespondError(w, http.StatusConflict, apierrors.ErrorReactionAlreadyExists, apierrors.CodeReactionAlreadyExists)
 			return
 		}
 
 		// Other errors
 		if err.Error() == apierrors.ErrorInvalidReactionCode {
-			http.Error(w, apierrors.ErrorInvalidReactionCode, http.StatusBadRequest)
+			apierrors.RespondError(w, http.StatusBadRequest, apierrors.ErrorInvalidReactionCode, apierrors.CodeInvalidReactionCode)
 		} else if err.Error() == apierrors.ErrorNotAuthorizedToReact {
-			http.Error(w, "Message not found or not authorized", http.StatusNotFound)
+			apierrors.RespondError(w, http.StatusNotFound, "Message not found or not authorized", apierrors.CodeMessageNotFound)
 		} else {
-			http.Error(w, "Server error", http.StatusInternalServerError)
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
 			log.Printf("Error adding reaction: %v", err)
 		}
 		return
 	}
 
-	// Get chat ID for the message for broadcasting
-	chatID, err := h.messagineService.GetChatIDForMessage(messageID)
-	if err != nil {
-		log.Printf("Error getting chat ID for message: %v", err)
-		// Continue to return success even if we can't broadcast
-	} else {
-		// Broadcast reaction to chat participants
-		msgData, _ := json.Marshal(ReactionMessage{
-			BaseMessage: BaseMessage{
-				Type:   MsgTypeReaction,
-				ChatID: chatID,
-			},
-			ReactionID:   req.ReactionID,
-			MessageID:    messageID,
-			UserID:       userID,
-			ReactionCode: req.ReactionCode,
-			ReactedAt:    time.Now(),
-		})
+	// Broadcast reaction to chat participants
+	msgData, _ := json.Marshal(ReactionMessage{
+		BaseMessage: BaseMessage{
+			Type:   MsgTypeReaction,
+			ChatID: chatID,
+		},
+		ReactionID:   req.ReactionID,
+		MessageID:    messageID,
+		UserID:       userID,
+		ReactionCode: req.ReactionCode,
+		ReactedAt:    time.Now(),
+	})
 
-		h.broadcastToChat(chatID, msgData)
-	}
+	h.broadcastReaction(reactionKey{chatID, messageID, userID, req.ReactionCode}, msgData)
 
 	// Return success
 	w.Header().Set("Content-Type", "application/json")
@@ -571,39 +1037,49 @@ func (h *Handler) AddReaction(w http.ResponseWriter, r *http.Request) {
 }
 
 // @Summary      Удалить реакцию с сообщения
-// @Description  Удаляет эмоциональную реакцию с сообщения
+// @Description  Удаляет эмоциональную реакцию с сообщения. Код реакции передается в параметре reaction_code или в теле запроса. Путь с кодом реакции устарел.
 // @Tags         messaging
+// @Accept       json
 // @Produce      json
 // @Param        messageID path string true "ID сообщения"
-// @Param        reactionCode path string true "Код реакции для удаления"
+// @Param        reaction_code query string false "Код реакции для удаления"
+// @Param        request body RemoveReactionRequest false "Код реакции для удаления"
 // @Security     BearerAuth
 // @Success      200 {object} map[string]string "Реакция успешно удалена"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      500 {string} string "Ошибка сервера"
-// @Router       /messages/{messageID}/reactions/{reactionCode} [delete]
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректный код реакции"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      404 {object} apierrors.ErrorResponse "Сообщение не найдено"
+// @Failure      409 {object} apierrors.ErrorResponse "ID сообщения используется в нескольких чатах"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /messages/{messageID}/reactions [delete]
 func (h *Handler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
-	// Get message ID and reaction code from URL
 	messageID := chi.URLParam(r, "messageID")
-	reactionCode := chi.URLParam(r, "reactionCode")
+	reactionCode, ok := removedReactionCode(r)
+	if !ok {
+		apierrors.RespondError(w, http.StatusBadRequest, apierrors.ErrorInvalidReactionCode, apierrors.CodeInvalidReactionCode)
+		return
+	}
 
-	// Get chat ID for the message for broadcasting
+	// Message IDs are only unique within a chat, the reaction is removed in the message's chat
 	chatID, err := h.messagineService.GetChatIDForMessage(messageID)
 	if err != nil {
-		log.Printf("Error getting chat ID for message: %v", err)
-		// We'll continue even if we can't broadcast
+		respondMessageChatError(w, err)
+		return
 	}
 
+	unlock := h.lockReaction(chatID, messageID, userID, reactionCode)
+	defer unlock()
+
 	// Remove reaction
-	err = h.messagineService.RemoveReaction(messageID, userID, reactionCode)
+	err = h.messagineService.RemoveReaction(chatID, messageID, userID, reactionCode)
 	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
 		log.Printf("Error removing reaction: %v", err)
 		return
 	}
@@ -621,7 +1097,7 @@ func (h *Handler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
 			RemovedAt:    time.Now(),
 		})
 
-		h.broadcastToChat(chatID, msgData)
+		h.broadcastReaction(reactionKey{chatID, messageID, userID, reactionCode}, msgData)
 	}
 
 	// Return success
@@ -630,6 +1106,103 @@ func (h *Handler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
 }
 
+// respondMessageChatError responds to a failed lookup of the chat a message belongs to
+func respondMessageChatError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		apierrors.RespondError(w, http.StatusNotFound, "Message not found or not authorized", apierrors.CodeMessageNotFound)
+	case err.Error() == apierrors.ErrorAmbiguousMessageID:
+		apierrors.RespondError(w, http.StatusConflict, apierrors.ErrorAmbiguousMessageID, apierrors.CodeAmbiguousMessageID)
+	default:
+		log.Printf("Error getting chat ID for message: %v", err)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Получить реакции на сообщение
+// @Description  Возвращает реакции на сообщение: кто и какую реакцию поставил, в порядке добавления
+// @Tags         messaging
+// @Produce      json
+// @Param        messageID path string true "ID сообщения"
+// @Param        limit query int false "Максимальное количество реакций (по умолчанию 50, не больше 100)"
+// @Param        offset query int false "Смещение (по умолчанию 0)"
+// @Security     BearerAuth
+// @Success      200 {array} messaging.MessageReaction "Реакции на сообщение"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      404 {object} apierrors.ErrorResponse "Сообщение не найдено"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /messages/{messageID}/reactions [get]
+func (h *Handler) GetReactions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	messageID := chi.URLParam(r, "messageID")
+
+	limit, offset := parsePagination(r, defaultPageSize, maxPageSize)
+
+	reactions, err := h.messagineService.GetReactions(messageID, userID, limit, offset)
+	if err != nil {
+		if err.Error() == apierrors.ErrorUserNotInChat {
+			apierrors.RespondError(w, http.StatusNotFound, "Message not found", apierrors.CodeMessageNotFound)
+		} else if err.Error() == apierrors.ErrorAmbiguousMessageID {
+			apierrors.RespondError(w, http.StatusConflict, apierrors.ErrorAmbiguousMessageID, apierrors.CodeAmbiguousMessageID)
+		} else {
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error fetching message reactions: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reactions)
+}
+
+// @Summary      Получить каталог реакций
+// @Description  Возвращает коды реакций, которые можно ставить на сообщения, вместе с эмодзи
+// @Tags         messaging
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200 {array} messaging.ReactionCatalogItem "Допустимые реакции"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /messaging/reactions [get]
+func (h *Handler) GetReactionCatalog(w http.ResponseWriter, r *http.Request) {
+	reactions, err := h.messagineService.GetReactionCatalog()
+	if err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+		log.Printf("Error fetching reaction catalog: %v", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reactions)
+}
+
+// removedReactionCode reads the code of the reaction to remove from the query or the
+// JSON body. Emoji percent-encoded in a path segment may not round-trip, so the code
+// is only read from the path for clients of the deprecated route, unescaped explicitly.
+func removedReactionCode(r *http.Request) (string, bool) {
+	code := r.URL.Query().Get("reaction_code")
+	if code == "" && r.Body != nil {
+		var req RemoveReactionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err == nil {
+			code = req.ReactionCode
+		}
+	}
+	if code == "" {
+		if pathCode := chi.URLParam(r, "reactionCode"); pathCode != "" {
+			unescaped, err := url.PathUnescape(pathCode)
+			if err != nil {
+				return "", false
+			}
+			code = unescaped
+		}
+	}
+	return code, code != "" && utf8.ValidString(code)
+}
+
 // @Summary      Отправить сообщение
 // @Description  Отправляет новое сообщение в чат
 // @Tags         messaging
@@ -638,18 +1211,17 @@ func (h *Handler) RemoveReaction(w http.ResponseWriter, r *http.Request) {
 // @Param        chatID path string true "ID чата"
 // @Param        request body SendMessageRequest true "Данные сообщения"
 // @Security     BearerAuth
-// @Success      200 {object} ChatMessage "Сообщение успешно отправлено"
-// @Failure      400 {string} string "Некорректный запрос"
-// @Failure      401 {string} string "Unauthorized"
-// @Failure      404 {string} string "Чат не найден"
-// @Failure      409 {string} string "Сообщение с таким ID уже существует"
-// @Failure      500 {string} string "Ошибка сервера"
+// @Success      201 {object} ChatMessage "Сообщение успешно отправлено"
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректный запрос"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат не найден"
+// @Failure      409 {object} apierrors.ErrorResponse "Сообщение с таким ID уже существует"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
 // @Router       /chats/{chatID}/messages [post]
 func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	// Get user ID from context
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -659,26 +1231,33 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	// Parse request body
 	var req SendMessageRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request", apierrors.CodeInvalidRequest)
 		return
 	}
+	req.MessageID = idOrNew(req.MessageID)
 
 	// Store message
-	sentAt, err := h.messagineService.AddMessage(req.MessageID, chatID, userID, req.Content)
+	expiresIn := time.Duration(req.ExpiresIn) * time.Second
+	sentAt, err := h.messagineService.AddMessage(req.MessageID, chatID, userID, req.Content, expiresIn)
 	if err != nil {
-		// Check if it's a duplicate message (UUID constraint violation)
-		if isPrimaryKeyViolation(err) {
-			http.Error(w, apierrors.ErrorMessageAlreadyExists, http.StatusConflict)
+		if err.Error() == apierrors.ErrorInvalidMessageExpiry {
+			apierrors.RespondError(w, http.StatusBadRequest, apierrors.ErrorInvalidMessageExpiry, apierrors.CodeInvalidMessageExpiry)
+			return
+		}
+
+		// Check if it's a duplicate message within the chat
+		if err.Error() == apierrors.ErrorMessageAlreadyExists {
+			apierrors.RespondError(w, http.StatusConflict, apierrors.ErrorMessageAlreadyExists, apierrors.CodeMessageAlreadyExists)
 			return
 		}
 
 		// Check for user not in chat
 		if err.Error() == apierrors.ErrorUserNotInChat {
-			http.Error(w, "Chat not found", http.StatusNotFound)
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
 			return
 		}
 
-		http.Error(w, "Server error", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
 		log.Printf("Error storing message: %v", err)
 		return
 	}
@@ -693,6 +1272,8 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 		SenderID:  userID,
 		Content:   req.Content,
 		SentAt:    sentAt,
+		ExpiresIn: req.ExpiresIn,
+		ExpiresAt: expiryTime(sentAt, expiresIn),
 	}
 
 	msgData, _ := json.Marshal(wsMsg)
@@ -700,8 +1281,153 @@ func (h *Handler) SendMessage(w http.ResponseWriter, r *http.Request) {
 	// Broadcast message to all participants in the chat
 	h.broadcastToChat(chatID, msgData)
 
-	// Return success with message details
+	// Return created message
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/api/"+apiversion.Current+"/chats/"+url.PathEscape(chatID)+"/messages/"+url.PathEscape(req.MessageID))
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(wsMsg)
+}
+
+// @Summary      Получить сообщение
+// @Description  Возвращает одно сообщение чата. Удалённое сообщение возвращается заглушкой с deleted=true
+// @Tags         messaging
+// @Produce      json
+// @Param        chatID path string true "ID чата"
+// @Param        messageID path string true "ID сообщения"
+// @Security     BearerAuth
+// @Success      200 {object} messaging.ChatMessage "Сообщение"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат или сообщение не найдены"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /chats/{chatID}/messages/{messageID} [get]
+func (h *Handler) GetMessage(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID := chi.URLParam(r, "chatID")
+	messageID := chi.URLParam(r, "messageID")
+
+	msg, err := h.messagineService.GetMessage(chatID, messageID, userID)
+	if err != nil {
+		switch err.Error() {
+		case apierrors.ErrorUserNotInChat:
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		case apierrors.ErrorMessageNotFound:
+			apierrors.RespondError(w, http.StatusNotFound, "Message not found", apierrors.CodeMessageNotFound)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error fetching message: %v", err)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(msg)
+}
+
+// @Summary      Редактировать сообщение
+// @Description  Изменяет текст сообщения. Редактировать может только отправитель, время отправки не меняется
+// @Tags         messaging
+// @Accept       json
+// @Produce      json
+// @Param        chatID path string true "ID чата"
+// @Param        messageID path string true "ID сообщения"
+// @Param        request body EditMessageRequest true "Новый текст сообщения"
+// @Security     BearerAuth
+// @Success      200 {object} MessageEditedMessage "Сообщение изменено"
+// @Failure      400 {object} apierrors.ErrorResponse "Некорректный запрос"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      403 {object} apierrors.ErrorResponse "Сообщение отправлено другим пользователем"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат или сообщение не найдены"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /chats/{chatID}/messages/{messageID} [put]
+func (h *Handler) EditMessage(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID := chi.URLParam(r, "chatID")
+	messageID := chi.URLParam(r, "messageID")
+
+	// Parse request body
+	var req EditMessageRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request", apierrors.CodeInvalidRequest)
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		apierrors.RespondError(w, http.StatusBadRequest, "Message content is required", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	edited, err := h.messagineService.EditMessage(chatID, messageID, userID, req.Content)
+	if err != nil {
+		switch err.Error() {
+		case apierrors.ErrorUserNotInChat:
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		case apierrors.ErrorMessageNotFound:
+			apierrors.RespondError(w, http.StatusNotFound, "Message not found", apierrors.CodeMessageNotFound)
+		case apierrors.ErrorNotMessageSender:
+			apierrors.RespondError(w, http.StatusForbidden, apierrors.ErrorNotMessageSender, apierrors.CodeNotMessageSender)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error editing message: %v", err)
+		}
+		return
+	}
+
+	h.broadcastMessageEdited(edited)
+
 	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(edited)
+}
+
+// @Summary      Удалить сообщение
+// @Description  Помечает сообщение удалённым. В истории чата остаётся заглушка с пустым текстом и deleted=true
+// @Tags         messaging
+// @Param        chatID path string true "ID чата"
+// @Param        messageID path string true "ID сообщения"
+// @Security     BearerAuth
+// @Success      204 "Сообщение удалено"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      403 {object} apierrors.ErrorResponse "Сообщение отправлено другим пользователем"
+// @Failure      404 {object} apierrors.ErrorResponse "Чат или сообщение не найдены"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /chats/{chatID}/messages/{messageID} [delete]
+func (h *Handler) DeleteMessage(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	chatID := chi.URLParam(r, "chatID")
+	messageID := chi.URLParam(r, "messageID")
+
+	deletedAt, err := h.messagineService.DeleteMessage(chatID, messageID, userID)
+	if err != nil {
+		switch err.Error() {
+		case apierrors.ErrorUserNotInChat:
+			apierrors.RespondError(w, http.StatusNotFound, "Chat not found", apierrors.CodeChatNotFound)
+		case apierrors.ErrorMessageNotFound:
+			apierrors.RespondError(w, http.StatusNotFound, "Message not found", apierrors.CodeMessageNotFound)
+		case apierrors.ErrorNotMessageSender:
+			apierrors.RespondError(w, http.StatusForbidden, apierrors.ErrorNotMessageSender, apierrors.CodeNotMessageSender)
+		default:
+			apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+			log.Printf("Error deleting message: %v", err)
+		}
+		return
+	}
+
+	h.broadcastMessageDeleted(chatID, messageID, userID, deletedAt)
+
+	w.WriteHeader(http.StatusNoContent)
 }
 
 // Helper function to parse int from string
@@ -709,18 +1435,144 @@ func parseInt(s string) (int, error) {
 	return strconv.Atoi(s)
 }
 
+// parsePagination reads the limit and offset query parameters. Missing or invalid values
+// fall back to def and 0, and the limit is capped at max.
+func parsePagination(r *http.Request, def, max int) (limit, offset int) {
+	limit = def
+	if val, err := parseInt(r.URL.Query().Get("limit")); err == nil && val > 0 {
+		limit = min(val, max)
+	}
+	if val, err := parseInt(r.URL.Query().Get("offset")); err == nil && val >= 0 {
+		offset = val
+	}
+	return limit, offset
+}
+
+// parseMessageInclude parses a comma-separated include parameter such as "reactions,reads"
+func parseMessageInclude(raw string) (messaging.MessageInclude, error) {
+	var include messaging.MessageInclude
+	for _, part := range strings.Split(raw, ",") {
+		switch strings.TrimSpace(part) {
+		case "":
+		case "reactions":
+			include.Reactions = true
+		case "reads":
+			include.Reads = true
+		default:
+			return include, errors.New("unknown include " + strconv.Quote(part))
+		}
+	}
+	return include, nil
+}
+
+// idOrNew keeps a client-supplied ID so retries stay idempotent, and generates
+// one for clients that don't send it
+func idOrNew(id string) string {
+	if id != "" {
+		return id
+	}
+	return uuid.New().String()
+}
+
 // Helper function to check if error is a primary key violation
 func isPrimaryKeyViolation(err error) bool {
-	// This implementation will depend on the specific database driver
-	// For PostgreSQL, the error message contains "duplicate key value violates unique constraint"
-	if err == nil {
-		return false
+	// PostgreSQL reports unique constraint violations with code 23505
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// @Summary      Получить реакции пользователя
+// @Description  Возвращает последние реакции текущего пользователя с контекстом сообщения и чата
+// @Tags         messaging
+// @Produce      json
+// @Param        limit query int false "Максимальное количество реакций (по умолчанию 50, не больше 100)"
+// @Param        offset query int false "Смещение (по умолчанию 0)"
+// @Security     BearerAuth
+// @Success      200 {array} messaging.UserReaction "Реакции пользователя"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /users/me/reactions [get]
+func (h *Handler) GetUserReactions(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := parsePagination(r, defaultPageSize, maxPageSize)
+
+	reactions, err := h.messagineService.GetUserReactions(userID, limit, offset)
+	if err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+		log.Printf("Error fetching user reactions: %v", err)
+		return
+	}
+
+	// Return reactions
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reactions)
+}
+
+// @Summary      Получить отправленные сообщения
+// @Description  Возвращает последние сообщения текущего пользователя во всех его чатах с контекстом чата
+// @Tags         messaging
+// @Produce      json
+// @Param        limit query int false "Максимальное количество сообщений (по умолчанию 50, не больше 100)"
+// @Param        offset query int false "Смещение (по умолчанию 0)"
+// @Security     BearerAuth
+// @Success      200 {array} messaging.SentMessage "Отправленные сообщения"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /messaging/sent [get]
+func (h *Handler) GetSentMessages(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
 	}
 
-	errMsg := err.Error()
-	return (errMsg != "" &&
-		(errMsg == "pq: duplicate key value violates unique constraint" ||
-			errMsg == "UNIQUE constraint failed" ||
-			errMsg == "Duplicate entry" ||
-			errMsg == "duplicate key value violates unique constraint"))
+	limit, offset := parsePagination(r, defaultPageSize, maxPageSize)
+
+	messages, err := h.messagineService.GetSentMessages(userID, limit, offset)
+	if err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+		log.Printf("Error fetching sent messages: %v", err)
+		return
+	}
+
+	// Return messages
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(messages)
+}
+
+// @Summary      Получить обзор чатов
+// @Description  Возвращает чаты пользователя с последним сообщением, количеством непрочитанных и участниками
+// @Tags         messaging
+// @Produce      json
+// @Param        limit query int false "Максимальное количество чатов (по умолчанию 50, не больше 100)"
+// @Param        offset query int false "Смещение (по умолчанию 0)"
+// @Security     BearerAuth
+// @Success      200 {array} messaging.ChatOverview "Обзор чатов"
+// @Failure      401 {object} apierrors.ErrorResponse "Unauthorized"
+// @Failure      500 {object} apierrors.ErrorResponse "Ошибка сервера"
+// @Router       /messaging/overview [get]
+func (h *Handler) GetChatOverviews(w http.ResponseWriter, r *http.Request) {
+	// Get user ID from context
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	limit, offset := parsePagination(r, defaultPageSize, maxPageSize)
+
+	overviews, err := h.messagineService.GetChatOverviews(userID, limit, offset)
+	if err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error", apierrors.CodeInternal)
+		log.Printf("Error fetching chat overviews: %v", err)
+		return
+	}
+
+	// Return overviews
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(overviews)
 }
@@ -0,0 +1,849 @@
+package messaging
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	messagingrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/messaging"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/messaging"
+)
+
+// newAuthRequest builds a request with the given URL parameters and an authenticated user
+func newAuthRequest(method, target string, userID int, body []byte, params map[string]string) *http.Request {
+	req := httptest.NewRequest(method, target, bytes.NewReader(body))
+	rctx := chi.NewRouteContext()
+	for key, value := range params {
+		rctx.URLParams.Add(key, value)
+	}
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	return req.WithContext(authctx.WithUserID(ctx, userID))
+}
+
+func TestSendMessage_ReturnsCreatedMessage(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service.On("AddMessage", "msg-1", "chat-1", 1, "Hello", time.Duration(0)).Return(sentAt, nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1}, nil)
+
+	body, _ := json.Marshal(SendMessageRequest{MessageID: "msg-1", Content: "Hello"})
+	req := newAuthRequest("POST", "/api/chats/chat-1/messages", 1, body, map[string]string{"chatID": "chat-1"})
+	rr := httptest.NewRecorder()
+
+	h.SendMessage(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	assert.Equal(t, "/api/v1/chats/chat-1/messages/msg-1", rr.Header().Get("Location"))
+
+	var msg ChatMessage
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &msg))
+	assert.Equal(t, "msg-1", msg.MessageID)
+	assert.Equal(t, "chat-1", msg.ChatID)
+	assert.Equal(t, 1, msg.SenderID)
+	assert.Equal(t, "Hello", msg.Content)
+	assert.True(t, sentAt.Equal(msg.SentAt))
+	assert.Nil(t, msg.ExpiresAt)
+}
+
+func TestSendMessage_GeneratesMissingMessageID(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service.On("AddMessage", mock.MatchedBy(func(id string) bool { return uuid.Validate(id) == nil }), "chat-1", 1, "Hello", time.Duration(0)).Return(sentAt, nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1}, nil)
+
+	body, _ := json.Marshal(SendMessageRequest{Content: "Hello"})
+	req := newAuthRequest("POST", "/api/chats/chat-1/messages", 1, body, map[string]string{"chatID": "chat-1"})
+	rr := httptest.NewRecorder()
+
+	h.SendMessage(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var msg ChatMessage
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &msg))
+	assert.NoError(t, uuid.Validate(msg.MessageID))
+	assert.Equal(t, "/api/v1/chats/chat-1/messages/"+msg.MessageID, rr.Header().Get("Location"))
+	service.AssertCalled(t, "AddMessage", msg.MessageID, "chat-1", 1, "Hello", time.Duration(0))
+}
+
+func TestSendMessage_DisappearingMessageReturnsExpiry(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service.On("AddMessage", "msg-1", "chat-1", 1, "Hello", time.Minute).Return(sentAt, nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1}, nil)
+
+	body, _ := json.Marshal(SendMessageRequest{MessageID: "msg-1", Content: "Hello", ExpiresIn: 60})
+	req := newAuthRequest("POST", "/api/chats/chat-1/messages", 1, body, map[string]string{"chatID": "chat-1"})
+	rr := httptest.NewRecorder()
+
+	h.SendMessage(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var msg ChatMessage
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &msg))
+	assert.Equal(t, 60, msg.ExpiresIn)
+	if assert.NotNil(t, msg.ExpiresAt) {
+		assert.True(t, sentAt.Add(time.Minute).Equal(*msg.ExpiresAt))
+	}
+}
+
+func TestSendMessage_InvalidExpiry(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	service.On("AddMessage", "msg-1", "chat-1", 1, "Hello", -time.Second).
+		Return(time.Time{}, errors.New(apierrors.ErrorInvalidMessageExpiry))
+
+	body, _ := json.Marshal(SendMessageRequest{MessageID: "msg-1", Content: "Hello", ExpiresIn: -1})
+	req := newAuthRequest("POST", "/api/chats/chat-1/messages", 1, body, map[string]string{"chatID": "chat-1"})
+	rr := httptest.NewRecorder()
+
+	h.SendMessage(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), apierrors.CodeInvalidMessageExpiry)
+}
+
+func TestRemoveReaction_MultibyteEmojiCode(t *testing.T) {
+	const thumbsUp = "👍🏽" // Thumbs up with a skin tone modifier, 8 bytes in UTF-8
+
+	tests := []struct {
+		name   string
+		target string
+		body   string
+	}{
+		{"query", "/messages/msg-1/reactions?reaction_code=" + url.QueryEscape(thumbsUp), ""},
+		{"body", "/messages/msg-1/reactions", `{"reaction_code":"` + thumbsUp + `"}`},
+		{"deprecated path", "/messages/msg-1/reactions/" + url.PathEscape(thumbsUp), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(MockMessagingService)
+			h := newTestHandler(service, Config{})
+			service.On("GetChatIDForMessage", "msg-1").Return("chat-1", nil)
+			service.On("RemoveReaction", "chat-1", "msg-1", 2, thumbsUp).Return(nil)
+			service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{}, nil)
+
+			r := chi.NewRouter()
+			r.Delete("/messages/{messageID}/reactions", h.RemoveReaction)
+			r.Delete("/messages/{messageID}/reactions/{reactionCode}", h.RemoveReaction)
+
+			req := httptest.NewRequest("DELETE", tt.target, bytes.NewBufferString(tt.body))
+			req = req.WithContext(authctx.WithUserID(req.Context(), 2))
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			service.AssertExpectations(t)
+		})
+	}
+}
+
+func TestAddReaction_ReactionIDs(t *testing.T) {
+	tests := []struct {
+		name       string
+		reactionID string
+	}{
+		{"supplied id is preserved", "reaction-1"},
+		{"missing id is generated", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(MockMessagingService)
+			h := newTestHandler(service, Config{})
+			service.On("AddReaction", mock.Anything, "msg-1", 2, "like").Return(nil)
+			service.On("GetChatIDForMessage", "msg-1").Return("chat-1", nil)
+			service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{}, nil).Maybe()
+
+			body, _ := json.Marshal(AddReactionRequest{ReactionID: tt.reactionID, ReactionCode: "like"})
+			rr := httptest.NewRecorder()
+			h.AddReaction(rr, newAuthRequest("POST", "/api/messages/msg-1/reactions", 2, body, map[string]string{"messageID": "msg-1"}))
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			var resp AddReactionResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			if tt.reactionID != "" {
+				assert.Equal(t, tt.reactionID, resp.ReactionID)
+			} else {
+				assert.NoError(t, uuid.Validate(resp.ReactionID))
+			}
+			service.AssertCalled(t, "AddReaction", resp.ReactionID, "msg-1", 2, "like")
+		})
+	}
+}
+
+func TestRemoveReaction_RequiresValidCode(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	for _, target := range []string{
+		"/api/messages/msg-1/reactions",
+		"/api/messages/msg-1/reactions?reaction_code=%FF%FE",
+	} {
+		rr := httptest.NewRecorder()
+		h.RemoveReaction(rr, newAuthRequest("DELETE", target, 2, nil, map[string]string{"messageID": "msg-1"}))
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, target)
+		assert.Contains(t, rr.Body.String(), apierrors.CodeInvalidReactionCode)
+	}
+	service.AssertNotCalled(t, "RemoveReaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestReactions_MessageIDMustIdentifyOneChat(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"used in several chats", errors.New(apierrors.ErrorAmbiguousMessageID), http.StatusConflict, apierrors.CodeAmbiguousMessageID},
+		{"unknown", sql.ErrNoRows, http.StatusNotFound, apierrors.CodeMessageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(MockMessagingService)
+			h := newTestHandler(service, Config{})
+			service.On("GetChatIDForMessage", "msg-1").Return("", tt.err)
+			params := map[string]string{"messageID": "msg-1"}
+
+			rr := httptest.NewRecorder()
+			h.RemoveReaction(rr, newAuthRequest("DELETE", "/api/messages/msg-1/reactions?reaction_code=like", 2, nil, params))
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.code)
+
+			rr = httptest.NewRecorder()
+			h.AddReaction(rr, newAuthRequest("POST", "/api/messages/msg-1/reactions", 2, []byte(`{"reaction_code":"like"}`), params))
+			assert.Equal(t, tt.status, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.code)
+
+			service.AssertNotCalled(t, "RemoveReaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			service.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestReactions_ConcurrentTogglesBroadcastStoredState(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	// The stored reactions, with the same semantics as the database: adding an
+	// existing reaction and removing a missing one are no-ops
+	var mu sync.Mutex
+	stored := make(map[string]struct{})
+	service.On("GetChatIDForMessage", "msg-1").Return("chat-1", nil)
+	service.On("AddReaction", mock.Anything, "msg-1", 2, "like").Return(nil).Run(func(mock.Arguments) {
+		mu.Lock()
+		stored["like"] = struct{}{}
+		mu.Unlock()
+		// Give a concurrent change the chance to be stored before this one is broadcast
+		time.Sleep(100 * time.Microsecond)
+	})
+	service.On("RemoveReaction", "chat-1", "msg-1", 2, "like").Return(nil).Run(func(mock.Arguments) {
+		mu.Lock()
+		delete(stored, "like")
+		mu.Unlock()
+		// Give a concurrent change the chance to be stored before this one is broadcast
+		time.Sleep(100 * time.Microsecond)
+	})
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1, 2}, nil)
+
+	conn := connectClient(h, service, 1, "chat-1")
+	defer conn.Close()
+
+	const workers, rounds = 8, 25
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for round := 0; round < rounds; round++ {
+				if (worker+round)%2 == 0 {
+					body, _ := json.Marshal(AddReactionRequest{ReactionCode: "like"})
+					h.AddReaction(httptest.NewRecorder(), newAuthRequest("POST", "/api/messages/msg-1/reactions", 2, body, map[string]string{"messageID": "msg-1"}))
+				} else {
+					h.RemoveReaction(httptest.NewRecorder(), newAuthRequest("DELETE", "/api/messages/msg-1/reactions?reaction_code=like", 2, nil, map[string]string{"messageID": "msg-1"}))
+				}
+			}
+		}(worker)
+	}
+	wg.Wait()
+
+	mu.Lock()
+	_, reacted := stored["like"]
+	assert.LessOrEqual(t, len(stored), 1)
+	mu.Unlock()
+
+	// Every change is broadcast, and the last event matches the stored state
+	var last BaseMessage
+	readWritten(t, conn, workers*rounds-1, &last)
+	if reacted {
+		assert.Equal(t, MsgTypeReaction, last.Type)
+	} else {
+		assert.Equal(t, MsgTypeRemoveReaction, last.Type)
+	}
+}
+
+func TestReactions_QuickTogglesCoalesceIntoOneBroadcast(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{ReactionCoalesceWindow: 50 * time.Millisecond})
+
+	service.On("GetChatIDForMessage", "msg-1").Return("chat-1", nil)
+	service.On("AddReaction", mock.Anything, "msg-1", 2, "like").Return(nil)
+	service.On("RemoveReaction", "chat-1", "msg-1", 2, "like").Return(nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1, 2}, nil)
+
+	conn := connectClient(h, service, 1, "chat-1")
+	defer conn.Close()
+
+	addParams := map[string]string{"messageID": "msg-1"}
+	removeParams := map[string]string{"messageID": "msg-1", "reactionCode": "like"}
+
+	// Add, remove and add the reaction again in quick succession
+	body, _ := json.Marshal(AddReactionRequest{ReactionID: "reaction-1", ReactionCode: "like"})
+	h.AddReaction(httptest.NewRecorder(), newAuthRequest("POST", "/api/messages/msg-1/reactions", 2, body, addParams))
+	h.RemoveReaction(httptest.NewRecorder(), newAuthRequest("DELETE", "/api/messages/msg-1/reactions/like", 2, nil, removeParams))
+	body, _ = json.Marshal(AddReactionRequest{ReactionID: "reaction-2", ReactionCode: "like"})
+	h.AddReaction(httptest.NewRecorder(), newAuthRequest("POST", "/api/messages/msg-1/reactions", 2, body, addParams))
+
+	var msg ReactionMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeReaction, msg.Type)
+	assert.Equal(t, "reaction-2", msg.ReactionID)
+	assert.Equal(t, "like", msg.ReactionCode)
+	assert.Equal(t, 2, msg.UserID)
+
+	// No further broadcasts follow the coalesced one
+	time.Sleep(100 * time.Millisecond)
+	assert.Len(t, conn.Written(), 1)
+	service.AssertNumberOfCalls(t, "GetChatParticipantsForBroadcast", 1)
+}
+
+func TestGetChatMessages_BeforeReturnsPageWithCursor(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	service.On("GetChatMessagesBefore", "chat-1", 1, "msg-9", 50).Return(&messaging.MessagePage{
+		Messages:   []messagingrepo.ChatMessage{{MessageID: "msg-8", ChatID: "chat-1"}},
+		NextCursor: "msg-8",
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	h.GetChatMessages(rr, newAuthRequest("GET", "/api/chats/chat-1/messages?before=msg-9&offset=10", 1, nil, map[string]string{"chatID": "chat-1"}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var page messaging.MessagePage
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&page))
+	assert.Equal(t, "msg-8", page.NextCursor)
+	assert.Len(t, page.Messages, 1)
+	service.AssertNotCalled(t, "GetChatMessages", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetChatMessages_IncludeAttachesDetails(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	messages := []messagingrepo.ChatMessage{{MessageID: "msg-1", ChatID: "chat-1"}}
+	service.On("GetChatMessages", "chat-1", 1, 50, 0).Return(messages, nil)
+	service.On("AttachMessageDetails", "chat-1", 1, messages, messaging.MessageInclude{Reactions: true, Reads: true}).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(2).([]messagingrepo.ChatMessage)[0].ReadBy = []int{2}
+	})
+
+	rr := httptest.NewRecorder()
+	h.GetChatMessages(rr, newAuthRequest("GET", "/api/chats/chat-1/messages?include=reactions,reads", 1, nil, map[string]string{"chatID": "chat-1"}))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var body []messagingrepo.ChatMessage
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, []int{2}, body[0].ReadBy)
+}
+
+func TestGetChatMessages_UnknownIncludeIsBadRequest(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	rr := httptest.NewRecorder()
+	h.GetChatMessages(rr, newAuthRequest("GET", "/api/chats/chat-1/messages?include=attachments", 1, nil, map[string]string{"chatID": "chat-1"}))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	service.AssertNotCalled(t, "GetChatMessages", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetMessage_ReturnsMessage(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service.On("GetMessage", "chat-1", "msg-1", 1).Return(&messagingrepo.ChatMessage{
+		MessageID: "msg-1", ChatID: "chat-1", SenderID: 2, Content: "Hello", SentAt: sentAt,
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	params := map[string]string{"chatID": "chat-1", "messageID": "msg-1"}
+	h.GetMessage(rr, newAuthRequest("GET", "/api/v1/chats/chat-1/messages/msg-1", 1, nil, params))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var msg messagingrepo.ChatMessage
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &msg))
+	assert.Equal(t, "msg-1", msg.MessageID)
+	assert.Equal(t, "Hello", msg.Content)
+	assert.True(t, sentAt.Equal(msg.SentAt))
+}
+
+func TestGetMessage_ErrorStatuses(t *testing.T) {
+	cases := []struct {
+		name string
+		err  string
+		code string
+	}{
+		{"not a member", apierrors.ErrorUserNotInChat, apierrors.CodeChatNotFound},
+		{"missing message", apierrors.ErrorMessageNotFound, apierrors.CodeMessageNotFound},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(MockMessagingService)
+			h := newTestHandler(service, Config{})
+
+			service.On("GetMessage", "chat-1", "msg-1", 2).Return(nil, errors.New(tc.err))
+
+			rr := httptest.NewRecorder()
+			params := map[string]string{"chatID": "chat-1", "messageID": "msg-1"}
+			h.GetMessage(rr, newAuthRequest("GET", "/api/v1/chats/chat-1/messages/msg-1", 2, nil, params))
+
+			assert.Equal(t, http.StatusNotFound, rr.Code)
+			var resp apierrors.ErrorResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Equal(t, tc.code, resp.Code)
+		})
+	}
+}
+
+func TestEditMessage_BroadcastsEditedMessage(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	editedAt := sentAt.Add(time.Hour)
+	service.On("EditMessage", "chat-1", "msg-1", 1, "Edited").Return(&messagingrepo.ChatMessage{
+		MessageID: "msg-1", ChatID: "chat-1", SenderID: 1, Content: "Edited", SentAt: sentAt, EditedAt: &editedAt,
+	}, nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1, 2}, nil)
+
+	conn := connectClient(h, service, 2, "chat-1")
+	defer conn.Close()
+
+	body, _ := json.Marshal(EditMessageRequest{Content: "Edited"})
+	params := map[string]string{"chatID": "chat-1", "messageID": "msg-1"}
+	rr := httptest.NewRecorder()
+	h.EditMessage(rr, newAuthRequest("PUT", "/api/chats/chat-1/messages/msg-1", 1, body, params))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var msg MessageEditedMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeMessageEdited, msg.Type)
+	assert.Equal(t, "msg-1", msg.MessageID)
+	assert.Equal(t, "Edited", msg.Content)
+	assert.True(t, sentAt.Equal(msg.SentAt))
+	assert.True(t, editedAt.Equal(msg.EditedAt))
+}
+
+func TestEditMessage_ErrorStatuses(t *testing.T) {
+	tests := []struct {
+		name           string
+		err            string
+		expectedStatus int
+	}{
+		{name: "Message of another user", err: apierrors.ErrorNotMessageSender, expectedStatus: http.StatusForbidden},
+		{name: "Missing message", err: apierrors.ErrorMessageNotFound, expectedStatus: http.StatusNotFound},
+		{name: "Not a participant", err: apierrors.ErrorUserNotInChat, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service := new(MockMessagingService)
+			h := newTestHandler(service, Config{})
+			service.On("EditMessage", "chat-1", "msg-1", 2, "Edited").Return(nil, errors.New(tc.err))
+
+			body, _ := json.Marshal(EditMessageRequest{Content: "Edited"})
+			params := map[string]string{"chatID": "chat-1", "messageID": "msg-1"}
+			rr := httptest.NewRecorder()
+			h.EditMessage(rr, newAuthRequest("PUT", "/api/chats/chat-1/messages/msg-1", 2, body, params))
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			service.AssertNotCalled(t, "GetChatParticipantsForBroadcast", mock.Anything)
+		})
+	}
+}
+
+func TestDeleteMessage_BroadcastsDeletion(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service.On("DeleteMessage", "chat-1", "msg-1", 1).Return(deletedAt, nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1, 2}, nil)
+
+	conn := connectClient(h, service, 2, "chat-1")
+	defer conn.Close()
+
+	params := map[string]string{"chatID": "chat-1", "messageID": "msg-1"}
+	rr := httptest.NewRecorder()
+	h.DeleteMessage(rr, newAuthRequest("DELETE", "/api/chats/chat-1/messages/msg-1", 1, nil, params))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	var msg MessageDeletedMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeMessageDeleted, msg.Type)
+	assert.Equal(t, "msg-1", msg.MessageID)
+	assert.Equal(t, 1, msg.SenderID)
+	assert.True(t, deletedAt.Equal(msg.DeletedAt))
+}
+
+func TestDeleteMessage_OnlySenderMayDelete(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	service.On("DeleteMessage", "chat-1", "msg-1", 2).Return(time.Time{}, errors.New(apierrors.ErrorNotMessageSender))
+
+	params := map[string]string{"chatID": "chat-1", "messageID": "msg-1"}
+	rr := httptest.NewRecorder()
+	h.DeleteMessage(rr, newAuthRequest("DELETE", "/api/chats/chat-1/messages/msg-1", 2, nil, params))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	service.AssertNotCalled(t, "GetChatParticipantsForBroadcast", mock.Anything)
+}
+
+func TestRemoveParticipant_ErrorStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"member removing another user", messaging.ErrNotChatAdmin, http.StatusForbidden, apierrors.CodeNotChatAdmin},
+		{"not a participant", messaging.ErrUserNotInChat, http.StatusNotFound, apierrors.CodeChatNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(MockMessagingService)
+			h := newTestHandler(service, Config{})
+			service.On("RemoveParticipant", "chat-1", 2, 3).Return(tt.err)
+
+			req := newAuthRequest("DELETE", "/api/chats/chat-1/participants/3", 2, nil, map[string]string{"chatID": "chat-1", "userID": "3"})
+			rr := httptest.NewRecorder()
+
+			h.RemoveParticipant(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			var body apierrors.ErrorResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body.Code)
+		})
+	}
+}
+
+func TestSetParticipantRole_ErrorStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"member", messaging.ErrNotChatAdmin, http.StatusForbidden, apierrors.CodeNotChatAdmin},
+		{"last admin", errors.New(apierrors.ErrorLastChatAdmin), http.StatusConflict, apierrors.CodeLastChatAdmin},
+		{"invalid role", errors.New(apierrors.ErrorInvalidChatRole), http.StatusBadRequest, apierrors.CodeInvalidChatRole},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(MockMessagingService)
+			h := newTestHandler(service, Config{})
+			service.On("SetParticipantRole", "chat-1", 2, 3, messaging.RoleMember).Return(tt.err)
+
+			body, _ := json.Marshal(SetParticipantRoleRequest{Role: messaging.RoleMember})
+			req := newAuthRequest("POST", "/api/chats/chat-1/participants/3/role", 2, body, map[string]string{"chatID": "chat-1", "userID": "3"})
+			rr := httptest.NewRecorder()
+
+			h.SetParticipantRole(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			var resp apierrors.ErrorResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Equal(t, tt.code, resp.Code)
+		})
+	}
+}
+
+func TestSetParticipantRole_AdminPromotes(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+	service.On("SetParticipantRole", "chat-1", 1, 3, messaging.RoleAdmin).Return(nil)
+
+	body, _ := json.Marshal(SetParticipantRoleRequest{Role: messaging.RoleAdmin})
+	req := newAuthRequest("POST", "/api/chats/chat-1/participants/3/role", 1, body, map[string]string{"chatID": "chat-1", "userID": "3"})
+	rr := httptest.NewRecorder()
+
+	h.SetParticipantRole(rr, req)
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	service.AssertExpectations(t)
+}
+
+func TestAddParticipant_BlockedUserForbidden(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+	service.On("AddParticipant", "chat-1", 1, 5).Return(messaging.ErrUsersBlocked)
+
+	body, _ := json.Marshal(AddParticipantRequest{UserID: 5})
+	req := newAuthRequest("POST", "/api/chats/chat-1/participants", 1, body, map[string]string{"chatID": "chat-1"})
+	rr := httptest.NewRecorder()
+
+	h.AddParticipant(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	var resp apierrors.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, apierrors.CodeUserBlocked, resp.Code)
+	// The message doesn't say which of the users made the block
+	assert.Equal(t, apierrors.ErrorUsersBlocked, resp.Error)
+}
+
+func TestCreateChat_BlockingPairForbidden(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+	service.On("CreateChat", mock.Anything, "chat-1", 1, "Team", []int{2, 3}).Return("", messaging.ErrUsersBlocked)
+
+	body, _ := json.Marshal(CreateChatRequest{ChatID: "chat-1", ChatName: "Team", Participants: []int{2, 3}})
+	req := newAuthRequest("POST", "/api/chats", 1, body, nil)
+	rr := httptest.NewRecorder()
+
+	h.CreateChat(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	var resp apierrors.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, apierrors.CodeUserBlocked, resp.Code)
+	service.AssertNotCalled(t, "GetChatParticipantsForBroadcast", mock.Anything)
+}
+
+func TestCreateChat_RespondsWithDirectChatID(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+	service.On("CreateChat", mock.Anything, "chat-1", 1, "Pair", []int{2}).Return("direct-1", nil)
+	service.On("GetChatParticipantsForBroadcast", mock.Anything).Return([]int{}, nil).Maybe()
+
+	body, _ := json.Marshal(CreateChatRequest{ChatID: "chat-1", ChatName: "Pair", Participants: []int{2}})
+	rr := httptest.NewRecorder()
+
+	h.CreateChat(rr, newAuthRequest("POST", "/api/chats", 1, body, nil))
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var resp ChatIDResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, "direct-1", resp.ChatID)
+}
+
+func TestAddParticipant_DirectChatRejected(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+	service.On("AddParticipant", "chat-1", 1, 5).Return(errors.New(apierrors.ErrorDirectChatParticipants))
+
+	body, _ := json.Marshal(AddParticipantRequest{UserID: 5})
+	rr := httptest.NewRecorder()
+
+	h.AddParticipant(rr, newAuthRequest("POST", "/api/chats/chat-1/participants", 1, body, map[string]string{"chatID": "chat-1"}))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var resp apierrors.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, apierrors.CodeNotGroupChat, resp.Code)
+}
+
+func TestRenameChat_BroadcastsNewName(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	service.On("RenameChat", "chat-1", 1, " Jam session ").Return(nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1, 2}, nil)
+
+	conn := connectClient(h, service, 2, "chat-1")
+	defer conn.Close()
+
+	body, _ := json.Marshal(RenameChatRequest{ChatName: " Jam session "})
+	rr := httptest.NewRecorder()
+	h.RenameChat(rr, newAuthRequest("PATCH", "/api/chats/chat-1", 1, body, map[string]string{"chatID": "chat-1"}))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	var msg ChatRenamedMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeChatRenamed, msg.Type)
+	assert.Equal(t, "chat-1", msg.ChatID)
+	assert.Equal(t, "Jam session", msg.ChatName)
+	assert.Equal(t, 1, msg.RenamedBy)
+}
+
+func TestRenameChat_ErrorStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"empty name", errors.New(apierrors.ErrorInvalidChatName), http.StatusBadRequest, apierrors.CodeInvalidChatName},
+		{"not a participant", messaging.ErrUserNotInChat, http.StatusNotFound, apierrors.CodeChatNotFound},
+		{"member", messaging.ErrNotChatAdmin, http.StatusForbidden, apierrors.CodeNotChatAdmin},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(MockMessagingService)
+			h := newTestHandler(service, Config{})
+			service.On("RenameChat", "chat-1", 2, "").Return(tt.err)
+
+			body, _ := json.Marshal(RenameChatRequest{})
+			rr := httptest.NewRecorder()
+			h.RenameChat(rr, newAuthRequest("PATCH", "/api/chats/chat-1", 2, body, map[string]string{"chatID": "chat-1"}))
+
+			assert.Equal(t, tt.status, rr.Code)
+			var resp apierrors.ErrorResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Equal(t, tt.code, resp.Code)
+			service.AssertNotCalled(t, "GetChatParticipantsForBroadcast", mock.Anything)
+		})
+	}
+}
+
+func TestGetReactions_OutsiderGetsNotFound(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+	service.On("GetReactions", "msg-1", 3, 50, 0).Return(nil, messaging.ErrUserNotInChat)
+
+	rr := httptest.NewRecorder()
+	h.GetReactions(rr, newAuthRequest("GET", "/api/messages/msg-1/reactions", 3, nil, map[string]string{"messageID": "msg-1"}))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	var resp apierrors.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, apierrors.CodeMessageNotFound, resp.Code)
+}
+
+func TestGetReactionCatalog(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+	service.On("GetReactionCatalog").Return([]messagingrepo.ReactionCatalogItem{{ReactionCode: "like", Emoji: "👍"}}, nil)
+
+	rr := httptest.NewRecorder()
+	h.GetReactionCatalog(rr, newAuthRequest("GET", "/api/messaging/reactions", 1, nil, nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var reactions []messagingrepo.ReactionCatalogItem
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &reactions))
+	assert.Equal(t, []messagingrepo.ReactionCatalogItem{{ReactionCode: "like", Emoji: "👍"}}, reactions)
+}
+
+func TestGetReactions_CapsLimit(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+	service.On("GetReactions", "msg-1", 1, maxPageSize, 5).Return([]messagingrepo.MessageReaction{}, nil)
+
+	req := newAuthRequest("GET", "/api/messages/msg-1/reactions?limit=1000&offset=5", 1, nil, map[string]string{"messageID": "msg-1"})
+	rr := httptest.NewRecorder()
+
+	h.GetReactions(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	service.AssertExpectations(t)
+}
+
+func TestParsePagination(t *testing.T) {
+	cases := []struct {
+		query         string
+		limit, offset int
+	}{
+		{"", defaultPageSize, 0},
+		{"limit=10&offset=20", 10, 20},
+		{"limit=1000", maxPageSize, 0},
+		{"limit=0&offset=-1", defaultPageSize, 0},
+		{"limit=ten&offset=x", defaultPageSize, 0},
+	}
+
+	for _, tc := range cases {
+		limit, offset := parsePagination(httptest.NewRequest("GET", "/?"+tc.query, nil), defaultPageSize, maxPageSize)
+		assert.Equal(t, tc.limit, limit, tc.query)
+		assert.Equal(t, tc.offset, offset, tc.query)
+	}
+}
+
+func TestSearchMessages_CapsLimit(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+	service.On("SearchMessages", "chat-1", 1, "jam", maxSearchPageSize, 10).Return([]messagingrepo.ChatMessage{{MessageID: "msg-1"}}, nil)
+
+	req := newAuthRequest("GET", "/api/chats/chat-1/messages/search?q=jam&limit=1000&offset=10", 1, nil, map[string]string{"chatID": "chat-1"})
+	rr := httptest.NewRecorder()
+
+	h.SearchMessages(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var messages []messagingrepo.ChatMessage
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &messages))
+	assert.Len(t, messages, 1)
+}
+
+func TestSearchMessages_ErrorStatuses(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+	}{
+		{"empty query", errors.New(apierrors.ErrorInvalidSearchQuery), http.StatusBadRequest, apierrors.CodeInvalidSearchQuery},
+		{"not a participant", messaging.ErrUserNotInChat, http.StatusNotFound, apierrors.CodeChatNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := new(MockMessagingService)
+			h := newTestHandler(service, Config{})
+			service.On("SearchMessages", "chat-1", 2, "", defaultSearchPageSize, 0).Return(nil, tt.err)
+
+			req := newAuthRequest("GET", "/api/chats/chat-1/messages/search", 2, nil, map[string]string{"chatID": "chat-1"})
+			rr := httptest.NewRecorder()
+
+			h.SearchMessages(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			var resp apierrors.ErrorResponse
+			assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+			assert.Equal(t, tt.code, resp.Code)
+		})
+	}
+}
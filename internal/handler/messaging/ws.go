@@ -2,11 +2,18 @@ package messaging
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash/fnv"
 	"log"
+	"strconv"
+	"strings"
 	"time"
 
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	messagingrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/messaging"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/push"
 	"github.com/gorilla/websocket"
 )
@@ -19,11 +26,51 @@ type BaseMessage struct {
 
 // ChatMessage represents a message sent in a chat
 type ChatMessage struct {
+	BaseMessage
+	MessageID string     `json:"message_id"`
+	SenderID  int        `json:"sender_id"`
+	Content   string     `json:"content"`
+	SentAt    time.Time  `json:"sent_at,omitempty"`
+	ExpiresIn int        `json:"expires_in,omitempty"` // Seconds until the message disappears, zero keeps it
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Set by the server for disappearing messages
+}
+
+// EditChatMessage represents a request to edit a sent message
+type EditChatMessage struct {
+	BaseMessage
+	MessageID string `json:"message_id"`
+	Content   string `json:"content"`
+}
+
+// MessageEditedMessage notifies chat participants that a message was edited
+type MessageEditedMessage struct {
 	BaseMessage
 	MessageID string    `json:"message_id"`
 	SenderID  int       `json:"sender_id"`
 	Content   string    `json:"content"`
-	SentAt    time.Time `json:"sent_at,omitempty"`
+	SentAt    time.Time `json:"sent_at"`
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+// DeleteChatMessage represents a request to delete a sent message
+type DeleteChatMessage struct {
+	BaseMessage
+	MessageID string `json:"message_id"`
+}
+
+// MessageDeletedMessage notifies chat participants that a message was deleted
+type MessageDeletedMessage struct {
+	BaseMessage
+	MessageID string    `json:"message_id"`
+	SenderID  int       `json:"sender_id"`
+	DeletedAt time.Time `json:"deleted_at"`
+}
+
+// ChatRenamedMessage notifies chat participants that the chat got a new name
+type ChatRenamedMessage struct {
+	BaseMessage
+	ChatName  string `json:"chat_name"`
+	RenamedBy int    `json:"renamed_by"`
 }
 
 // JoinMessage represents a user joining a chat
@@ -76,65 +123,261 @@ type ReadReceiptMessage struct {
 	ReadAt    time.Time `json:"read_at"`
 }
 
+// ErrorMessage reports a failed client request to the client that sent it
+type ErrorMessage struct {
+	BaseMessage
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Ref     string `json:"ref,omitempty"` // ID of the offending message or reaction
+}
+
+// MessageAckMessage confirms to the sender that a chat message was stored
+type MessageAckMessage struct {
+	BaseMessage
+	MessageID string     `json:"message_id"`
+	SentAt    time.Time  `json:"sent_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	Status    string     `json:"status"`
+}
+
+// AckStatusSent is the status of a stored message in a message_ack
+const AckStatusSent = "sent"
+
+// MessageErrorMessage tells the sender that a chat message was not stored. A
+// duplicate code means a message with this ID was already stored earlier.
+type MessageErrorMessage struct {
+	BaseMessage
+	MessageID string `json:"message_id"`
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+}
+
+// Error codes sent in error messages
+const (
+	ErrCodeNotInChat      = "not_in_chat"
+	ErrCodeDuplicate      = "duplicate"
+	ErrCodeInvalidPayload = "invalid_payload"
+	ErrCodeRateLimited    = "rate_limited"
+	ErrCodeNotFound       = "not_found"
+	ErrCodeForbidden      = "forbidden"
+	ErrCodeInternal       = "internal_error"
+)
+
+// messageRef holds the client-supplied IDs used to correlate errors with requests
+type messageRef struct {
+	MessageID  string `json:"message_id"`
+	ReactionID string `json:"reaction_id"`
+}
+
+// ref returns the ID identifying the request, preferring the reaction ID
+func (r messageRef) ref() string {
+	if r.ReactionID != "" {
+		return r.ReactionID
+	}
+	return r.MessageID
+}
+
 // Message type constants
 const (
 	MsgTypeChatMessage    = "chat_message"
+	MsgTypeMessageAck     = "message_ack"
+	MsgTypeMessageError   = "message_error"
+	MsgTypeEditMessage    = "edit_message"
+	MsgTypeMessageEdited  = "message_edited"
+	MsgTypeDeleteMessage  = "delete_message"
+	MsgTypeMessageDeleted = "message_deleted"
+	MsgTypeChatRenamed    = "chat_renamed"
 	MsgTypeReaction       = "reaction"
 	MsgTypeRemoveReaction = "remove_reaction"
 	MsgTypeTyping         = "typing"
 	MsgTypeReadReceipt    = "read_receipt"
+	MsgTypeJoinChat       = "join_chat"
+	MsgTypeError          = "error"
 )
 
+// ActiveClients returns the number of connected WebSocket clients
+func (h *Handler) ActiveClients() int {
+	h.clientsMutex.RLock()
+	defer h.clientsMutex.RUnlock()
+	return len(h.clients)
+}
+
 func (h *Handler) handleWSConnection(conn WSConn, userID int) {
+	// Activate all chats the user belongs to
+	chatRooms, err := h.messagineService.GetUserChatRooms(userID)
+	if err != nil {
+		log.Printf("Error fetching chat rooms for user %d: %v", userID, err)
+		chatRooms = make(map[string]struct{})
+	}
+
 	// Create new client
 	client := &Client{
-		conn:   conn,
-		userID: userID,
+		conn:      conn,
+		userID:    userID,
+		chatRooms: chatRooms,
+		send:      make(chan []byte, h.sendBufferSize),
+		done:      make(chan struct{}),
 	}
 
 	// Add client to clients map
 	h.clientsMutex.Lock()
 	h.clients[userID] = client
 	h.clientsMutex.Unlock()
+	h.recordPresence(userID)
+
+	// An oversized frame fails the read, the connection is closed with 1009
+	conn.SetReadLimit(h.maxFrameBytes)
 
 	// Handle WebSocket connection
+	go h.writeClient(client)
 	go h.handleClient(client)
 }
 
+// IsOnline reports whether the user has an open WebSocket connection
+func (h *Handler) IsOnline(userID int) bool {
+	h.clientsMutex.RLock()
+	defer h.clientsMutex.RUnlock()
+	_, ok := h.clients[userID]
+	return ok
+}
+
+// recordPresence marks the user as seen now
+func (h *Handler) recordPresence(userID int) {
+	if h.presence != nil {
+		h.presence.Record(userID)
+	}
+}
+
+// writeClient is the only writer of data frames to the client's connection, gorilla
+// connections do not support concurrent writers. Once the client disconnects the
+// frames still queued are flushed and the connection is closed.
+func (h *Handler) writeClient(client *Client) {
+	defer client.conn.Close()
+
+	for {
+		select {
+		case message := <-client.send:
+			if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+				log.Printf("Error sending message to user %d: %v", client.userID, err)
+				return
+			}
+		case <-client.done:
+			for {
+				select {
+				case message := <-client.send:
+					if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// sendToClient queues a frame for the client without blocking. A client that does not
+// keep up with its queue is disconnected rather than slowing down everyone else.
+func (h *Handler) sendToClient(client *Client, message []byte) {
+	select {
+	case <-client.done:
+		return
+	default:
+	}
+
+	select {
+	case client.send <- message:
+	default:
+		log.Printf("Send buffer of user %d is full, closing connection", client.userID)
+		client.conn.Close()
+	}
+}
+
 // handleClient handles messages from a specific client
 func (h *Handler) handleClient(client *Client) {
+	// Close the connection if the client stays silent for too long,
+	// which unblocks ReadMessage below and runs the cleanup
+	var idleTimer *time.Timer
+	if h.idleTimeout > 0 {
+		idleTimer = time.AfterFunc(h.idleTimeout, func() {
+			log.Printf("Closing idle WebSocket connection for user %d", client.userID)
+			client.conn.Close()
+		})
+	}
+
+	// Ping the client so that half-open connections are noticed and dropped
+	stopPing := make(chan struct{})
+	if h.pingInterval > 0 {
+		h.startHeartbeat(client, stopPing)
+	}
+
 	defer func() {
-		client.conn.Close()
+		close(stopPing)
+		if idleTimer != nil {
+			idleTimer.Stop()
+		}
+		// The writer flushes queued frames and closes the connection
+		client.doneOnce.Do(func() { close(client.done) })
 		h.clientsMutex.Lock()
 		delete(h.clients, client.userID)
 		h.clientsMutex.Unlock()
+		h.recordPresence(client.userID)
 	}()
 
+	// Consecutive frames that could not be parsed
+	malformed := 0
+
 	for {
 		// Read message from client
 		_, data, err := client.conn.ReadMessage()
 		if err != nil {
-			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				log.Printf("Closing WebSocket connection for user %d: frame exceeds %d bytes", client.userID, h.maxFrameBytes)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("WebSocket error: %v", err)
 			}
 			break
 		}
 
+		// Any inbound message counts as activity
+		if idleTimer != nil {
+			idleTimer.Reset(h.idleTimeout)
+		}
+		if h.pingInterval > 0 {
+			client.conn.SetReadDeadline(time.Now().Add(2 * h.pingInterval))
+		}
+
 		// Parse message to get the type
 		var baseMsg BaseMessage
 		if err := json.Unmarshal(data, &baseMsg); err != nil {
 			log.Printf("Error parsing message: %v", err)
+			if h.rejectMalformed(client, &malformed, "", "invalid message", "") {
+				return
+			}
+			continue
+		}
+
+		// Best effort, the IDs are only used to correlate errors
+		var ref messageRef
+		_ = json.Unmarshal(data, &ref)
+
+		// Join reports membership problems back to the client itself
+		if baseMsg.Type == MsgTypeJoinChat {
+			malformed = 0
+			h.handleJoinChat(client, baseMsg.ChatID)
 			continue
 		}
 
 		isUserInChat, err := h.messagineService.IsUserInChat(client.userID, baseMsg.ChatID)
 		if err != nil {
 			log.Printf("Error checking if user is in chat: %v", err)
+			h.sendError(client, baseMsg.ChatID, ErrCodeInternal, "failed to check chat membership", ref.ref())
 			continue
 		}
 
 		if !isUserInChat {
 			log.Printf("User %d not in chat %s", client.userID, baseMsg.ChatID)
+			h.sendError(client, baseMsg.ChatID, ErrCodeNotInChat, "not a chat participant", ref.ref())
 			continue
 		}
 
@@ -144,13 +387,39 @@ func (h *Handler) handleClient(client *Client) {
 			var chatMsg ChatMessage
 			if err := json.Unmarshal(data, &chatMsg); err != nil {
 				log.Printf("Error parsing chat message: %v", err)
+				if h.rejectMalformed(client, &malformed, baseMsg.ChatID, "invalid chat message", ref.ref()) {
+					return
+				}
 				continue
 			}
 			h.handleChatMessage(client, chatMsg)
+		case MsgTypeEditMessage:
+			var editMsg EditChatMessage
+			if err := json.Unmarshal(data, &editMsg); err != nil {
+				log.Printf("Error parsing edit message: %v", err)
+				if h.rejectMalformed(client, &malformed, baseMsg.ChatID, "invalid edit message", ref.ref()) {
+					return
+				}
+				continue
+			}
+			h.handleEditMessage(client, editMsg)
+		case MsgTypeDeleteMessage:
+			var deleteMsg DeleteChatMessage
+			if err := json.Unmarshal(data, &deleteMsg); err != nil {
+				log.Printf("Error parsing delete message: %v", err)
+				if h.rejectMalformed(client, &malformed, baseMsg.ChatID, "invalid delete message", ref.ref()) {
+					return
+				}
+				continue
+			}
+			h.handleDeleteMessage(client, deleteMsg)
 		case MsgTypeReaction:
 			var reactionMsg ReactionMessage
 			if err := json.Unmarshal(data, &reactionMsg); err != nil {
 				log.Printf("Error parsing reaction message: %v", err)
+				if h.rejectMalformed(client, &malformed, baseMsg.ChatID, "invalid reaction message", ref.ref()) {
+					return
+				}
 				continue
 			}
 			h.handleReaction(client, reactionMsg)
@@ -158,6 +427,9 @@ func (h *Handler) handleClient(client *Client) {
 			var typingMsg TypingMessage
 			if err := json.Unmarshal(data, &typingMsg); err != nil {
 				log.Printf("Error parsing typing message: %v", err)
+				if h.rejectMalformed(client, &malformed, baseMsg.ChatID, "invalid typing message", ref.ref()) {
+					return
+				}
 				continue
 			}
 			h.handleTypingIndicator(client, typingMsg)
@@ -165,33 +437,221 @@ func (h *Handler) handleClient(client *Client) {
 			var readReceiptMsg ReadReceiptMessage
 			if err := json.Unmarshal(data, &readReceiptMsg); err != nil {
 				log.Printf("Error parsing read receipt message: %v", err)
+				if h.rejectMalformed(client, &malformed, baseMsg.ChatID, "invalid read receipt message", ref.ref()) {
+					return
+				}
 				continue
 			}
 			h.handleReadReceipt(client, readReceiptMsg)
 		default:
 			log.Printf("Unknown message type: %s", baseMsg.Type)
+			if h.rejectMalformed(client, &malformed, baseMsg.ChatID, "unknown message type", ref.ref()) {
+				return
+			}
+			continue
+		}
+
+		malformed = 0
+	}
+}
+
+// pingWriteTimeout bounds how long sending a ping may block
+const pingWriteTimeout = 10 * time.Second
+
+// startHeartbeat pings the client every interval until stop is closed. Every pong or
+// inbound message extends the read deadline, so ReadMessage fails and the connection
+// is cleaned up when the client stops answering.
+func (h *Handler) startHeartbeat(client *Client, stop <-chan struct{}) {
+	pongWait := 2 * h.pingInterval
+	client.conn.SetReadDeadline(time.Now().Add(pongWait))
+	client.conn.SetPongHandler(func(string) error {
+		return client.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	go func() {
+		ticker := time.NewTicker(h.pingInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				if err := client.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(pingWriteTimeout)); err != nil {
+					log.Printf("Error pinging user %d, closing connection: %v", client.userID, err)
+					client.conn.Close()
+					return
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// rejectMalformed reports a malformed frame to the client and reports whether the
+// connection should be closed after too many consecutive malformed frames
+func (h *Handler) rejectMalformed(client *Client, malformed *int, chatID string, message string, ref string) bool {
+	h.sendError(client, chatID, ErrCodeInvalidPayload, message, ref)
+
+	*malformed++
+	if h.maxMalformedFrames <= 0 || *malformed < h.maxMalformedFrames {
+		return false
+	}
+
+	log.Printf("Closing WebSocket connection for user %d after %d malformed messages", client.userID, *malformed)
+	return true
+}
+
+// handleJoinChat (re)activates realtime delivery of a chat for the client.
+// Joining never grants access: the user must already be a participant in the database.
+func (h *Handler) handleJoinChat(client *Client, chatID string) {
+	// Activate before checking: a removal that completes after the check then also
+	// deactivates the chat again, instead of being overwritten by a late join
+	client.joinRoom(chatID)
+
+	isUserInChat, err := h.messagineService.IsUserInChat(client.userID, chatID)
+	if err != nil {
+		client.leaveRoom(chatID)
+		log.Printf("Error checking if user is in chat: %v", err)
+		h.sendError(client, chatID, ErrCodeInternal, "failed to join chat", "")
+		return
+	}
+
+	if !isUserInChat {
+		client.leaveRoom(chatID)
+		h.sendError(client, chatID, ErrCodeNotInChat, "not a chat participant", "")
+		return
+	}
+
+	msgData, err := json.Marshal(JoinMessage{
+		BaseMessage: BaseMessage{
+			Type:   MsgTypeJoinChat,
+			ChatID: chatID,
+		},
+		UserID:   client.userID,
+		JoinedAt: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error marshaling join confirmation: %v", err)
+		return
+	}
+
+	h.sendToClient(client, msgData)
+}
+
+// sendError sends an error event to a single client, ref identifies the failed request
+func (h *Handler) sendError(client *Client, chatID string, code string, message string, ref string) {
+	msgData, err := json.Marshal(ErrorMessage{
+		BaseMessage: BaseMessage{
+			Type:   MsgTypeError,
+			ChatID: chatID,
+		},
+		Code:    code,
+		Message: message,
+		Ref:     ref,
+	})
+	if err != nil {
+		log.Printf("Error marshaling error message: %v", err)
+		return
+	}
+
+	h.sendToClient(client, msgData)
+}
+
+// sendMessageAck confirms to the sender that their chat message was stored
+func (h *Handler) sendMessageAck(client *Client, msg ChatMessage) {
+	msgData, err := json.Marshal(MessageAckMessage{
+		BaseMessage: BaseMessage{
+			Type:   MsgTypeMessageAck,
+			ChatID: msg.ChatID,
+		},
+		MessageID: msg.MessageID,
+		SentAt:    msg.SentAt,
+		ExpiresAt: msg.ExpiresAt,
+		Status:    AckStatusSent,
+	})
+	if err != nil {
+		log.Printf("Error marshaling message ack: %v", err)
+		return
+	}
+
+	h.sendToClient(client, msgData)
+}
+
+// sendMessageError tells the sender that their chat message was not stored
+func (h *Handler) sendMessageError(client *Client, msg ChatMessage, code string, message string) {
+	msgData, err := json.Marshal(MessageErrorMessage{
+		BaseMessage: BaseMessage{
+			Type:   MsgTypeMessageError,
+			ChatID: msg.ChatID,
+		},
+		MessageID: msg.MessageID,
+		Code:      code,
+		Message:   message,
+	})
+	if err != nil {
+		log.Printf("Error marshaling message error: %v", err)
+		return
+	}
+
+	h.sendToClient(client, msgData)
+}
+
+// activateChatRoom activates a chat for the online clients of the given users
+func (h *Handler) activateChatRoom(chatID string, userIDs ...int) {
+	h.clientsMutex.RLock()
+	defer h.clientsMutex.RUnlock()
+
+	for _, userID := range userIDs {
+		if client, ok := h.clients[userID]; ok {
+			client.joinRoom(chatID)
+		}
+	}
+}
+
+// deactivateChatRoom deactivates a chat for the online clients of the given users
+func (h *Handler) deactivateChatRoom(chatID string, userIDs ...int) {
+	h.clientsMutex.RLock()
+	defer h.clientsMutex.RUnlock()
+
+	for _, userID := range userIDs {
+		if client, ok := h.clients[userID]; ok {
+			client.leaveRoom(chatID)
 		}
 	}
 }
 
 // handleChatMessage handles a chat message from a client
 func (h *Handler) handleChatMessage(client *Client, msg ChatMessage) {
+	msg.MessageID = idOrNew(msg.MessageID)
+
 	// Store message using the service
-	sentAt, err := h.messagineService.AddMessage(msg.MessageID, msg.ChatID, client.userID, msg.Content)
+	expiresIn := time.Duration(msg.ExpiresIn) * time.Second
+	sentAt, err := h.messagineService.AddMessage(msg.MessageID, msg.ChatID, client.userID, msg.Content, expiresIn)
 	if err != nil {
-		// Check if it's a duplicate message (UUID constraint violation)
-		if isPrimaryKeyViolation(err) {
+		if err.Error() == apierrors.ErrorInvalidMessageExpiry {
+			h.sendMessageError(client, msg, ErrCodeInvalidPayload, apierrors.ErrorInvalidMessageExpiry)
+			return
+		}
+
+		// Check if it's a duplicate message within the chat
+		if err.Error() == apierrors.ErrorMessageAlreadyExists {
 			log.Printf("Duplicate message detected (ID: %s), ignoring", msg.MessageID)
+			h.sendMessageError(client, msg, ErrCodeDuplicate, apierrors.ErrorMessageAlreadyExists)
 			return
 		}
 		log.Printf("Error storing message: %v", err)
+		h.sendMessageError(client, msg, ErrCodeInternal, "failed to store message")
 		return
 	}
 
-	// Update the sent time and sender ID in the message
+	// Update the sent time, expiry and sender ID in the message
 	msg.SentAt = sentAt
+	msg.ExpiresAt = expiryTime(sentAt, expiresIn)
 	msg.SenderID = client.userID
 
+	// Confirm to the sender before fanning out, so the client can mark the message as sent
+	h.sendMessageAck(client, msg)
+
 	// Marshal message to JSON
 	msgData, err := json.Marshal(msg)
 	if err != nil {
@@ -206,23 +666,8 @@ func (h *Handler) handleChatMessage(client *Client, msg ChatMessage) {
 		return
 	}
 
-	// Track which participants are offline to send push notifications
-	offlineParticipants := make([]int, 0)
-
-	// Send message to all online participants
-	h.clientsMutex.RLock()
-	for _, userID := range participants {
-		if client, ok := h.clients[userID]; ok {
-			// Participant is online, send via WebSocket
-			if err := client.conn.WriteMessage(websocket.TextMessage, msgData); err != nil {
-				log.Printf("Error sending message to user %d: %v", userID, err)
-			}
-		} else {
-			// Participant is offline, add to list for push notification
-			offlineParticipants = append(offlineParticipants, userID)
-		}
-	}
-	h.clientsMutex.RUnlock()
+	// Participants that are offline or haven't joined the chat get a push notification
+	offlineParticipants := h.deliverToChat(msg.ChatID, participants, msgData)
 
 	// Send push notifications to offline participants
 	if len(offlineParticipants) > 0 {
@@ -230,6 +675,154 @@ func (h *Handler) handleChatMessage(client *Client, msg ChatMessage) {
 	}
 }
 
+// handleEditMessage handles an edit of a sent message from a client
+func (h *Handler) handleEditMessage(client *Client, msg EditChatMessage) {
+	if strings.TrimSpace(msg.Content) == "" {
+		h.sendError(client, msg.ChatID, ErrCodeInvalidPayload, "message content is empty", msg.MessageID)
+		return
+	}
+
+	edited, err := h.messagineService.EditMessage(msg.ChatID, msg.MessageID, client.userID, msg.Content)
+	if err != nil {
+		switch err.Error() {
+		case apierrors.ErrorMessageNotFound:
+			h.sendError(client, msg.ChatID, ErrCodeNotFound, apierrors.ErrorMessageNotFound, msg.MessageID)
+		case apierrors.ErrorNotMessageSender:
+			h.sendError(client, msg.ChatID, ErrCodeForbidden, apierrors.ErrorNotMessageSender, msg.MessageID)
+		default:
+			log.Printf("Error editing message: %v", err)
+			h.sendError(client, msg.ChatID, ErrCodeInternal, "failed to edit message", msg.MessageID)
+		}
+		return
+	}
+
+	h.broadcastMessageEdited(edited)
+}
+
+// broadcastMessageEdited notifies all participants of the chat about an edited message
+func (h *Handler) broadcastMessageEdited(msg *messagingrepo.ChatMessage) {
+	event := MessageEditedMessage{
+		BaseMessage: BaseMessage{
+			Type:   MsgTypeMessageEdited,
+			ChatID: msg.ChatID,
+		},
+		MessageID: msg.MessageID,
+		SenderID:  msg.SenderID,
+		Content:   msg.Content,
+		SentAt:    msg.SentAt,
+	}
+	if msg.EditedAt != nil {
+		event.EditedAt = *msg.EditedAt
+	}
+
+	msgData, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("Error marshaling message edit: %v", err)
+		return
+	}
+
+	h.broadcastToChat(msg.ChatID, msgData)
+}
+
+// handleDeleteMessage handles a deletion of a sent message from a client
+func (h *Handler) handleDeleteMessage(client *Client, msg DeleteChatMessage) {
+	deletedAt, err := h.messagineService.DeleteMessage(msg.ChatID, msg.MessageID, client.userID)
+	if err != nil {
+		switch err.Error() {
+		case apierrors.ErrorMessageNotFound:
+			h.sendError(client, msg.ChatID, ErrCodeNotFound, apierrors.ErrorMessageNotFound, msg.MessageID)
+		case apierrors.ErrorNotMessageSender:
+			h.sendError(client, msg.ChatID, ErrCodeForbidden, apierrors.ErrorNotMessageSender, msg.MessageID)
+		default:
+			log.Printf("Error deleting message: %v", err)
+			h.sendError(client, msg.ChatID, ErrCodeInternal, "failed to delete message", msg.MessageID)
+		}
+		return
+	}
+
+	h.broadcastMessageDeleted(msg.ChatID, msg.MessageID, client.userID, deletedAt)
+}
+
+// broadcastMessageDeleted notifies all participants of the chat about a deleted message
+func (h *Handler) broadcastMessageDeleted(chatID string, messageID string, senderID int, deletedAt time.Time) {
+	msgData, err := json.Marshal(MessageDeletedMessage{
+		BaseMessage: BaseMessage{
+			Type:   MsgTypeMessageDeleted,
+			ChatID: chatID,
+		},
+		MessageID: messageID,
+		SenderID:  senderID,
+		DeletedAt: deletedAt,
+	})
+	if err != nil {
+		log.Printf("Error marshaling message deletion: %v", err)
+		return
+	}
+
+	h.broadcastToChat(chatID, msgData)
+}
+
+// broadcastChatRenamed notifies all participants of the chat about its new name
+func (h *Handler) broadcastChatRenamed(chatID string, chatName string, renamedBy int) {
+	msgData, err := json.Marshal(ChatRenamedMessage{
+		BaseMessage: BaseMessage{
+			Type:   MsgTypeChatRenamed,
+			ChatID: chatID,
+		},
+		ChatName:  chatName,
+		RenamedBy: renamedBy,
+	})
+	if err != nil {
+		log.Printf("Error marshaling chat rename: %v", err)
+		return
+	}
+
+	h.broadcastToChat(chatID, msgData)
+}
+
+// expiryTime returns when a message sent at sentAt disappears, nil if it is kept
+func expiryTime(sentAt time.Time, expiresIn time.Duration) *time.Time {
+	if expiresIn <= 0 {
+		return nil
+	}
+	expiresAt := sentAt.Add(expiresIn)
+	return &expiresAt
+}
+
+// RunExpirySweeper periodically removes expired disappearing messages until the
+// context is cancelled. It returns immediately when the sweeper is disabled.
+func (h *Handler) RunExpirySweeper(ctx context.Context) {
+	if h.expirySweepInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(h.expirySweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			h.sweepExpiredMessages()
+		}
+	}
+}
+
+// sweepExpiredMessages removes expired disappearing messages and notifies the
+// participants of their chats
+func (h *Handler) sweepExpiredMessages() {
+	expired, err := h.messagineService.DeleteExpiredMessages()
+	if err != nil {
+		log.Printf("Error deleting expired messages: %v", err)
+		return
+	}
+
+	for _, msg := range expired {
+		h.broadcastMessageDeleted(msg.ChatID, msg.MessageID, msg.SenderID, msg.ExpiresAt)
+	}
+}
+
 // sendChatPushNotifications sends push notifications to offline participants
 func (h *Handler) sendChatPushNotifications(senderID int, msg ChatMessage, recipients []int) {
 	// Get sender profile to include name in notification
@@ -280,22 +873,44 @@ func (h *Handler) sendChatPushNotifications(senderID int, msg ChatMessage, recip
 
 // handleReaction handles client adding a reaction via WebSocket
 func (h *Handler) handleReaction(client *Client, msg ReactionMessage) {
+	msg.ReactionID = idOrNew(msg.ReactionID)
+
+	// Get chat ID for the message, the reaction is locked and broadcast within it
+	chatID, err := h.messagineService.GetChatIDForMessage(msg.MessageID)
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			h.sendError(client, msg.ChatID, ErrCodeNotInChat, apierrors.ErrorNotAuthorizedToReact, msg.ReactionID)
+		case err.Error() == apierrors.ErrorAmbiguousMessageID:
+			h.sendError(client, msg.ChatID, ErrCodeInvalidPayload, apierrors.ErrorAmbiguousMessageID, msg.ReactionID)
+		default:
+			log.Printf("Error getting chat ID for message: %v", err)
+			h.sendError(client, msg.ChatID, ErrCodeInternal, "failed to add reaction", msg.ReactionID)
+		}
+		return
+	}
+
+	unlock := h.lockReaction(chatID, msg.MessageID, client.userID, msg.ReactionCode)
+	defer unlock()
+
 	// Add reaction using service
-	err := h.messagineService.AddReaction(msg.ReactionID, msg.MessageID, client.userID, msg.ReactionCode)
+	err = h.messagineService.AddReaction(msg.ReactionID, msg.MessageID, client.userID, msg.ReactionCode)
 	if err != nil {
 		// Check if it's a duplicate reaction (UUID constraint violation)
 		if isPrimaryKeyViolation(err) {
 			log.Printf("Duplicate reaction detected (ID: %s), ignoring", msg.ReactionID)
+			h.sendError(client, msg.ChatID, ErrCodeDuplicate, apierrors.ErrorReactionAlreadyExists, msg.ReactionID)
 			return
 		}
-		log.Printf("Error adding reaction: %v", err)
-		return
-	}
-
-	// Get chat ID for the message
-	chatID, err := h.messagineService.GetChatIDForMessage(msg.MessageID)
-	if err != nil {
-		log.Printf("Error getting chat ID for message: %v", err)
+		switch err.Error() {
+		case apierrors.ErrorInvalidReactionCode:
+			h.sendError(client, msg.ChatID, ErrCodeInvalidPayload, apierrors.ErrorInvalidReactionCode, msg.ReactionID)
+		case apierrors.ErrorNotAuthorizedToReact:
+			h.sendError(client, msg.ChatID, ErrCodeNotInChat, apierrors.ErrorNotAuthorizedToReact, msg.ReactionID)
+		default:
+			log.Printf("Error adding reaction: %v", err)
+			h.sendError(client, msg.ChatID, ErrCodeInternal, "failed to add reaction", msg.ReactionID)
+		}
 		return
 	}
 
@@ -312,7 +927,65 @@ func (h *Handler) handleReaction(client *Client, msg ReactionMessage) {
 	}
 
 	// Broadcast reaction to all participants in the chat
-	h.broadcastToChat(chatID, msgData)
+	h.broadcastReaction(reactionKey{chatID, msg.MessageID, client.userID, msg.ReactionCode}, msgData)
+}
+
+// reactionKey identifies a single reaction of a user on a message
+type reactionKey struct {
+	chatID       string
+	messageID    string
+	userID       int
+	reactionCode string
+}
+
+// reactionLockStripes is the number of locks reaction changes are spread over
+const reactionLockStripes = 64
+
+// lockReaction serializes changes to one reaction of a user on a message and returns the
+// unlock function. The lock is held from the database write until the event is queued,
+// so concurrent adds and removes are broadcast in the order they were stored and the
+// last event clients see matches the stored state.
+func (h *Handler) lockReaction(chatID string, messageID string, userID int, reactionCode string) func() {
+	hash := fnv.New32a()
+	hash.Write([]byte(chatID))
+	hash.Write([]byte{0})
+	hash.Write([]byte(messageID))
+	hash.Write([]byte{0})
+	hash.Write([]byte(strconv.Itoa(userID)))
+	hash.Write([]byte{0})
+	hash.Write([]byte(reactionCode))
+
+	lock := &h.reactionLocks[hash.Sum32()%reactionLockStripes]
+	lock.Lock()
+	return lock.Unlock
+}
+
+// broadcastReaction broadcasts a reaction change, coalescing changes to the same
+// reaction within the window so that only the final state is sent
+func (h *Handler) broadcastReaction(key reactionKey, msgData []byte) {
+	if h.reactionWindow <= 0 {
+		h.broadcastToChat(key.chatID, msgData)
+		return
+	}
+
+	h.reactionsMutex.Lock()
+	defer h.reactionsMutex.Unlock()
+
+	// A broadcast is already scheduled, replace its payload with the latest state
+	if _, pending := h.pendingReactions[key]; pending {
+		h.pendingReactions[key] = msgData
+		return
+	}
+
+	h.pendingReactions[key] = msgData
+	time.AfterFunc(h.reactionWindow, func() {
+		h.reactionsMutex.Lock()
+		data := h.pendingReactions[key]
+		delete(h.pendingReactions, key)
+		h.reactionsMutex.Unlock()
+
+		h.broadcastToChat(key.chatID, data)
+	})
 }
 
 // handleTypingIndicator handles typing indicators from clients
@@ -335,7 +1008,66 @@ func (h *Handler) handleTypingIndicator(client *Client, msg TypingMessage) {
 	}
 
 	// Broadcast to other participants (excluding the sender)
-	h.broadcastToChatExcept(msg.ChatID, msgData, client.userID)
+	key := ephemeralKey{msg.ChatID, client.userID, MsgTypeTyping}
+	h.broadcastEphemeral(key, msgData)
+	h.scheduleTypingExpiry(key, msg.IsTyping)
+}
+
+// typingExpiry is a pending expiry of a typing indicator
+type typingExpiry struct {
+	timer *time.Timer
+}
+
+// scheduleTypingExpiry restarts the expiry of a typing indicator on every typing
+// event, and cancels it once the user has stopped typing
+func (h *Handler) scheduleTypingExpiry(key ephemeralKey, isTyping bool) {
+	if h.typingTimeout <= 0 {
+		return
+	}
+
+	h.typingMutex.Lock()
+	defer h.typingMutex.Unlock()
+
+	if pending, ok := h.typingTimers[key]; ok {
+		pending.timer.Stop()
+		delete(h.typingTimers, key)
+	}
+	if !isTyping {
+		return
+	}
+
+	expiry := &typingExpiry{}
+	expiry.timer = time.AfterFunc(h.typingTimeout, func() { h.expireTyping(key, expiry) })
+	h.typingTimers[key] = expiry
+}
+
+// expireTyping broadcasts that the user stopped typing, unless a newer typing event
+// has replaced the expiry in the meantime
+func (h *Handler) expireTyping(key ephemeralKey, expiry *typingExpiry) {
+	h.typingMutex.Lock()
+	if h.typingTimers[key] != expiry {
+		h.typingMutex.Unlock()
+		return
+	}
+	delete(h.typingTimers, key)
+	h.typingMutex.Unlock()
+
+	msgData, err := json.Marshal(TypingMessage{
+		BaseMessage: BaseMessage{
+			Type:   MsgTypeTyping,
+			ChatID: key.chatID,
+		},
+		UserID:    key.userID,
+		IsTyping:  false,
+		Timestamp: time.Now(),
+	})
+	if err != nil {
+		log.Printf("Error marshaling typing expiry: %v", err)
+		return
+	}
+
+	// Goes through the throttle so it replaces a suppressed is_typing true
+	h.broadcastEphemeral(key, msgData)
 }
 
 // handleReadReceipt handles read receipts from clients
@@ -343,6 +1075,7 @@ func (h *Handler) handleReadReceipt(client *Client, msg ReadReceiptMessage) {
 	// Store read receipt
 	if err := h.messagineService.StoreReadReceipt(client.userID, msg.ChatID, msg.MessageID); err != nil {
 		log.Printf("Error storing read receipt: %v", err)
+		h.sendError(client, msg.ChatID, ErrCodeInternal, "failed to store read receipt", msg.MessageID)
 		return
 	}
 
@@ -358,7 +1091,60 @@ func (h *Handler) handleReadReceipt(client *Client, msg ReadReceiptMessage) {
 	}
 
 	// Broadcast read receipt to other participants
-	h.broadcastToChatExcept(msg.ChatID, msgData, client.userID)
+	h.broadcastEphemeral(ephemeralKey{msg.ChatID, client.userID, MsgTypeReadReceipt}, msgData)
+}
+
+// ephemeralKey identifies a stream of ephemeral events of one kind by a user in a chat
+type ephemeralKey struct {
+	chatID  string
+	userID  int
+	msgType string
+}
+
+// ephemeralState tracks an open throttle window
+type ephemeralState struct {
+	pending []byte // Latest event suppressed in the current window, nil if none
+}
+
+// broadcastEphemeral broadcasts an ephemeral event to the chat except its sender,
+// at most once per interval. Events arriving within the interval are suppressed and
+// only the latest of them is sent once the interval ends.
+func (h *Handler) broadcastEphemeral(key ephemeralKey, msgData []byte) {
+	if h.ephemeralInterval <= 0 {
+		h.broadcastToChatExcept(key.chatID, msgData, key.userID)
+		return
+	}
+
+	h.ephemeralMutex.Lock()
+	if state, ok := h.ephemeralEvents[key]; ok {
+		// A window is open, keep the latest event for when it ends
+		state.pending = msgData
+		h.ephemeralMutex.Unlock()
+		return
+	}
+	h.ephemeralEvents[key] = &ephemeralState{}
+	h.ephemeralMutex.Unlock()
+
+	h.broadcastToChatExcept(key.chatID, msgData, key.userID)
+	time.AfterFunc(h.ephemeralInterval, func() { h.flushEphemeral(key) })
+}
+
+// flushEphemeral ends the throttle window of the key, sending the suppressed event
+// if there is one and opening a new window for it
+func (h *Handler) flushEphemeral(key ephemeralKey) {
+	h.ephemeralMutex.Lock()
+	state := h.ephemeralEvents[key]
+	if state.pending == nil {
+		delete(h.ephemeralEvents, key)
+		h.ephemeralMutex.Unlock()
+		return
+	}
+	data := state.pending
+	state.pending = nil
+	h.ephemeralMutex.Unlock()
+
+	h.broadcastToChatExcept(key.chatID, data, key.userID)
+	time.AfterFunc(h.ephemeralInterval, func() { h.flushEphemeral(key) })
 }
 
 // broadcastToChat sends a message to all clients in a chat
@@ -370,17 +1156,7 @@ func (h *Handler) broadcastToChat(chatID string, message []byte) {
 		return
 	}
 
-	// Send message to all online participants
-	h.clientsMutex.RLock()
-	defer h.clientsMutex.RUnlock()
-
-	for _, userID := range participants {
-		if client, ok := h.clients[userID]; ok {
-			if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("Error sending message to user %d: %v", userID, err)
-			}
-		}
-	}
+	h.deliverToChat(chatID, participants, message)
 }
 
 // broadcastToChatExcept sends a message to all clients in a chat except the specified user
@@ -391,18 +1167,32 @@ func (h *Handler) broadcastToChatExcept(chatID string, message []byte, exceptUse
 		return
 	}
 
+	recipients := make([]int, 0, len(participants))
+	for _, userID := range participants {
+		if userID != exceptUserID {
+			recipients = append(recipients, userID)
+		}
+	}
+
+	h.deliverToChat(chatID, recipients, message)
+}
+
+// deliverToChat sends a message to the online clients of the given participants.
+// The participants are a snapshot taken before sending, so whether each client still
+// has the chat active is checked at send time: a user who left in between is skipped.
+// Returns the participants that were not reached.
+func (h *Handler) deliverToChat(chatID string, participants []int, message []byte) []int {
 	h.clientsMutex.RLock()
 	defer h.clientsMutex.RUnlock()
 
+	unreached := make([]int, 0)
 	for _, userID := range participants {
-		if userID == exceptUserID {
-			continue // Skip the excluded user
-		}
-
-		if client, ok := h.clients[userID]; ok {
-			if err := client.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-				log.Printf("Error sending message to user %d: %v", userID, err)
-			}
+		client, ok := h.clients[userID]
+		if !ok || !client.inRoom(chatID) {
+			unreached = append(unreached, userID)
+			continue
 		}
+		h.sendToClient(client, message)
 	}
+	return unreached
 }
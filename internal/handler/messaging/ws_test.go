@@ -0,0 +1,1162 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	messagingrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/messaging"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/messaging"
+)
+
+// MockConn is a channel-driven implementation of WSConn
+type MockConn struct {
+	incoming  chan []byte
+	closed    chan struct{}
+	closeOnce sync.Once
+
+	mu           sync.Mutex
+	written      [][]byte
+	pings        int
+	readDeadline time.Time
+	readLimit    int64
+	pongHandler  func(string) error
+
+	writeGate  chan struct{} // When set, writes block until it is closed
+	writing    atomic.Int32
+	concurrent atomic.Bool // Set when two writes overlapped
+}
+
+func NewMockConn() *MockConn {
+	return &MockConn{
+		incoming: make(chan []byte, 16),
+		closed:   make(chan struct{}),
+	}
+}
+
+func (c *MockConn) ReadMessage() (int, []byte, error) {
+	for {
+		// Wake up periodically to honour deadlines moved by the pong handler
+		select {
+		case data := <-c.incoming:
+			return websocket.TextMessage, data, nil
+		case <-c.closed:
+			return 0, nil, errors.New("connection closed")
+		case <-time.After(5 * time.Millisecond):
+			c.mu.Lock()
+			deadline := c.readDeadline
+			c.mu.Unlock()
+			if !deadline.IsZero() && time.Now().After(deadline) {
+				return 0, nil, errors.New("i/o timeout")
+			}
+		}
+	}
+}
+
+func (c *MockConn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	select {
+	case <-c.closed:
+		return errors.New("connection closed")
+	default:
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if messageType == websocket.PingMessage {
+		c.pings++
+	}
+	return nil
+}
+
+func (c *MockConn) SetReadDeadline(t time.Time) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readDeadline = t
+	return nil
+}
+
+func (c *MockConn) SetReadLimit(limit int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readLimit = limit
+}
+
+// ReadLimit returns the inbound frame size limit set on the connection
+func (c *MockConn) ReadLimit() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.readLimit
+}
+
+func (c *MockConn) SetPongHandler(h func(appData string) error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pongHandler = h
+}
+
+// Pong answers a ping as a live client would
+func (c *MockConn) Pong() {
+	c.mu.Lock()
+	handler := c.pongHandler
+	c.mu.Unlock()
+	if handler != nil {
+		handler("")
+	}
+}
+
+// Pings returns the number of pings sent to the connection
+func (c *MockConn) Pings() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pings
+}
+
+func (c *MockConn) WriteMessage(messageType int, data []byte) error {
+	if c.writing.Add(1) > 1 {
+		c.concurrent.Store(true)
+	}
+	defer c.writing.Add(-1)
+
+	if c.writeGate != nil {
+		select {
+		case <-c.writeGate:
+		case <-c.closed:
+		}
+	}
+
+	select {
+	case <-c.closed:
+		return errors.New("connection closed")
+	default:
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.written = append(c.written, data)
+	return nil
+}
+
+func (c *MockConn) Close() error {
+	c.closeOnce.Do(func() { close(c.closed) })
+	return nil
+}
+
+// Send queues an inbound message as if it came from the client
+func (c *MockConn) Send(data string) {
+	c.incoming <- []byte(data)
+}
+
+// Written returns a copy of all messages written to the connection
+func (c *MockConn) Written() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.written...)
+}
+
+func (c *MockConn) IsClosed() bool {
+	select {
+	case <-c.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// MockMessagingService is a mock implementation of messaging.Service
+type MockMessagingService struct {
+	mock.Mock
+}
+
+func (m *MockMessagingService) GetUserChats(userID int) ([]messaging.Chat, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.Chat), args.Error(1)
+}
+
+func (m *MockMessagingService) GetUserTeams(userID int) ([]messaging.Chat, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.Chat), args.Error(1)
+}
+
+func (m *MockMessagingService) GetChat(chatID string, userID int) (*messaging.Chat, error) {
+	args := m.Called(chatID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*messaging.Chat), args.Error(1)
+}
+
+func (m *MockMessagingService) CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int) (string, error) {
+	args := m.Called(ctx, chatID, creatorID, chatName, participants)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMessagingService) AddMessage(messageID string, chatID string, senderID int, content string, expiresIn time.Duration) (time.Time, error) {
+	args := m.Called(messageID, chatID, senderID, content, expiresIn)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockMessagingService) GetChatParticipants(chatID string) ([]int, error) {
+	args := m.Called(chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockMessagingService) IsUserInChat(userID int, chatID string) (bool, error) {
+	args := m.Called(userID, chatID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMessagingService) AddParticipant(chatID string, actorID int, userID int) error {
+	args := m.Called(chatID, actorID, userID)
+	return args.Error(0)
+}
+
+func (m *MockMessagingService) RemoveParticipant(chatID string, actorID int, userID int) error {
+	args := m.Called(chatID, actorID, userID)
+	return args.Error(0)
+}
+
+func (m *MockMessagingService) SetParticipantRole(chatID string, actorID int, userID int, role string) error {
+	args := m.Called(chatID, actorID, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockMessagingService) RenameChat(chatID string, actorID int, chatName string) error {
+	args := m.Called(chatID, actorID, chatName)
+	return args.Error(0)
+}
+
+func (m *MockMessagingService) AddReaction(reactionID string, messageID string, userID int, reactionCode string) error {
+	args := m.Called(reactionID, messageID, userID, reactionCode)
+	return args.Error(0)
+}
+
+func (m *MockMessagingService) RemoveReaction(chatID string, messageID string, userID int, reactionCode string) error {
+	args := m.Called(chatID, messageID, userID, reactionCode)
+	return args.Error(0)
+}
+
+func (m *MockMessagingService) GetChatIDForMessage(messageID string) (string, error) {
+	args := m.Called(messageID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMessagingService) GetChatMessagesBefore(chatID string, userID int, before string, limit int) (*messaging.MessagePage, error) {
+	args := m.Called(chatID, userID, before, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*messaging.MessagePage), args.Error(1)
+}
+
+func (m *MockMessagingService) SearchMessages(chatID string, userID int, query string, limit, offset int) ([]messagingrepo.ChatMessage, error) {
+	args := m.Called(chatID, userID, query, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messagingrepo.ChatMessage), args.Error(1)
+}
+
+func (m *MockMessagingService) EditMessage(chatID string, messageID string, userID int, content string) (*messagingrepo.ChatMessage, error) {
+	args := m.Called(chatID, messageID, userID, content)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*messagingrepo.ChatMessage), args.Error(1)
+}
+
+func (m *MockMessagingService) DeleteMessage(chatID string, messageID string, userID int) (time.Time, error) {
+	args := m.Called(chatID, messageID, userID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockMessagingService) DeleteExpiredMessages() ([]messagingrepo.ExpiredMessage, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messagingrepo.ExpiredMessage), args.Error(1)
+}
+
+func (m *MockMessagingService) GetChatMessages(chatID string, userID int, limit, offset int) ([]messagingrepo.ChatMessage, error) {
+	args := m.Called(chatID, userID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messagingrepo.ChatMessage), args.Error(1)
+}
+
+func (m *MockMessagingService) GetMessage(chatID string, messageID string, userID int) (*messagingrepo.ChatMessage, error) {
+	args := m.Called(chatID, messageID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*messagingrepo.ChatMessage), args.Error(1)
+}
+
+func (m *MockMessagingService) StoreTypingIndicator(userID int, chatID string) error {
+	args := m.Called(userID, chatID)
+	return args.Error(0)
+}
+
+func (m *MockMessagingService) StoreReadReceipt(userID int, chatID string, messageID string) error {
+	args := m.Called(userID, chatID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockMessagingService) GetUserChatRooms(userID int) (map[string]struct{}, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]struct{}), args.Error(1)
+}
+
+func (m *MockMessagingService) GetChatParticipantsForBroadcast(chatID string) ([]int, error) {
+	args := m.Called(chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockMessagingService) GetOrCreateDirectChat(ctx context.Context, userID1 int, userID2 int) (string, error) {
+	args := m.Called(ctx, userID1, userID2)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMessagingService) GetUserReactions(userID int, limit, offset int) ([]messagingrepo.UserReaction, error) {
+	args := m.Called(userID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messagingrepo.UserReaction), args.Error(1)
+}
+
+func (m *MockMessagingService) GetSentMessages(userID int, limit, offset int) ([]messagingrepo.SentMessage, error) {
+	args := m.Called(userID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messagingrepo.SentMessage), args.Error(1)
+}
+
+func (m *MockMessagingService) GetChatOverviews(userID int, limit, offset int) ([]messagingrepo.ChatOverview, error) {
+	args := m.Called(userID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messagingrepo.ChatOverview), args.Error(1)
+}
+
+func (m *MockMessagingService) GetReactions(messageID string, userID int, limit, offset int) ([]messagingrepo.MessageReaction, error) {
+	args := m.Called(messageID, userID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messagingrepo.MessageReaction), args.Error(1)
+}
+
+func (m *MockMessagingService) GetReactionCatalog() ([]messagingrepo.ReactionCatalogItem, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messagingrepo.ReactionCatalogItem), args.Error(1)
+}
+
+func (m *MockMessagingService) GetReadPositions(chatID string, userID int) ([]messagingrepo.ReadPosition, error) {
+	args := m.Called(chatID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messagingrepo.ReadPosition), args.Error(1)
+}
+
+func (m *MockMessagingService) AttachMessageDetails(chatID string, userID int, messages []messagingrepo.ChatMessage, include messaging.MessageInclude) error {
+	args := m.Called(chatID, userID, messages, include)
+	return args.Error(0)
+}
+
+func newTestHandler(service *MockMessagingService, config Config) *Handler {
+	return NewHandler(service, nil, nil, config)
+}
+
+// connectClient connects a mock client with the given chats active
+func connectClient(h *Handler, service *MockMessagingService, userID int, chatIDs ...string) *MockConn {
+	rooms := make(map[string]struct{})
+	for _, chatID := range chatIDs {
+		rooms[chatID] = struct{}{}
+	}
+	service.On("GetUserChatRooms", userID).Return(rooms, nil).Once()
+
+	conn := NewMockConn()
+	h.handleWSConnection(conn, userID)
+	return conn
+}
+
+// readWritten waits for the n-th message written to the connection and decodes it
+func readWritten(t *testing.T, conn *MockConn, n int, v interface{}) {
+	t.Helper()
+	assert.Eventually(t, func() bool { return len(conn.Written()) > n }, time.Second, 5*time.Millisecond)
+	written := conn.Written()
+	if len(written) <= n {
+		t.FailNow()
+	}
+	assert.NoError(t, json.Unmarshal(written[n], v))
+}
+
+func isClientConnected(h *Handler, userID int) bool {
+	h.clientsMutex.RLock()
+	defer h.clientsMutex.RUnlock()
+	_, ok := h.clients[userID]
+	return ok
+}
+
+func TestActiveClients_CountsConnectedClients(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	first := connectClient(h, service, 1)
+	second := connectClient(h, service, 2)
+	defer second.Close()
+	assert.Equal(t, 2, h.ActiveClients())
+
+	first.Close()
+	assert.Eventually(t, func() bool { return h.ActiveClients() == 1 }, time.Second, 5*time.Millisecond)
+}
+
+// presenceLog records the users passed to Record
+type presenceLog struct {
+	mu      sync.Mutex
+	records []int
+}
+
+func (p *presenceLog) Record(userID int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.records = append(p.records, userID)
+}
+
+func (p *presenceLog) Records() []int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]int(nil), p.records...)
+}
+
+func TestIsOnline_TracksConnectionAndRecordsPresence(t *testing.T) {
+	service := new(MockMessagingService)
+	presence := &presenceLog{}
+	h := newTestHandler(service, Config{Presence: presence})
+
+	assert.False(t, h.IsOnline(1))
+	conn := connectClient(h, service, 1)
+	assert.True(t, h.IsOnline(1))
+	assert.False(t, h.IsOnline(2))
+	assert.Equal(t, []int{1}, presence.Records())
+
+	conn.Close()
+	assert.Eventually(t, func() bool { return !h.IsOnline(1) }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return len(presence.Records()) == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleClient_IdleTimeoutClosesConnection(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{IdleTimeout: 50 * time.Millisecond})
+
+	conn := connectClient(h, service, 1)
+
+	assert.Eventually(t, conn.IsClosed, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return !isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleClient_InboundMessageResetsIdleTimeout(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(false, nil)
+	h := newTestHandler(service, Config{IdleTimeout: 100 * time.Millisecond})
+
+	conn := connectClient(h, service, 1)
+
+	// Keep the connection active for longer than a single idle window
+	for i := 0; i < 4; i++ {
+		time.Sleep(50 * time.Millisecond)
+		conn.Send(`{"type":"typing","chat_id":"chat-1"}`)
+	}
+	assert.False(t, conn.IsClosed())
+
+	assert.Eventually(t, conn.IsClosed, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleClient_NoIdleTimeoutWhenDisabled(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.False(t, conn.IsClosed())
+	assert.True(t, isClientConnected(h, 1))
+
+	conn.Close()
+	assert.Eventually(t, func() bool { return !isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleJoinChat_NonMemberGetsError(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(false, nil)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	conn.Send(`{"type":"join_chat","chat_id":"chat-1"}`)
+
+	var msg ErrorMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeError, msg.Type)
+	assert.Equal(t, "chat-1", msg.ChatID)
+	assert.Equal(t, ErrCodeNotInChat, msg.Code)
+	assert.NotEmpty(t, msg.Message)
+
+	h.clientsMutex.RLock()
+	client := h.clients[1]
+	h.clientsMutex.RUnlock()
+	assert.False(t, client.inRoom("chat-1"))
+}
+
+func TestHandleJoinChat_MembershipCheckFailureGetsError(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(false, errors.New("db error"))
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	conn.Send(`{"type":"join_chat","chat_id":"chat-1"}`)
+
+	var msg ErrorMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeError, msg.Type)
+	assert.Equal(t, "chat-1", msg.ChatID)
+	assert.Equal(t, ErrCodeInternal, msg.Code)
+}
+
+func TestHandleJoinChat_MemberActivatesRoom(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	conn.Send(`{"type":"join_chat","chat_id":"chat-1"}`)
+
+	var msg JoinMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeJoinChat, msg.Type)
+	assert.Equal(t, "chat-1", msg.ChatID)
+	assert.Equal(t, 1, msg.UserID)
+
+	h.clientsMutex.RLock()
+	client := h.clients[1]
+	h.clientsMutex.RUnlock()
+	assert.True(t, client.inRoom("chat-1"))
+
+	// Broadcasts for the chat now reach the client
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1}, nil)
+	h.broadcastToChat("chat-1", []byte(`{"type":"reaction"}`))
+	assert.Eventually(t, func() bool { return len(conn.Written()) == 2 }, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleJoinChat_RemovalDuringJoinDeactivatesRoom(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	// The user is removed from the chat while the membership check is in flight
+	service.On("IsUserInChat", 1, "chat-1").Run(func(args mock.Arguments) {
+		h.deactivateChatRoom("chat-1", 1)
+	}).Return(true, nil)
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	conn.Send(`{"type":"join_chat","chat_id":"chat-1"}`)
+
+	var msg JoinMessage
+	readWritten(t, conn, 0, &msg)
+
+	h.clientsMutex.RLock()
+	client := h.clients[1]
+	h.clientsMutex.RUnlock()
+	assert.False(t, client.inRoom("chat-1"))
+}
+
+func TestBroadcastToChat_SkipsConnectedNonParticipant(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1}, nil)
+	h := newTestHandler(service, Config{})
+
+	member := connectClient(h, service, 1, "chat-1")
+	defer member.Close()
+	// Still has the chat active but is no longer a participant
+	former := connectClient(h, service, 2, "chat-1")
+	defer former.Close()
+
+	h.broadcastToChat("chat-1", []byte(`{"type":"chat_message"}`))
+
+	assert.Eventually(t, func() bool { return len(member.Written()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, former.Written())
+}
+
+func TestBroadcastToChat_SkipsParticipantThatLeftMidBroadcast(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	// User 2 leaves right after the participants were read
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Run(func(args mock.Arguments) {
+		h.deactivateChatRoom("chat-1", 2)
+	}).Return([]int{1, 2}, nil)
+
+	member := connectClient(h, service, 1, "chat-1")
+	defer member.Close()
+	leaving := connectClient(h, service, 2, "chat-1")
+	defer leaving.Close()
+
+	h.broadcastToChat("chat-1", []byte(`{"type":"chat_message"}`))
+
+	assert.Eventually(t, func() bool { return len(member.Written()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Empty(t, leaving.Written())
+}
+
+func TestHandleClient_MessageToForeignChatGetsError(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(false, nil)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	conn.Send(`{"type":"chat_message","chat_id":"chat-1","message_id":"msg-1","content":"Hello"}`)
+
+	var msg ErrorMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeError, msg.Type)
+	assert.Equal(t, ErrCodeNotInChat, msg.Code)
+	assert.Equal(t, "chat-1", msg.ChatID)
+	assert.Equal(t, "msg-1", msg.Ref)
+	service.AssertNotCalled(t, "AddMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestHandleClient_StoredMessageIsAcknowledged(t *testing.T) {
+	service := new(MockMessagingService)
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	service.On("AddMessage", "msg-1", "chat-1", 1, "Hello", time.Duration(0)).Return(sentAt, nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1}, nil)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1, "chat-1")
+	defer conn.Close()
+
+	conn.Send(`{"type":"chat_message","chat_id":"chat-1","message_id":"msg-1","content":"Hello"}`)
+
+	// The ack comes before the sender's copy of the broadcast
+	var ack MessageAckMessage
+	readWritten(t, conn, 0, &ack)
+	assert.Equal(t, MsgTypeMessageAck, ack.Type)
+	assert.Equal(t, "chat-1", ack.ChatID)
+	assert.Equal(t, "msg-1", ack.MessageID)
+	assert.Equal(t, AckStatusSent, ack.Status)
+	assert.True(t, sentAt.Equal(ack.SentAt))
+
+	var broadcast ChatMessage
+	readWritten(t, conn, 1, &broadcast)
+	assert.Equal(t, MsgTypeChatMessage, broadcast.Type)
+}
+
+func TestHandleClient_MissingMessageIDIsGenerated(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	service.On("AddMessage", mock.Anything, "chat-1", 1, "Hello", time.Duration(0)).Return(time.Now(), nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1}, nil)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1, "chat-1")
+	defer conn.Close()
+
+	conn.Send(`{"type":"chat_message","chat_id":"chat-1","content":"Hello"}`)
+
+	var ack MessageAckMessage
+	readWritten(t, conn, 0, &ack)
+	assert.NotEmpty(t, ack.MessageID)
+	service.AssertCalled(t, "AddMessage", ack.MessageID, "chat-1", 1, "Hello", time.Duration(0))
+
+	var broadcast ChatMessage
+	readWritten(t, conn, 1, &broadcast)
+	assert.Equal(t, ack.MessageID, broadcast.MessageID)
+}
+
+func TestHandleClient_DuplicateMessageGetsMessageError(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	service.On("AddMessage", "msg-1", "chat-1", 1, "Hello", time.Duration(0)).
+		Return(time.Time{}, errors.New(apierrors.ErrorMessageAlreadyExists))
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1, "chat-1")
+	defer conn.Close()
+
+	conn.Send(`{"type":"chat_message","chat_id":"chat-1","message_id":"msg-1","content":"Hello"}`)
+
+	var msg MessageErrorMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeMessageError, msg.Type)
+	assert.Equal(t, "msg-1", msg.MessageID)
+	assert.Equal(t, ErrCodeDuplicate, msg.Code)
+
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(t, conn.Written(), 1)
+	service.AssertNotCalled(t, "GetChatParticipantsForBroadcast", mock.Anything)
+}
+
+func TestHandleClient_FailedStoreGetsMessageError(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	service.On("AddMessage", "msg-1", "chat-1", 1, "Hello", time.Duration(0)).Return(time.Time{}, errors.New("db error"))
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1, "chat-1")
+	defer conn.Close()
+
+	conn.Send(`{"type":"chat_message","chat_id":"chat-1","message_id":"msg-1","content":"Hello"}`)
+
+	var msg MessageErrorMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeMessageError, msg.Type)
+	assert.Equal(t, ErrCodeInternal, msg.Code)
+	assert.NotContains(t, msg.Message, "db error")
+}
+
+func TestHandleClient_InvalidPayloadGetsError(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	// The reaction code must be a string
+	conn.Send(`{"type":"reaction","chat_id":"chat-1","reaction_id":"reaction-1","message_id":"msg-1","reaction_code":42}`)
+
+	var msg ErrorMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeError, msg.Type)
+	assert.Equal(t, ErrCodeInvalidPayload, msg.Code)
+	assert.Equal(t, "reaction-1", msg.Ref)
+
+	// Malformed JSON is reported as well
+	conn.Send(`{not json`)
+
+	var parseErr ErrorMessage
+	readWritten(t, conn, 1, &parseErr)
+	assert.Equal(t, ErrCodeInvalidPayload, parseErr.Code)
+	assert.Empty(t, parseErr.Ref)
+}
+
+func TestHandleClient_RapidTypingEventsAreThrottled(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	var stored atomic.Int32
+	service.On("StoreTypingIndicator", 1, "chat-1").Return(nil).Run(func(mock.Arguments) { stored.Add(1) })
+	service.On("GetChatParticipants", "chat-1").Return([]int{1, 2}, nil)
+	h := newTestHandler(service, Config{EphemeralEventInterval: 100 * time.Millisecond})
+
+	sender := connectClient(h, service, 1, "chat-1")
+	defer sender.Close()
+	receiver := connectClient(h, service, 2, "chat-1")
+	defer receiver.Close()
+
+	for i := 0; i < 4; i++ {
+		sender.Send(`{"type":"typing","chat_id":"chat-1","is_typing":true}`)
+	}
+	sender.Send(`{"type":"typing","chat_id":"chat-1","is_typing":false}`)
+
+	var first TypingMessage
+	readWritten(t, receiver, 0, &first)
+	assert.Equal(t, MsgTypeTyping, first.Type)
+	assert.Equal(t, 1, first.UserID)
+	assert.True(t, first.IsTyping)
+
+	// Only one broadcast goes out within the window
+	assert.Eventually(t, func() bool { return stored.Load() == 5 }, time.Second, 5*time.Millisecond)
+	assert.Len(t, receiver.Written(), 1)
+
+	// The latest suppressed event follows once the window ends
+	var last TypingMessage
+	readWritten(t, receiver, 1, &last)
+	assert.False(t, last.IsTyping)
+
+	time.Sleep(250 * time.Millisecond)
+	assert.Len(t, receiver.Written(), 2)
+	assert.Empty(t, sender.Written())
+}
+
+func TestHandleClient_TypingIndicatorExpires(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	service.On("StoreTypingIndicator", 1, "chat-1").Return(nil)
+	service.On("GetChatParticipants", "chat-1").Return([]int{1, 2}, nil)
+	h := newTestHandler(service, Config{TypingTimeout: 50 * time.Millisecond})
+
+	sender := connectClient(h, service, 1, "chat-1")
+	defer sender.Close()
+	receiver := connectClient(h, service, 2, "chat-1")
+	defer receiver.Close()
+
+	sender.Send(`{"type":"typing","chat_id":"chat-1","is_typing":true}`)
+
+	var typing TypingMessage
+	readWritten(t, receiver, 0, &typing)
+	assert.True(t, typing.IsTyping)
+
+	// The sender goes silent without clearing the indicator
+	var expired TypingMessage
+	readWritten(t, receiver, 1, &expired)
+	assert.Equal(t, MsgTypeTyping, expired.Type)
+	assert.Equal(t, 1, expired.UserID)
+	assert.False(t, expired.IsTyping)
+}
+
+func TestHandleClient_ClearedTypingIndicatorDoesNotExpire(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	service.On("StoreTypingIndicator", 1, "chat-1").Return(nil)
+	service.On("GetChatParticipants", "chat-1").Return([]int{1, 2}, nil)
+	h := newTestHandler(service, Config{TypingTimeout: 50 * time.Millisecond})
+
+	sender := connectClient(h, service, 1, "chat-1")
+	defer sender.Close()
+	receiver := connectClient(h, service, 2, "chat-1")
+	defer receiver.Close()
+
+	sender.Send(`{"type":"typing","chat_id":"chat-1","is_typing":true}`)
+	sender.Send(`{"type":"typing","chat_id":"chat-1","is_typing":false}`)
+
+	var cleared TypingMessage
+	readWritten(t, receiver, 1, &cleared)
+	assert.False(t, cleared.IsTyping)
+
+	time.Sleep(150 * time.Millisecond)
+	assert.Len(t, receiver.Written(), 2)
+}
+
+func TestHandleClient_EditOfForeignMessageGetsError(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 2, "chat-1").Return(true, nil)
+	service.On("EditMessage", "chat-1", "msg-1", 2, "Edited").Return(nil, errors.New(apierrors.ErrorNotMessageSender))
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 2)
+	defer conn.Close()
+
+	conn.Send(`{"type":"edit_message","chat_id":"chat-1","message_id":"msg-1","content":"Edited"}`)
+
+	var msg ErrorMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeError, msg.Type)
+	assert.Equal(t, ErrCodeForbidden, msg.Code)
+	assert.Equal(t, "msg-1", msg.Ref)
+}
+
+func TestHandleClient_DeleteMessageBroadcastsTombstone(t *testing.T) {
+	service := new(MockMessagingService)
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	service.On("DeleteMessage", "chat-1", "msg-1", 1).Return(deletedAt, nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1}, nil)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1, "chat-1")
+	defer conn.Close()
+
+	conn.Send(`{"type":"delete_message","chat_id":"chat-1","message_id":"msg-1"}`)
+
+	var msg MessageDeletedMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeMessageDeleted, msg.Type)
+	assert.Equal(t, "msg-1", msg.MessageID)
+}
+
+func TestSweepExpiredMessages_BroadcastsRemoval(t *testing.T) {
+	service := new(MockMessagingService)
+	expiresAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	service.On("DeleteExpiredMessages").Return([]messagingrepo.ExpiredMessage{
+		{MessageID: "msg-1", ChatID: "chat-1", SenderID: 2, ExpiresAt: expiresAt},
+	}, nil)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1, 2}, nil)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1, "chat-1")
+	defer conn.Close()
+
+	h.sweepExpiredMessages()
+
+	var msg MessageDeletedMessage
+	readWritten(t, conn, 0, &msg)
+	assert.Equal(t, MsgTypeMessageDeleted, msg.Type)
+	assert.Equal(t, "msg-1", msg.MessageID)
+	assert.Equal(t, 2, msg.SenderID)
+	assert.True(t, expiresAt.Equal(msg.DeletedAt))
+	service.AssertExpectations(t)
+}
+
+func TestHandleClient_RepeatedMalformedFramesCloseConnection(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	h := newTestHandler(service, Config{MaxMalformedFrames: 3})
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	// A valid frame in between resets the streak
+	conn.Send(`{not json`)
+	conn.Send(`{not json`)
+	conn.Send(`{"type":"join_chat","chat_id":"chat-1"}`)
+
+	var joined JoinMessage
+	readWritten(t, conn, 2, &joined)
+	assert.Equal(t, MsgTypeJoinChat, joined.Type)
+	assert.False(t, conn.IsClosed())
+
+	conn.Send(`{not json`)
+	conn.Send(`{"type":"bogus","chat_id":"chat-1"}`)
+	conn.Send(`{not json`)
+
+	assert.Eventually(t, conn.IsClosed, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return !isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+
+	// Every malformed frame got an error frame before the connection was closed
+	written := conn.Written()
+	assert.Len(t, written, 6)
+	for _, i := range []int{0, 1, 3, 4, 5} {
+		var msg ErrorMessage
+		assert.NoError(t, json.Unmarshal(written[i], &msg))
+		assert.Equal(t, ErrCodeInvalidPayload, msg.Code)
+	}
+}
+
+func TestHandleClient_UnansweredPingsCloseConnection(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{PingInterval: 30 * time.Millisecond})
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool { return conn.Pings() > 0 }, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return !isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+	assert.True(t, conn.IsClosed())
+}
+
+func TestHandleClient_PongsKeepConnectionAlive(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{PingInterval: 30 * time.Millisecond})
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	// Answer pings for several deadlines
+	for i := 0; i < 10; i++ {
+		time.Sleep(15 * time.Millisecond)
+		conn.Pong()
+	}
+
+	assert.True(t, isClientConnected(h, 1))
+	assert.False(t, conn.IsClosed())
+	assert.Greater(t, conn.Pings(), 1)
+}
+
+func TestBroadcastToChat_ConcurrentBroadcastsNeverOverlapWrites(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("GetChatParticipantsForBroadcast", mock.Anything).Return([]int{1}, nil)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1, "chat-1", "chat-2", "chat-3")
+	defer conn.Close()
+
+	// The user is in several active chats at once
+	var wg sync.WaitGroup
+	for _, chatID := range []string{"chat-1", "chat-2", "chat-3"} {
+		wg.Add(1)
+		go func(chatID string) {
+			defer wg.Done()
+			for i := 0; i < 20; i++ {
+				h.broadcastToChat(chatID, []byte(`{"type":"reaction"}`))
+			}
+		}(chatID)
+	}
+	wg.Wait()
+
+	assert.Eventually(t, func() bool { return len(conn.Written()) == 60 }, time.Second, 5*time.Millisecond)
+	assert.False(t, conn.concurrent.Load())
+}
+
+func TestBroadcastToChat_SlowClientIsDroppedWhenBufferOverflows(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("GetChatParticipantsForBroadcast", "chat-1").Return([]int{1}, nil)
+	h := newTestHandler(service, Config{SendBufferSize: 2})
+
+	rooms := map[string]struct{}{"chat-1": {}}
+	service.On("GetUserChatRooms", 1).Return(rooms, nil).Once()
+	conn := NewMockConn()
+	conn.writeGate = make(chan struct{})
+	h.handleWSConnection(conn, 1)
+
+	// One frame is stuck in the writer, two fill the buffer and the last overflows it
+	for i := 0; i < 4; i++ {
+		h.broadcastToChat("chat-1", []byte(`{"type":"reaction"}`))
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	assert.Eventually(t, conn.IsClosed, time.Second, 5*time.Millisecond)
+	assert.Eventually(t, func() bool { return !isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+}
+
+// startWSServer serves HandleWebSocket as the given user
+func startWSServer(t *testing.T, h *Handler, userID int) string {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.HandleWebSocket(w, r.WithContext(authctx.WithUserID(r.Context(), userID)))
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+func TestHandleWebSocket_NegotiatesKnownSubprotocol(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("GetUserChatRooms", 1).Return(map[string]struct{}{}, nil)
+	h := newTestHandler(service, Config{})
+
+	dialer := websocket.Dialer{Subprotocols: []string{"brigadka.v2", WSSubprotocol}}
+	conn, resp, err := dialer.Dial(startWSServer(t, h, 1), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	assert.Equal(t, WSSubprotocol, conn.Subprotocol())
+	assert.Eventually(t, func() bool { return isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleWebSocket_RejectsUnknownSubprotocol(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	dialer := websocket.Dialer{Subprotocols: []string{"brigadka.v9"}}
+	_, resp, err := dialer.Dial(startWSServer(t, h, 1), nil)
+
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+		var body apierrors.ErrorResponse
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, apierrors.CodeUnsupportedSubprotocol, body.Code)
+	}
+	assert.False(t, isClientConnected(h, 1))
+}
+
+func TestHandleWebSocket_AcceptsAllowedOrigin(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("GetUserChatRooms", 1).Return(map[string]struct{}{}, nil)
+	h := newTestHandler(service, Config{AllowedOrigins: []string{"https://app.example.com"}})
+
+	header := http.Header{"Origin": []string{"https://app.example.com"}}
+	conn, _, err := websocket.DefaultDialer.Dial(startWSServer(t, h, 1), header)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool { return isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleWebSocket_RejectsOriginNotAllowed(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{AllowedOrigins: []string{"https://app.example.com"}})
+
+	header := http.Header{"Origin": []string{"https://evil.example.com"}}
+	_, resp, err := websocket.DefaultDialer.Dial(startWSServer(t, h, 1), header)
+
+	assert.ErrorIs(t, err, websocket.ErrBadHandshake)
+	if assert.NotNil(t, resp) {
+		assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	}
+	assert.False(t, isClientConnected(h, 1))
+}
+
+func TestHandleWebSocket_AllowAnyOriginBypassesAllowlist(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("GetUserChatRooms", 1).Return(map[string]struct{}{}, nil)
+	h := newTestHandler(service, Config{AllowAnyOrigin: true})
+
+	header := http.Header{"Origin": []string{"http://localhost:3000"}}
+	conn, _, err := websocket.DefaultDialer.Dial(startWSServer(t, h, 1), header)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	assert.Eventually(t, func() bool { return isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+}
+
+func TestHandleWebSocket_OversizedFrameClosesConnection(t *testing.T) {
+	service := new(MockMessagingService)
+	service.On("GetUserChatRooms", 1).Return(map[string]struct{}{}, nil)
+	h := newTestHandler(service, Config{MaxFrameBytes: 512})
+
+	conn, _, err := websocket.DefaultDialer.Dial(startWSServer(t, h, 1), nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	assert.Eventually(t, func() bool { return isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+
+	frame := `{"type":"chat","chat_id":"chat-1","content":"` + strings.Repeat("a", 1024) + `"}`
+	assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(frame)))
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err = conn.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.CloseMessageTooBig), "unexpected error: %v", err)
+	assert.Eventually(t, func() bool { return !isClientConnected(h, 1) }, time.Second, 5*time.Millisecond)
+}
+
+func TestNewHandler_DefaultsFrameLimit(t *testing.T) {
+	service := new(MockMessagingService)
+	h := newTestHandler(service, Config{})
+
+	conn := connectClient(h, service, 1)
+	defer conn.Close()
+
+	assert.Equal(t, int64(DefaultMaxFrameBytes), conn.ReadLimit())
+}
@@ -0,0 +1,60 @@
+package profile
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/profile"
+)
+
+// profileETag identifies a version of a profile response. last_seen_at changes
+// without touching updated_at, so it is part of the version too. The tag is weak
+// because media URLs are resolved when the response is built.
+func profileETag(prof *profile.Profile) string {
+	version := strconv.FormatInt(prof.UpdatedAt.UnixNano(), 36)
+	if prof.LastSeenAt != nil {
+		version += "-" + strconv.FormatInt(prof.LastSeenAt.UnixNano(), 36)
+	}
+	return fmt.Sprintf(`W/"%d-%s"`, prof.UserID, version)
+}
+
+// profileLastModified is the latest change to a profile response
+func profileLastModified(prof *profile.Profile) time.Time {
+	lastModified := prof.UpdatedAt
+	if prof.LastSeenAt != nil && prof.LastSeenAt.After(lastModified) {
+		lastModified = *prof.LastSeenAt
+	}
+	return lastModified
+}
+
+// etagMatches reports whether an If-None-Match header lists the tag, using the weak
+// comparison conditional GET requests call for
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeProfileValidators sets the caching headers of a profile response and answers
+// 304 Not Modified if the client already has this version
+func writeProfileValidators(w http.ResponseWriter, r *http.Request, prof *profile.Profile) bool {
+	etag := profileETag(prof)
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if lastModified := profileLastModified(prof); !lastModified.IsZero() {
+		w.Header().Set("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" && etagMatches(ifNoneMatch, etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return true
+	}
+	return false
+}
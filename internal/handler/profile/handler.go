@@ -7,6 +7,8 @@ import (
 	"strconv"
 	"time"
 
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/profile"
 	"github.com/go-chi/chi/v5"
 )
@@ -61,10 +63,69 @@ type ProfileResponse struct {
 	Avatar         *profile.Media  `json:"avatar,omitempty"`
 	Videos         []profile.Media `json:"videos,omitempty"`
 	CreatedAt      time.Time       `json:"created_at,omitempty"`
+	UpdatedAt      time.Time       `json:"updated_at,omitempty"`
+	LastSeenAt     *time.Time      `json:"last_seen_at,omitempty"` // Nil until the user is first seen active
+}
+
+// ProfileSearchItem is the public card of a profile shown to other users in search
+// results and feeds. Fields are listed explicitly so that nothing added to the full
+// profile is exposed in listings by accident.
+type ProfileSearchItem struct {
+	UserID         int             `json:"user_id"`
+	FullName       string          `json:"full_name"`
+	Birthday       Date            `json:"birthday,omitempty"`
+	Gender         string          `json:"gender,omitempty"`
+	CityID         int             `json:"city_id,omitempty"`
+	Bio            string          `json:"bio,omitempty"`
+	Goal           string          `json:"goal,omitempty"`
+	LookingForTeam bool            `json:"looking_for_team"`
+	ImprovStyles   []string        `json:"improv_styles,omitempty"`
+	Avatar         *profile.Media  `json:"avatar,omitempty"`
+	Videos         []profile.Media `json:"videos,omitempty"`
+	CreatedAt      time.Time       `json:"created_at,omitempty"`
+	DistanceKm     *float64        `json:"distance_km,omitempty"` // Distance to the search point, set when searching near a point
+	UpdatedAt      time.Time       `json:"updated_at,omitempty"`
+	LastSeenAt     *time.Time      `json:"last_seen_at,omitempty"`
+	Online         bool            `json:"online"` // The user has an open chat connection
+}
+
+// Supported profile activity types
+const (
+	ActivityTypeImprov = "improv"
+)
+
+// EnumsResponse lists the values the server accepts for enumerated profile fields
+type EnumsResponse struct {
+	ActivityTypes []string `json:"activity_types"`
+	Genders       []string `json:"genders"`
+	ImprovGoals   []string `json:"improv_goals"`
+	ImprovStyles  []string `json:"improv_styles"`
+	SortOrders    []string `json:"sort_orders"` // Values of sort_by in profile search, the default first
+}
+
+// CatalogLanguagesResponse lists the supported catalog languages
+type CatalogLanguagesResponse struct {
+	Languages []string `json:"languages"`
+	Default   string   `json:"default"` // Used when no language is requested
+}
+
+// ProfileEditCatalogs holds the catalogs an edit form chooses values from
+type ProfileEditCatalogs struct {
+	ImprovStyles []profile.TranslatedItem `json:"improv_styles"`
+	ImprovGoals  []profile.TranslatedItem `json:"improv_goals"`
+	Genders      []profile.TranslatedItem `json:"genders"`
+	Cities       []profile.City           `json:"cities"`
+}
+
+// ProfileEditResponse is everything needed to render the profile edit form
+type ProfileEditResponse struct {
+	Profile  ProfileResponse     `json:"profile"`
+	Catalogs ProfileEditCatalogs `json:"catalogs"`
 }
 
 // ProfileCreateRequest represents data needed to create a profile
 type ProfileCreateRequest struct {
+	ActivityType   string   `json:"activity_type,omitempty"` // Defaults to improv
 	UserID         int      `json:"user_id" validate:"required"`
 	FullName       string   `json:"full_name" validate:"required"`
 	Birthday       Date     `json:"birthday" validate:"required"`
@@ -92,29 +153,69 @@ type ProfileUpdateRequest struct {
 	Videos         []int    `json:"videos,omitempty"`
 }
 
+// PatchImprovProfileRequest lists the improv fields to change. Omitted fields are left
+// untouched; improv_styles replaces the styles and must not be empty.
+type PatchImprovProfileRequest struct {
+	Goal           *string   `json:"goal,omitempty"`
+	ImprovStyles   *[]string `json:"improv_styles,omitempty"`
+	LookingForTeam *bool     `json:"looking_for_team,omitempty"`
+}
+
+// LookingForTeamRequest toggles whether the user is looking for a team
+type LookingForTeamRequest struct {
+	LookingForTeam *bool `json:"looking_for_team"`
+}
+
+// MediaOrderRequest lists the profile videos in the new gallery order
+type MediaOrderRequest struct {
+	MediaIDs []int `json:"media_ids"`
+}
+
 // SearchRequest represents the search query parameters
 type SearchRequest struct {
+	ActivityType   string     `json:"activity_type,omitempty"`  // Kept for older clients, merged into ActivityTypes
+	ActivityTypes  []string   `json:"activity_types,omitempty"` // Defaults to improv
 	FullName       *string    `json:"full_name,omitempty"`
 	LookingForTeam *bool      `json:"looking_for_team,omitempty"`
 	Goals          []string   `json:"goals,omitempty"`
 	ImprovStyles   []string   `json:"improv_styles,omitempty"`
 	AgeMin         *int       `json:"age_min,omitempty"`
 	AgeMax         *int       `json:"age_max,omitempty"`
+	WithUnknownAge bool       `json:"include_unknown_age,omitempty"` // Keep profiles without a birthday when age_min or age_max is set
 	Genders        []string   `json:"genders,omitempty"`
 	CityID         *int       `json:"city_id,omitempty"`
+	NearLat        *float64   `json:"near_lat,omitempty"`  // Latitude of the search point, requires near_lng and radius_km
+	NearLng        *float64   `json:"near_lng,omitempty"`  // Longitude of the search point
+	RadiusKm       *float64   `json:"radius_km,omitempty"` // Search radius around the point in kilometres
 	HasAvatar      *bool      `json:"has_avatar,omitempty"`
 	HasVideo       *bool      `json:"has_video,omitempty"`
+	HasMedia       *bool      `json:"has_media,omitempty"` // Only profiles with at least one media item, nil means no filter
 	CreatedAfter   *time.Time `json:"created_after,omitempty"`
+	SortBy         string     `json:"sort_by,omitempty" enums:"created_at_desc,created_at_asc,age_asc,age_desc,relevance,distance_asc,last_seen_desc"` // Defaults to created_at_desc
 	Page           int        `json:"page"`
 	PageSize       int        `json:"page_size"`
 }
 
 // SearchResponse represents the search response
 type SearchResponse struct {
-	Profiles   []ProfileResponse `json:"profiles"`
-	TotalCount int               `json:"total_count"`
-	Page       int               `json:"page"`
-	PageSize   int               `json:"page_size"`
+	Profiles   []ProfileSearchItem `json:"profiles"`
+	TotalCount int                 `json:"total_count"`
+	Page       int                 `json:"page"`
+	PageSize   int                 `json:"page_size"`
+	TotalPages int                 `json:"total_pages"`
+}
+
+// FeedResponse represents a page of the new profiles feed
+type FeedResponse struct {
+	Profiles   []ProfileSearchItem `json:"profiles"`
+	NextCursor string              `json:"next_cursor,omitempty"`
+}
+
+// TrendingResponse is the most used catalog items among recent profiles
+type TrendingResponse struct {
+	ActivityType string                 `json:"activity_type"`
+	Since        time.Time              `json:"since"`
+	Items        []profile.TrendingItem `json:"items"`
 }
 
 // TranslatedItem represents a catalog item with translations
@@ -137,16 +238,31 @@ type ProfileService interface {
 	CreateProfile(req profile.ProfileCreateRequest) (*profile.Profile, error)
 	GetProfile(userID int) (*profile.Profile, error)
 	UpdateProfile(userID int, req profile.ProfileUpdateRequest) (*profile.Profile, error)
+	PatchImprovProfile(userID int, patch profile.ImprovProfilePatch) (*profile.Profile, error)
+	SetLookingForTeam(userID int, lookingForTeam bool) (*profile.Profile, error)
+	ReorderMedia(userID int, mediaIDs []int) (*profile.Profile, error)
+	DeleteProfile(userID int) error
+	RestoreProfile(userID int) (*profile.Profile, error)
+	GetAllCatalogs(lang string) (*profile.Catalogs, error)
 	GetImprovStyles(lang string) ([]profile.TranslatedItem, error)
 	GetImprovGoals(lang string) ([]profile.TranslatedItem, error)
 	GetGenders(lang string) ([]profile.TranslatedItem, error)
 	GetCities() ([]profile.City, error)
+	GetCatalogTranslations(catalogType string) ([]profile.CatalogItemTranslations, error)
 	Search(userID int, filter profile.SearchFilter) (*profile.SearchResult, error)
+	GetNewProfiles(userID int, cursor string, limit int) (*profile.FeedResult, error)
+	GetTrendingImprovStyles(userID int) (*profile.TrendingResult, error)
+}
+
+// Presence reports whether a user is currently connected
+type Presence interface {
+	IsOnline(userID int) bool
 }
 
 // ProfileHandler handles requests related to profiles
 type ProfileHandler struct {
 	profileService ProfileService
+	presence       Presence
 }
 
 // NewProfileHandler creates a new instance of ProfileHandler
@@ -156,26 +272,65 @@ func NewProfileHandler(profileService ProfileService) *ProfileHandler {
 	}
 }
 
+// SetPresence sets where the online status shown in search results comes from.
+// Without it every user is shown offline.
+func (h *ProfileHandler) SetPresence(presence Presence) {
+	h.presence = presence
+}
+
+// searchItem converts a profile to its public card with the user's online status
+func (h *ProfileHandler) searchItem(profile *profile.Profile) ProfileSearchItem {
+	item := convertToSearchItem(profile)
+	if h.presence != nil {
+		item.Online = h.presence.IsOnline(profile.UserID)
+	}
+	return item
+}
+
 // handleError handles errors and returns appropriate HTTP status
 func handleError(w http.ResponseWriter, err error) {
 	// Return different HTTP status codes based on error type
 	switch {
 	case errors.Is(err, profile.ErrUserNotFound):
-		http.Error(w, "User not found", http.StatusNotFound)
+		apierrors.RespondError(w, http.StatusNotFound, "User not found", apierrors.CodeUserNotFound)
 	case errors.Is(err, profile.ErrProfileAlreadyExists):
-		http.Error(w, "Profile already exists for this user", http.StatusConflict)
+		apierrors.RespondError(w, http.StatusConflict, "Profile already exists for this user", apierrors.CodeProfileAlreadyExists)
 	case errors.Is(err, profile.ErrInvalidImprovGoal):
-		http.Error(w, "Invalid improv goal", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid improv goal", apierrors.CodeInvalidImprovGoal)
 	case errors.Is(err, profile.ErrInvalidImprovStyle):
-		http.Error(w, "Invalid improv style", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid improv style", apierrors.CodeInvalidImprovStyle)
+	case errors.Is(err, profile.ErrImprovStylesRequired):
+		apierrors.RespondError(w, http.StatusBadRequest, "At least one improv style is required", apierrors.CodeImprovStylesRequired)
+	case errors.Is(err, profile.ErrTooManyImprovStyles):
+		apierrors.RespondError(w, http.StatusBadRequest, "Too many improv styles", apierrors.CodeTooManyImprovStyles)
+	case errors.Is(err, profile.ErrTooManyVideos):
+		apierrors.RespondError(w, http.StatusBadRequest, "Too many videos", apierrors.CodeTooManyVideos)
 	case errors.Is(err, profile.ErrProfileNotFound):
-		http.Error(w, "Profile not found", http.StatusNotFound)
+		apierrors.RespondError(w, http.StatusNotFound, "Profile not found", apierrors.CodeProfileNotFound)
 	case errors.Is(err, profile.ErrInvalidGender):
-		http.Error(w, "Invalid gender", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid gender", apierrors.CodeInvalidGender)
 	case errors.Is(err, profile.ErrInvalidCity):
-		http.Error(w, "Invalid city", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid city", apierrors.CodeInvalidCity)
+	case errors.Is(err, profile.ErrUnsupportedLanguage):
+		apierrors.RespondError(w, http.StatusBadRequest, "Unsupported language", apierrors.CodeUnsupportedLanguage)
+	case errors.Is(err, profile.ErrInvalidCatalog):
+		apierrors.RespondError(w, http.StatusNotFound, "Catalog not found", apierrors.CodeCatalogNotFound)
+	case errors.Is(err, profile.ErrInvalidMedia):
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid media", apierrors.CodeInvalidMedia)
+	case errors.Is(err, profile.ErrInvalidFeedCursor):
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid cursor", apierrors.CodeInvalidCursor)
+	case errors.Is(err, profile.ErrInvalidSort):
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid sort order", apierrors.CodeInvalidSort)
+	case errors.Is(err, profile.ErrInvalidProximity):
+		apierrors.RespondError(w, http.StatusBadRequest, "near_lat, near_lng and radius_km must be valid and given together", apierrors.CodeInvalidProximity)
+	case errors.Is(err, profile.ErrInvalidMediaOrder):
+		apierrors.RespondError(w, http.StatusBadRequest, "Media order must list every profile video exactly once", apierrors.CodeInvalidMediaOrder)
+	case errors.Is(err, profile.ErrProfileNotDeleted):
+		apierrors.RespondError(w, http.StatusConflict, "Profile is not deleted", apierrors.CodeProfileNotDeleted)
+	case errors.Is(err, profile.ErrRestoreExpired):
+		apierrors.RespondError(w, http.StatusGone, "Profile can no longer be restored", apierrors.CodeProfileRestoreExpired)
 	default:
-		http.Error(w, "Server error: "+err.Error(), http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Server error: "+err.Error(), apierrors.CodeInternal)
 	}
 }
 
@@ -193,6 +348,28 @@ func convertToProfileResponse(profile *profile.Profile) ProfileResponse {
 		Avatar:         profile.Avatar,
 		Videos:         profile.Videos,
 		CreatedAt:      profile.CreatedAt,
+		UpdatedAt:      profile.UpdatedAt,
+		LastSeenAt:     profile.LastSeenAt,
+	}
+}
+
+func convertToSearchItem(profile *profile.Profile) ProfileSearchItem {
+	return ProfileSearchItem{
+		UserID:         profile.UserID,
+		FullName:       profile.FullName,
+		Birthday:       Date{Time: profile.Birthday},
+		Gender:         profile.Gender,
+		CityID:         profile.CityID,
+		Bio:            profile.Bio,
+		Goal:           profile.Goal,
+		ImprovStyles:   profile.ImprovStyles,
+		LookingForTeam: profile.LookingForTeam,
+		Avatar:         profile.Avatar,
+		Videos:         profile.Videos,
+		CreatedAt:      profile.CreatedAt,
+		DistanceKm:     profile.DistanceKm,
+		UpdatedAt:      profile.UpdatedAt,
+		LastSeenAt:     profile.LastSeenAt,
 	}
 }
 
@@ -239,10 +416,10 @@ func convertToUpdateProfileRequest(req ProfileUpdateRequest) profile.ProfileUpda
 // @Produce      json
 // @Param        request  body  profile.ProfileCreateRequest  true  "Profile data"
 // @Success      201  {object}  profile.Profile
-// @Failure      400  {string}  string  "Invalid request body"
-// @Failure      404  {string}  string  "User not found"
-// @Failure      409  {string}  string  "Profile already exists for this user"
-// @Failure      500  {string}  string  "Server error"
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid request body"
+// @Failure      404  {object}  apierrors.ErrorResponse  "User not found"
+// @Failure      409  {object}  apierrors.ErrorResponse  "Profile already exists for this user"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
 // @Router       /profiles [post]
 // @Security     BearerAuth
 func (h *ProfileHandler) CreateProfile(w http.ResponseWriter, r *http.Request) {
@@ -250,7 +427,16 @@ func (h *ProfileHandler) CreateProfile(w http.ResponseWriter, r *http.Request) {
 
 	// Parse the request body
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	// Improv is the only activity type profiles support
+	if req.ActivityType == "" {
+		req.ActivityType = ActivityTypeImprov
+	}
+	if req.ActivityType != ActivityTypeImprov {
+		apierrors.RespondError(w, http.StatusBadRequest, "Unsupported activity type", apierrors.CodeUnsupportedActivityType)
 		return
 	}
 
@@ -267,7 +453,7 @@ func (h *ProfileHandler) CreateProfile(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusCreated)
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
 	}
 }
 
@@ -278,23 +464,22 @@ func (h *ProfileHandler) CreateProfile(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        request  body  profile.ProfileUpdateRequest  true  "Profile update data"
 // @Success      200  {object}  profile.Profile
-// @Failure      400  {string}  string  "Invalid request body"
-// @Failure      401  {string}  string  "Unauthorized"
-// @Failure      404  {string}  string  "Profile not found"
-// @Failure      500  {string}  string  "Server error"
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid request body"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Profile not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
 // @Router       /profiles [patch]
 // @Security     BearerAuth
 func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Parse request body
 	var updateReq ProfileUpdateRequest
 	if err := json.NewDecoder(r.Body).Decode(&updateReq); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
 		return
 	}
 
@@ -311,31 +496,281 @@ func (h *ProfileHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
 	// Return the updated profile
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
 	}
 }
 
-// @Summary      Get Profile
-// @Description  Retrieves a user profile by ID
+// @Summary      Patch Improv Profile
+// @Description  Changes only the given improv fields of the profile, omitted fields keep their current values
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        userID   path  int                        true  "User ID"
+// @Param        request  body  PatchImprovProfileRequest  true  "Improv fields to change"
+// @Success      200  {object}  ProfileResponse
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid request body or field value"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  apierrors.ErrorResponse  "Not the profile owner"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Profile not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /profiles/{userID}/improv [patch]
+// @Security     BearerAuth
+func (h *ProfileHandler) PatchImprovProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ownerID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
+		return
+	}
+
+	// Only the owner may change their profile
+	if ownerID != userID {
+		apierrors.RespondError(w, http.StatusForbidden, "Cannot update another user's profile", apierrors.CodeForbidden)
+		return
+	}
+
+	var req PatchImprovProfileRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	prof, err := h.profileService.PatchImprovProfile(userID, profile.ImprovProfilePatch{
+		Goal:           req.Goal,
+		ImprovStyles:   req.ImprovStyles,
+		LookingForTeam: req.LookingForTeam,
+	})
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := convertToProfileResponse(prof)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Set Looking For Team
+// @Description  Toggles whether the user is looking for a team without a full profile update
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        userID   path  int                    true  "User ID"
+// @Param        request  body  LookingForTeamRequest  true  "New looking for team flag"
+// @Success      200  {object}  ProfileResponse
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid request body"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  apierrors.ErrorResponse  "Not the profile owner"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Profile not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /profiles/{userID}/improv/looking-for-team [put]
+// @Security     BearerAuth
+func (h *ProfileHandler) SetLookingForTeam(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ownerID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
+		return
+	}
+
+	// Only the owner may change their profile
+	if ownerID != userID {
+		apierrors.RespondError(w, http.StatusForbidden, "Cannot update another user's profile", apierrors.CodeForbidden)
+		return
+	}
+
+	var req LookingForTeamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.LookingForTeam == nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	prof, err := h.profileService.SetLookingForTeam(userID, *req.LookingForTeam)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := convertToProfileResponse(prof)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Reorder Profile Media
+// @Description  Sets the gallery order of the profile videos. The request must list every video of the profile exactly once
+// @Tags         profile
+// @Accept       json
+// @Produce      json
+// @Param        userID   path  int                true  "User ID"
+// @Param        request  body  MediaOrderRequest  true  "Video IDs in the new order"
+// @Success      200  {object}  ProfileResponse
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid request body or media set"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  apierrors.ErrorResponse  "Not the profile owner"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Profile not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /profiles/{userID}/media/order [put]
+// @Security     BearerAuth
+func (h *ProfileHandler) ReorderMedia(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ownerID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
+		return
+	}
+
+	// Only the owner may change their profile
+	if ownerID != userID {
+		apierrors.RespondError(w, http.StatusForbidden, "Cannot update another user's profile", apierrors.CodeForbidden)
+		return
+	}
+
+	var req MediaOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.MediaIDs == nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	prof, err := h.profileService.ReorderMedia(userID, req.MediaIDs)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := convertToProfileResponse(prof)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Delete Profile
+// @Description  Hides the profile from other users and search. Its data is kept, so the owner can restore it within the grace period
+// @Tags         profile
+// @Param        userID  path  int  true  "User ID"
+// @Success      204  "Profile deleted"
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid user ID"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  apierrors.ErrorResponse  "Not the profile owner"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Profile not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /profiles/{userID} [delete]
+// @Security     BearerAuth
+func (h *ProfileHandler) DeleteProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ownerID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
+		return
+	}
+
+	// Only the owner may delete their profile
+	if ownerID != userID {
+		apierrors.RespondError(w, http.StatusForbidden, "Cannot delete another user's profile", apierrors.CodeForbidden)
+		return
+	}
+
+	if err := h.profileService.DeleteProfile(userID); err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// @Summary      Restore Profile
+// @Description  Restores a deleted profile if the grace period has not passed yet
 // @Tags         profile
 // @Produce      json
 // @Param        userID  path  int  true  "User ID"
 // @Success      200  {object}  ProfileResponse
-// @Failure      400  {string}  string  "Invalid user ID"
-// @Failure      404  {string}  string  "Profile not found"
-// @Failure      500  {string}  string  "Server error"
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid user ID"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  apierrors.ErrorResponse  "Not the profile owner"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Profile not found"
+// @Failure      409  {object}  apierrors.ErrorResponse  "Profile is not deleted"
+// @Failure      410  {object}  apierrors.ErrorResponse  "Grace period has passed"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /profiles/{userID}/restore [post]
+// @Security     BearerAuth
+func (h *ProfileHandler) RestoreProfile(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	ownerID, err := strconv.Atoi(chi.URLParam(r, "userID"))
+	if err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
+		return
+	}
+
+	// Only the owner may restore their profile
+	if ownerID != userID {
+		apierrors.RespondError(w, http.StatusForbidden, "Cannot restore another user's profile", apierrors.CodeForbidden)
+		return
+	}
+
+	prof, err := h.profileService.RestoreProfile(userID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := convertToProfileResponse(prof)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Get Profile
+// @Description  Retrieves a user profile by ID. The response carries ETag and Last-Modified headers; a request whose If-None-Match matches the ETag gets 304 Not Modified
+// @Tags         profile
+// @Produce      json
+// @Param        userID         path    int     true   "User ID"
+// @Param        If-None-Match  header  string  false  "ETag of the cached profile"
+// @Success      200  {object}  ProfileResponse
+// @Success      304  "Profile not modified"
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid user ID"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Profile not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
 // @Router       /profiles/{userID} [get]
 func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	// Extract userID from URL path using Chi router
 	userIDStr := chi.URLParam(r, "userID")
 	if userIDStr == "" {
-		http.Error(w, "Missing user ID", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Missing user ID", apierrors.CodeInvalidUserID)
 		return
 	}
 
 	userID, err := strconv.Atoi(userIDStr)
 	if err != nil {
-		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid user ID", apierrors.CodeInvalidUserID)
 		return
 	}
 
@@ -345,30 +780,69 @@ func (h *ProfileHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 		handleError(w, err)
 		return
 	}
+	if writeProfileValidators(w, r, prof) {
+		return
+	}
 
 	response := convertToProfileResponse(prof)
 
 	// Return the profile
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Get All Catalogs
+// @Description  Retrieves every profile catalog in one response, keyed by catalog name
+// @Tags         catalog
+// @Produce      json
+// @Param        lang  query  string  false  "Language code (default: ru)"
+// @Success      200  {object}  profile.Catalogs
+// @Failure      400  {object}  apierrors.ErrorResponse  "Unsupported language"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /profiles/catalog [get]
+func (h *ProfileHandler) GetAllCatalogs(w http.ResponseWriter, r *http.Request) {
+	lang := r.URL.Query().Get("lang")
+
+	catalogs, err := h.profileService.GetAllCatalogs(lang)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(catalogs); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
 	}
 }
 
+// @Summary      Get Catalog Languages
+// @Description  Lists the languages catalog labels are available in
+// @Tags         catalog
+// @Produce      json
+// @Success      200  {object}  CatalogLanguagesResponse
+// @Router       /profiles/catalog/languages [get]
+func (h *ProfileHandler) GetCatalogLanguages(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(CatalogLanguagesResponse{
+		Languages: profile.SupportedLanguages,
+		Default:   profile.DefaultLanguage,
+	})
+}
+
 // @Summary      Get Improv Styles
 // @Description  Retrieves a catalog of improv styles with translations
 // @Tags         catalog
 // @Produce      json
-// @Param        lang  query  string  false  "Language code (default: en)"
+// @Param        lang  query  string  false  "Language code (default: ru)"
 // @Success      200  {array}  profile.TranslatedItem
-// @Failure      500  {string}  string  "Server error"
+// @Failure      400  {object}  apierrors.ErrorResponse  "Unsupported language"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
 // @Router       /profiles/catalog/improv-styles [get]
 func (h *ProfileHandler) GetImprovStyles(w http.ResponseWriter, r *http.Request) {
-	// Get language from query parameter or use default
+	// An empty language is defaulted and validated by the service
 	lang := r.URL.Query().Get("lang")
-	if lang == "" {
-		lang = "ru" // Default language
-	}
 
 	// Call the service to get the styles
 	styles, err := h.profileService.GetImprovStyles(lang)
@@ -380,7 +854,7 @@ func (h *ProfileHandler) GetImprovStyles(w http.ResponseWriter, r *http.Request)
 	// Return the styles
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(styles); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
 	}
 }
 
@@ -388,16 +862,14 @@ func (h *ProfileHandler) GetImprovStyles(w http.ResponseWriter, r *http.Request)
 // @Description  Retrieves a catalog of improv goals with translations
 // @Tags         catalog
 // @Produce      json
-// @Param        lang  query  string  false  "Language code (default: en)"
+// @Param        lang  query  string  false  "Language code (default: ru)"
 // @Success      200  {array}  profile.TranslatedItem
-// @Failure      500  {string}  string  "Server error"
+// @Failure      400  {object}  apierrors.ErrorResponse  "Unsupported language"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
 // @Router       /profiles/catalog/improv-goals [get]
 func (h *ProfileHandler) GetImprovGoals(w http.ResponseWriter, r *http.Request) {
-	// Get language from query parameter or use default
+	// An empty language is defaulted and validated by the service
 	lang := r.URL.Query().Get("lang")
-	if lang == "" {
-		lang = "ru" // Default language
-	}
 
 	// Call the service to get the goals
 	goals, err := h.profileService.GetImprovGoals(lang)
@@ -409,7 +881,7 @@ func (h *ProfileHandler) GetImprovGoals(w http.ResponseWriter, r *http.Request)
 	// Return the goals
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(goals); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
 	}
 }
 
@@ -417,16 +889,14 @@ func (h *ProfileHandler) GetImprovGoals(w http.ResponseWriter, r *http.Request)
 // @Description  Retrieves a catalog of genders with translations
 // @Tags         catalog
 // @Produce      json
-// @Param        lang  query  string  false  "Language code (default: en)"
+// @Param        lang  query  string  false  "Language code (default: ru)"
 // @Success      200  {array}  profile.TranslatedItem
-// @Failure      500  {string}  string  "Server error"
+// @Failure      400  {object}  apierrors.ErrorResponse  "Unsupported language"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
 // @Router       /profiles/catalog/genders [get]
 func (h *ProfileHandler) GetGenders(w http.ResponseWriter, r *http.Request) {
-	// Get language from query parameter or use default
+	// An empty language is defaulted and validated by the service
 	lang := r.URL.Query().Get("lang")
-	if lang == "" {
-		lang = "ru" // Default language
-	}
 
 	// Call the service to get the genders
 	genders, err := h.profileService.GetGenders(lang)
@@ -438,7 +908,85 @@ func (h *ProfileHandler) GetGenders(w http.ResponseWriter, r *http.Request) {
 	// Return the genders
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(genders); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Get Enums
+// @Description  Retrieves the allowed values of enumerated profile fields
+// @Tags         catalog
+// @Produce      json
+// @Success      200  {object}  EnumsResponse
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /meta/enums [get]
+func (h *ProfileHandler) GetEnums(w http.ResponseWriter, r *http.Request) {
+	// Codes don't depend on the language, labels are not returned
+	genders, err := h.profileService.GetGenders("en")
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	goals, err := h.profileService.GetImprovGoals("en")
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	styles, err := h.profileService.GetImprovStyles("en")
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := EnumsResponse{
+		ActivityTypes: []string{ActivityTypeImprov},
+		Genders:       catalogCodes(genders),
+		ImprovGoals:   catalogCodes(goals),
+		ImprovStyles:  catalogCodes(styles),
+		SortOrders:    profile.SortOrders,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// catalogCodes extracts the codes of catalog items
+func catalogCodes(items []profile.TranslatedItem) []string {
+	codes := make([]string, len(items))
+	for i, item := range items {
+		codes[i] = item.Code
+	}
+	return codes
+}
+
+// @Summary      Get Catalog Translations
+// @Description  Retrieves every item of a catalog with labels in all supported languages and flags missing translations
+// @Tags         admin
+// @Produce      json
+// @Param        type  path  string  true  "Catalog type (improv-styles, improv-goals, genders)"
+// @Success      200  {array}  profile.CatalogItemTranslations
+// @Failure      403  {object}  apierrors.ErrorResponse  "Forbidden"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Catalog not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /admin/catalog/{type}/translations [get]
+// @Security     BearerAuth
+func (h *ProfileHandler) GetCatalogTranslations(w http.ResponseWriter, r *http.Request) {
+	catalogType := chi.URLParam(r, "type")
+
+	// Call the service to get the translations
+	items, err := h.profileService.GetCatalogTranslations(catalogType)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	// Return the translations
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(items); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
 	}
 }
 
@@ -447,7 +995,7 @@ func (h *ProfileHandler) GetGenders(w http.ResponseWriter, r *http.Request) {
 // @Tags         catalog
 // @Produce      json
 // @Success      200  {array}  profile.City
-// @Failure      500  {string}  string  "Server error"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
 // @Router       /profiles/catalog/cities [get]
 func (h *ProfileHandler) GetCities(w http.ResponseWriter, r *http.Request) {
 	// Call the service to get the cities
@@ -460,7 +1008,7 @@ func (h *ProfileHandler) GetCities(w http.ResponseWriter, r *http.Request) {
 	// Return the cities
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(cities); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
 	}
 }
 
@@ -471,13 +1019,12 @@ func (h *ProfileHandler) GetCities(w http.ResponseWriter, r *http.Request) {
 // @Produce      json
 // @Param        request  body      SearchRequest  true  "Search filters"
 // @Success      200      {object}  SearchResponse
-// @Failure      400      {string}  string  "Invalid request"
-// @Failure      500      {string}  string  "Server error"
+// @Failure      400      {object}  apierrors.ErrorResponse  "Invalid request"
+// @Failure      500      {object}  apierrors.ErrorResponse  "Server error"
 // @Router       /profiles/search [post]
 func (h *ProfileHandler) SearchProfiles(w http.ResponseWriter, r *http.Request) {
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
@@ -485,10 +1032,19 @@ func (h *ProfileHandler) SearchProfiles(w http.ResponseWriter, r *http.Request)
 
 	// Parse the request body
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body: "+err.Error(), http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body: "+err.Error(), apierrors.CodeInvalidRequest)
 		return
 	}
 
+	// Improv is the only activity type profiles support, so every supported
+	// type matches all profiles and the improv filters always apply
+	for _, activityType := range searchActivityTypes(req) {
+		if activityType != ActivityTypeImprov {
+			apierrors.RespondError(w, http.StatusBadRequest, "Unsupported activity type", apierrors.CodeUnsupportedActivityType)
+			return
+		}
+	}
+
 	// Convert request to service filter
 	filter := profile.SearchFilter{
 		FullName:       req.FullName,
@@ -497,11 +1053,17 @@ func (h *ProfileHandler) SearchProfiles(w http.ResponseWriter, r *http.Request)
 		ImprovStyles:   req.ImprovStyles,
 		AgeMin:         req.AgeMin,
 		AgeMax:         req.AgeMax,
+		WithUnknownAge: req.WithUnknownAge,
 		Genders:        req.Genders,
 		CityID:         req.CityID,
+		NearLat:        req.NearLat,
+		NearLng:        req.NearLng,
+		RadiusKm:       req.RadiusKm,
 		HasAvatar:      req.HasAvatar,
 		HasVideo:       req.HasVideo,
+		HasMedia:       req.HasMedia,
 		CreatedAfter:   req.CreatedAfter,
+		SortBy:         req.SortBy,
 		Page:           req.Page,
 		PageSize:       req.PageSize,
 	}
@@ -514,9 +1076,9 @@ func (h *ProfileHandler) SearchProfiles(w http.ResponseWriter, r *http.Request)
 	}
 
 	// Convert service profiles to response profiles
-	profiles := make([]ProfileResponse, 0, len(result.Profiles))
+	profiles := make([]ProfileSearchItem, 0, len(result.Profiles))
 	for _, p := range result.Profiles {
-		profiles = append(profiles, convertToProfileResponse(&p))
+		profiles = append(profiles, h.searchItem(&p))
 	}
 
 	// Create the response
@@ -525,11 +1087,210 @@ func (h *ProfileHandler) SearchProfiles(w http.ResponseWriter, r *http.Request)
 		TotalCount: result.TotalCount,
 		Page:       result.Page,
 		PageSize:   result.PageSize,
+		TotalPages: result.TotalPages,
 	}
 
 	// Return the response
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		http.Error(w, "Failed to encode response", http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Search Preview
+// @Description  Returns the caller's profile exactly as it appears in other users' search results
+// @Tags         profile
+// @Produce      json
+// @Success      200  {object}  ProfileSearchItem
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Profile not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /profiles/me/search-preview [get]
+func (h *ProfileHandler) GetSearchPreview(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	prof, err := h.profileService.GetProfile(userID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	// Search results use the same projection
+	response := h.searchItem(prof)
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      Get Profile Edit Form
+// @Description  Retrieves the caller's profile together with the catalogs its fields take values from
+// @Tags         profile
+// @Produce      json
+// @Param        lang  query  string  false  "Language of catalog labels (default: ru)"
+// @Success      200  {object}  ProfileEditResponse
+// @Failure      400  {object}  apierrors.ErrorResponse  "Unsupported language"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Profile not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /profiles/me/edit [get]
+// @Security     BearerAuth
+func (h *ProfileHandler) GetProfileEditForm(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	lang := r.URL.Query().Get("lang")
+
+	prof, err := h.profileService.GetProfile(userID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	styles, err := h.profileService.GetImprovStyles(lang)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	goals, err := h.profileService.GetImprovGoals(lang)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	genders, err := h.profileService.GetGenders(lang)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	cities, err := h.profileService.GetCities()
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := ProfileEditResponse{
+		Profile: convertToProfileResponse(prof),
+		Catalogs: ProfileEditCatalogs{
+			ImprovStyles: styles,
+			ImprovGoals:  goals,
+			Genders:      genders,
+			Cities:       cities,
+		},
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// @Summary      New Profiles Feed
+// @Description  Retrieves recently created profiles, newest first, without blocked users. Results are cached for a short time.
+// @Tags         profile
+// @Produce      json
+// @Param        cursor  query     string  false  "Cursor from the previous page"
+// @Param        limit   query     int     false  "Page size (default 20, max 100)"
+// @Success      200     {object}  FeedResponse
+// @Failure      400     {object}  apierrors.ErrorResponse  "Invalid request"
+// @Failure      401     {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      500     {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /feed/new [get]
+// @Security     BearerAuth
+func (h *ProfileHandler) GetNewProfilesFeed(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed <= 0 {
+			apierrors.RespondError(w, http.StatusBadRequest, "Invalid limit", apierrors.CodeInvalidLimit)
+			return
+		}
+		limit = parsed
+	}
+
+	result, err := h.profileService.GetNewProfiles(userID, r.URL.Query().Get("cursor"), limit)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	profiles := make([]ProfileSearchItem, 0, len(result.Profiles))
+	for _, p := range result.Profiles {
+		profiles = append(profiles, h.searchItem(&p))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(FeedResponse{Profiles: profiles, NextCursor: result.NextCursor}); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
+	}
+}
+
+// searchActivityTypes returns the activity types a search asks for, defaulting to improv
+func searchActivityTypes(req SearchRequest) []string {
+	activityTypes := req.ActivityTypes
+	if req.ActivityType != "" {
+		activityTypes = append(activityTypes, req.ActivityType)
+	}
+	if len(activityTypes) == 0 {
+		return []string{ActivityTypeImprov}
+	}
+	return activityTypes
+}
+
+// @Summary      Trending
+// @Description  Returns the most used improv styles among profiles created this week, most used first. Results are cached for a short time.
+// @Tags         stats
+// @Produce      json
+// @Param        activity_type  query     string  false  "Activity type (default improv)"
+// @Success      200            {object}  TrendingResponse
+// @Failure      400            {object}  apierrors.ErrorResponse  "Unsupported activity type"
+// @Failure      401            {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      500            {object}  apierrors.ErrorResponse  "Server error"
+// @Router       /stats/trending [get]
+// @Security     BearerAuth
+func (h *ProfileHandler) GetTrending(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Improv is the only activity type profiles support
+	activityType := r.URL.Query().Get("activity_type")
+	if activityType == "" {
+		activityType = ActivityTypeImprov
+	}
+	if activityType != ActivityTypeImprov {
+		apierrors.RespondError(w, http.StatusBadRequest, "Unsupported activity type", apierrors.CodeUnsupportedActivityType)
+		return
+	}
+
+	result, err := h.profileService.GetTrendingImprovStyles(userID)
+	if err != nil {
+		handleError(w, err)
+		return
+	}
+
+	response := TrendingResponse{
+		ActivityType: activityType,
+		Since:        result.Since,
+		Items:        result.Items,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to encode response", apierrors.CodeInternal)
 	}
 }
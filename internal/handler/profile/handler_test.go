@@ -0,0 +1,753 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/service/profile"
+)
+
+// MockProfileService is a mock implementation of ProfileService
+type MockProfileService struct {
+	mock.Mock
+}
+
+func (m *MockProfileService) CreateProfile(req profile.ProfileCreateRequest) (*profile.Profile, error) {
+	args := m.Called(req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) GetProfile(userID int) (*profile.Profile, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) UpdateProfile(userID int, req profile.ProfileUpdateRequest) (*profile.Profile, error) {
+	args := m.Called(userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) PatchImprovProfile(userID int, patch profile.ImprovProfilePatch) (*profile.Profile, error) {
+	args := m.Called(userID, patch)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) SetLookingForTeam(userID int, lookingForTeam bool) (*profile.Profile, error) {
+	args := m.Called(userID, lookingForTeam)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) ReorderMedia(userID int, mediaIDs []int) (*profile.Profile, error) {
+	args := m.Called(userID, mediaIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) DeleteProfile(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockProfileService) RestoreProfile(userID int) (*profile.Profile, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.Profile), args.Error(1)
+}
+
+func (m *MockProfileService) GetTrendingImprovStyles(userID int) (*profile.TrendingResult, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.TrendingResult), args.Error(1)
+}
+
+func (m *MockProfileService) GetAllCatalogs(lang string) (*profile.Catalogs, error) {
+	args := m.Called(lang)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.Catalogs), args.Error(1)
+}
+
+func (m *MockProfileService) GetImprovStyles(lang string) ([]profile.TranslatedItem, error) {
+	args := m.Called(lang)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profile.TranslatedItem), args.Error(1)
+}
+
+func (m *MockProfileService) GetImprovGoals(lang string) ([]profile.TranslatedItem, error) {
+	args := m.Called(lang)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profile.TranslatedItem), args.Error(1)
+}
+
+func (m *MockProfileService) GetGenders(lang string) ([]profile.TranslatedItem, error) {
+	args := m.Called(lang)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profile.TranslatedItem), args.Error(1)
+}
+
+func (m *MockProfileService) GetCities() ([]profile.City, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profile.City), args.Error(1)
+}
+
+func (m *MockProfileService) GetCatalogTranslations(catalogType string) ([]profile.CatalogItemTranslations, error) {
+	args := m.Called(catalogType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profile.CatalogItemTranslations), args.Error(1)
+}
+
+func (m *MockProfileService) Search(userID int, filter profile.SearchFilter) (*profile.SearchResult, error) {
+	args := m.Called(userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.SearchResult), args.Error(1)
+}
+
+func (m *MockProfileService) GetNewProfiles(userID int, cursor string, limit int) (*profile.FeedResult, error) {
+	args := m.Called(userID, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.FeedResult), args.Error(1)
+}
+
+func TestCreateProfile_ActivityType(t *testing.T) {
+	tests := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectCreate   bool
+	}{
+		{
+			name:           "Improv activity type",
+			body:           `{"activity_type":"improv","user_id":1,"full_name":"Test User"}`,
+			expectedStatus: http.StatusCreated,
+			expectCreate:   true,
+		},
+		{
+			name:           "Missing activity type defaults to improv",
+			body:           `{"user_id":1,"full_name":"Test User"}`,
+			expectedStatus: http.StatusCreated,
+			expectCreate:   true,
+		},
+		{
+			name:           "Music activity type is not supported",
+			body:           `{"activity_type":"music","user_id":1,"full_name":"Test User"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "Unknown activity type",
+			body:           `{"activity_type":"juggling","user_id":1,"full_name":"Test User"}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockService := new(MockProfileService)
+			handler := NewProfileHandler(mockService)
+
+			if tc.expectCreate {
+				mockService.On("CreateProfile", mock.MatchedBy(func(req profile.ProfileCreateRequest) bool {
+					return req.UserID == 1 && req.FullName == "Test User"
+				})).Return(&profile.Profile{UserID: 1, FullName: "Test User"}, nil)
+			}
+
+			req := httptest.NewRequest("POST", "/api/profiles", bytes.NewBufferString(tc.body))
+			rr := httptest.NewRecorder()
+
+			handler.CreateProfile(rr, req)
+
+			assert.Equal(t, tc.expectedStatus, rr.Code)
+			if tc.expectCreate {
+				mockService.AssertExpectations(t)
+			} else {
+				mockService.AssertNotCalled(t, "CreateProfile", mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGetEnums_ReturnsAllowedValues(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("GetGenders", "en").Return([]profile.TranslatedItem{
+		{Code: "male", Label: "Male"},
+		{Code: "female", Label: "Female"},
+	}, nil)
+	mockService.On("GetImprovGoals", "en").Return([]profile.TranslatedItem{
+		{Code: "hobby", Label: "Hobby"},
+		{Code: "career", Label: "Career"},
+	}, nil)
+	mockService.On("GetImprovStyles", "en").Return([]profile.TranslatedItem{
+		{Code: "shortform", Label: "Short form"},
+		{Code: "longform", Label: "Long form"},
+	}, nil)
+
+	req := httptest.NewRequest("GET", "/api/meta/enums", nil)
+	rr := httptest.NewRecorder()
+
+	handler.GetEnums(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response EnumsResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, []string{ActivityTypeImprov}, response.ActivityTypes)
+	assert.Equal(t, []string{"male", "female"}, response.Genders)
+	assert.Equal(t, []string{"hobby", "career"}, response.ImprovGoals)
+	assert.Equal(t, []string{"shortform", "longform"}, response.ImprovStyles)
+	assert.Equal(t, profile.SortOrders, response.SortOrders)
+	assert.Equal(t, profile.SortCreatedAtDesc, response.SortOrders[0])
+}
+
+func TestGetEnums_CatalogError(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("GetGenders", "en").Return(nil, errors.New("db error"))
+
+	req := httptest.NewRequest("GET", "/api/meta/enums", nil)
+	rr := httptest.NewRecorder()
+
+	handler.GetEnums(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+func TestGetProfileEditForm_CombinesProfileAndCatalogs(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("GetProfile", 1).Return(&profile.Profile{
+		UserID:       1,
+		FullName:     "Test User",
+		Gender:       "female",
+		CityID:       2,
+		Goal:         "hobby",
+		ImprovStyles: []string{"shortform"},
+	}, nil)
+	mockService.On("GetImprovStyles", "en").Return([]profile.TranslatedItem{{Code: "shortform", Label: "Short form"}}, nil)
+	mockService.On("GetImprovGoals", "en").Return([]profile.TranslatedItem{{Code: "hobby", Label: "Hobby"}}, nil)
+	mockService.On("GetGenders", "en").Return([]profile.TranslatedItem{{Code: "female", Label: "Female"}}, nil)
+	mockService.On("GetCities").Return([]profile.City{{ID: 2, Name: "Saint Petersburg"}}, nil)
+
+	req := httptest.NewRequest("GET", "/api/profiles/me/edit?lang=en", nil)
+	req = req.WithContext(authctx.WithUserID(req.Context(), 1))
+	rr := httptest.NewRecorder()
+
+	handler.GetProfileEditForm(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var response ProfileEditResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &response))
+	assert.Equal(t, 1, response.Profile.UserID)
+	assert.Equal(t, "female", response.Profile.Gender)
+	assert.Equal(t, 2, response.Profile.CityID)
+	assert.Equal(t, []string{"shortform"}, response.Profile.ImprovStyles)
+	assert.Equal(t, []profile.TranslatedItem{{Code: "shortform", Label: "Short form"}}, response.Catalogs.ImprovStyles)
+	assert.Equal(t, []profile.TranslatedItem{{Code: "hobby", Label: "Hobby"}}, response.Catalogs.ImprovGoals)
+	assert.Equal(t, []profile.TranslatedItem{{Code: "female", Label: "Female"}}, response.Catalogs.Genders)
+	assert.Equal(t, []profile.City{{ID: 2, Name: "Saint Petersburg"}}, response.Catalogs.Cities)
+}
+
+func TestGetProfileEditForm_NoProfile(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("GetProfile", 1).Return(nil, profile.ErrProfileNotFound)
+
+	req := httptest.NewRequest("GET", "/api/profiles/me/edit", nil)
+	req = req.WithContext(authctx.WithUserID(req.Context(), 1))
+	rr := httptest.NewRecorder()
+
+	handler.GetProfileEditForm(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	mockService.AssertNotCalled(t, "GetImprovStyles", mock.Anything)
+}
+
+func TestSearchProfiles_UnknownSortIsBadRequest(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("Search", 1, mock.MatchedBy(func(filter profile.SearchFilter) bool {
+		return filter.SortBy == "name_asc"
+	})).Return(nil, profile.ErrInvalidSort)
+
+	req := httptest.NewRequest("POST", "/api/profiles/search", bytes.NewBufferString(`{"sort_by":"name_asc"}`))
+	req = req.WithContext(authctx.WithUserID(req.Context(), 1))
+	rr := httptest.NewRecorder()
+
+	handler.SearchProfiles(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body apierrors.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, apierrors.CodeInvalidSort, body.Code)
+}
+
+func TestSearchProfiles_PartialProximityIsBadRequest(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("Search", 1, mock.MatchedBy(func(filter profile.SearchFilter) bool {
+		return filter.NearLat != nil && *filter.NearLat == 55.75 && filter.NearLng == nil && filter.RadiusKm != nil
+	})).Return(nil, profile.ErrInvalidProximity)
+
+	req := httptest.NewRequest("POST", "/api/profiles/search", bytes.NewBufferString(`{"near_lat":55.75,"radius_km":10}`))
+	req = req.WithContext(authctx.WithUserID(req.Context(), 1))
+	rr := httptest.NewRecorder()
+
+	handler.SearchProfiles(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body apierrors.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, apierrors.CodeInvalidProximity, body.Code)
+}
+
+func TestSearchProfiles_ActivityTypes(t *testing.T) {
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"default", `{}`, http.StatusOK},
+		{"list", `{"activity_types":["improv"]}`, http.StatusOK},
+		{"singular", `{"activity_type":"improv"}`, http.StatusOK},
+		{"unsupported in list", `{"activity_types":["improv","music"]}`, http.StatusBadRequest},
+		{"unsupported singular", `{"activity_types":["improv"],"activity_type":"music"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockProfileService)
+			handler := NewProfileHandler(mockService)
+			mockService.On("Search", 1, mock.Anything).Return(&profile.SearchResult{Profiles: []profile.Profile{}}, nil)
+
+			req := httptest.NewRequest("POST", "/api/profiles/search", bytes.NewBufferString(tt.body))
+			req = req.WithContext(authctx.WithUserID(req.Context(), 1))
+			rr := httptest.NewRecorder()
+			handler.SearchProfiles(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code)
+			if tt.status == http.StatusBadRequest {
+				var body apierrors.ErrorResponse
+				assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+				assert.Equal(t, apierrors.CodeUnsupportedActivityType, body.Code)
+				mockService.AssertNotCalled(t, "Search", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGetSearchPreview_MatchesSearchResult(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	prof := &profile.Profile{
+		UserID:         1,
+		FullName:       "Test User",
+		Birthday:       time.Date(1990, 5, 1, 0, 0, 0, 0, time.UTC),
+		Gender:         "female",
+		CityID:         2,
+		Goal:           "hobby",
+		ImprovStyles:   []string{"shortform"},
+		LookingForTeam: true,
+		Avatar:         &profile.Media{ID: 7, URL: "https://example.com/7.jpg"},
+		CreatedAt:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+	}
+	mockService.On("GetProfile", 1).Return(prof, nil)
+	mockService.On("Search", 2, mock.Anything).Return(&profile.SearchResult{Profiles: []profile.Profile{*prof}, TotalCount: 1}, nil)
+
+	req := httptest.NewRequest("GET", "/api/profiles/me/search-preview", nil)
+	req = req.WithContext(authctx.WithUserID(req.Context(), 1))
+	previewRR := httptest.NewRecorder()
+	handler.GetSearchPreview(previewRR, req)
+	assert.Equal(t, http.StatusOK, previewRR.Code)
+
+	// Another user finds the same profile through search
+	req = httptest.NewRequest("POST", "/api/profiles/search", bytes.NewBufferString(`{}`))
+	req = req.WithContext(authctx.WithUserID(req.Context(), 2))
+	searchRR := httptest.NewRecorder()
+	handler.SearchProfiles(searchRR, req)
+	assert.Equal(t, http.StatusOK, searchRR.Code)
+
+	var preview map[string]interface{}
+	assert.NoError(t, json.Unmarshal(previewRR.Body.Bytes(), &preview))
+	var search struct {
+		Profiles []map[string]interface{} `json:"profiles"`
+	}
+	assert.NoError(t, json.Unmarshal(searchRR.Body.Bytes(), &search))
+	assert.Len(t, search.Profiles, 1)
+	assert.Equal(t, search.Profiles[0], preview)
+}
+
+func TestSearchProfiles_ResponseHasOnlyPublicFields(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	prof := profile.Profile{
+		UserID:       3,
+		FullName:     "Test User",
+		Bio:          "Reach me at test@example.com",
+		ImprovStyles: []string{"shortform"},
+		Avatar:       &profile.Media{ID: 7, URL: "https://example.com/7.jpg"},
+	}
+	mockService.On("Search", 1, mock.Anything).Return(&profile.SearchResult{Profiles: []profile.Profile{prof}, TotalCount: 1}, nil)
+
+	req := httptest.NewRequest("POST", "/api/profiles/search", bytes.NewBufferString(`{}`))
+	req = req.WithContext(authctx.WithUserID(req.Context(), 1))
+	rr := httptest.NewRecorder()
+	handler.SearchProfiles(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var body struct {
+		Profiles []map[string]json.RawMessage `json:"profiles"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Len(t, body.Profiles, 1)
+
+	allowed := map[string]bool{
+		"user_id": true, "full_name": true, "birthday": true, "gender": true, "city_id": true, "bio": true,
+		"goal": true, "looking_for_team": true, "improv_styles": true, "avatar": true, "videos": true, "created_at": true,
+		"updated_at": true, "last_seen_at": true, "online": true,
+	}
+	for key := range body.Profiles[0] {
+		assert.True(t, allowed[key], "unexpected field %q in search result", key)
+	}
+	assert.NotContains(t, body.Profiles[0], "email")
+}
+
+type fakePresence map[int]bool
+
+func (p fakePresence) IsOnline(userID int) bool { return p[userID] }
+
+func TestSearchProfiles_ShowsPresence(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+	handler.SetPresence(fakePresence{3: true})
+
+	lastSeen := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	profiles := []profile.Profile{
+		{UserID: 3, FullName: "Online User", LastSeenAt: &lastSeen},
+		{UserID: 4, FullName: "Never Seen"},
+	}
+	mockService.On("Search", 1, mock.Anything).Return(&profile.SearchResult{Profiles: profiles, TotalCount: 2}, nil)
+
+	req := httptest.NewRequest("POST", "/api/profiles/search", bytes.NewBufferString(`{}`))
+	req = req.WithContext(authctx.WithUserID(req.Context(), 1))
+	rr := httptest.NewRecorder()
+	handler.SearchProfiles(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var body SearchResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &body))
+	assert.Len(t, body.Profiles, 2)
+	assert.True(t, body.Profiles[0].Online)
+	assert.Equal(t, lastSeen, *body.Profiles[0].LastSeenAt)
+	assert.False(t, body.Profiles[1].Online)
+	assert.Nil(t, body.Profiles[1].LastSeenAt)
+}
+
+// Helper function to create a looking for team toggle request
+func createLookingForTeamRequest(requesterID int, ownerID string, body string) *http.Request {
+	req := httptest.NewRequest("PUT", "/api/profiles/"+ownerID+"/improv/looking-for-team", bytes.NewBufferString(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("userID", ownerID)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = authctx.WithUserID(ctx, requesterID)
+	return req.WithContext(ctx)
+}
+
+func TestSetLookingForTeam_OwnerTogglesFlag(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("SetLookingForTeam", 1, true).Return(&profile.Profile{UserID: 1, LookingForTeam: true}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.SetLookingForTeam(rr, createLookingForTeamRequest(1, "1", `{"looking_for_team":true}`))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response ProfileResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.True(t, response.LookingForTeam)
+	mockService.AssertExpectations(t)
+}
+
+func TestSetLookingForTeam_NonOwnerIsForbidden(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	rr := httptest.NewRecorder()
+	handler.SetLookingForTeam(rr, createLookingForTeamRequest(2, "1", `{"looking_for_team":true}`))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	var body apierrors.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, apierrors.CodeForbidden, body.Code)
+	mockService.AssertNotCalled(t, "SetLookingForTeam", mock.Anything, mock.Anything)
+}
+
+func TestPatchImprovProfile_PassesOnlyGivenFields(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	goal := "career"
+	mockService.On("PatchImprovProfile", 1, profile.ImprovProfilePatch{Goal: &goal}).
+		Return(&profile.Profile{UserID: 1, Goal: goal, ImprovStyles: []string{"shortform"}}, nil)
+
+	req := createProfileRequest("PATCH", "/api/profiles/1/improv", 1, "1")
+	req.Body = io.NopCloser(bytes.NewBufferString(`{"goal":"career"}`))
+	rr := httptest.NewRecorder()
+	handler.PatchImprovProfile(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response ProfileResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, []string{"shortform"}, response.ImprovStyles)
+	mockService.AssertExpectations(t)
+}
+
+func TestPatchImprovProfile_EmptyStylesIsBadRequest(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("PatchImprovProfile", 1, profile.ImprovProfilePatch{ImprovStyles: &[]string{}}).
+		Return(nil, profile.ErrImprovStylesRequired)
+
+	req := createProfileRequest("PATCH", "/api/profiles/1/improv", 1, "1")
+	req.Body = io.NopCloser(bytes.NewBufferString(`{"improv_styles":[]}`))
+	rr := httptest.NewRecorder()
+	handler.PatchImprovProfile(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body apierrors.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, apierrors.CodeImprovStylesRequired, body.Code)
+}
+
+func createMediaOrderRequest(requesterID int, ownerID string, body string) *http.Request {
+	req := httptest.NewRequest("PUT", "/api/profiles/"+ownerID+"/media/order", bytes.NewBufferString(body))
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("userID", ownerID)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = authctx.WithUserID(ctx, requesterID)
+	return req.WithContext(ctx)
+}
+
+func TestReorderMedia_ReturnsProfileInNewOrder(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("ReorderMedia", 1, []int{12, 10}).Return(&profile.Profile{
+		UserID: 1,
+		Videos: []profile.Media{{ID: 12}, {ID: 10}},
+	}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.ReorderMedia(rr, createMediaOrderRequest(1, "1", `{"media_ids":[12,10]}`))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response ProfileResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, []int{12, 10}, []int{response.Videos[0].ID, response.Videos[1].ID})
+	mockService.AssertExpectations(t)
+}
+
+func TestReorderMedia_MismatchedSetIsBadRequest(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("ReorderMedia", 1, []int{12, 99}).Return(nil, profile.ErrInvalidMediaOrder)
+
+	rr := httptest.NewRecorder()
+	handler.ReorderMedia(rr, createMediaOrderRequest(1, "1", `{"media_ids":[12,99]}`))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body apierrors.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, apierrors.CodeInvalidMediaOrder, body.Code)
+}
+
+func TestReorderMedia_NonOwnerIsForbidden(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	rr := httptest.NewRecorder()
+	handler.ReorderMedia(rr, createMediaOrderRequest(2, "1", `{"media_ids":[12,10]}`))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	mockService.AssertNotCalled(t, "ReorderMedia", mock.Anything, mock.Anything)
+}
+
+func TestSetLookingForTeam_MissingFlagIsBadRequest(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	rr := httptest.NewRecorder()
+	handler.SetLookingForTeam(rr, createLookingForTeamRequest(1, "1", `{}`))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func createProfileRequest(method, target string, requesterID int, ownerID string) *http.Request {
+	req := httptest.NewRequest(method, target, nil)
+	rctx := chi.NewRouteContext()
+	rctx.URLParams.Add("userID", ownerID)
+	ctx := context.WithValue(req.Context(), chi.RouteCtxKey, rctx)
+	ctx = authctx.WithUserID(ctx, requesterID)
+	return req.WithContext(ctx)
+}
+
+func TestDeleteProfile_OwnerSoftDeletes(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+	mockService.On("DeleteProfile", 1).Return(nil)
+
+	rr := httptest.NewRecorder()
+	handler.DeleteProfile(rr, createProfileRequest("DELETE", "/api/profiles/1", 1, "1"))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	mockService.AssertExpectations(t)
+}
+
+func TestDeleteProfile_NonOwnerIsForbidden(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	rr := httptest.NewRecorder()
+	handler.DeleteProfile(rr, createProfileRequest("DELETE", "/api/profiles/1", 2, "1"))
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	mockService.AssertNotCalled(t, "DeleteProfile", mock.Anything)
+}
+
+func TestRestoreProfile_AfterGracePeriodIsGone(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+	mockService.On("RestoreProfile", 1).Return(nil, profile.ErrRestoreExpired)
+
+	rr := httptest.NewRecorder()
+	handler.RestoreProfile(rr, createProfileRequest("POST", "/api/profiles/1/restore", 1, "1"))
+
+	assert.Equal(t, http.StatusGone, rr.Code)
+	var body apierrors.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, apierrors.CodeProfileRestoreExpired, body.Code)
+}
+
+func TestGetProfile_ConditionalRequest(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	mockService.On("GetProfile", 1).Return(&profile.Profile{UserID: 1, FullName: "Test User", UpdatedAt: updatedAt}, nil)
+
+	rr := httptest.NewRecorder()
+	handler.GetProfile(rr, createProfileRequest("GET", "/api/profiles/1", 2, "1"))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	etag := rr.Header().Get("ETag")
+	assert.NotEmpty(t, etag)
+	assert.Equal(t, "Wed, 01 May 2024 12:00:00 GMT", rr.Header().Get("Last-Modified"))
+
+	// The cached version is still current
+	req := createProfileRequest("GET", "/api/profiles/1", 2, "1")
+	req.Header.Set("If-None-Match", `"other", `+etag)
+	rr = httptest.NewRecorder()
+	handler.GetProfile(rr, req)
+
+	assert.Equal(t, http.StatusNotModified, rr.Code)
+	assert.Empty(t, rr.Body.Bytes())
+
+	// A stale version gets the full profile
+	req = createProfileRequest("GET", "/api/profiles/1", 2, "1")
+	req.Header.Set("If-None-Match", `W/"1-stale"`)
+	rr = httptest.NewRecorder()
+	handler.GetProfile(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response ProfileResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, updatedAt, response.UpdatedAt)
+}
+
+func TestCatalogs_UnsupportedLanguageIsBadRequest(t *testing.T) {
+	mockService := new(MockProfileService)
+	handler := NewProfileHandler(mockService)
+
+	mockService.On("GetGenders", "de").Return(nil, profile.ErrUnsupportedLanguage)
+
+	rr := httptest.NewRecorder()
+	handler.GetGenders(rr, httptest.NewRequest("GET", "/api/profiles/catalog/genders?lang=de", nil))
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	var body apierrors.ErrorResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&body))
+	assert.Equal(t, apierrors.CodeUnsupportedLanguage, body.Code)
+}
+
+func TestGetCatalogLanguages_ListsSupportedLanguages(t *testing.T) {
+	handler := NewProfileHandler(new(MockProfileService))
+
+	rr := httptest.NewRecorder()
+	handler.GetCatalogLanguages(rr, httptest.NewRequest("GET", "/api/profiles/catalog/languages", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var response CatalogLanguagesResponse
+	assert.NoError(t, json.NewDecoder(rr.Body).Decode(&response))
+	assert.Equal(t, profile.SupportedLanguages, response.Languages)
+	assert.Equal(t, "ru", response.Default)
+}
@@ -5,6 +5,8 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
 	pushservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/push"
 )
 
@@ -40,37 +42,36 @@ func NewHandler(service pushservice.PushService) *Handler {
 // @Produce json
 // @Param token body RegisterTokenRequest true "Push Token Information"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 401 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} apierrors.ErrorResponse
+// @Failure 401 {object} apierrors.ErrorResponse
+// @Failure 500 {object} apierrors.ErrorResponse
 // @Security BearerAuth
 // @Router /api/push/register [post]
 func (h *Handler) RegisterToken(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from context (set by auth middleware)
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	var req RegisterTokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
 		return
 	}
 
 	if req.Token == "" {
-		http.Error(w, "Token is required", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Token is required", apierrors.CodeTokenRequired)
 		return
 	}
 
 	if req.Platform == "" {
-		http.Error(w, "Platform is required", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Platform is required", apierrors.CodePlatformRequired)
 		return
 	}
 
 	if err := h.service.SaveToken(r.Context(), userID, req.Token, req.Platform, req.DeviceID); err != nil {
-		http.Error(w, "Failed to save token: "+err.Error(), http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to save token: "+err.Error(), apierrors.CodeInternal)
 		return
 	}
 
@@ -87,35 +88,34 @@ func (h *Handler) RegisterToken(w http.ResponseWriter, r *http.Request) {
 // @Produce json
 // @Param token body UnregisterTokenRequest true "Push Token Information"
 // @Success 200 {object} map[string]string
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} apierrors.ErrorResponse
+// @Failure 500 {object} apierrors.ErrorResponse
 // @Router /api/push/unregister [delete]
 func (h *Handler) UnregisterToken(w http.ResponseWriter, r *http.Request) {
 	// Extract user ID from context (set by auth middleware)
-	userID, ok := r.Context().Value("user_id").(int)
+	userID, ok := authctx.RequireUserID(w, r)
 	if !ok {
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	var req UnregisterTokenRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request body", apierrors.CodeInvalidRequest)
 		return
 	}
 
 	if req.Token == "" {
-		http.Error(w, "Token is required", http.StatusBadRequest)
+		apierrors.RespondError(w, http.StatusBadRequest, "Token is required", apierrors.CodeTokenRequired)
 		return
 	}
 
 	if err := h.service.DeleteToken(r.Context(), userID, req.Token); err != nil {
 		if err == pushservice.ErrTokenNotFound {
-			http.Error(w, "Token does not exist", http.StatusBadRequest)
+			apierrors.RespondError(w, http.StatusBadRequest, "Token does not exist", apierrors.CodePushTokenNotFound)
 			return
 		}
 
-		http.Error(w, "Failed to delete token: "+err.Error(), http.StatusInternalServerError)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Failed to delete token: "+err.Error(), apierrors.CodeInternal)
 		return
 	}
 
@@ -0,0 +1,166 @@
+package report
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	reportservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/report"
+)
+
+const (
+	defaultReportsPageSize = 50
+	maxReportsPageSize     = 100
+)
+
+// TargetID is the ID of the reported content. Profiles and users have numeric
+// IDs and messages have UUIDs, so both JSON numbers and strings are accepted.
+type TargetID string
+
+// UnmarshalJSON accepts the ID as a JSON string or number
+func (id *TargetID) UnmarshalJSON(data []byte) error {
+	if bytes.HasPrefix(data, []byte(`"`)) {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*id = TargetID(s)
+		return nil
+	}
+
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*id = TargetID(n.String())
+	return nil
+}
+
+// CreateReportRequest is the body of a report
+type CreateReportRequest struct {
+	TargetType string   `json:"target_type" example:"profile" enums:"profile,message,user"`
+	TargetID   TargetID `json:"target_id" swaggertype:"string" example:"42"`
+	Reason     string   `json:"reason" example:"Fake profile"`
+}
+
+// Handler handles report endpoints
+type Handler struct {
+	service reportservice.Service
+}
+
+// NewHandler creates a new report handler
+func NewHandler(service reportservice.Service) *Handler {
+	return &Handler{service: service}
+}
+
+// @Summary      Report abusive content
+// @Description  Reports a profile, message or user to the moderators. Reporting the same target again replaces the reason
+// @Tags         reports
+// @Accept       json
+// @Produce      json
+// @Param        request  body  CreateReportRequest  true  "Report"
+// @Success      201  {object}  reportservice.Report
+// @Failure      400  {object}  apierrors.ErrorResponse  "Invalid target type or reason"
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      404  {object}  apierrors.ErrorResponse  "Reported target not found"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/reports [post]
+// @Security     BearerAuth
+func (h *Handler) CreateReport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := authctx.RequireUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req CreateReportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apierrors.RespondError(w, http.StatusBadRequest, "Invalid request", apierrors.CodeInvalidRequest)
+		return
+	}
+
+	report, err := h.service.CreateReport(userID, req.TargetType, string(req.TargetID), req.Reason)
+	if err != nil {
+		switch {
+		case errors.Is(err, reportservice.ErrInvalidTargetType):
+			apierrors.RespondError(w, http.StatusBadRequest, "Target type must be profile, message or user", apierrors.CodeInvalidReportTarget)
+		case errors.Is(err, reportservice.ErrInvalidReason):
+			apierrors.RespondError(w, http.StatusBadRequest, "Reason is required and must be at most 1000 characters", apierrors.CodeInvalidReportReason)
+		case errors.Is(err, reportservice.ErrTargetNotFound):
+			apierrors.RespondError(w, http.StatusNotFound, "Reported target not found", apierrors.CodeReportTargetNotFound)
+		default:
+			log.Printf("Error creating report: %v", err)
+			apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(report)
+}
+
+// AdminMiddleware restricts access to users flagged as admins. It must run
+// after AuthMiddleware.
+func (h *Handler) AdminMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, ok := authctx.RequireUserID(w, r)
+		if !ok {
+			return
+		}
+
+		isAdmin, err := h.service.IsAdmin(userID)
+		if err != nil {
+			log.Printf("Error checking admin flag: %v", err)
+			apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+			return
+		}
+		if !isAdmin {
+			apierrors.RespondError(w, http.StatusForbidden, "Forbidden", apierrors.CodeForbidden)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// @Summary      List reports
+// @Description  Returns reports, most recently filed or updated first. Available to admins only
+// @Tags         reports
+// @Produce      json
+// @Param        limit   query  int  false  "Page size (default 50, max 100)"
+// @Param        offset  query  int  false  "Number of reports to skip"
+// @Success      200  {array}   reportservice.Report
+// @Failure      401  {object}  apierrors.ErrorResponse  "Unauthorized"
+// @Failure      403  {object}  apierrors.ErrorResponse  "Caller is not an admin"
+// @Failure      500  {object}  apierrors.ErrorResponse  "Internal server error"
+// @Router       /api/reports [get]
+// @Security     BearerAuth
+func (h *Handler) GetReports(w http.ResponseWriter, r *http.Request) {
+	limit := defaultReportsPageSize
+	offset := 0
+
+	if val, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && val > 0 {
+		limit = val
+	}
+	if limit > maxReportsPageSize {
+		limit = maxReportsPageSize
+	}
+	if val, err := strconv.Atoi(r.URL.Query().Get("offset")); err == nil && val >= 0 {
+		offset = val
+	}
+
+	reports, err := h.service.GetReports(limit, offset)
+	if err != nil {
+		log.Printf("Error fetching reports: %v", err)
+		apierrors.RespondError(w, http.StatusInternalServerError, "Internal server error", apierrors.CodeInternal)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(reports)
+}
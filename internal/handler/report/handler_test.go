@@ -0,0 +1,77 @@
+package report
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	reportservice "github.com/bulatminnakhmetov/brigadka-backend/internal/service/report"
+)
+
+// MockService is a mock implementation of the report service
+type MockService struct {
+	mock.Mock
+}
+
+func (m *MockService) CreateReport(reporterID int, targetType, targetID, reason string) (*reportservice.Report, error) {
+	args := m.Called(reporterID, targetType, targetID, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*reportservice.Report), args.Error(1)
+}
+
+func (m *MockService) GetReports(limit, offset int) ([]reportservice.Report, error) {
+	args := m.Called(limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]reportservice.Report), args.Error(1)
+}
+
+func (m *MockService) IsAdmin(userID int) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func serveReports(h *Handler, userID int) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", "/api/reports", nil)
+	req = req.WithContext(authctx.WithUserID(req.Context(), userID))
+	rr := httptest.NewRecorder()
+	h.AdminMiddleware(http.HandlerFunc(h.GetReports)).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestAdminMiddleware_RejectsUserWithoutAdminFlag(t *testing.T) {
+	service := new(MockService)
+	h := NewHandler(service)
+	service.On("IsAdmin", 1).Return(false, nil)
+
+	rr := serveReports(h, 1)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	var resp apierrors.ErrorResponse
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &resp))
+	assert.Equal(t, apierrors.CodeForbidden, resp.Code)
+	service.AssertNotCalled(t, "GetReports", mock.Anything, mock.Anything)
+}
+
+func TestAdminMiddleware_AllowsFlaggedAdmin(t *testing.T) {
+	service := new(MockService)
+	h := NewHandler(service)
+	service.On("IsAdmin", 1).Return(true, nil)
+	service.On("GetReports", defaultReportsPageSize, 0).Return([]reportservice.Report{{ID: 3}}, nil)
+
+	rr := serveReports(h, 1)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var reports []reportservice.Report
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &reports))
+	assert.Equal(t, 3, reports[0].ID)
+}
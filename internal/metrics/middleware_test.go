@@ -0,0 +1,54 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	t.Helper()
+	rr := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rr, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := io.ReadAll(rr.Body)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestMiddleware_LabelsByRoutePatternAndStatus(t *testing.T) {
+	m := New()
+	r := chi.NewRouter()
+	r.Use(m.Middleware)
+	r.Get("/profiles/{userID}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	for _, path := range []string{"/profiles/1", "/profiles/2", "/health", "/no/such/path"} {
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	body := scrape(t, m)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/profiles/{userID}",status="404"} 2`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="/health",status="200"} 1`)
+	assert.Contains(t, body, `http_requests_total{method="GET",route="unmatched",status="404"} 1`)
+	assert.Contains(t, body, `http_request_duration_seconds_count{method="GET",route="/health",status="200"} 1`)
+	assert.Contains(t, body, "http_requests_in_flight 0")
+}
+
+func TestRegisterGauge_ReadsValueOnScrape(t *testing.T) {
+	m := New()
+	clients := 3
+	m.RegisterGauge("websocket_clients_active", "Connected WebSocket clients.", func() float64 { return float64(clients) })
+
+	assert.Contains(t, scrape(t, m), "websocket_clients_active 3")
+
+	clients = 1
+	assert.Contains(t, scrape(t, m), "websocket_clients_active 1")
+}
@@ -0,0 +1,107 @@
+package presence
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+)
+
+// DefaultTouchInterval is how often a user's activity is written when not configured
+const DefaultTouchInterval = time.Minute
+
+// maxTrackedUsers bounds the number of users whose last write is remembered
+const maxTrackedUsers = 10000
+
+// Store persists when users were last seen
+type Store interface {
+	UpdateLastSeen(userID int, at time.Time) error
+}
+
+// Tracker records when users were last active. Activity from requests is written
+// at most once per interval for each user, so busy clients don't cause a write
+// per request.
+type Tracker struct {
+	store    Store
+	interval time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	lastWrite map[int]time.Time
+}
+
+// NewTracker creates a tracker writing to store. A non-positive interval uses DefaultTouchInterval.
+func NewTracker(store Store, interval time.Duration) *Tracker {
+	if interval <= 0 {
+		interval = DefaultTouchInterval
+	}
+	return &Tracker{
+		store:     store,
+		interval:  interval,
+		now:       time.Now,
+		lastWrite: make(map[int]time.Time),
+	}
+}
+
+// Touch records that the user is active, skipping the write if the user was
+// recorded less than an interval ago
+func (t *Tracker) Touch(userID int) {
+	now := t.now()
+
+	t.mu.Lock()
+	if last, ok := t.lastWrite[userID]; ok && now.Sub(last) < t.interval {
+		t.mu.Unlock()
+		return
+	}
+	t.remember(userID, now)
+	t.mu.Unlock()
+
+	t.write(userID, now)
+}
+
+// Record writes that the user is active now regardless of the interval, for
+// events that matter on their own such as a WebSocket connecting or closing
+func (t *Tracker) Record(userID int) {
+	now := t.now()
+
+	t.mu.Lock()
+	t.remember(userID, now)
+	t.mu.Unlock()
+
+	t.write(userID, now)
+}
+
+// Middleware touches the authenticated user of every request. It must run after
+// the auth middleware, unauthenticated requests are passed through.
+func (t *Tracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if userID, ok := authctx.UserIDFromContext(r.Context()); ok {
+			t.Touch(userID)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// remember stores the time of the user's last write. Must be called with mu held.
+func (t *Tracker) remember(userID int, at time.Time) {
+	if len(t.lastWrite) >= maxTrackedUsers {
+		for id, last := range t.lastWrite {
+			if at.Sub(last) >= t.interval {
+				delete(t.lastWrite, id)
+			}
+		}
+	}
+	// Every entry is still fresh, start over rather than grow without bound
+	if len(t.lastWrite) >= maxTrackedUsers {
+		t.lastWrite = make(map[int]time.Time)
+	}
+	t.lastWrite[userID] = at
+}
+
+func (t *Tracker) write(userID int, at time.Time) {
+	if err := t.store.UpdateLastSeen(userID, at); err != nil {
+		log.Printf("Error updating last seen time of user %d: %v", userID, err)
+	}
+}
@@ -0,0 +1,79 @@
+package presence
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/authctx"
+)
+
+type fakeStore struct {
+	mu     sync.Mutex
+	writes map[int][]time.Time
+}
+
+func (s *fakeStore) UpdateLastSeen(userID int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.writes == nil {
+		s.writes = make(map[int][]time.Time)
+	}
+	s.writes[userID] = append(s.writes[userID], at)
+	return nil
+}
+
+func newTestTracker(store Store, now *time.Time) *Tracker {
+	tracker := NewTracker(store, time.Minute)
+	tracker.now = func() time.Time { return *now }
+	return tracker
+}
+
+func TestTouch_WritesAtMostOncePerInterval(t *testing.T) {
+	store := &fakeStore{}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(store, &now)
+
+	tracker.Touch(1)
+	now = now.Add(30 * time.Second)
+	tracker.Touch(1)
+	tracker.Touch(2)
+	now = now.Add(31 * time.Second)
+	tracker.Touch(1)
+
+	assert.Equal(t, []time.Time{
+		time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC),
+		time.Date(2024, 1, 1, 12, 1, 1, 0, time.UTC),
+	}, store.writes[1])
+	assert.Len(t, store.writes[2], 1)
+}
+
+func TestRecord_IgnoresInterval(t *testing.T) {
+	store := &fakeStore{}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(store, &now)
+
+	tracker.Touch(1)
+	now = now.Add(time.Second)
+	tracker.Record(1)
+
+	assert.Len(t, store.writes[1], 2)
+}
+
+func TestMiddleware_TouchesAuthenticatedUser(t *testing.T) {
+	store := &fakeStore{}
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	tracker := newTestTracker(store, &now)
+	handler := tracker.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest("GET", "/profiles/2", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(authctx.WithUserID(req.Context(), 7)))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	assert.Len(t, store.writes, 1)
+	assert.Len(t, store.writes[7], 1)
+}
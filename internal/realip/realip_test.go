@@ -0,0 +1,63 @@
+package realip
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func remoteAddrSeen(t *testing.T, proxies *TrustedProxies, req *http.Request) string {
+	t.Helper()
+	var seen string
+	handler := proxies.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = r.RemoteAddr
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	return seen
+}
+
+func TestMiddleware_IgnoresHeadersFromUntrustedSource(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/auth/login", nil)
+	req.RemoteAddr = "203.0.113.7:51234"
+	req.Header.Set("X-Forwarded-For", "198.51.100.1")
+	req.Header.Set("X-Real-IP", "198.51.100.2")
+
+	assert.Equal(t, "203.0.113.7", remoteAddrSeen(t, proxies, req))
+}
+
+func TestMiddleware_TrustedProxyForwardsClientAddress(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8", " 192.0.2.5 ", ""})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("POST", "/auth/login", nil)
+	req.RemoteAddr = "10.1.2.3:443"
+	// The client prepended a spoofed hop, the proxies appended the real ones
+	req.Header.Set("X-Forwarded-For", "198.51.100.1, 203.0.113.7, 192.0.2.5")
+
+	assert.Equal(t, "203.0.113.7", remoteAddrSeen(t, proxies, req))
+}
+
+func TestMiddleware_TrustedProxyFallsBackToRealIP(t *testing.T) {
+	proxies, err := NewTrustedProxies([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("GET", "/api/profiles/1", nil)
+	req.RemoteAddr = "10.1.2.3:443"
+	req.Header.Set("X-Real-IP", "203.0.113.7")
+
+	assert.Equal(t, "203.0.113.7", remoteAddrSeen(t, proxies, req))
+}
+
+func TestNewTrustedProxies_RejectsInvalidEntries(t *testing.T) {
+	_, err := NewTrustedProxies([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+
+	_, err = NewTrustedProxies([]string{"not-an-ip"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,70 @@
+package block
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+var ErrUserNotFound = errors.New("user not found")
+
+// Repository stores the users a user has blocked
+type Repository interface {
+	Block(blockerID, blockedID int) error
+	Unblock(blockerID, blockedID int) error
+	GetBlockedUserIDs(blockerID int) ([]int, error)
+}
+
+// PostgresRepository implements Repository on top of the user_blocks table
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new block repository
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// Block records that blocker has blocked the user. Blocking twice is not an error.
+func (r *PostgresRepository) Block(blockerID, blockedID int) error {
+	_, err := r.db.Exec(`
+        INSERT INTO user_blocks (blocker_id, blocked_id)
+        VALUES ($1, $2)
+        ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+    `, blockerID, blockedID)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+		return ErrUserNotFound
+	}
+	return err
+}
+
+// Unblock removes the block. Removing a block that doesn't exist is not an error.
+func (r *PostgresRepository) Unblock(blockerID, blockedID int) error {
+	_, err := r.db.Exec("DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2", blockerID, blockedID)
+	return err
+}
+
+// GetBlockedUserIDs returns the users blocked by blocker, most recently blocked first
+func (r *PostgresRepository) GetBlockedUserIDs(blockerID int) ([]int, error) {
+	rows, err := r.db.Query(`
+        SELECT blocked_id FROM user_blocks
+        WHERE blocker_id = $1
+        ORDER BY created_at DESC, blocked_id
+    `, blockerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	userIDs := []int{}
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, userID)
+	}
+	return userIDs, rows.Err()
+}
@@ -0,0 +1,56 @@
+package block
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *PostgresRepository) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	return db, mock, NewPostgresRepository(db)
+}
+
+func TestBlock_IsIdempotent(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("ON CONFLICT (blocker_id, blocked_id) DO NOTHING")).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.Block(1, 2))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBlock_UnknownUser(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta("INSERT INTO user_blocks")).
+		WithArgs(1, 99).
+		WillReturnError(&pq.Error{Code: "23503"})
+
+	assert.ErrorIs(t, repo.Block(1, 99), ErrUserNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetBlockedUserIDs(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT blocked_id FROM user_blocks")).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"blocked_id"}).AddRow(5).AddRow(3))
+
+	userIDs, err := repo.GetBlockedUserIDs(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5, 3}, userIDs)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
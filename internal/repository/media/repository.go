@@ -6,10 +6,19 @@ import (
 	"fmt"
 	"log"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 var (
-	ErrMediaNotFound = errors.New("media not found")
+	ErrMediaNotFound       = errors.New("media not found")
+	ErrProfileMediaMinimum = errors.New("profile would be left with too few media items")
+)
+
+// Media statuses
+const (
+	StatusPending = "pending" // Uploaded directly to storage, completion not confirmed yet
+	StatusReady   = "ready"
 )
 
 // Media представляет запись о медиафайле
@@ -20,6 +29,8 @@ type Media struct {
 	URL          string    `json:"url"`
 	ThumbnailURL string    `json:"thumbnail_url"`
 	UploadedAt   time.Time `json:"uploaded_at"`
+	Status       string    `json:"status"`
+	ObjectKey    string    `json:"-"` // Empty for media uploaded through the server
 }
 
 // RepositoryImpl implements the Repository interface
@@ -49,6 +60,38 @@ func (r *RepositoryImpl) CreateMedia(userID int, mediaType, mediaURL, thumbnailU
 	return mediaID, nil
 }
 
+// CreatePendingMedia saves a media record for an object the client uploads directly to storage
+func (r *RepositoryImpl) CreatePendingMedia(userID int, mediaType, mediaURL, objectKey string) (int, error) {
+	var mediaID int
+	err := r.db.QueryRow(
+		"INSERT INTO media (owner_id, type, url, thumbnail_url, status, object_key) VALUES ($1, $2, $3, '', $4, $5) RETURNING id",
+		userID, mediaType, mediaURL, StatusPending, objectKey,
+	).Scan(&mediaID)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to save media info: %w", err)
+	}
+
+	return mediaID, nil
+}
+
+// MarkMediaReady marks a pending media record as uploaded
+func (r *RepositoryImpl) MarkMediaReady(mediaID int) error {
+	result, err := r.db.Exec("UPDATE media SET status = $1 WHERE id = $2", StatusReady, mediaID)
+	if err != nil {
+		return fmt.Errorf("failed to update media status: %w", err)
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to update media status: %w", err)
+	}
+	if rows == 0 {
+		return ErrMediaNotFound
+	}
+	return nil
+}
+
 // DeleteMediaByID deletes media by its ID
 func (r *RepositoryImpl) DeleteMedia(userID, mediaID int) error {
 	_, err := r.db.Exec("DELETE FROM media WHERE id = $1 AND owner_id = $2", mediaID, userID)
@@ -58,13 +101,127 @@ func (r *RepositoryImpl) DeleteMedia(userID, mediaID int) error {
 	return nil
 }
 
+// DeleteMediaKeepingMinimum deletes media like DeleteMedia, but refuses to remove media
+// shown on the user's profile if the profile would be left with fewer than minimum
+// media items. The profile media rows are locked, so concurrent deletions can't both
+// pass the check.
+func (r *RepositoryImpl) DeleteMediaKeepingMinimum(userID, mediaID, minimum int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	rows, err := tx.Query(`SELECT media_id FROM profile_media WHERE user_id = $1 FOR UPDATE`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to lock profile media: %w", err)
+	}
+	profileMedia := make(map[int]struct{})
+	for rows.Next() {
+		var id int
+		if err = rows.Scan(&id); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read profile media: %w", err)
+		}
+		profileMedia[id] = struct{}{}
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("failed to read profile media: %w", err)
+	}
+
+	if _, shown := profileMedia[mediaID]; shown && len(profileMedia)-1 < minimum {
+		return ErrProfileMediaMinimum
+	}
+
+	if _, err = tx.Exec("DELETE FROM media WHERE id = $1 AND owner_id = $2", mediaID, userID); err != nil {
+		return fmt.Errorf("failed to delete media from DB: %w", err)
+	}
+	return tx.Commit()
+}
+
+// DeleteStalePendingMedia deletes pending media created more than olderThan ago, whose
+// uploads were never completed, and returns the storage keys of their objects
+func (r *RepositoryImpl) DeleteStalePendingMedia(olderThan time.Duration) ([]string, error) {
+	rows, err := r.db.Query(`
+        DELETE FROM media
+        WHERE status = $1 AND uploaded_at < CURRENT_TIMESTAMP - make_interval(secs => $2)
+        RETURNING object_key
+    `, StatusPending, olderThan.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete stale pending media: %w", err)
+	}
+	defer rows.Close()
+
+	var objectKeys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to read stale pending media: %w", err)
+		}
+		if key != "" {
+			objectKeys = append(objectKeys, key)
+		}
+	}
+	return objectKeys, rows.Err()
+}
+
+// EnqueueObjectCleanup records storage objects that are left behind by deleted media
+// so that their removal can be retried
+func (r *RepositoryImpl) EnqueueObjectCleanup(objectKeys []string) error {
+	if len(objectKeys) == 0 {
+		return nil
+	}
+	_, err := r.db.Exec(
+		"INSERT INTO media_cleanup_queue (object_key) SELECT unnest($1::text[])",
+		pq.Array(objectKeys),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to queue objects for cleanup: %w", err)
+	}
+	return nil
+}
+
+// TakeObjectCleanup removes up to limit of the longest queued storage objects from the
+// cleanup queue and returns their keys. Concurrent callers get different objects.
+func (r *RepositoryImpl) TakeObjectCleanup(limit int) ([]string, error) {
+	rows, err := r.db.Query(`
+        DELETE FROM media_cleanup_queue
+        WHERE id IN (
+            SELECT id FROM media_cleanup_queue
+            ORDER BY queued_at, id
+            LIMIT $1
+            FOR UPDATE SKIP LOCKED
+        )
+        RETURNING object_key
+    `, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to take objects from cleanup queue: %w", err)
+	}
+	defer rows.Close()
+
+	var objectKeys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, fmt.Errorf("failed to read cleanup queue: %w", err)
+		}
+		objectKeys = append(objectKeys, key)
+	}
+	return objectKeys, rows.Err()
+}
+
 // GetMediaByID retrieves media by its ID
 func (r *RepositoryImpl) GetMediaByID(mediaID int) (*Media, error) {
 	var m Media
 	err := r.db.QueryRow(
-		"SELECT id, owner_id, type, url, thumbnail_url, uploaded_at FROM media WHERE id = $1",
+		"SELECT id, owner_id, type, url, thumbnail_url, uploaded_at, status, object_key FROM media WHERE id = $1",
 		mediaID,
-	).Scan(&m.ID, &m.UserID, &m.Role, &m.URL, &m.ThumbnailURL, &m.UploadedAt)
+	).Scan(&m.ID, &m.UserID, &m.Role, &m.URL, &m.ThumbnailURL, &m.UploadedAt, &m.Status, &m.ObjectKey)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -76,7 +233,7 @@ func (r *RepositoryImpl) GetMediaByID(mediaID int) (*Media, error) {
 	return &m, nil
 }
 
-// GetMediaByID retrieves media by its ID
+// GetMediaByIDs retrieves ready media by their IDs, pending and missing media is skipped
 func (r *RepositoryImpl) GetMediaByIDs(mediaIDs []int) ([]Media, error) {
 	if len(mediaIDs) == 0 {
 		return nil, nil
@@ -88,7 +245,54 @@ func (r *RepositoryImpl) GetMediaByIDs(mediaIDs []int) ([]Media, error) {
 			log.Printf("failed to get media by ID %d: %v", id, err)
 			continue
 		}
+		if media.Status != StatusReady {
+			continue
+		}
 		result = append(result, *media)
 	}
 	return result, nil
 }
+
+// GetMediaByOwner retrieves a page of media uploaded by a user together with the total
+// number of matching media. Media attached to the profile comes first in gallery order,
+// followed by unattached uploads; ties are broken by upload time and ID, newest first,
+// so pages don't overlap. Media is considered public once it is attached to the owner's
+// profile; unattached uploads are only returned when includePrivate is set.
+func (r *RepositoryImpl) GetMediaByOwner(ownerID int, includePrivate bool, limit, offset int) ([]Media, int, error) {
+	var total int
+	err := r.db.QueryRow(`
+        SELECT COUNT(*)
+        FROM media m
+        WHERE m.owner_id = $1
+          AND m.status = 'ready'
+          AND ($2 OR EXISTS (SELECT 1 FROM profile_media pm WHERE pm.media_id = m.id))
+    `, ownerID, includePrivate).Scan(&total)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to count media in DB: %w", err)
+	}
+
+	rows, err := r.db.Query(`
+        SELECT m.id, m.owner_id, m.type, m.url, m.thumbnail_url, m.uploaded_at, m.status, m.object_key
+        FROM media m
+        WHERE m.owner_id = $1
+          AND m.status = 'ready'
+          AND ($2 OR EXISTS (SELECT 1 FROM profile_media pm WHERE pm.media_id = m.id))
+        ORDER BY (SELECT MIN(pm.position) FROM profile_media pm WHERE pm.media_id = m.id) ASC NULLS LAST,
+                 m.uploaded_at DESC, m.id DESC
+        LIMIT $3 OFFSET $4
+    `, ownerID, includePrivate, limit, offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to get media from DB: %w", err)
+	}
+	defer rows.Close()
+
+	result := []Media{}
+	for rows.Next() {
+		var m Media
+		if err := rows.Scan(&m.ID, &m.UserID, &m.Role, &m.URL, &m.ThumbnailURL, &m.UploadedAt, &m.Status, &m.ObjectKey); err != nil {
+			return nil, 0, fmt.Errorf("failed to scan media row: %w", err)
+		}
+		result = append(result, m)
+	}
+	return result, total, rows.Err()
+}
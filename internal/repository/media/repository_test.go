@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -91,6 +92,93 @@ func TestDeleteMediaError(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestDeleteMediaKeepingMinimum(t *testing.T) {
+	tests := []struct {
+		name         string
+		profileMedia []int
+		wantErr      error
+	}{
+		{name: "Not the last profile media", profileMedia: []int{42, 43}},
+		{name: "Not shown on the profile", profileMedia: []int{43}},
+		{name: "Last profile media", profileMedia: []int{42}, wantErr: ErrProfileMediaMinimum},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, repo := setupMock(t)
+			defer db.Close()
+
+			rows := sqlmock.NewRows([]string{"media_id"})
+			for _, id := range tc.profileMedia {
+				rows.AddRow(id)
+			}
+			mock.ExpectBegin()
+			mock.ExpectQuery(`SELECT media_id FROM profile_media WHERE user_id = \$1 FOR UPDATE`).
+				WithArgs(1).
+				WillReturnRows(rows)
+			if tc.wantErr == nil {
+				mock.ExpectExec("DELETE FROM media").
+					WithArgs(42, 1).
+					WillReturnResult(sqlmock.NewResult(0, 1))
+				mock.ExpectCommit()
+			} else {
+				mock.ExpectRollback()
+			}
+
+			err := repo.DeleteMediaKeepingMinimum(1, 42, 1)
+
+			assert.ErrorIs(t, err, tc.wantErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteStalePendingMedia(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`DELETE FROM media\s+WHERE status = \$1 AND uploaded_at < CURRENT_TIMESTAMP - make_interval\(secs => \$2\)\s+RETURNING object_key`).
+		WithArgs(StatusPending, float64(86400)).
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}).AddRow("media/a.mp4").AddRow("media/b.jpg"))
+
+	keys, err := repo.DeleteStalePendingMedia(24 * time.Hour)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"media/a.mp4", "media/b.jpg"}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEnqueueObjectCleanup(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO media_cleanup_queue \(object_key\) SELECT unnest\(\$1::text\[\]\)`).
+		WithArgs(pq.Array([]string{"media/a.jpg", "media/b.jpg"})).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	err := repo.EnqueueObjectCleanup([]string{"media/a.jpg", "media/b.jpg"})
+	assert.NoError(t, err)
+
+	// Nothing to queue, no query
+	assert.NoError(t, repo.EnqueueObjectCleanup(nil))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTakeObjectCleanup(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`DELETE FROM media_cleanup_queue\s+WHERE id IN \(\s+SELECT id FROM media_cleanup_queue\s+ORDER BY queued_at, id\s+LIMIT \$1\s+FOR UPDATE SKIP LOCKED\s+\)\s+RETURNING object_key`).
+		WithArgs(100).
+		WillReturnRows(sqlmock.NewRows([]string{"object_key"}).AddRow("media/a.jpg").AddRow("media/b.jpg"))
+
+	keys, err := repo.TakeObjectCleanup(100)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"media/a.jpg", "media/b.jpg"}, keys)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetMediaByID(t *testing.T) {
 	db, mock, repo := setupMock(t)
 	defer db.Close()
@@ -104,12 +192,13 @@ func TestGetMediaByID(t *testing.T) {
 		URL:          "https://example.com/image.jpg",
 		ThumbnailURL: "https://example.com/thumbnail.jpg",
 		UploadedAt:   now,
+		Status:       StatusReady,
 	}
 
-	rows := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at"}).
-		AddRow(expectedMedia.ID, expectedMedia.UserID, expectedMedia.Role, expectedMedia.URL, expectedMedia.ThumbnailURL, expectedMedia.UploadedAt)
+	rows := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at", "status", "object_key"}).
+		AddRow(expectedMedia.ID, expectedMedia.UserID, expectedMedia.Role, expectedMedia.URL, expectedMedia.ThumbnailURL, expectedMedia.UploadedAt, expectedMedia.Status, expectedMedia.ObjectKey)
 
-	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at FROM media").
+	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at, status, object_key FROM media").
 		WithArgs(mediaID).
 		WillReturnRows(rows)
 
@@ -125,7 +214,7 @@ func TestGetMediaByIDNotFound(t *testing.T) {
 
 	mediaID := 42
 
-	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at FROM media").
+	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at, status, object_key FROM media").
 		WithArgs(mediaID).
 		WillReturnError(sql.ErrNoRows)
 
@@ -142,7 +231,7 @@ func TestGetMediaByIDError(t *testing.T) {
 
 	mediaID := 42
 
-	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at FROM media").
+	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at, status, object_key FROM media").
 		WithArgs(mediaID).
 		WillReturnError(errors.New("database error"))
 
@@ -166,6 +255,7 @@ func TestGetMediaByIDs(t *testing.T) {
 		URL:          "https://example.com/image1.jpg",
 		ThumbnailURL: "https://example.com/thumbnail1.jpg",
 		UploadedAt:   now,
+		Status:       StatusReady,
 	}
 
 	expectedMedia2 := Media{
@@ -175,21 +265,22 @@ func TestGetMediaByIDs(t *testing.T) {
 		URL:          "https://example.com/image2.jpg",
 		ThumbnailURL: "https://example.com/thumbnail2.jpg",
 		UploadedAt:   now,
+		Status:       StatusReady,
 	}
 
 	// For first media
-	rows1 := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at"}).
-		AddRow(expectedMedia1.ID, expectedMedia1.UserID, expectedMedia1.Role, expectedMedia1.URL, expectedMedia1.ThumbnailURL, expectedMedia1.UploadedAt)
+	rows1 := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at", "status", "object_key"}).
+		AddRow(expectedMedia1.ID, expectedMedia1.UserID, expectedMedia1.Role, expectedMedia1.URL, expectedMedia1.ThumbnailURL, expectedMedia1.UploadedAt, expectedMedia1.Status, expectedMedia1.ObjectKey)
 
-	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at FROM media").
+	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at, status, object_key FROM media").
 		WithArgs(1).
 		WillReturnRows(rows1)
 
 	// For second media
-	rows2 := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at"}).
-		AddRow(expectedMedia2.ID, expectedMedia2.UserID, expectedMedia2.Role, expectedMedia2.URL, expectedMedia2.ThumbnailURL, expectedMedia2.UploadedAt)
+	rows2 := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at", "status", "object_key"}).
+		AddRow(expectedMedia2.ID, expectedMedia2.UserID, expectedMedia2.Role, expectedMedia2.URL, expectedMedia2.ThumbnailURL, expectedMedia2.UploadedAt, expectedMedia2.Status, expectedMedia2.ObjectKey)
 
-	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at FROM media").
+	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at, status, object_key FROM media").
 		WithArgs(2).
 		WillReturnRows(rows2)
 
@@ -228,17 +319,18 @@ func TestGetMediaByIDsWithError(t *testing.T) {
 		URL:          "https://example.com/image1.jpg",
 		ThumbnailURL: "https://example.com/thumbnail1.jpg",
 		UploadedAt:   now,
+		Status:       StatusReady,
 	}
 
-	rows1 := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at"}).
-		AddRow(expectedMedia1.ID, expectedMedia1.UserID, expectedMedia1.Role, expectedMedia1.URL, expectedMedia1.ThumbnailURL, expectedMedia1.UploadedAt)
+	rows1 := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at", "status", "object_key"}).
+		AddRow(expectedMedia1.ID, expectedMedia1.UserID, expectedMedia1.Role, expectedMedia1.URL, expectedMedia1.ThumbnailURL, expectedMedia1.UploadedAt, expectedMedia1.Status, expectedMedia1.ObjectKey)
 
-	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at FROM media").
+	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at, status, object_key FROM media").
 		WithArgs(1).
 		WillReturnRows(rows1)
 
 	// Second media returns error
-	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at FROM media").
+	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at, status, object_key FROM media").
 		WithArgs(2).
 		WillReturnError(sql.ErrNoRows)
 
@@ -248,3 +340,69 @@ func TestGetMediaByIDsWithError(t *testing.T) {
 	assert.Equal(t, expectedMedia1, media[0])
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestGetMediaByOwner(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	now := time.Now()
+	expectedMedia := Media{
+		ID:           3,
+		UserID:       7,
+		Role:         "image",
+		URL:          "https://example.com/image3.jpg",
+		ThumbnailURL: "https://example.com/thumbnail3.jpg",
+		UploadedAt:   now,
+		Status:       StatusReady,
+	}
+
+	rows := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at", "status", "object_key"}).
+		AddRow(expectedMedia.ID, expectedMedia.UserID, expectedMedia.Role, expectedMedia.URL, expectedMedia.ThumbnailURL, expectedMedia.UploadedAt, expectedMedia.Status, expectedMedia.ObjectKey)
+
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM media m`).
+		WithArgs(7, false).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(21))
+	mock.ExpectQuery(`SELECT m.id, m.owner_id, m.type, m.url, m.thumbnail_url, m.uploaded_at, m.status, m.object_key FROM media m .* ORDER BY \(SELECT MIN\(pm.position\) FROM profile_media pm WHERE pm.media_id = m.id\) ASC NULLS LAST, m.uploaded_at DESC, m.id DESC`).
+		WithArgs(7, false, 20, 0).
+		WillReturnRows(rows)
+
+	media, total, err := repo.GetMediaByOwner(7, false, 20, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []Media{expectedMedia}, media)
+	assert.Equal(t, 21, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMediaByIDsSkipsPending(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{"id", "owner_id", "type", "url", "thumbnail_url", "uploaded_at", "status", "object_key"}).
+		AddRow(1, 1, "video", "https://example.com/video.mp4", "", now, StatusPending, "media/video.mp4")
+
+	mock.ExpectQuery("SELECT id, owner_id, type, url, thumbnail_url, uploaded_at, status, object_key FROM media").
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	media, err := repo.GetMediaByIDs([]int{1})
+	assert.NoError(t, err)
+	assert.Empty(t, media)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMarkMediaReady(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectExec("UPDATE media SET status").
+		WithArgs(StatusReady, 9).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE media SET status").
+		WithArgs(StatusReady, 10).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.MarkMediaReady(9))
+	assert.ErrorIs(t, repo.MarkMediaReady(10), ErrMediaNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
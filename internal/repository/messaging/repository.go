@@ -4,21 +4,50 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"strings"
 	"time"
 
 	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 // Chat message structure
 type ChatMessage struct {
-	MessageID string    `json:"message_id"`
-	ChatID    string    `json:"chat_id"`
-	SenderID  int       `json:"sender_id"`
-	Content   string    `json:"content"`
-	SentAt    time.Time `json:"sent_at"`
+	MessageID string     `json:"message_id"`
+	ChatID    string     `json:"chat_id"`
+	SenderID  int        `json:"sender_id"`
+	Content   string     `json:"content"`
+	SentAt    time.Time  `json:"sent_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`  // Nil unless the sender edited the message
+	Deleted   bool       `json:"deleted,omitempty"`    // Deleted messages are kept with empty content
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Nil unless the message disappears
+
+	Reactions []ReactionSummary `json:"reactions,omitempty"` // Set only when requested
+	ReadBy    []int             `json:"read_by,omitempty"`   // Participants other than the sender who read the message, set only when requested
+}
+
+// ReactionSummary is the number of reactions with one code on a message
+type ReactionSummary struct {
+	ReactionCode string `json:"reaction_code"`
+	Count        int    `json:"count"`
+	Reacted      bool   `json:"reacted"` // The requesting user left this reaction
+}
+
+// ExpiredMessage identifies a disappearing message removed after its expiry
+type ExpiredMessage struct {
+	MessageID string
+	ChatID    string
+	SenderID  int
+	ExpiresAt time.Time
 }
 
+// Roles of chat participants. Admins manage group chat membership and the chat name.
+const (
+	RoleAdmin  = "admin"
+	RoleMember = "member"
+)
+
 // Chat structure
 type Chat struct {
 	ChatID       string    `json:"chat_id"`
@@ -26,26 +55,109 @@ type Chat struct {
 	CreatedAt    time.Time `json:"created_at"`
 	IsGroup      bool      `json:"is_group"`
 	Participants []int     `json:"participants"`
+	Admins       []int     `json:"admins,omitempty"` // Filled in for a single chat
+}
+
+// SentMessage is a message sent by a user together with its chat context
+type SentMessage struct {
+	MessageID string     `json:"message_id"`
+	Content   string     `json:"content"`
+	SentAt    time.Time  `json:"sent_at"`
+	EditedAt  *time.Time `json:"edited_at,omitempty"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	ChatID    string     `json:"chat_id"`
+	ChatName  *string    `json:"chat_name"`
+	IsGroup   bool       `json:"is_group"`
+}
+
+// UserReaction is a reaction made by a user together with its message and chat context
+type UserReaction struct {
+	ReactionID     string    `json:"reaction_id"`
+	ReactionCode   string    `json:"reaction_code"`
+	ReactedAt      time.Time `json:"reacted_at"`
+	MessageID      string    `json:"message_id"`
+	MessageSender  int       `json:"message_sender_id"`
+	MessageContent string    `json:"message_content"`
+	MessageSentAt  time.Time `json:"message_sent_at"`
+	ChatID         string    `json:"chat_id"`
+	ChatName       *string   `json:"chat_name"`
+	IsGroup        bool      `json:"is_group"`
+}
+
+// MessageReaction is a reaction left on a message
+type MessageReaction struct {
+	UserID       int       `json:"user_id"`
+	ReactionCode string    `json:"reaction_code"`
+	ReactedAt    time.Time `json:"reacted_at"`
+}
+
+// ReactionCatalogItem is a reaction clients may put on a message
+type ReactionCatalogItem struct {
+	ReactionCode string `json:"reaction_code"`
+	Emoji        string `json:"emoji"`
+}
+
+// ReadPosition is the last message a chat participant has read
+type ReadPosition struct {
+	UserID            int        `json:"user_id"`
+	LastReadMessageID *string    `json:"last_read_message_id"` // Nil until the participant reads a message
+	ReadAt            *time.Time `json:"read_at"`
+}
+
+// ParticipantSummary is a short description of a chat participant
+type ParticipantSummary struct {
+	UserID   int    `json:"user_id"`
+	FullName string `json:"full_name"`
+}
+
+// ChatOverview is a chat list entry with its latest message and unread count
+type ChatOverview struct {
+	ChatID       string               `json:"chat_id"`
+	ChatName     *string              `json:"chat_name"`
+	CreatedAt    time.Time            `json:"created_at"`
+	IsGroup      bool                 `json:"is_group"`
+	Participants []ParticipantSummary `json:"participants"`
+	LastMessage  *ChatMessage         `json:"last_message"`
+	UnreadCount  int                  `json:"unread_count"`
 }
 
 type MessagingRepository interface {
 	GetUserChats(userID int) ([]Chat, error)
 	GetChat(chatID string, userID int) (*Chat, error)
-	CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int) error
-	AddMessage(messageID string, chatID string, senderID int, content string) (time.Time, error)
+	CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int, isGroup bool) error
+	AddMessage(messageID string, chatID string, senderID int, content string, expiresIn time.Duration) (time.Time, error)
 	GetChatParticipants(chatID string) ([]int, error)
 	IsUserInChat(userID int, chatID string) (bool, error)
 	AddParticipant(chatID string, userID int) error
 	RemoveParticipant(chatID string, userID int) error
-	AddReaction(reactionID string, messageID string, userID int, reactionCode string) error
-	RemoveReaction(messageID string, userID int, reactionCode string) error
+	GetParticipantRole(chatID string, userID int) (string, error)
+	SetParticipantRole(chatID string, userID int, role string) error
+	RenameChat(chatID string, chatName string) error
+	AddReaction(reactionID string, chatID string, messageID string, userID int, reactionCode string) error
+	ReactionCodeExists(reactionCode string) (bool, error)
+	GetMessageReactions(chatID string, messageID string, limit, offset int) ([]MessageReaction, error)
+	GetReactionCatalog() ([]ReactionCatalogItem, error)
+	RemoveReaction(chatID string, messageID string, userID int, reactionCode string) error
 	GetChatIDForMessage(messageID string) (string, error)
 	GetChatMessages(chatID string, userID int, limit, offset int) ([]ChatMessage, error)
+	GetMessage(chatID string, messageID string) (*ChatMessage, error)
+	GetChatMessagesBefore(chatID string, beforeMessageID string, limit int) ([]ChatMessage, error)
+	SearchMessages(chatID string, query string, limit, offset int) ([]ChatMessage, error)
+	EditMessage(chatID string, messageID string, senderID int, content string) (*ChatMessage, error)
+	DeleteMessage(chatID string, messageID string, senderID int) (time.Time, error)
+	DeleteExpiredMessages() ([]ExpiredMessage, error)
 	StoreTypingIndicator(userID int, chatID string) error
 	StoreReadReceipt(userID int, chatID string, messageID string) error
 	GetUserChatRooms(userID int) (map[string]struct{}, error)
 	GetChatParticipantsForBroadcast(chatID string) ([]int, error)
 	GetOrCreateDirectChat(ctx context.Context, userID1 int, userID2 int) (string, error)
+	GetUserReactions(userID int, limit, offset int) ([]UserReaction, error)
+	GetSentMessages(senderID int, limit, offset int) ([]SentMessage, error)
+	GetChatOverviews(userID int, limit, offset int) ([]ChatOverview, error)
+	GetReadPositions(chatID string) ([]ReadPosition, error)
+	GetReactionSummaries(chatID string, messageIDs []string, userID int) (map[string][]ReactionSummary, error)
+	GetMessageReaders(chatID string, messageIDs []string) (map[string][]int, error)
+	HasBlockBetween(userIDs []int) (bool, error)
 }
 
 // MessagingRepositoryImpl encapsulates database operations for messaging
@@ -143,7 +255,7 @@ func (r *MessagingRepositoryImpl) GetChat(chatID string, userID int) (*Chat, err
 	}
 
 	// Get chat participants
-	rows, err := r.db.Query("SELECT user_id FROM chat_participants WHERE chat_id = $1", chatID)
+	rows, err := r.db.Query("SELECT user_id, role FROM chat_participants WHERE chat_id = $1", chatID)
 	if err != nil {
 		return nil, err
 	}
@@ -151,17 +263,21 @@ func (r *MessagingRepositoryImpl) GetChat(chatID string, userID int) (*Chat, err
 
 	for rows.Next() {
 		var participantID int
-		if err := rows.Scan(&participantID); err != nil {
+		var role string
+		if err := rows.Scan(&participantID, &role); err != nil {
 			return nil, err
 		}
 		chat.Participants = append(chat.Participants, participantID)
+		if role == RoleAdmin {
+			chat.Admins = append(chat.Admins, participantID)
+		}
 	}
 
 	return &chat, nil
 }
 
 // CreateChat creates a new chat with the specified participants
-func (r *MessagingRepositoryImpl) CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int) error {
+func (r *MessagingRepositoryImpl) CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int, isGroup bool) error {
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -169,13 +285,13 @@ func (r *MessagingRepositoryImpl) CreateChat(ctx context.Context, chatID string,
 	defer tx.Rollback()
 
 	// Create chat
-	_, err = tx.Exec("INSERT INTO chats (id, chat_name, is_group) VALUES ($1, $2, true)", chatID, chatName)
+	_, err = tx.Exec("INSERT INTO chats (id, chat_name, is_group) VALUES ($1, $2, $3)", chatID, chatName, isGroup)
 	if err != nil {
 		return err
 	}
 
-	// Add creator as a participant
-	_, err = tx.Exec("INSERT INTO chat_participants (chat_id, user_id) VALUES ($1, $2)", chatID, creatorID)
+	// Add creator as a participant and the chat admin
+	_, err = tx.Exec("INSERT INTO chat_participants (chat_id, user_id, role) VALUES ($1, $2, $3)", chatID, creatorID, RoleAdmin)
 	if err != nil {
 		return err
 	}
@@ -254,14 +370,20 @@ func (r *MessagingRepositoryImpl) GetOrCreateDirectChat(ctx context.Context, use
 	return chatID, nil
 }
 
-// AddMessage adds a message to the database and returns the sent time
-func (r *MessagingRepositoryImpl) AddMessage(messageID string, chatID string, senderID int, content string) (time.Time, error) {
+// AddMessage adds a message to the database and returns the sent time.
+// Message IDs only need to be unique within a chat. A positive expiresIn makes the
+// message expire that long after it was sent.
+func (r *MessagingRepositoryImpl) AddMessage(messageID string, chatID string, senderID int, content string, expiresIn time.Duration) (time.Time, error) {
 	var sentAt time.Time
-	err := r.db.QueryRow(
-		"INSERT INTO messages (id, chat_id, sender_id, content) VALUES ($1, $2, $3, $4) RETURNING sent_at",
-		messageID, chatID, senderID, content,
-	).Scan(&sentAt)
+	err := r.db.QueryRow(`
+        INSERT INTO messages (id, chat_id, sender_id, content, expires_at)
+        VALUES ($1, $2, $3, $4, CASE WHEN $5::bigint > 0 THEN CURRENT_TIMESTAMP + $5::bigint * INTERVAL '1 millisecond' END)
+        RETURNING sent_at
+    `, messageID, chatID, senderID, content, expiresIn.Milliseconds()).Scan(&sentAt)
 	if err != nil {
+		if isUniqueViolation(err) {
+			return time.Time{}, errors.New(apierrors.ErrorMessageAlreadyExists)
+		}
 		return time.Time{}, err
 	}
 	return sentAt, nil
@@ -296,62 +418,258 @@ func (r *MessagingRepositoryImpl) IsUserInChat(userID int, chatID string) (bool,
 	return count > 0, nil
 }
 
-// AddParticipant adds a user to a chat
+// AddParticipant adds a user to a group chat. The block check is part of the insert,
+// so a block made while the user is being added still keeps them out. Direct chats
+// always stay between their two participants.
 func (r *MessagingRepositoryImpl) AddParticipant(chatID string, userID int) error {
-	_, err := r.db.Exec("INSERT INTO chat_participants (chat_id, user_id) VALUES ($1, $2)", chatID, userID)
-	return err
+	result, err := r.db.Exec(`
+        INSERT INTO chat_participants (chat_id, user_id)
+        SELECT $1, $2
+        WHERE EXISTS (SELECT 1 FROM chats WHERE id = $1 AND is_group)
+        AND NOT EXISTS (
+            SELECT 1 FROM chat_participants cp
+            JOIN user_blocks ub
+                ON (ub.blocker_id = $2 AND ub.blocked_id = cp.user_id)
+                OR (ub.blocked_id = $2 AND ub.blocker_id = cp.user_id)
+            WHERE cp.chat_id = $1
+        )
+    `, chatID, userID)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	// Tell a direct chat apart from a block
+	var isGroup bool
+	if err := r.db.QueryRow("SELECT is_group FROM chats WHERE id = $1", chatID).Scan(&isGroup); err != nil {
+		return err
+	}
+	if !isGroup {
+		return errors.New(apierrors.ErrorDirectChatParticipants)
+	}
+	return errors.New(apierrors.ErrorUsersBlocked)
 }
 
-// RemoveParticipant removes a user from a chat
+// RemoveParticipant removes a user from a chat. When the last admin leaves,
+// the longest-standing remaining participant becomes the admin.
 func (r *MessagingRepositoryImpl) RemoveParticipant(chatID string, userID int) error {
-	_, err := r.db.Exec("DELETE FROM chat_participants WHERE chat_id = $1 AND user_id = $2", chatID, userID)
-	return err
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var role string
+	err = tx.QueryRow("DELETE FROM chat_participants WHERE chat_id = $1 AND user_id = $2 RETURNING role", chatID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if role == RoleAdmin {
+		_, err = tx.Exec(`
+            UPDATE chat_participants SET role = $2
+            WHERE chat_id = $1
+            AND NOT EXISTS (SELECT 1 FROM chat_participants WHERE chat_id = $1 AND role = $2)
+            AND user_id = (
+                SELECT user_id FROM chat_participants
+                WHERE chat_id = $1
+                ORDER BY joined_at, user_id
+                LIMIT 1
+            )
+        `, chatID, RoleAdmin)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
 }
 
-// AddReaction adds a reaction to a message
-func (r *MessagingRepositoryImpl) AddReaction(reactionID string, messageID string, userID int, reactionCode string) error {
-	// Check if reaction code exists
-	var count int
-	err := r.db.QueryRow("SELECT COUNT(*) FROM reaction_catalog WHERE reaction_code = $1", reactionCode).Scan(&count)
+// GetParticipantRole returns the user's role in a chat, or an empty string if they aren't a participant
+func (r *MessagingRepositoryImpl) GetParticipantRole(chatID string, userID int) (string, error) {
+	var role string
+	err := r.db.QueryRow("SELECT role FROM chat_participants WHERE chat_id = $1 AND user_id = $2", chatID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return role, err
+}
+
+// SetParticipantRole changes a participant's role. A chat always keeps at least
+// one admin, so demoting the last one fails.
+func (r *MessagingRepositoryImpl) SetParticipantRole(chatID string, userID int, role string) error {
+	result, err := r.db.Exec(`
+        UPDATE chat_participants SET role = $3
+        WHERE chat_id = $1 AND user_id = $2
+        AND ($3 = 'admin' OR EXISTS (
+            SELECT 1 FROM chat_participants
+            WHERE chat_id = $1 AND user_id <> $2 AND role = 'admin'
+        ))
+    `, chatID, userID, role)
 	if err != nil {
 		return err
 	}
-	if count == 0 {
-		return errors.New(apierrors.ErrorInvalidReactionCode)
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
 	}
 
-	// Add reaction - will fail with constraint error if duplicate
-	_, err = r.db.Exec(`
-        INSERT INTO message_reactions (id, message_id, user_id, reaction_code)
-        VALUES ($1, $2, $3, $4)
-    `, reactionID, messageID, userID, reactionCode)
+	// Tell a missing participant apart from the last admin
+	current, err := r.GetParticipantRole(chatID, userID)
+	if err != nil {
+		return err
+	}
+	if current == "" {
+		return errors.New(apierrors.ErrorUserNotInChat)
+	}
+	return errors.New(apierrors.ErrorLastChatAdmin)
+}
+
+// RenameChat sets the name of a group chat
+func (r *MessagingRepositoryImpl) RenameChat(chatID string, chatName string) error {
+	result, err := r.db.Exec("UPDATE chats SET chat_name = $2 WHERE id = $1 AND is_group", chatID, chatName)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return errors.New(apierrors.ErrorNotGroupChat)
+	}
+	return nil
+}
+
+// AddReaction adds a reaction to a message. The reaction code must be in the reaction catalog.
+// Adding a reaction the user has already left is a no-op, so concurrent adds end up with
+// a single row; reusing a reaction ID for a different reaction still fails with a
+// primary key violation.
+func (r *MessagingRepositoryImpl) AddReaction(reactionID string, chatID string, messageID string, userID int, reactionCode string) error {
+	_, err := r.db.Exec(`
+        INSERT INTO message_reactions (id, chat_id, message_id, user_id, reaction_code)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (chat_id, message_id, user_id, reaction_code) DO NOTHING
+    `, reactionID, chatID, messageID, userID, reactionCode)
 
 	return err
 }
 
+// GetMessageReactions retrieves the reactions on a message, oldest first
+func (r *MessagingRepositoryImpl) GetMessageReactions(chatID string, messageID string, limit, offset int) ([]MessageReaction, error) {
+	rows, err := r.db.Query(`
+        SELECT user_id, reaction_code, reacted_at
+        FROM message_reactions
+        WHERE chat_id = $1 AND message_id = $2
+        ORDER BY reacted_at, user_id, reaction_code
+        LIMIT $3 OFFSET $4
+    `, chatID, messageID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := []MessageReaction{}
+	for rows.Next() {
+		var reaction MessageReaction
+		if err := rows.Scan(&reaction.UserID, &reaction.ReactionCode, &reaction.ReactedAt); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+
+	return reactions, rows.Err()
+}
+
+// ReactionCodeExists checks if a reaction code is in the reaction catalog
+func (r *MessagingRepositoryImpl) ReactionCodeExists(reactionCode string) (bool, error) {
+	var exists bool
+	err := r.db.QueryRow("SELECT EXISTS(SELECT 1 FROM reaction_catalog WHERE reaction_code = $1)", reactionCode).Scan(&exists)
+	return exists, err
+}
+
+// GetReactionCatalog retrieves all allowed reactions
+func (r *MessagingRepositoryImpl) GetReactionCatalog() ([]ReactionCatalogItem, error) {
+	rows, err := r.db.Query("SELECT reaction_code, emoji FROM reaction_catalog ORDER BY reaction_code")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []ReactionCatalogItem{}
+	for rows.Next() {
+		var item ReactionCatalogItem
+		if err := rows.Scan(&item.ReactionCode, &item.Emoji); err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+
+	return items, rows.Err()
+}
+
 // RemoveReaction removes a reaction from a message
-func (r *MessagingRepositoryImpl) RemoveReaction(messageID string, userID int, reactionCode string) error {
+func (r *MessagingRepositoryImpl) RemoveReaction(chatID string, messageID string, userID int, reactionCode string) error {
 	_, err := r.db.Exec(
-		"DELETE FROM message_reactions WHERE message_id = $1 AND user_id = $2 AND reaction_code = $3",
-		messageID, userID, reactionCode,
+		"DELETE FROM message_reactions WHERE chat_id = $1 AND message_id = $2 AND user_id = $3 AND reaction_code = $4",
+		chatID, messageID, userID, reactionCode,
 	)
 	return err
 }
 
-// GetChatIDForMessage retrieves the chat ID for a message
+// GetChatIDForMessage retrieves the chat ID for a message. Message IDs are only unique
+// within a chat, so an ID used in several chats is refused rather than guessed.
 func (r *MessagingRepositoryImpl) GetChatIDForMessage(messageID string) (string, error) {
-	var chatID string
-	err := r.db.QueryRow("SELECT chat_id FROM messages WHERE id = $1", messageID).Scan(&chatID)
-	return chatID, err
+	rows, err := r.db.Query("SELECT chat_id FROM messages WHERE id = $1 LIMIT 2", messageID)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var chatIDs []string
+	for rows.Next() {
+		var chatID string
+		if err := rows.Scan(&chatID); err != nil {
+			return "", err
+		}
+		chatIDs = append(chatIDs, chatID)
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+
+	switch len(chatIDs) {
+	case 0:
+		return "", sql.ErrNoRows
+	case 1:
+		return chatIDs[0], nil
+	default:
+		return "", errors.New(apierrors.ErrorAmbiguousMessageID)
+	}
 }
 
 // GetChatMessages retrieves messages for a chat with pagination
 func (r *MessagingRepositoryImpl) GetChatMessages(chatID string, userID int, limit, offset int) ([]ChatMessage, error) {
 	// Get messages
 	rows, err := r.db.Query(`
-        SELECT id, chat_id, sender_id, content, sent_at
+        SELECT id, chat_id, sender_id, content, sent_at, edited_at, deleted_at IS NOT NULL, expires_at
         FROM messages
-        WHERE chat_id = $1
+        WHERE chat_id = $1 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
         ORDER BY sent_at DESC
         LIMIT $2 OFFSET $3
     `, chatID, limit, offset)
@@ -363,7 +681,7 @@ func (r *MessagingRepositoryImpl) GetChatMessages(chatID string, userID int, lim
 	messages := []ChatMessage{}
 	for rows.Next() {
 		var msg ChatMessage
-		if err := rows.Scan(&msg.MessageID, &msg.ChatID, &msg.SenderID, &msg.Content, &msg.SentAt); err != nil {
+		if err := rows.Scan(&msg.MessageID, &msg.ChatID, &msg.SenderID, &msg.Content, &msg.SentAt, &msg.EditedAt, &msg.Deleted, &msg.ExpiresAt); err != nil {
 			return nil, err
 		}
 		messages = append(messages, msg)
@@ -371,6 +689,189 @@ func (r *MessagingRepositoryImpl) GetChatMessages(chatID string, userID int, lim
 	return messages, nil
 }
 
+// GetMessage retrieves a single message of a chat. Deleted messages are returned as
+// tombstones, expired ones are not found.
+func (r *MessagingRepositoryImpl) GetMessage(chatID string, messageID string) (*ChatMessage, error) {
+	var msg ChatMessage
+	err := r.db.QueryRow(`
+        SELECT id, chat_id, sender_id, content, sent_at, edited_at, deleted_at IS NOT NULL, expires_at
+        FROM messages
+        WHERE chat_id = $1 AND id = $2 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+    `, chatID, messageID).Scan(&msg.MessageID, &msg.ChatID, &msg.SenderID, &msg.Content, &msg.SentAt, &msg.EditedAt, &msg.Deleted, &msg.ExpiresAt)
+	if err == sql.ErrNoRows {
+		return nil, errors.New(apierrors.ErrorMessageNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// SearchMessages returns messages of a chat whose content contains the query,
+// ignoring case, newest first. Deleted and expired messages are skipped.
+func (r *MessagingRepositoryImpl) SearchMessages(chatID string, query string, limit, offset int) ([]ChatMessage, error) {
+	rows, err := r.db.Query(`
+        SELECT id, chat_id, sender_id, content, sent_at, edited_at, deleted_at IS NOT NULL, expires_at
+        FROM messages
+        WHERE chat_id = $1 AND content ILIKE '%' || $2 || '%' ESCAPE '\'
+          AND deleted_at IS NULL
+          AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+        ORDER BY sent_at DESC, id DESC
+        LIMIT $3 OFFSET $4
+    `, chatID, escapeLikePattern(query), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []ChatMessage{}
+	for rows.Next() {
+		var msg ChatMessage
+		if err := rows.Scan(&msg.MessageID, &msg.ChatID, &msg.SenderID, &msg.Content, &msg.SentAt, &msg.EditedAt, &msg.Deleted, &msg.ExpiresAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// escapeLikePattern escapes LIKE wildcards so the text is matched literally
+func escapeLikePattern(text string) string {
+	return likeEscaper.Replace(text)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// GetChatMessagesBefore retrieves up to limit messages sent before the given message,
+// newest first. An empty message ID starts from the newest message.
+func (r *MessagingRepositoryImpl) GetChatMessagesBefore(chatID string, beforeMessageID string, limit int) ([]ChatMessage, error) {
+	var rows *sql.Rows
+	var err error
+	if beforeMessageID == "" {
+		rows, err = r.db.Query(`
+            SELECT id, chat_id, sender_id, content, sent_at, edited_at, deleted_at IS NOT NULL, expires_at
+            FROM messages
+            WHERE chat_id = $1 AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+            ORDER BY sent_at DESC, id DESC
+            LIMIT $2
+        `, chatID, limit)
+	} else {
+		var beforeSentAt time.Time
+		err = r.db.QueryRow(`
+            SELECT sent_at FROM messages WHERE id = $1 AND chat_id = $2
+        `, beforeMessageID, chatID).Scan(&beforeSentAt)
+		if err == sql.ErrNoRows {
+			return nil, errors.New(apierrors.ErrorInvalidMessageCursor)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rows, err = r.db.Query(`
+            SELECT id, chat_id, sender_id, content, sent_at, edited_at, deleted_at IS NOT NULL, expires_at
+            FROM messages
+            WHERE chat_id = $1 AND (sent_at, id) < ($2, $3)
+              AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+            ORDER BY sent_at DESC, id DESC
+            LIMIT $4
+        `, chatID, beforeSentAt, beforeMessageID, limit)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []ChatMessage{}
+	for rows.Next() {
+		var msg ChatMessage
+		if err := rows.Scan(&msg.MessageID, &msg.ChatID, &msg.SenderID, &msg.Content, &msg.SentAt, &msg.EditedAt, &msg.Deleted, &msg.ExpiresAt); err != nil {
+			return nil, err
+		}
+		messages = append(messages, msg)
+	}
+	return messages, rows.Err()
+}
+
+// EditMessage replaces the content of a message sent by the user and records the edit time.
+// The original sent time is kept.
+func (r *MessagingRepositoryImpl) EditMessage(chatID string, messageID string, senderID int, content string) (*ChatMessage, error) {
+	var msg ChatMessage
+	err := r.db.QueryRow(`
+        UPDATE messages
+        SET content = $4, edited_at = CURRENT_TIMESTAMP
+        WHERE chat_id = $1 AND id = $2 AND sender_id = $3 AND deleted_at IS NULL
+        RETURNING id, chat_id, sender_id, content, sent_at, edited_at
+    `, chatID, messageID, senderID, content).Scan(&msg.MessageID, &msg.ChatID, &msg.SenderID, &msg.Content, &msg.SentAt, &msg.EditedAt)
+	if err == nil {
+		return &msg, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return nil, r.unchangedMessageError(chatID, messageID)
+}
+
+// DeleteMessage turns a message sent by the user into a tombstone: the row is kept so
+// the thread stays continuous, but the content is cleared. Returns the deletion time.
+func (r *MessagingRepositoryImpl) DeleteMessage(chatID string, messageID string, senderID int) (time.Time, error) {
+	var deletedAt time.Time
+	err := r.db.QueryRow(`
+        UPDATE messages
+        SET content = '', deleted_at = CURRENT_TIMESTAMP
+        WHERE chat_id = $1 AND id = $2 AND sender_id = $3 AND deleted_at IS NULL
+        RETURNING deleted_at
+    `, chatID, messageID, senderID).Scan(&deletedAt)
+	if err == nil {
+		return deletedAt, nil
+	}
+	if err != sql.ErrNoRows {
+		return time.Time{}, err
+	}
+
+	return time.Time{}, r.unchangedMessageError(chatID, messageID)
+}
+
+// DeleteExpiredMessages removes disappearing messages whose expiry has passed and
+// returns them so their removal can be announced
+func (r *MessagingRepositoryImpl) DeleteExpiredMessages() ([]ExpiredMessage, error) {
+	rows, err := r.db.Query(`
+        DELETE FROM messages
+        WHERE expires_at <= CURRENT_TIMESTAMP
+        RETURNING id, chat_id, sender_id, expires_at
+    `)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expired := []ExpiredMessage{}
+	for rows.Next() {
+		var msg ExpiredMessage
+		if err := rows.Scan(&msg.MessageID, &msg.ChatID, &msg.SenderID, &msg.ExpiresAt); err != nil {
+			return nil, err
+		}
+		expired = append(expired, msg)
+	}
+	return expired, rows.Err()
+}
+
+// unchangedMessageError explains why a sender-only update matched no message,
+// telling a missing or deleted message apart from someone else's
+func (r *MessagingRepositoryImpl) unchangedMessageError(chatID string, messageID string) error {
+	var exists bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS(SELECT 1 FROM messages WHERE chat_id = $1 AND id = $2 AND deleted_at IS NULL)
+    `, chatID, messageID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New(apierrors.ErrorMessageNotFound)
+	}
+	return errors.New(apierrors.ErrorNotMessageSender)
+}
+
 // StoreTypingIndicator records that a user is typing in a chat
 // This could use a cache/Redis instead of DB for better performance
 func (r *MessagingRepositoryImpl) StoreTypingIndicator(userID int, chatID string) error {
@@ -383,7 +884,7 @@ func (r *MessagingRepositoryImpl) StoreTypingIndicator(userID int, chatID string
 func (r *MessagingRepositoryImpl) StoreReadReceipt(userID int, chatID string, messageID string) error {
 	// First, get the sequence number for the message
 	var seq int64
-	err := r.db.QueryRow("SELECT seq FROM messages WHERE id = $1", messageID).Scan(&seq)
+	err := r.db.QueryRow("SELECT seq FROM messages WHERE chat_id = $1 AND id = $2", chatID, messageID).Scan(&seq)
 	if err != nil {
 		return err
 	}
@@ -422,3 +923,264 @@ func (r *MessagingRepositoryImpl) GetUserChatRooms(userID int) (map[string]struc
 func (r *MessagingRepositoryImpl) GetChatParticipantsForBroadcast(chatID string) ([]int, error) {
 	return r.GetChatParticipants(chatID)
 }
+
+// GetUserReactions retrieves reactions made by a user, most recent first,
+// limited to chats the user still participates in
+func (r *MessagingRepositoryImpl) GetUserReactions(userID int, limit, offset int) ([]UserReaction, error) {
+	rows, err := r.db.Query(`
+        SELECT mr.id, mr.reaction_code, mr.reacted_at,
+               m.id, m.sender_id, m.content, m.sent_at,
+               c.id, c.chat_name, c.is_group
+        FROM message_reactions mr
+        JOIN messages m ON m.chat_id = mr.chat_id AND m.id = mr.message_id
+        JOIN chats c ON c.id = m.chat_id
+        JOIN chat_participants cp ON cp.chat_id = c.id AND cp.user_id = mr.user_id
+        WHERE mr.user_id = $1
+        ORDER BY mr.reacted_at DESC, mr.id DESC
+        LIMIT $2 OFFSET $3
+    `, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reactions := []UserReaction{}
+	for rows.Next() {
+		var reaction UserReaction
+		if err := rows.Scan(
+			&reaction.ReactionID, &reaction.ReactionCode, &reaction.ReactedAt,
+			&reaction.MessageID, &reaction.MessageSender, &reaction.MessageContent, &reaction.MessageSentAt,
+			&reaction.ChatID, &reaction.ChatName, &reaction.IsGroup,
+		); err != nil {
+			return nil, err
+		}
+		reactions = append(reactions, reaction)
+	}
+	return reactions, rows.Err()
+}
+
+// GetSentMessages retrieves messages sent by a user, most recent first, limited to
+// chats the user still participates in. Deleted and expired messages are skipped.
+func (r *MessagingRepositoryImpl) GetSentMessages(senderID int, limit, offset int) ([]SentMessage, error) {
+	rows, err := r.db.Query(`
+        SELECT m.id, m.content, m.sent_at, m.edited_at, m.expires_at,
+               c.id, c.chat_name, c.is_group
+        FROM messages m
+        JOIN chats c ON c.id = m.chat_id
+        JOIN chat_participants cp ON cp.chat_id = c.id AND cp.user_id = m.sender_id
+        WHERE m.sender_id = $1
+          AND m.deleted_at IS NULL
+          AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+        ORDER BY m.sent_at DESC, m.id DESC
+        LIMIT $2 OFFSET $3
+    `, senderID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	messages := []SentMessage{}
+	for rows.Next() {
+		var message SentMessage
+		if err := rows.Scan(
+			&message.MessageID, &message.Content, &message.SentAt, &message.EditedAt, &message.ExpiresAt,
+			&message.ChatID, &message.ChatName, &message.IsGroup,
+		); err != nil {
+			return nil, err
+		}
+		messages = append(messages, message)
+	}
+	return messages, rows.Err()
+}
+
+// GetChatOverviews retrieves the user's chats with participants, the latest message and
+// the unread count in a single query, ordered by latest activity
+func (r *MessagingRepositoryImpl) GetChatOverviews(userID int, limit, offset int) ([]ChatOverview, error) {
+	rows, err := r.db.Query(`
+        SELECT c.id, c.chat_name, c.created_at, c.is_group,
+               lm.id, lm.sender_id, lm.content, lm.sent_at,
+               uc.unread_count,
+               pa.user_ids, pa.full_names
+        FROM chats c
+        JOIN chat_participants cp ON cp.chat_id = c.id AND cp.user_id = $1
+        LEFT JOIN message_read_receipts rr ON rr.chat_id = c.id AND rr.user_id = $1
+        LEFT JOIN LATERAL (
+            SELECT m.id, m.sender_id, m.content, m.sent_at
+            FROM messages m
+            WHERE m.chat_id = c.id
+              AND m.deleted_at IS NULL
+              AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+            ORDER BY m.seq DESC
+            LIMIT 1
+        ) lm ON TRUE
+        LEFT JOIN LATERAL (
+            SELECT COUNT(*) AS unread_count
+            FROM messages m
+            WHERE m.chat_id = c.id
+              AND m.sender_id <> $1
+              AND m.seq > COALESCE(rr.last_read_seq, 0)
+              AND m.deleted_at IS NULL
+              AND (m.expires_at IS NULL OR m.expires_at > CURRENT_TIMESTAMP)
+        ) uc ON TRUE
+        LEFT JOIN LATERAL (
+            SELECT array_agg(p.user_id ORDER BY p.user_id) AS user_ids,
+                   array_agg(COALESCE(pr.full_name, '') ORDER BY p.user_id) AS full_names
+            FROM chat_participants p
+            LEFT JOIN profiles pr ON pr.user_id = p.user_id
+            WHERE p.chat_id = c.id
+        ) pa ON TRUE
+        ORDER BY COALESCE(lm.sent_at, c.created_at) DESC, c.id
+        LIMIT $2 OFFSET $3
+    `, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	overviews := []ChatOverview{}
+	for rows.Next() {
+		var (
+			overview      ChatOverview
+			lastID        sql.NullString
+			lastSenderID  sql.NullInt64
+			lastContent   sql.NullString
+			lastSentAt    sql.NullTime
+			participantID pq.Int64Array
+			fullNames     pq.StringArray
+		)
+
+		if err := rows.Scan(
+			&overview.ChatID, &overview.ChatName, &overview.CreatedAt, &overview.IsGroup,
+			&lastID, &lastSenderID, &lastContent, &lastSentAt,
+			&overview.UnreadCount,
+			&participantID, &fullNames,
+		); err != nil {
+			return nil, err
+		}
+
+		if lastID.Valid {
+			overview.LastMessage = &ChatMessage{
+				MessageID: lastID.String,
+				ChatID:    overview.ChatID,
+				SenderID:  int(lastSenderID.Int64),
+				Content:   lastContent.String,
+				SentAt:    lastSentAt.Time,
+			}
+		}
+
+		overview.Participants = make([]ParticipantSummary, len(participantID))
+		for i, id := range participantID {
+			overview.Participants[i] = ParticipantSummary{UserID: int(id)}
+			if i < len(fullNames) {
+				overview.Participants[i].FullName = fullNames[i]
+			}
+		}
+
+		overviews = append(overviews, overview)
+	}
+	return overviews, rows.Err()
+}
+
+// isUniqueViolation checks if the error is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// GetReadPositions retrieves the read position of every current participant of a chat.
+// Participants who haven't read anything yet are included with an empty position.
+func (r *MessagingRepositoryImpl) GetReadPositions(chatID string) ([]ReadPosition, error) {
+	rows, err := r.db.Query(`
+        SELECT cp.user_id, m.id, rr.read_at
+        FROM chat_participants cp
+        LEFT JOIN message_read_receipts rr ON rr.chat_id = cp.chat_id AND rr.user_id = cp.user_id
+        LEFT JOIN messages m ON m.chat_id = rr.chat_id AND m.seq = rr.last_read_seq
+        WHERE cp.chat_id = $1
+        ORDER BY cp.user_id
+    `, chatID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := []ReadPosition{}
+	for rows.Next() {
+		var position ReadPosition
+		var readAt sql.NullTime
+		if err := rows.Scan(&position.UserID, &position.LastReadMessageID, &readAt); err != nil {
+			return nil, err
+		}
+		if position.LastReadMessageID != nil && readAt.Valid {
+			position.ReadAt = &readAt.Time
+		}
+		positions = append(positions, position)
+	}
+	return positions, rows.Err()
+}
+
+// GetReactionSummaries counts the reactions of each code on the given messages of a chat.
+// Messages without reactions have no entry.
+func (r *MessagingRepositoryImpl) GetReactionSummaries(chatID string, messageIDs []string, userID int) (map[string][]ReactionSummary, error) {
+	rows, err := r.db.Query(`
+        SELECT mr.message_id, mr.reaction_code, COUNT(*), BOOL_OR(mr.user_id = $3)
+        FROM message_reactions mr
+        WHERE mr.chat_id = $1 AND mr.message_id = ANY($2)
+        GROUP BY mr.message_id, mr.reaction_code
+        ORDER BY mr.message_id, COUNT(*) DESC, mr.reaction_code
+    `, chatID, pq.Array(messageIDs), userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	summaries := make(map[string][]ReactionSummary)
+	for rows.Next() {
+		var messageID string
+		var summary ReactionSummary
+		if err := rows.Scan(&messageID, &summary.ReactionCode, &summary.Count, &summary.Reacted); err != nil {
+			return nil, err
+		}
+		summaries[messageID] = append(summaries[messageID], summary)
+	}
+	return summaries, rows.Err()
+}
+
+// GetMessageReaders lists the current participants other than the sender who have read
+// each of the given messages of a chat. Messages nobody has read have no entry.
+func (r *MessagingRepositoryImpl) GetMessageReaders(chatID string, messageIDs []string) (map[string][]int, error) {
+	rows, err := r.db.Query(`
+        SELECT m.id, rr.user_id
+        FROM messages m
+        JOIN message_read_receipts rr ON rr.chat_id = m.chat_id AND rr.last_read_seq >= m.seq
+        JOIN chat_participants cp ON cp.chat_id = rr.chat_id AND cp.user_id = rr.user_id
+        WHERE m.chat_id = $1 AND m.id = ANY($2) AND rr.user_id <> m.sender_id
+        ORDER BY m.id, rr.user_id
+    `, chatID, pq.Array(messageIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	readers := make(map[string][]int)
+	for rows.Next() {
+		var messageID string
+		var userID int
+		if err := rows.Scan(&messageID, &userID); err != nil {
+			return nil, err
+		}
+		readers[messageID] = append(readers[messageID], userID)
+	}
+	return readers, rows.Err()
+}
+
+// HasBlockBetween reports whether any of the users has blocked another one of them
+func (r *MessagingRepositoryImpl) HasBlockBetween(userIDs []int) (bool, error) {
+	var blocked bool
+	err := r.db.QueryRow(`
+        SELECT EXISTS (
+            SELECT 1 FROM user_blocks
+            WHERE blocker_id = ANY($1) AND blocked_id = ANY($1)
+        )
+    `, pq.Array(userIDs)).Scan(&blocked)
+	return blocked, err
+}
@@ -8,8 +8,11 @@ import (
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
 )
 
 func setupMock(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *MessagingRepositoryImpl) {
@@ -122,12 +125,12 @@ func TestGetChat(t *testing.T) {
 			AddRow(chatID, chatName, mockTime, true))
 
 	// Get participants
-	mock.ExpectQuery(`SELECT user_id FROM chat_participants WHERE chat_id = \$1`).
+	mock.ExpectQuery(`SELECT user_id, role FROM chat_participants WHERE chat_id = \$1`).
 		WithArgs(chatID).
-		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).
-			AddRow(1).
-			AddRow(2).
-			AddRow(3))
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "role"}).
+			AddRow(1, RoleAdmin).
+			AddRow(2, RoleMember).
+			AddRow(3, RoleMember))
 
 	chat, err := repo.GetChat(chatID, userID)
 
@@ -171,12 +174,12 @@ func TestCreateChat(t *testing.T) {
 	participants := []int{1, 2, 3}
 
 	mock.ExpectBegin()
-	mock.ExpectExec(`INSERT INTO chats \(id, chat_name, is_group\) VALUES \(\$1, \$2, true\)`).
-		WithArgs(chatID, chatName).
+	mock.ExpectExec(`INSERT INTO chats \(id, chat_name, is_group\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs(chatID, chatName, true).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	mock.ExpectExec(`INSERT INTO chat_participants \(chat_id, user_id\) VALUES \(\$1, \$2\)`).
-		WithArgs(chatID, creatorID).
+	mock.ExpectExec(`INSERT INTO chat_participants \(chat_id, user_id, role\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs(chatID, creatorID, RoleAdmin).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
 	// Skip creator as already added
@@ -190,7 +193,7 @@ func TestCreateChat(t *testing.T) {
 
 	mock.ExpectCommit()
 
-	err := repo.CreateChat(ctx, chatID, creatorID, chatName, participants)
+	err := repo.CreateChat(ctx, chatID, creatorID, chatName, participants, true)
 
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -263,11 +266,41 @@ func TestAddMessage(t *testing.T) {
 	content := "Hello world"
 	mockTime := time.Now()
 
-	mock.ExpectQuery(`INSERT INTO messages \(id, chat_id, sender_id, content\) VALUES \(\$1, \$2, \$3, \$4\) RETURNING sent_at`).
-		WithArgs(messageID, chatID, senderID, content).
+	mock.ExpectQuery(`INSERT INTO messages \(id, chat_id, sender_id, content, expires_at\)\s+VALUES \(\$1, \$2, \$3, \$4, CASE WHEN \$5::bigint > 0 .* END\)\s+RETURNING sent_at`).
+		WithArgs(messageID, chatID, senderID, content, int64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"sent_at"}).AddRow(mockTime))
+
+	sentAt, err := repo.AddMessage(messageID, chatID, senderID, content, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, mockTime, sentAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddMessageDuplicateInChat(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO messages`).
+		WithArgs("msg1", "chat1", 1, "Hello world", int64(0)).
+		WillReturnError(&pq.Error{Code: "23505"})
+
+	_, err := repo.AddMessage("msg1", "chat1", 1, "Hello world", 0)
+
+	assert.EqualError(t, err, apierrors.ErrorMessageAlreadyExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddMessage_StoresExpiry(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mockTime := time.Now()
+	mock.ExpectQuery(`INSERT INTO messages`).
+		WithArgs("msg1", "chat1", 1, "Self-destructing", int64(90000)).
 		WillReturnRows(sqlmock.NewRows([]string{"sent_at"}).AddRow(mockTime))
 
-	sentAt, err := repo.AddMessage(messageID, chatID, senderID, content)
+	sentAt, err := repo.AddMessage("msg1", "chat1", 1, "Self-destructing", 90*time.Second)
 
 	assert.NoError(t, err)
 	assert.Equal(t, mockTime, sentAt)
@@ -319,7 +352,7 @@ func TestAddParticipant(t *testing.T) {
 	chatID := "chat1"
 	userID := 1
 
-	mock.ExpectExec(`INSERT INTO chat_participants \(chat_id, user_id\) VALUES \(\$1, \$2\)`).
+	mock.ExpectExec(`INSERT INTO chat_participants \(chat_id, user_id\)\s+SELECT \$1, \$2\s+WHERE EXISTS \(SELECT 1 FROM chats WHERE id = \$1 AND is_group\)\s+AND NOT EXISTS`).
 		WithArgs(chatID, userID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
@@ -329,6 +362,40 @@ func TestAddParticipant(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestAddParticipant_BlockedByParticipant(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO chat_participants`).
+		WithArgs("chat1", 5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT is_group FROM chats WHERE id = \$1`).
+		WithArgs("chat1").
+		WillReturnRows(sqlmock.NewRows([]string{"is_group"}).AddRow(true))
+
+	err := repo.AddParticipant("chat1", 5)
+
+	assert.EqualError(t, err, apierrors.ErrorUsersBlocked)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAddParticipant_DirectChat(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO chat_participants`).
+		WithArgs("chat1", 5).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT is_group FROM chats WHERE id = \$1`).
+		WithArgs("chat1").
+		WillReturnRows(sqlmock.NewRows([]string{"is_group"}).AddRow(false))
+
+	err := repo.AddParticipant("chat1", 5)
+
+	assert.EqualError(t, err, apierrors.ErrorDirectChatParticipants)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestRemoveParticipant(t *testing.T) {
 	db, mock, repo := setupMock(t)
 	defer db.Close()
@@ -336,9 +403,11 @@ func TestRemoveParticipant(t *testing.T) {
 	chatID := "chat1"
 	userID := 1
 
-	mock.ExpectExec(`DELETE FROM chat_participants WHERE chat_id = \$1 AND user_id = \$2`).
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM chat_participants WHERE chat_id = \$1 AND user_id = \$2 RETURNING role`).
 		WithArgs(chatID, userID).
-		WillReturnResult(sqlmock.NewResult(0, 1))
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(RoleMember))
+	mock.ExpectCommit()
 
 	err := repo.RemoveParticipant(chatID, userID)
 
@@ -346,48 +415,106 @@ func TestRemoveParticipant(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestRemoveParticipant_LastAdminHandsOver(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`DELETE FROM chat_participants WHERE chat_id = \$1 AND user_id = \$2 RETURNING role`).
+		WithArgs("chat1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(RoleAdmin))
+	mock.ExpectExec(`UPDATE chat_participants SET role = \$2`).
+		WithArgs("chat1", RoleAdmin).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.RemoveParticipant("chat1", 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetParticipantRole_LastAdmin(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE chat_participants SET role = \$3`).
+		WithArgs("chat1", 1, RoleMember).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT role FROM chat_participants WHERE chat_id = \$1 AND user_id = \$2`).
+		WithArgs("chat1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow(RoleAdmin))
+
+	err := repo.SetParticipantRole("chat1", 1, RoleMember)
+
+	assert.EqualError(t, err, apierrors.ErrorLastChatAdmin)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAddReaction(t *testing.T) {
 	db, mock, repo := setupMock(t)
 	defer db.Close()
 
 	reactionID := "react1"
+	chatID := "chat1"
 	messageID := "msg1"
 	userID := 1
-	reactionCode := "👍"
-
-	// Check if reaction code exists
-	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM reaction_catalog WHERE reaction_code = \$1`).
-		WithArgs(reactionCode).
-		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	reactionCode := "like"
 
 	// Add reaction
-	mock.ExpectExec(`INSERT INTO message_reactions \(id, message_id, user_id, reaction_code\) VALUES \(\$1, \$2, \$3, \$4\)`).
-		WithArgs(reactionID, messageID, userID, reactionCode).
+	mock.ExpectExec(`INSERT INTO message_reactions \(id, chat_id, message_id, user_id, reaction_code\) VALUES \(\$1, \$2, \$3, \$4, \$5\) ON CONFLICT \(chat_id, message_id, user_id, reaction_code\) DO NOTHING`).
+		WithArgs(reactionID, chatID, messageID, userID, reactionCode).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err := repo.AddReaction(reactionID, messageID, userID, reactionCode)
+	err := repo.AddReaction(reactionID, chatID, messageID, userID, reactionCode)
 
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-func TestAddReactionInvalidCode(t *testing.T) {
+func TestGetMessageReactions(t *testing.T) {
 	db, mock, repo := setupMock(t)
 	defer db.Close()
 
-	reactionID := "react1"
-	messageID := "msg1"
-	userID := 1
-	reactionCode := "invalid"
+	reactedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(`SELECT user_id, reaction_code, reacted_at FROM message_reactions WHERE chat_id = \$1 AND message_id = \$2 ORDER BY reacted_at, user_id, reaction_code LIMIT \$3 OFFSET \$4`).
+		WithArgs("chat1", "msg1", 20, 40).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "reaction_code", "reacted_at"}).
+			AddRow(2, "like", reactedAt))
 
-	// Check if reaction code exists - not found
-	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM reaction_catalog WHERE reaction_code = \$1`).
-		WithArgs(reactionCode).
-		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	reactions, err := repo.GetMessageReactions("chat1", "msg1", 20, 40)
 
-	err := repo.AddReaction(reactionID, messageID, userID, reactionCode)
+	assert.NoError(t, err)
+	assert.Equal(t, []MessageReaction{{UserID: 2, ReactionCode: "like", ReactedAt: reactedAt}}, reactions)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
 
-	assert.Error(t, err)
+func TestReactionCodeExistsInvalidCode(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM reaction_catalog WHERE reaction_code = \$1\)`).
+		WithArgs("invalid").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	exists, err := repo.ReactionCodeExists("invalid")
+
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReactionCatalog(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT reaction_code, emoji FROM reaction_catalog ORDER BY reaction_code`).
+		WillReturnRows(sqlmock.NewRows([]string{"reaction_code", "emoji"}).
+			AddRow("heart", "❤️").
+			AddRow("like", "👍"))
+
+	items, err := repo.GetReactionCatalog()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []ReactionCatalogItem{{ReactionCode: "heart", Emoji: "❤️"}, {ReactionCode: "like", Emoji: "👍"}}, items)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -395,15 +522,16 @@ func TestRemoveReaction(t *testing.T) {
 	db, mock, repo := setupMock(t)
 	defer db.Close()
 
+	chatID := "chat1"
 	messageID := "msg1"
 	userID := 1
 	reactionCode := "👍"
 
-	mock.ExpectExec(`DELETE FROM message_reactions WHERE message_id = \$1 AND user_id = \$2 AND reaction_code = \$3`).
-		WithArgs(messageID, userID, reactionCode).
+	mock.ExpectExec(`DELETE FROM message_reactions WHERE chat_id = \$1 AND message_id = \$2 AND user_id = \$3 AND reaction_code = \$4`).
+		WithArgs(chatID, messageID, userID, reactionCode).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 
-	err := repo.RemoveReaction(messageID, userID, reactionCode)
+	err := repo.RemoveReaction(chatID, messageID, userID, reactionCode)
 
 	assert.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
@@ -427,6 +555,34 @@ func TestGetChatIDForMessage(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
+func TestGetChatIDForMessage_IDUsedInSeveralChats(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT chat_id FROM messages WHERE id = \$1 LIMIT 2`).
+		WithArgs("msg1").
+		WillReturnRows(sqlmock.NewRows([]string{"chat_id"}).AddRow("chat1").AddRow("chat2"))
+
+	_, err := repo.GetChatIDForMessage("msg1")
+
+	assert.EqualError(t, err, apierrors.ErrorAmbiguousMessageID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetChatIDForMessage_Unknown(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT chat_id FROM messages WHERE id = \$1 LIMIT 2`).
+		WithArgs("msg1").
+		WillReturnRows(sqlmock.NewRows([]string{"chat_id"}))
+
+	_, err := repo.GetChatIDForMessage("msg1")
+
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetChatMessages(t *testing.T) {
 	db, mock, repo := setupMock(t)
 	defer db.Close()
@@ -437,23 +593,33 @@ func TestGetChatMessages(t *testing.T) {
 	offset := 0
 	mockTime := time.Now()
 
-	mock.ExpectQuery(`SELECT id, chat_id, sender_id, content, sent_at FROM messages WHERE chat_id = \$1 ORDER BY sent_at DESC LIMIT \$2 OFFSET \$3`).
+	mock.ExpectQuery(`SELECT id, chat_id, sender_id, content, sent_at, edited_at, deleted_at IS NOT NULL, expires_at FROM messages WHERE chat_id = \$1 AND \(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP\) ORDER BY sent_at DESC LIMIT \$2 OFFSET \$3`).
 		WithArgs(chatID, limit, offset).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "chat_id", "sender_id", "content", "sent_at"}).
-			AddRow("msg1", chatID, userID, "Hello", mockTime).
-			AddRow("msg2", chatID, userID+1, "Hi there", mockTime.Add(-1*time.Minute)))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chat_id", "sender_id", "content", "sent_at", "edited_at", "deleted", "expires_at"}).
+			AddRow("msg1", chatID, userID, "Hello", mockTime, nil, false, nil).
+			AddRow("msg2", chatID, userID+1, "Hi there", mockTime.Add(-1*time.Minute), mockTime, false, mockTime.Add(time.Hour)).
+			AddRow("msg3", chatID, userID+1, "", mockTime.Add(-2*time.Minute), nil, true, nil))
 
 	messages, err := repo.GetChatMessages(chatID, userID, limit, offset)
 
 	assert.NoError(t, err)
-	assert.Equal(t, 2, len(messages))
+	assert.Equal(t, 3, len(messages))
 	assert.Equal(t, "msg1", messages[0].MessageID)
 	assert.Equal(t, chatID, messages[0].ChatID)
 	assert.Equal(t, userID, messages[0].SenderID)
 	assert.Equal(t, "Hello", messages[0].Content)
 	assert.Equal(t, mockTime, messages[0].SentAt)
 
+	assert.Nil(t, messages[0].EditedAt)
+	assert.Nil(t, messages[0].ExpiresAt)
+
 	assert.Equal(t, "msg2", messages[1].MessageID)
+	assert.Equal(t, mockTime, *messages[1].EditedAt)
+	assert.Equal(t, mockTime.Add(time.Hour), *messages[1].ExpiresAt)
+
+	// Deleted messages stay in the history as placeholders
+	assert.True(t, messages[2].Deleted)
+	assert.Empty(t, messages[2].Content)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -467,8 +633,8 @@ func TestStoreReadReceipt(t *testing.T) {
 	seq := int64(42)
 
 	// Get message sequence
-	mock.ExpectQuery(`SELECT seq FROM messages WHERE id = \$1`).
-		WithArgs(messageID).
+	mock.ExpectQuery(`SELECT seq FROM messages WHERE chat_id = \$1 AND id = \$2`).
+		WithArgs(chatID, messageID).
 		WillReturnRows(sqlmock.NewRows([]string{"seq"}).AddRow(seq))
 
 	// Store read receipt
@@ -537,3 +703,296 @@ func TestNewRepository(t *testing.T) {
 	assert.NotNil(t, repo)
 	assert.Equal(t, db, repo.db)
 }
+
+func TestGetUserReactions(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	now := time.Now()
+	chatName := "Group Chat"
+
+	rows := sqlmock.NewRows([]string{
+		"id", "reaction_code", "reacted_at",
+		"id", "sender_id", "content", "sent_at",
+		"id", "chat_name", "is_group",
+	}).
+		AddRow("reaction2", "like", now, "msg2", 2, "Hi", now, "chat1", chatName, true).
+		AddRow("reaction1", "heart", now.Add(-time.Hour), "msg1", 3, "Hello", now, "chat2", nil, false)
+
+	mock.ExpectQuery(`SELECT mr.id, mr.reaction_code, mr.reacted_at, .* FROM message_reactions mr .* JOIN chat_participants cp ON cp.chat_id = c.id AND cp.user_id = mr.user_id WHERE mr.user_id = \$1 ORDER BY mr.reacted_at DESC`).
+		WithArgs(1, 20, 0).
+		WillReturnRows(rows)
+
+	reactions, err := repo.GetUserReactions(1, 20, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, reactions, 2)
+	assert.Equal(t, "reaction2", reactions[0].ReactionID)
+	assert.Equal(t, "chat1", reactions[0].ChatID)
+	assert.Equal(t, chatName, *reactions[0].ChatName)
+	assert.Equal(t, "reaction1", reactions[1].ReactionID)
+	assert.Nil(t, reactions[1].ChatName)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetSentMessages(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	now := time.Now()
+	chatName := "Group Chat"
+
+	rows := sqlmock.NewRows([]string{
+		"id", "content", "sent_at", "edited_at", "expires_at",
+		"id", "chat_name", "is_group",
+	}).
+		AddRow("msg2", "Hi", now, nil, nil, "chat1", chatName, true).
+		AddRow("msg1", "Hello", now.Add(-time.Hour), now, nil, "chat2", nil, false)
+
+	mock.ExpectQuery(`SELECT m.id, m.content, m.sent_at, .* FROM messages m .* JOIN chat_participants cp ON cp.chat_id = c.id AND cp.user_id = m.sender_id WHERE m.sender_id = \$1 AND m.deleted_at IS NULL .* ORDER BY m.sent_at DESC`).
+		WithArgs(1, 20, 0).
+		WillReturnRows(rows)
+
+	messages, err := repo.GetSentMessages(1, 20, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 2)
+	assert.Equal(t, "msg2", messages[0].MessageID)
+	assert.Equal(t, chatName, *messages[0].ChatName)
+	assert.Nil(t, messages[0].EditedAt)
+	assert.Equal(t, "chat2", messages[1].ChatID)
+	assert.NotNil(t, messages[1].EditedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetChatMessagesBefore_UsesKeysetAfterCursor(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	cursorSentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(`SELECT sent_at FROM messages WHERE id = \$1 AND chat_id = \$2`).
+		WithArgs("msg-9", "chat-1").
+		WillReturnRows(sqlmock.NewRows([]string{"sent_at"}).AddRow(cursorSentAt))
+	mock.ExpectQuery(`WHERE chat_id = \$1 AND \(sent_at, id\) < \(\$2, \$3\)\s+AND \(expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP\)\s+ORDER BY sent_at DESC, id DESC\s+LIMIT \$4`).
+		WithArgs("chat-1", cursorSentAt, "msg-9", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chat_id", "sender_id", "content", "sent_at", "edited_at", "deleted", "expires_at"}).
+			AddRow("msg-8", "chat-1", 1, "Older", cursorSentAt.Add(-time.Minute), nil, false, nil))
+
+	messages, err := repo.GetChatMessagesBefore("chat-1", "msg-9", 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "msg-8", messages[0].MessageID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetChatMessagesBefore_UnknownCursor(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT sent_at FROM messages WHERE id = \$1 AND chat_id = \$2`).
+		WithArgs("msg-404", "chat-1").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := repo.GetChatMessagesBefore("chat-1", "msg-404", 2)
+
+	assert.EqualError(t, err, apierrors.ErrorInvalidMessageCursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMessage(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(`SELECT id, chat_id, sender_id, content, sent_at, edited_at, deleted_at IS NOT NULL, expires_at\s+FROM messages\s+WHERE chat_id = \$1 AND id = \$2`).
+		WithArgs("chat-1", "msg-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chat_id", "sender_id", "content", "sent_at", "edited_at", "deleted", "expires_at"}).
+			AddRow("msg-1", "chat-1", 1, "Hello", sentAt, nil, false, nil))
+
+	msg, err := repo.GetMessage("chat-1", "msg-1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "msg-1", msg.MessageID)
+	assert.Equal(t, "Hello", msg.Content)
+	assert.Equal(t, sentAt, msg.SentAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetMessage_NotFound(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM messages\s+WHERE chat_id = \$1 AND id = \$2`).
+		WithArgs("chat-2", "msg-1").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := repo.GetMessage("chat-2", "msg-1")
+
+	assert.EqualError(t, err, apierrors.ErrorMessageNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEditMessage_KeepsSentAt(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	editedAt := sentAt.Add(time.Hour)
+	mock.ExpectQuery(`UPDATE messages\s+SET content = \$4, edited_at = CURRENT_TIMESTAMP\s+WHERE chat_id = \$1 AND id = \$2 AND sender_id = \$3`).
+		WithArgs("chat-1", "msg-1", 1, "Edited").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chat_id", "sender_id", "content", "sent_at", "edited_at"}).
+			AddRow("msg-1", "chat-1", 1, "Edited", sentAt, editedAt))
+
+	msg, err := repo.EditMessage("chat-1", "msg-1", 1, "Edited")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Edited", msg.Content)
+	assert.Equal(t, sentAt, msg.SentAt)
+	assert.Equal(t, editedAt, *msg.EditedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestEditMessage_DistinguishesMissingFromForeignMessage(t *testing.T) {
+	tests := []struct {
+		name        string
+		exists      bool
+		expectedErr string
+	}{
+		{name: "Missing message", exists: false, expectedErr: apierrors.ErrorMessageNotFound},
+		{name: "Message of another user", exists: true, expectedErr: apierrors.ErrorNotMessageSender},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			db, mock, repo := setupMock(t)
+			defer db.Close()
+
+			mock.ExpectQuery(`UPDATE messages`).
+				WithArgs("chat-1", "msg-1", 2, "Edited").
+				WillReturnError(sql.ErrNoRows)
+			mock.ExpectQuery(`SELECT EXISTS\(SELECT 1 FROM messages WHERE chat_id = \$1 AND id = \$2 AND deleted_at IS NULL\)`).
+				WithArgs("chat-1", "msg-1").
+				WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(tc.exists))
+
+			_, err := repo.EditMessage("chat-1", "msg-1", 2, "Edited")
+
+			assert.EqualError(t, err, tc.expectedErr)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestDeleteMessage_LeavesTombstone(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	deletedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(`UPDATE messages\s+SET content = '', deleted_at = CURRENT_TIMESTAMP\s+WHERE chat_id = \$1 AND id = \$2 AND sender_id = \$3 AND deleted_at IS NULL\s+RETURNING deleted_at`).
+		WithArgs("chat-1", "msg-1", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"deleted_at"}).AddRow(deletedAt))
+
+	result, err := repo.DeleteMessage("chat-1", "msg-1", 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, deletedAt, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteExpiredMessages(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	expiresAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(`DELETE FROM messages\s+WHERE expires_at <= CURRENT_TIMESTAMP\s+RETURNING id, chat_id, sender_id, expires_at`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chat_id", "sender_id", "expires_at"}).
+			AddRow("msg-1", "chat-1", 1, expiresAt))
+
+	expired, err := repo.DeleteExpiredMessages()
+
+	assert.NoError(t, err)
+	assert.Equal(t, []ExpiredMessage{{MessageID: "msg-1", ChatID: "chat-1", SenderID: 1, ExpiresAt: expiresAt}}, expired)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteMessage_OfAnotherUser(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`UPDATE messages`).
+		WithArgs("chat-1", "msg-1", 2).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`SELECT EXISTS`).
+		WithArgs("chat-1", "msg-1").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	_, err := repo.DeleteMessage("chat-1", "msg-1", 2)
+
+	assert.EqualError(t, err, apierrors.ErrorNotMessageSender)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchMessages_EscapesWildcardsAndSkipsDeleted(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	sentAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	mock.ExpectQuery(`content ILIKE '%' \|\| \$2 \|\| '%' ESCAPE '\\'\s+AND deleted_at IS NULL`).
+		WithArgs("chat1", `100\%\_done`, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "chat_id", "sender_id", "content", "sent_at", "edited_at", "deleted", "expires_at"}).
+			AddRow("msg1", "chat1", 1, "100%_done!", sentAt, nil, false, nil))
+
+	messages, err := repo.SearchMessages("chat1", "100%_done", 20, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	assert.Equal(t, "msg1", messages[0].MessageID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetChatOverviews_SkipsDeletedAndExpiredMessages(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{
+		"id", "chat_name", "created_at", "is_group",
+		"id", "sender_id", "content", "sent_at",
+		"unread_count",
+		"user_ids", "full_names",
+	}).AddRow("chat1", nil, now, false, nil, nil, nil, nil, 0, "{1,2}", "{Alice,Bob}")
+
+	// Neither the latest message nor the unread count may include tombstones or expired messages
+	live := `AND m\.deleted_at IS NULL AND \(m\.expires_at IS NULL OR m\.expires_at > CURRENT_TIMESTAMP\)`
+	mock.ExpectQuery(`WHERE m\.chat_id = c\.id `+live+` ORDER BY m\.seq DESC LIMIT 1 \) lm ON TRUE`+
+		`.*AND m\.seq > COALESCE\(rr\.last_read_seq, 0\) `+live+` \) uc ON TRUE`).
+		WithArgs(1, 20, 0).
+		WillReturnRows(rows)
+
+	overviews, err := repo.GetChatOverviews(1, 20, 0)
+
+	assert.NoError(t, err)
+	require.Len(t, overviews, 1)
+	assert.Nil(t, overviews[0].LastMessage)
+	assert.Equal(t, 0, overviews[0].UnreadCount)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetReactionSummaries_MessageIDSharedWithAnotherChat(t *testing.T) {
+	db, mock, repo := setupMock(t)
+	defer db.Close()
+
+	// msg-1 also exists in chat-2, its reactions must not be counted here
+	mock.ExpectQuery(`FROM message_reactions mr WHERE mr\.chat_id = \$1 AND mr\.message_id = ANY\(\$2\) GROUP BY`).
+		WithArgs("chat-1", pq.Array([]string{"msg-1"}), 1).
+		WillReturnRows(sqlmock.NewRows([]string{"message_id", "reaction_code", "count", "reacted"}).
+			AddRow("msg-1", "like", 2, true))
+
+	summaries, err := repo.GetReactionSummaries("chat-1", []string{"msg-1"}, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]ReactionSummary{
+		"msg-1": {{ReactionCode: "like", Count: 2, Reacted: true}},
+	}, summaries)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
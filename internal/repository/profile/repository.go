@@ -18,6 +18,8 @@ var (
 	ErrInvalidGender    = errors.New("invalid gender")
 	ErrInvalidCity      = errors.New("invalid city")
 	ErrInvalidMediaRole = errors.New("invalid media role")
+	ErrInvalidCatalog   = errors.New("invalid catalog type")
+	ErrInvalidSort      = errors.New("invalid sort order")
 )
 
 var (
@@ -36,8 +38,12 @@ type ProfileModel struct {
 	Goal           string
 	LookingForTeam bool
 	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	LastSeenAt     *time.Time // Nil until the user is first seen active
+	DeletedAt      *time.Time // Set while the profile is soft-deleted
 	Avatar         *int
 	Videos         []int
+	DistanceKm     *float64 // Set by searches near a point
 }
 
 // UpdateProfileModel represents the updated profile data
@@ -54,6 +60,18 @@ type UpdateProfileModel struct {
 	Videos         []int
 }
 
+// StyleCount is the number of profiles that list an improv style
+type StyleCount struct {
+	Style string
+	Count int
+}
+
+// FeedPosition is the position of a profile in the new profiles feed
+type FeedPosition struct {
+	CreatedAt time.Time
+	UserID    int
+}
+
 // TranslatedItem represents a catalog item with translations
 type TranslatedItem struct {
 	Code        string
@@ -61,6 +79,26 @@ type TranslatedItem struct {
 	Description string
 }
 
+// CatalogTranslations represents a catalog item with its labels keyed by language
+type CatalogTranslations struct {
+	Code   string
+	Labels map[string]string
+}
+
+// catalogTable describes where a translated catalog is stored
+type catalogTable struct {
+	catalog     string
+	translation string
+	codeColumn  string
+}
+
+// translatedCatalogs maps catalog types to their tables
+var translatedCatalogs = map[string]catalogTable{
+	"improv-styles": {catalog: "improv_style_catalog", translation: "improv_style_translation", codeColumn: "style_code"},
+	"improv-goals":  {catalog: "improv_goals_catalog", translation: "improv_goals_translation", codeColumn: "goal_id"},
+	"genders":       {catalog: "gender_catalog", translation: "gender_catalog_translation", codeColumn: "gender_code"},
+}
+
 // PostgresRepository implements Repository interface
 type PostgresRepository struct {
 	db *sql.DB
@@ -125,12 +163,13 @@ func (r *PostgresRepository) GetProfile(userID int) (*ProfileModel, error) {
 	profile := &ProfileModel{}
 	err := r.db.QueryRow(`
         SELECT user_id, full_name, birthday, gender, city_id, 
-               bio, goal, looking_for_team, created_at 
+               bio, goal, looking_for_team, created_at, updated_at, last_seen_at, deleted_at
         FROM profiles WHERE user_id = $1
     `, userID).Scan(
 		&profile.UserID, &profile.FullName, &profile.Birthday,
 		&profile.Gender, &profile.CityID, &profile.Bio,
-		&profile.Goal, &profile.LookingForTeam, &profile.CreatedAt)
+		&profile.Goal, &profile.LookingForTeam, &profile.CreatedAt, &profile.UpdatedAt,
+		&profile.LastSeenAt, &profile.DeletedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -178,11 +217,12 @@ func (r *PostgresRepository) GetProfileAvatar(userID int) (*int, error) {
 	return &mediaID, nil
 }
 
-// GetProfileVideos retrieves videos for a profile
+// GetProfileVideos retrieves videos for a profile in gallery order
 func (r *PostgresRepository) GetProfileVideos(userID int) ([]int, error) {
 	rows, err := r.db.Query(`
-        SELECT media_id FROM profile_media 
+        SELECT media_id FROM profile_media
         WHERE user_id = $1 AND role = 'video'
+        ORDER BY position, media_id
     `, userID)
 	if err != nil {
 		return nil, err
@@ -204,9 +244,9 @@ func (r *PostgresRepository) GetProfileVideos(userID int) ([]int, error) {
 func (r *PostgresRepository) SetProfileVideos(tx *sql.Tx, userID int, videos []int) error {
 	// Remove existing videos
 	r.RemoveProfileMediaByRole(tx, userID, roleVideo)
-	// Add new videos
-	for _, videoID := range videos {
-		err := r.addProfileMedia(tx, userID, videoID, "video")
+	// Add new videos, keeping the given order
+	for position, videoID := range videos {
+		err := r.addProfileMedia(tx, userID, videoID, "video", position)
 		if err != nil {
 			return err
 		}
@@ -214,12 +254,27 @@ func (r *PostgresRepository) SetProfileVideos(tx *sql.Tx, userID int, videos []i
 	return nil
 }
 
-// addProfileMedia adds media to a profile with the specified role
-func (r *PostgresRepository) addProfileMedia(tx *sql.Tx, userID int, mediaID int, role string) error {
+// SetProfileVideoPositions rewrites the gallery order of the profile videos to the
+// order of the given IDs
+func (r *PostgresRepository) SetProfileVideoPositions(tx *sql.Tx, userID int, videos []int) error {
+	for position, videoID := range videos {
+		_, err := tx.Exec(`
+            UPDATE profile_media SET position = $3
+            WHERE user_id = $1 AND media_id = $2 AND role = 'video'
+        `, userID, videoID, position)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addProfileMedia adds media to a profile with the specified role and gallery position
+func (r *PostgresRepository) addProfileMedia(tx *sql.Tx, userID int, mediaID int, role string, position int) error {
 	_, err := tx.Exec(`
-        INSERT INTO profile_media (user_id, media_id, role)
-        VALUES ($1, $2, $3)
-    `, userID, mediaID, role)
+        INSERT INTO profile_media (user_id, media_id, role, position)
+        VALUES ($1, $2, $3, $4)
+    `, userID, mediaID, role, position)
 	return err
 }
 
@@ -245,7 +300,7 @@ func (r *PostgresRepository) SetProfileAvatar(tx *sql.Tx, userID int, mediaID in
 		return err
 	}
 	// Add new avatar
-	return r.addProfileMedia(tx, userID, mediaID, "avatar")
+	return r.addProfileMedia(tx, userID, mediaID, "avatar", 0)
 }
 
 // RemoveProfileMedia removes specific media from a profile
@@ -285,7 +340,9 @@ func (r *PostgresRepository) GetImprovStyles(userID int) ([]string, error) {
 	return styles, rows.Err()
 }
 
-// UpdateProfile updates a profile, only changing fields that are not nil in the update model
+// UpdateProfile updates a profile, only changing fields that are not nil in the update model.
+// updated_at is always bumped, so an update without fields marks changes made elsewhere
+// in the transaction, such as styles or media.
 func (r *PostgresRepository) UpdateProfile(tx *sql.Tx, profile *UpdateProfileModel) error {
 	// Start with base query
 	query := "UPDATE profiles SET "
@@ -338,10 +395,7 @@ func (r *PostgresRepository) UpdateProfile(tx *sql.Tx, profile *UpdateProfileMod
 		paramPositions = append(paramPositions, fmt.Sprintf("looking_for_team = $%d", paramCount))
 	}
 
-	// If no parameters were provided, return without executing query
-	if len(params) == 0 {
-		return nil
-	}
+	paramPositions = append(paramPositions, "updated_at = CURRENT_TIMESTAMP")
 
 	// Add all parameters to the query
 	query += strings.Join(paramPositions, ", ")
@@ -356,6 +410,70 @@ func (r *PostgresRepository) UpdateProfile(tx *sql.Tx, profile *UpdateProfileMod
 	return err
 }
 
+// SetLookingForTeam updates only the looking_for_team flag of a profile
+func (r *PostgresRepository) SetLookingForTeam(userID int, lookingForTeam bool) error {
+	result, err := r.db.Exec(`UPDATE profiles SET looking_for_team = $1, updated_at = CURRENT_TIMESTAMP WHERE user_id = $2 AND deleted_at IS NULL`, lookingForTeam, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrProfileNotExists
+	}
+	return nil
+}
+
+// UpdateLastSeen records when the user was last active. Users without a profile
+// have nowhere to store it and are skipped. An older time never overwrites a newer one.
+func (r *PostgresRepository) UpdateLastSeen(userID int, at time.Time) error {
+	_, err := r.db.Exec(`
+        UPDATE profiles SET last_seen_at = $2
+        WHERE user_id = $1 AND (last_seen_at IS NULL OR last_seen_at < $2)
+    `, userID, at)
+	return err
+}
+
+// SoftDeleteProfile hides the profile from other users without removing its data
+func (r *PostgresRepository) SoftDeleteProfile(userID int, at time.Time) error {
+	result, err := r.db.Exec(`UPDATE profiles SET deleted_at = $2 WHERE user_id = $1 AND deleted_at IS NULL`, userID, at)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrProfileNotExists
+	}
+	return nil
+}
+
+// RestoreProfile undoes a soft delete made after deletedAfter
+func (r *PostgresRepository) RestoreProfile(userID int, deletedAfter time.Time) error {
+	result, err := r.db.Exec(`
+        UPDATE profiles SET deleted_at = NULL
+        WHERE user_id = $1 AND deleted_at > $2
+    `, userID, deletedAfter)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrProfileNotExists
+	}
+	return nil
+}
+
 // ClearImprovStyles removes all styles from a profile
 func (r *PostgresRepository) ClearImprovStyles(tx *sql.Tx, userID int) error {
 	_, err := tx.Exec(`DELETE FROM improv_profile_styles WHERE user_id = $1`, userID)
@@ -428,6 +546,37 @@ func (r *PostgresRepository) GetImprovStylesCatalog(lang string) ([]TranslatedIt
 	return items, rows.Err()
 }
 
+// GetImprovStyleCounts counts the profiles created since the given time per improv style.
+// Users that userID has blocked or was blocked by are not counted.
+func (r *PostgresRepository) GetImprovStyleCounts(userID int, since time.Time) ([]StyleCount, error) {
+	rows, err := r.db.Query(`
+        SELECT ips.style, COUNT(*)
+        FROM improv_profile_styles ips
+        JOIN profiles p ON p.user_id = ips.user_id
+        WHERE p.created_at >= $2 AND p.deleted_at IS NULL
+          AND NOT EXISTS (
+              SELECT 1 FROM user_blocks ub
+              WHERE (ub.blocker_id = $1 AND ub.blocked_id = p.user_id)
+                 OR (ub.blocker_id = p.user_id AND ub.blocked_id = $1)
+          )
+        GROUP BY ips.style
+    `, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []StyleCount
+	for rows.Next() {
+		var count StyleCount
+		if err := rows.Scan(&count.Style, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
 // GetImprovGoalsCatalog retrieves improv goals catalog
 func (r *PostgresRepository) GetImprovGoalsCatalog(lang string) ([]TranslatedItem, error) {
 	if lang == "" {
@@ -482,6 +631,67 @@ func (r *PostgresRepository) GetGendersCatalog(lang string) ([]TranslatedItem, e
 	return items, rows.Err()
 }
 
+// GetCatalogTranslations retrieves every item of a catalog with its labels in the given languages.
+// Languages without a translation are absent from the item's labels.
+func (r *PostgresRepository) GetCatalogTranslations(catalogType string, langs []string) ([]CatalogTranslations, error) {
+	table, ok := translatedCatalogs[catalogType]
+	if !ok {
+		return nil, ErrInvalidCatalog
+	}
+
+	// Pivot translations into one column per language
+	columns := make([]string, len(langs))
+	args := make([]interface{}, len(langs))
+	for i, lang := range langs {
+		columns[i] = fmt.Sprintf("MAX(t.label) FILTER (WHERE t.lang = $%d)", i+1)
+		args[i] = lang
+	}
+
+	selectColumns := "c." + table.codeColumn
+	if len(columns) > 0 {
+		selectColumns += ", " + strings.Join(columns, ", ")
+	}
+
+	query := fmt.Sprintf(`
+        SELECT %s
+        FROM %s c
+        LEFT JOIN %s t ON c.%s = t.%s
+        GROUP BY c.%s
+        ORDER BY c.%s
+    `, selectColumns, table.catalog, table.translation, table.codeColumn, table.codeColumn, table.codeColumn, table.codeColumn)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var items []CatalogTranslations
+	for rows.Next() {
+		labels := make([]sql.NullString, len(langs))
+		dest := make([]interface{}, 0, len(langs)+1)
+
+		var item CatalogTranslations
+		dest = append(dest, &item.Code)
+		for i := range labels {
+			dest = append(dest, &labels[i])
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		item.Labels = make(map[string]string)
+		for i, label := range labels {
+			if label.Valid {
+				item.Labels[langs[i]] = label.String
+			}
+		}
+		items = append(items, item)
+	}
+	return items, rows.Err()
+}
+
 // GetCities retrieves available cities
 func (r *PostgresRepository) GetCities() ([]struct {
 	ID   int
@@ -512,7 +722,57 @@ func (r *PostgresRepository) GetCities() ([]struct {
 	return cities, rows.Err()
 }
 
-// SearchProfiles searches for profiles and sorts them based on matching improv styles
+// Search result orderings
+const (
+	SortCreatedAtDesc = "created_at_desc"
+	SortCreatedAtAsc  = "created_at_asc"
+	SortAgeAsc        = "age_asc"
+	SortAgeDesc       = "age_desc"
+	SortRelevance     = "relevance"
+	SortDistance      = "distance_asc" // Requires a NearFilter
+	SortLastSeenDesc  = "last_seen_desc"
+)
+
+// SortOrders lists the supported search orderings, the default first
+var SortOrders = []string{
+	SortCreatedAtDesc,
+	SortCreatedAtAsc,
+	SortAgeAsc,
+	SortAgeDesc,
+	SortRelevance,
+	SortDistance,
+	SortLastSeenDesc,
+}
+
+// NearFilter restricts a search to profiles whose city is within the radius of a point
+type NearFilter struct {
+	Lat      float64
+	Lng      float64
+	RadiusKm float64
+}
+
+// searchOrderClauses maps search orderings to ORDER BY clauses over profile_matches.
+// The user ID keeps the order stable between pages.
+var searchOrderClauses = map[string]string{
+	SortCreatedAtDesc: "created_at DESC, user_id DESC",
+	SortCreatedAtAsc:  "created_at ASC, user_id ASC",
+	SortAgeAsc:        "birthday DESC NULLS LAST, user_id DESC",
+	SortAgeDesc:       "birthday ASC NULLS LAST, user_id DESC",
+	SortRelevance:     "style_match_count DESC, created_at DESC, user_id DESC",
+	SortDistance:      "distance_km ASC NULLS LAST, user_id DESC",
+	SortLastSeenDesc:  "last_seen_at DESC NULLS LAST, user_id DESC",
+}
+
+// IsValidSort reports whether the search ordering is supported
+func IsValidSort(sortBy string) bool {
+	_, ok := searchOrderClauses[sortBy]
+	return ok
+}
+
+// SearchProfiles searches for profiles and sorts them in the given order.
+// Relevance is the number of improv styles shared with the current user.
+// With a near filter the distance is measured between city centres using the
+// haversine formula, profiles in cities without coordinates don't match.
 func (r *PostgresRepository) SearchProfiles(
 	currentUserID int,
 	fullName *string,
@@ -521,14 +781,40 @@ func (r *PostgresRepository) SearchProfiles(
 	improvStyles []string,
 	birthDateMin *time.Time,
 	birthDateMax *time.Time,
+	includeUnknownAge bool,
 	genders []string,
 	cityID *int,
+	near *NearFilter,
 	hasAvatar *bool,
 	hasVideo *bool,
+	hasMedia *bool,
 	createdAfter *time.Time,
+	sortBy string,
 	page int,
 	pageSize int,
 ) ([]*ProfileModel, int, error) {
+	orderBy, ok := searchOrderClauses[sortBy]
+	if !ok {
+		return nil, 0, ErrInvalidSort
+	}
+
+	// Build WHERE clause
+	conditions := []string{}
+	args := []interface{}{currentUserID} // First argument is current user ID
+	argIndex := 2
+
+	// The distance is computed in the CTE so it can be returned and sorted by
+	distance := "NULL::double precision"
+	if near != nil {
+		distance = fmt.Sprintf(`(2 * 6371 * ASIN(SQRT(
+                    POWER(SIN(RADIANS(pc.latitude - $%[1]d) / 2), 2) +
+                    COS(RADIANS($%[1]d)) * COS(RADIANS(pc.latitude)) *
+                    POWER(SIN(RADIANS(pc.longitude - $%[2]d) / 2), 2)
+                )))`, argIndex, argIndex+1)
+		args = append(args, near.Lat, near.Lng)
+		argIndex += 2
+	}
+
 	// Start building the query
 	baseQuery := `
         WITH current_user_styles AS (
@@ -550,7 +836,10 @@ func (r *PostgresRepository) SearchProfiles(
                     FROM improv_profile_styles ips
                     JOIN current_user_styles cus ON ips.style = cus.style
                     WHERE ips.user_id = p.user_id
-                ) AS style_match_count
+                ) AS style_match_count,
+                ` + distance + ` AS distance_km,
+                p.last_seen_at,
+                p.updated_at
             FROM profiles p
     `
 
@@ -600,19 +889,26 @@ func (r *PostgresRepository) SearchProfiles(
 		}
 	}
 
+	// For the near filter
+	if near != nil {
+		joins = append(joins, "JOIN cities pc ON pc.city_id = p.city_id")
+	}
+
 	// Add all joins to the queries
 	for _, join := range joins {
 		baseQuery += " " + join
 		countQuery += " " + join
 	}
 
-	// Build WHERE clause
-	conditions := []string{}
-	args := []interface{}{currentUserID} // First argument is current user ID
-	argIndex := 2
+	// Exclude current user and soft-deleted profiles from results
+	conditions = append(conditions, "p.user_id <> $1", "p.deleted_at IS NULL")
 
-	// Exclude current user from results
-	conditions = append(conditions, "p.user_id <> $1")
+	// Exclude users the current user has blocked or was blocked by
+	conditions = append(conditions, `NOT EXISTS (
+                SELECT 1 FROM user_blocks ub
+                WHERE (ub.blocker_id = $1 AND ub.blocked_id = p.user_id)
+                   OR (ub.blocker_id = p.user_id AND ub.blocked_id = $1)
+            )`)
 
 	// Full name search (using ILIKE for case-insensitive search)
 	if fullName != nil && *fullName != "" {
@@ -650,17 +946,26 @@ func (r *PostgresRepository) SearchProfiles(
 		}
 	}
 
-	// Age range filter (converted to birthday range)
+	// Age range filter (converted to birthday range). Profiles without a birthday
+	// don't match a range unless includeUnknownAge is set.
+	var ageConditions []string
 	if birthDateMin != nil {
-		conditions = append(conditions, fmt.Sprintf("p.birthday >= $%d", argIndex))
+		ageConditions = append(ageConditions, fmt.Sprintf("p.birthday >= $%d", argIndex))
 		args = append(args, *birthDateMin)
 		argIndex++
 	}
 	if birthDateMax != nil {
-		conditions = append(conditions, fmt.Sprintf("p.birthday <= $%d", argIndex))
+		ageConditions = append(ageConditions, fmt.Sprintf("p.birthday <= $%d", argIndex))
 		args = append(args, *birthDateMax)
 		argIndex++
 	}
+	if len(ageConditions) > 0 {
+		ageCondition := strings.Join(ageConditions, " AND ")
+		if includeUnknownAge {
+			ageCondition = "p.birthday IS NULL OR " + ageCondition
+		}
+		conditions = append(conditions, "("+ageCondition+")")
+	}
 
 	// Genders filter - ANY of the specified values (OR logic)
 	if len(genders) > 0 {
@@ -680,6 +985,13 @@ func (r *PostgresRepository) SearchProfiles(
 		argIndex++
 	}
 
+	// Near filter, the radius is inclusive
+	if near != nil {
+		conditions = append(conditions, fmt.Sprintf("%s <= $%d", distance, argIndex))
+		args = append(args, near.RadiusKm)
+		argIndex++
+	}
+
 	// Has avatar filter (if NOT included in joins)
 	if hasAvatar != nil && !*hasAvatar {
 		conditions = append(conditions, "pa.media_id IS NULL")
@@ -690,6 +1002,15 @@ func (r *PostgresRepository) SearchProfiles(
 		conditions = append(conditions, "pv.media_id IS NULL")
 	}
 
+	// Has media filter, any media role counts
+	if hasMedia != nil {
+		exists := "EXISTS (SELECT 1 FROM profile_media pm WHERE pm.user_id = p.user_id)"
+		if !*hasMedia {
+			exists = "NOT " + exists
+		}
+		conditions = append(conditions, exists)
+	}
+
 	// Add createdAfter condition to the WHERE clause if provided
 	if createdAfter != nil {
 		conditions = append(conditions, fmt.Sprintf("p.created_at >= $%d", argIndex))
@@ -704,8 +1025,8 @@ func (r *PostgresRepository) SearchProfiles(
 		countQuery += whereClause
 	}
 
-	// Close the CTE and add ORDER BY for style matches
-	baseQuery += `) SELECT * FROM profile_matches ORDER BY style_match_count DESC, created_at DESC`
+	// Close the CTE and add the requested ordering
+	baseQuery += `) SELECT * FROM profile_matches ORDER BY ` + orderBy
 	countQuery += `) SELECT COUNT(*) FROM profile_matches`
 
 	// Get total count
@@ -735,7 +1056,7 @@ func (r *PostgresRepository) SearchProfiles(
 			&profile.UserID, &profile.FullName, &profile.Birthday,
 			&profile.Gender, &profile.CityID, &profile.Bio,
 			&profile.Goal, &profile.LookingForTeam, &profile.CreatedAt,
-			&styleMatchCount,
+			&styleMatchCount, &profile.DistanceKm, &profile.LastSeenAt, &profile.UpdatedAt,
 		); err != nil {
 			return nil, 0, err
 		}
@@ -761,3 +1082,69 @@ func (r *PostgresRepository) SearchProfiles(
 
 	return profiles, totalCount, nil
 }
+
+// GetNewProfiles returns the most recently created profiles, newest first, leaving out
+// users that userID has blocked or was blocked by.
+// If after is set, only profiles that follow that position in the feed are returned.
+func (r *PostgresRepository) GetNewProfiles(userID int, after *FeedPosition, limit int) ([]*ProfileModel, error) {
+	query := `
+        SELECT p.user_id, p.full_name, p.birthday, p.gender, p.city_id,
+               p.bio, p.goal, p.looking_for_team, p.created_at, p.updated_at, p.last_seen_at
+        FROM profiles p
+        WHERE p.deleted_at IS NULL
+          AND NOT EXISTS (
+              SELECT 1 FROM user_blocks ub
+              WHERE (ub.blocker_id = $1 AND ub.blocked_id = p.user_id)
+                 OR (ub.blocker_id = p.user_id AND ub.blocked_id = $1)
+          )
+    `
+	args := []interface{}{userID}
+
+	// Keyset pagination, the user ID breaks ties between equal creation times
+	if after != nil {
+		query += ` AND (p.created_at, p.user_id) < ($2, $3)`
+		args = append(args, after.CreatedAt, after.UserID)
+	}
+
+	query += fmt.Sprintf(` ORDER BY p.created_at DESC, p.user_id DESC LIMIT $%d`, len(args)+1)
+	args = append(args, limit)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	profiles := []*ProfileModel{}
+	for rows.Next() {
+		profile := &ProfileModel{}
+		if err := rows.Scan(
+			&profile.UserID, &profile.FullName, &profile.Birthday,
+			&profile.Gender, &profile.CityID, &profile.Bio,
+			&profile.Goal, &profile.LookingForTeam, &profile.CreatedAt, &profile.UpdatedAt,
+			&profile.LastSeenAt,
+		); err != nil {
+			return nil, err
+		}
+		profiles = append(profiles, profile)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, profile := range profiles {
+		// Get avatar
+		avatar, err := r.GetProfileAvatar(profile.UserID)
+		if err == nil && avatar != nil {
+			profile.Avatar = avatar
+		}
+
+		// Get videos
+		videos, err := r.GetProfileVideos(profile.UserID)
+		if err == nil {
+			profile.Videos = videos
+		}
+	}
+
+	return profiles, nil
+}
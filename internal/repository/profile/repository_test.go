@@ -2,8 +2,10 @@ package profile
 
 import (
 	"database/sql"
+	"errors"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -48,7 +50,7 @@ func TestGetProfile_NotFound(t *testing.T) {
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
         SELECT user_id, full_name, birthday, gender, city_id, 
-               bio, goal, looking_for_team, created_at 
+               bio, goal, looking_for_team, created_at, updated_at, last_seen_at, deleted_at
         FROM profiles WHERE user_id = $1
     `)).
 		WithArgs(3).
@@ -59,6 +61,32 @@ func TestGetProfile_NotFound(t *testing.T) {
 	assert.Equal(t, ErrProfileNotExists, err)
 }
 
+func TestUpdateLastSeen_NeverMovesBackwards(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE profiles SET last_seen_at = $2 WHERE user_id = $1 AND (last_seen_at IS NULL OR last_seen_at < $2)`)).
+		WithArgs(3, at).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.UpdateLastSeen(3, at))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreProfile_OutsideGraceWindow(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	deletedAfter := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE profiles SET deleted_at = NULL WHERE user_id = $1 AND deleted_at > $2`)).
+		WithArgs(3, deletedAfter).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.Equal(t, ErrProfileNotExists, repo.RestoreProfile(3, deletedAfter))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestGetProfileAvatar_NoAvatar(t *testing.T) {
 	db, mock, repo := setupMockDB(t)
 	defer db.Close()
@@ -76,6 +104,59 @@ func TestGetProfileAvatar_NoAvatar(t *testing.T) {
 	assert.Nil(t, avatar)
 }
 
+func TestGetProfileVideos_InGalleryOrder(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT media_id FROM profile_media\s+WHERE user_id = \$1 AND role = 'video'\s+ORDER BY position, media_id`).
+		WithArgs(4).
+		WillReturnRows(sqlmock.NewRows([]string{"media_id"}).AddRow(12).AddRow(10).AddRow(11))
+
+	videos, err := repo.GetProfileVideos(4)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{12, 10, 11}, videos)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetProfileVideos_StoresPositions(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+
+	mock.ExpectExec(`DELETE FROM profile_media`).
+		WithArgs(4, "video").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	for position, mediaID := range []int{12, 10} {
+		mock.ExpectExec(`INSERT INTO profile_media \(user_id, media_id, role, position\)`).
+			WithArgs(4, mediaID, "video", position).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+
+	assert.NoError(t, repo.SetProfileVideos(tx, 4, []int{12, 10}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetProfileVideoPositions(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+
+	for position, mediaID := range []int{11, 10, 12} {
+		mock.ExpectExec(`UPDATE profile_media SET position = \$3\s+WHERE user_id = \$1 AND media_id = \$2 AND role = 'video'`).
+			WithArgs(4, mediaID, position).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+
+	assert.NoError(t, repo.SetProfileVideoPositions(tx, 4, []int{11, 10, 12}))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestAddImprovStyles(t *testing.T) {
 	db, mock, repo := setupMockDB(t)
 	defer db.Close()
@@ -102,6 +183,36 @@ func TestAddImprovStyles(t *testing.T) {
 	tx.Rollback()
 }
 
+func TestAddImprovStyles_StopsAtFailedInsert(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+            INSERT INTO improv_profile_styles (user_id, style)
+            VALUES ($1, $2)
+        `)).
+		WithArgs(5, "style1").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`
+            INSERT INTO improv_profile_styles (user_id, style)
+            VALUES ($1, $2)
+        `)).
+		WithArgs(5, "style2").
+		WillReturnError(errors.New("insert failed"))
+	mock.ExpectRollback()
+
+	err = repo.AddImprovStyles(tx, 5, []string{"style1", "style2", "style3"})
+	assert.Error(t, err)
+
+	// The third style is never inserted and the caller can roll back
+	assert.NoError(t, tx.Rollback())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestUpdateProfile_NoFields(t *testing.T) {
 	db, mock, repo := setupMockDB(t)
 	defer db.Close()
@@ -110,10 +221,16 @@ func TestUpdateProfile_NoFields(t *testing.T) {
 	tx, err := db.Begin()
 	assert.NoError(t, err)
 
+	// An update without fields still marks the profile as changed
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE profiles SET updated_at = CURRENT_TIMESTAMP WHERE user_id = $1")).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
 	update := &UpdateProfileModel{UserID: 1}
 	err = repo.UpdateProfile(tx, update)
 	assert.NoError(t, err)
 	tx.Rollback()
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
 func TestUpdateProfile_WithFields(t *testing.T) {
@@ -127,7 +244,7 @@ func TestUpdateProfile_WithFields(t *testing.T) {
 	fullName := "Test User"
 	update := &UpdateProfileModel{UserID: 1, FullName: &fullName}
 
-	mock.ExpectExec(regexp.QuoteMeta("UPDATE profiles SET full_name = $1 WHERE user_id = $2")).
+	mock.ExpectExec(regexp.QuoteMeta("UPDATE profiles SET full_name = $1, updated_at = CURRENT_TIMESTAMP WHERE user_id = $2")).
 		WithArgs(fullName, 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
@@ -169,3 +286,253 @@ func TestGetImprovStylesCatalog(t *testing.T) {
 	assert.Equal(t, "style1", items[0].Code)
 	assert.Equal(t, "Style 1", items[0].Label)
 }
+
+func TestGetCatalogTranslations(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`
+        SELECT c.gender_code, MAX(t.label) FILTER (WHERE t.lang = $1), MAX(t.label) FILTER (WHERE t.lang = $2)
+        FROM gender_catalog c
+        LEFT JOIN gender_catalog_translation t ON c.gender_code = t.gender_code
+        GROUP BY c.gender_code
+        ORDER BY c.gender_code
+    `)).
+		WithArgs("ru", "en").
+		WillReturnRows(sqlmock.NewRows([]string{"gender_code", "ru", "en"}).
+			AddRow("female", "Женщина", "Female").
+			AddRow("male", "Мужчина", nil))
+
+	items, err := repo.GetCatalogTranslations("genders", []string{"ru", "en"})
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, map[string]string{"ru": "Женщина", "en": "Female"}, items[0].Labels)
+	assert.Equal(t, map[string]string{"ru": "Мужчина"}, items[1].Labels)
+}
+
+func TestGetCatalogTranslations_InvalidCatalog(t *testing.T) {
+	db, _, repo := setupMockDB(t)
+	defer db.Close()
+
+	items, err := repo.GetCatalogTranslations("cities", []string{"ru"})
+	assert.Nil(t, items)
+	assert.Equal(t, ErrInvalidCatalog, err)
+}
+
+func TestGetNewProfiles_NewestFirstAfterCursor(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	cursorTime := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	newer := cursorTime.Add(-time.Minute)
+	older := cursorTime.Add(-time.Hour)
+	birthday := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`AND (p.created_at, p.user_id) < ($2, $3) ORDER BY p.created_at DESC, p.user_id DESC LIMIT $4`)).
+		WithArgs(1, cursorTime, 10, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "full_name", "birthday", "gender", "city_id", "bio", "goal", "looking_for_team", "created_at", "updated_at", "last_seen_at"}).
+			AddRow(7, "Newer", birthday, "male", 1, "", "hobby", false, newer, newer, newer).
+			AddRow(3, "Older", birthday, "female", 1, "", "hobby", true, older, older, nil))
+	for _, userID := range []int{7, 3} {
+		mock.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = $1 AND role = 'avatar'`)).
+			WithArgs(userID).
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = $1 AND role = 'video'`)).
+			WithArgs(userID).
+			WillReturnRows(sqlmock.NewRows([]string{"media_id"}))
+	}
+
+	profiles, err := repo.GetNewProfiles(1, &FeedPosition{CreatedAt: cursorTime, UserID: 10}, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{7, 3}, []int{profiles[0].UserID, profiles[1].UserID})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetNewProfiles_ExcludesBlockedUsers(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(`WHERE p\.deleted_at IS NULL AND NOT EXISTS \( SELECT 1 FROM user_blocks ub `+
+		`WHERE \(ub\.blocker_id = \$1 AND ub\.blocked_id = p\.user_id\) OR \(ub\.blocker_id = p\.user_id AND ub\.blocked_id = \$1\) \) `+
+		`ORDER BY p\.created_at DESC, p\.user_id DESC LIMIT \$2`).
+		WithArgs(1, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "full_name", "birthday", "gender", "city_id", "bio", "goal", "looking_for_team", "created_at", "updated_at", "last_seen_at"}))
+
+	profiles, err := repo.GetNewProfiles(1, nil, 20)
+
+	assert.NoError(t, err)
+	assert.Empty(t, profiles)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetImprovStyleCounts_ExcludesBlockedUsers(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	since := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectQuery(`WHERE p\.created_at >= \$2 AND p\.deleted_at IS NULL AND NOT EXISTS \( SELECT 1 FROM user_blocks ub `+
+		`WHERE \(ub\.blocker_id = \$1 AND ub\.blocked_id = p\.user_id\) OR \(ub\.blocker_id = p\.user_id AND ub\.blocked_id = \$1\) \) `+
+		`GROUP BY ips\.style`).
+		WithArgs(1, since).
+		WillReturnRows(sqlmock.NewRows([]string{"style", "count"}).AddRow("longform", 2))
+
+	counts, err := repo.GetImprovStyleCounts(1, since)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []StyleCount{{Style: "longform", Count: 2}}, counts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchProfiles_OrdersBySort(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM profile_matches`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT * FROM profile_matches ORDER BY birthday DESC NULLS LAST, user_id DESC LIMIT $2 OFFSET $3`)).
+		WithArgs(1, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "full_name", "birthday", "gender", "city_id", "bio", "goal", "looking_for_team", "created_at", "style_match_count", "distance_km", "last_seen_at", "updated_at"}))
+
+	profiles, total, err := repo.SearchProfiles(1, nil, nil, nil, nil, nil, nil, false, nil, nil, nil, nil, nil, nil, nil, SortAgeAsc, 1, 20)
+
+	assert.NoError(t, err)
+	assert.Empty(t, profiles)
+	assert.Equal(t, 0, total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchProfiles_ExcludesBlockedUsers(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	blocked := `(ub.blocker_id = $1 AND ub.blocked_id = p.user_id)
+                   OR (ub.blocker_id = p.user_id AND ub.blocked_id = $1)`
+	mock.ExpectQuery(regexp.QuoteMeta(blocked) + `(?s).*` + regexp.QuoteMeta(`SELECT COUNT(*) FROM profile_matches`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(blocked)+`(?s).*`+regexp.QuoteMeta(`SELECT * FROM profile_matches`)).
+		WithArgs(1, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "full_name", "birthday", "gender", "city_id", "bio", "goal", "looking_for_team", "created_at", "style_match_count", "distance_km", "last_seen_at", "updated_at"}))
+
+	_, _, err := repo.SearchProfiles(1, nil, nil, nil, nil, nil, nil, false, nil, nil, nil, nil, nil, nil, nil, SortCreatedAtDesc, 1, 20)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchProfiles_ExcludesDeletedProfiles(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE p.user_id <> $1 AND p.deleted_at IS NULL`) + `(?s).*` + regexp.QuoteMeta(`SELECT COUNT(*) FROM profile_matches`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE p.user_id <> $1 AND p.deleted_at IS NULL`)+`(?s).*`+regexp.QuoteMeta(`SELECT * FROM profile_matches`)).
+		WithArgs(1, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "full_name", "birthday", "gender", "city_id", "bio", "goal", "looking_for_team", "created_at", "style_match_count", "distance_km", "last_seen_at", "updated_at"}))
+
+	_, _, err := repo.SearchProfiles(1, nil, nil, nil, nil, nil, nil, false, nil, nil, nil, nil, nil, nil, nil, SortCreatedAtDesc, 1, 20)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchProfiles_HasMediaUsesExistsSubquery(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	exists := `EXISTS (SELECT 1 FROM profile_media pm WHERE pm.user_id = p.user_id)`
+	mock.ExpectQuery(regexp.QuoteMeta(` AND ` + exists + `) SELECT COUNT(*) FROM profile_matches`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+	mock.ExpectQuery(regexp.QuoteMeta(` AND `+exists+`) SELECT * FROM profile_matches`)).
+		WithArgs(1, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "full_name", "birthday", "gender", "city_id", "bio", "goal", "looking_for_team", "created_at", "style_match_count", "distance_km", "last_seen_at", "updated_at"}))
+
+	hasMedia := true
+	_, _, err := repo.SearchProfiles(1, nil, nil, nil, nil, nil, nil, false, nil, nil, nil, nil, nil, &hasMedia, nil, SortCreatedAtDesc, 1, 20)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchProfiles_AgeRangeUnknownAge(t *testing.T) {
+	birthDateMin := time.Date(1990, 1, 1, 0, 0, 0, 0, time.UTC)
+	birthDateMax := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name           string
+		withUnknownAge bool
+		condition      string
+	}{
+		{"excluded", false, `AND (p.birthday >= $2 AND p.birthday <= $3)`},
+		{"included", true, `AND (p.birthday IS NULL OR p.birthday >= $2 AND p.birthday <= $3)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, repo := setupMockDB(t)
+			defer db.Close()
+
+			mock.ExpectQuery(regexp.QuoteMeta(tt.condition)).
+				WithArgs(1, birthDateMin, birthDateMax).
+				WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+			mock.ExpectQuery(regexp.QuoteMeta(tt.condition)).
+				WithArgs(1, birthDateMin, birthDateMax, 20, 0).
+				WillReturnRows(sqlmock.NewRows([]string{"user_id", "full_name", "birthday", "gender", "city_id", "bio", "goal", "looking_for_team", "created_at", "style_match_count", "distance_km", "last_seen_at", "updated_at"}))
+
+			_, _, err := repo.SearchProfiles(1, nil, nil, nil, nil, &birthDateMin, &birthDateMax, tt.withUnknownAge, nil, nil, nil, nil, nil, nil, nil, SortCreatedAtDesc, 1, 20)
+
+			assert.NoError(t, err)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSearchProfiles_NearFilterBoundsDistance(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`JOIN cities pc ON pc.city_id = p.city_id WHERE`)+`(?s).*`+regexp.QuoteMeta(`<= $4) SELECT COUNT(*) FROM profile_matches`)).
+		WithArgs(1, 55.75, 37.62, 10.0).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(1))
+	mock.ExpectQuery(regexp.QuoteMeta(`AS distance_km`)+`(?s).*`+regexp.QuoteMeta(`ORDER BY distance_km ASC NULLS LAST, user_id DESC LIMIT $5 OFFSET $6`)).
+		WithArgs(1, 55.75, 37.62, 10.0, 20, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "full_name", "birthday", "gender", "city_id", "bio", "goal", "looking_for_team", "created_at", "style_match_count", "distance_km", "last_seen_at", "updated_at"}).
+			AddRow(2, "Nearby", time.Now(), "male", 1, "", "hobby", true, time.Now(), 0, 3.2, nil, time.Now()))
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = $1 AND role = 'avatar'`)).
+		WithArgs(2).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(regexp.QuoteMeta(`WHERE user_id = $1 AND role = 'video'`)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"media_id"}))
+
+	near := &NearFilter{Lat: 55.75, Lng: 37.62, RadiusKm: 10}
+	profiles, total, err := repo.SearchProfiles(1, nil, nil, nil, nil, nil, nil, false, nil, nil, near, nil, nil, nil, nil, SortDistance, 1, 20)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, total)
+	if assert.Len(t, profiles, 1) && assert.NotNil(t, profiles[0].DistanceKm) {
+		assert.Equal(t, 3.2, *profiles[0].DistanceKm)
+	}
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSearchProfiles_RejectsUnknownSort(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	_, _, err := repo.SearchProfiles(1, nil, nil, nil, nil, nil, nil, false, nil, nil, nil, nil, nil, nil, nil, "name_asc", 1, 20)
+
+	assert.ErrorIs(t, err, ErrInvalidSort)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSortOrders_ListsEverySupportedOrdering(t *testing.T) {
+	assert.Len(t, SortOrders, len(searchOrderClauses))
+	for _, sortBy := range SortOrders {
+		assert.True(t, IsValidSort(sortBy), sortBy)
+	}
+}
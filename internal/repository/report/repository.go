@@ -0,0 +1,129 @@
+package report
+
+import (
+	"database/sql"
+	"time"
+)
+
+// Types of content that can be reported
+const (
+	TargetProfile = "profile"
+	TargetMessage = "message"
+	TargetUser    = "user"
+)
+
+// Report is a complaint about a profile, message or user
+type Report struct {
+	ID         int       `json:"id"`
+	ReporterID int       `json:"reporter_id"`
+	TargetType string    `json:"target_type"`
+	TargetID   string    `json:"target_id"`
+	Reason     string    `json:"reason"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// Repository stores reports
+type Repository interface {
+	Upsert(reporterID int, targetType, targetID, reason string) (*Report, error)
+	List(limit, offset int) ([]Report, error)
+	TargetExists(targetType, targetID string) (bool, error)
+	IsAdmin(userID int) (bool, error)
+}
+
+// PostgresRepository implements Repository on top of the reports table
+type PostgresRepository struct {
+	db *sql.DB
+}
+
+// NewPostgresRepository creates a new report repository
+func NewPostgresRepository(db *sql.DB) *PostgresRepository {
+	return &PostgresRepository{db: db}
+}
+
+// Upsert stores the report. If the reporter has already reported the target,
+// the reason is replaced and the report is moved to the top of the list.
+func (r *PostgresRepository) Upsert(reporterID int, targetType, targetID, reason string) (*Report, error) {
+	var report Report
+	err := r.db.QueryRow(`
+        INSERT INTO reports (reporter_id, target_type, target_id, reason)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (reporter_id, target_type, target_id)
+        DO UPDATE SET reason = EXCLUDED.reason, updated_at = CURRENT_TIMESTAMP
+        RETURNING id, reporter_id, target_type, target_id, reason, created_at, updated_at
+    `, reporterID, targetType, targetID, reason).Scan(
+		&report.ID,
+		&report.ReporterID,
+		&report.TargetType,
+		&report.TargetID,
+		&report.Reason,
+		&report.CreatedAt,
+		&report.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &report, nil
+}
+
+// List returns reports, most recently filed or updated first
+func (r *PostgresRepository) List(limit, offset int) ([]Report, error) {
+	rows, err := r.db.Query(`
+        SELECT id, reporter_id, target_type, target_id, reason, created_at, updated_at
+        FROM reports
+        ORDER BY updated_at DESC, id DESC
+        LIMIT $1 OFFSET $2
+    `, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	reports := []Report{}
+	for rows.Next() {
+		var report Report
+		if err := rows.Scan(
+			&report.ID,
+			&report.ReporterID,
+			&report.TargetType,
+			&report.TargetID,
+			&report.Reason,
+			&report.CreatedAt,
+			&report.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		reports = append(reports, report)
+	}
+	return reports, rows.Err()
+}
+
+// TargetExists reports whether the reported profile, message or user exists.
+// IDs are compared as text so a malformed ID is simply not found.
+func (r *PostgresRepository) TargetExists(targetType, targetID string) (bool, error) {
+	var query string
+	switch targetType {
+	case TargetProfile:
+		query = "SELECT EXISTS (SELECT 1 FROM profiles WHERE user_id::text = $1)"
+	case TargetMessage:
+		query = "SELECT EXISTS (SELECT 1 FROM messages WHERE id::text = $1)"
+	case TargetUser:
+		query = "SELECT EXISTS (SELECT 1 FROM users WHERE id::text = $1)"
+	default:
+		return false, nil
+	}
+
+	var exists bool
+	err := r.db.QueryRow(query, targetID).Scan(&exists)
+	return exists, err
+}
+
+// IsAdmin reports whether the user is flagged as an admin
+func (r *PostgresRepository) IsAdmin(userID int) (bool, error) {
+	var isAdmin bool
+	err := r.db.QueryRow("SELECT is_admin FROM users WHERE id = $1", userID).Scan(&isAdmin)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return isAdmin, err
+}
@@ -0,0 +1,68 @@
+package report
+
+import (
+	"database/sql"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupMockDB(t *testing.T) (*sql.DB, sqlmock.Sqlmock, *PostgresRepository) {
+	db, mock, err := sqlmock.New()
+	assert.NoError(t, err)
+	return db, mock, NewPostgresRepository(db)
+}
+
+var reportColumns = []string{"id", "reporter_id", "target_type", "target_id", "reason", "created_at", "updated_at"}
+
+func TestUpsert_UpdatesExistingReport(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	createdAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	updatedAt := createdAt.Add(time.Hour)
+	mock.ExpectQuery(regexp.QuoteMeta("DO UPDATE SET reason = EXCLUDED.reason, updated_at = CURRENT_TIMESTAMP")).
+		WithArgs(1, TargetUser, "2", "spam").
+		WillReturnRows(sqlmock.NewRows(reportColumns).AddRow(7, 1, TargetUser, "2", "spam", createdAt, updatedAt))
+
+	report, err := repo.Upsert(1, TargetUser, "2", "spam")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 7, report.ID)
+	assert.Equal(t, createdAt, report.CreatedAt)
+	assert.Equal(t, updatedAt, report.UpdatedAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTargetExists_QueriesTargetTable(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT EXISTS (SELECT 1 FROM messages WHERE id::text = $1)")).
+		WithArgs("not-a-uuid").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+
+	exists, err := repo.TargetExists(TargetMessage, "not-a-uuid")
+
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsAdmin_UnknownUser(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta("SELECT is_admin FROM users")).
+		WithArgs(99).
+		WillReturnError(sql.ErrNoRows)
+
+	isAdmin, err := repo.IsAdmin(99)
+
+	assert.NoError(t, err)
+	assert.False(t, isAdmin)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -3,17 +3,19 @@ package user
 import (
 	"database/sql"
 	"errors"
+	"time"
 )
 
 type User struct {
-	ID            int    `json:"id"`
+	ID            int    `json:"user_id"`
 	Email         string `json:"email"`
 	PasswordHash  string `json:"-"`
 	EmailVerified bool   `json:"email_verified"`
 }
 
 var (
-	ErrUserNotFound = errors.New("user not found")
+	ErrUserNotFound       = errors.New("user not found")
+	ErrInviteCodeNotFound = errors.New("invite code not found or already used")
 )
 
 type PostgresUserRepository struct {
@@ -140,3 +142,125 @@ func (r *PostgresUserRepository) UpdateEmailVerificationStatus(userID int, verif
 	_, err := r.db.Exec(query, userID, verified)
 	return err
 }
+
+// UpdatePassword replaces the password hash of a user
+func (r *PostgresUserRepository) UpdatePassword(userID int, passwordHash string) error {
+	query := `
+        UPDATE users
+        SET password_hash = $2
+        WHERE id = $1
+    `
+
+	result, err := r.db.Exec(query, userID, passwordHash)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// DeleteUser removes the user and everything that belongs to them in a single transaction.
+// Media, chat memberships, messages, reactions and verification tokens are removed by
+// ON DELETE CASCADE, the remaining references are deleted explicitly.
+func (r *PostgresUserRepository) DeleteUser(userID int) (err error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// Profile styles and profile media cascade from the profile
+	if _, err = tx.Exec(`DELETE FROM profiles WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	if _, err = tx.Exec(`DELETE FROM push_tokens WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+
+	result, err := tx.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		err = ErrUserNotFound
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RevokeToken marks a token as revoked until it expires. Entries for tokens
+// that have already expired are removed along the way.
+func (r *PostgresUserRepository) RevokeToken(jti string, expiresAt time.Time) error {
+	if _, err := r.db.Exec(`DELETE FROM revoked_tokens WHERE expires_at < NOW()`); err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO revoked_tokens (jti, expires_at)
+        VALUES ($1, $2)
+        ON CONFLICT (jti) DO NOTHING
+    `
+
+	_, err := r.db.Exec(query, jti, expiresAt)
+	return err
+}
+
+// IsTokenRevoked checks whether a token has been revoked
+func (r *PostgresUserRepository) IsTokenRevoked(jti string) (bool, error) {
+	var revoked bool
+	err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`, jti).Scan(&revoked)
+	return revoked, err
+}
+
+// CreateInviteCode stores a new unused invite code
+func (r *PostgresUserRepository) CreateInviteCode(code string, createdBy int) error {
+	_, err := r.db.Exec(`INSERT INTO invite_codes (code, created_by) VALUES ($1, $2)`, code, createdBy)
+	return err
+}
+
+// ClaimInviteCode marks an unused invite code as used. Returns ErrInviteCodeNotFound
+// if the code doesn't exist or has been used already.
+func (r *PostgresUserRepository) ClaimInviteCode(code string) error {
+	result, err := r.db.Exec(`
+        UPDATE invite_codes
+        SET used_at = CURRENT_TIMESTAMP
+        WHERE code = $1 AND used_at IS NULL
+    `, code)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return ErrInviteCodeNotFound
+	}
+	return nil
+}
+
+// ReleaseInviteCode makes a claimed invite code usable again
+func (r *PostgresUserRepository) ReleaseInviteCode(code string) error {
+	_, err := r.db.Exec(`UPDATE invite_codes SET used_at = NULL WHERE code = $1`, code)
+	return err
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"regexp"
 	"testing"
+	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
@@ -22,13 +23,13 @@ func TestGetUserByEmail_Success(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-        SELECT id, email, password_hash
+        SELECT id, email, password_hash, email_verified
         FROM users 
         WHERE email = $1
     `)).
 		WithArgs("test@example.com").
-		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash"}).
-			AddRow(1, "test@example.com", "hashed_password"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash", "email_verified"}).
+			AddRow(1, "test@example.com", "hashed_password", true))
 
 	user, err := repo.GetUserByEmail("test@example.com")
 	assert.NoError(t, err)
@@ -43,7 +44,7 @@ func TestGetUserByEmail_NotFound(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-        SELECT id, email, password_hash
+        SELECT id, email, password_hash, email_verified
         FROM users 
         WHERE email = $1
     `)).
@@ -60,11 +61,11 @@ func TestCreateUser_Success(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-        INSERT INTO users (email, password_hash)
-        VALUES ($1, $2)
+        INSERT INTO users (email, password_hash, email_verified)
+        VALUES ($1, $2, $3)
         RETURNING id
     `)).
-		WithArgs("newuser@example.com", "hashed_password").
+		WithArgs("newuser@example.com", "hashed_password", false).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 
 	user := &User{
@@ -82,11 +83,11 @@ func TestCreateUser_Error(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-        INSERT INTO users (email, password_hash)
-        VALUES ($1, $2)
+        INSERT INTO users (email, password_hash, email_verified)
+        VALUES ($1, $2, $3)
         RETURNING id
     `)).
-		WithArgs("newuser@example.com", "hashed_password").
+		WithArgs("newuser@example.com", "hashed_password", false).
 		WillReturnError(errors.New("database error"))
 
 	user := &User{
@@ -104,13 +105,13 @@ func TestGetUserByID_Success(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-        SELECT id, email, password_hash
+        SELECT id, email, password_hash, email_verified
         FROM users 
         WHERE id = $1
     `)).
 		WithArgs(1).
-		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash"}).
-			AddRow(1, "test@example.com", "hashed_password"))
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash", "email_verified"}).
+			AddRow(1, "test@example.com", "hashed_password", true))
 
 	user, err := repo.GetUserByID(1)
 	assert.NoError(t, err)
@@ -125,7 +126,7 @@ func TestGetUserByID_NotFound(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-        SELECT id, email, password_hash
+        SELECT id, email, password_hash, email_verified
         FROM users 
         WHERE id = $1
     `)).
@@ -193,3 +194,143 @@ func TestUpdateUser_Error(t *testing.T) {
 	err := repo.UpdateUser(user)
 	assert.Error(t, err)
 }
+
+func TestRevokeToken_Success(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	expiresAt := time.Now().Add(time.Hour)
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM revoked_tokens WHERE expires_at < NOW()`)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`
+        INSERT INTO revoked_tokens (jti, expires_at)
+        VALUES ($1, $2)
+        ON CONFLICT (jti) DO NOTHING
+    `)).
+		WithArgs("token-id", expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.RevokeToken("token-id", expiresAt)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIsTokenRevoked(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM revoked_tokens WHERE jti = $1)`)).
+		WithArgs("token-id").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	revoked, err := repo.IsTokenRevoked("token-id")
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestUpdatePassword_Success(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+        UPDATE users
+        SET password_hash = $2
+        WHERE id = $1
+    `)).
+		WithArgs(1, "new_hashed_password").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := repo.UpdatePassword(1, "new_hashed_password")
+	assert.NoError(t, err)
+}
+
+func TestUpdatePassword_NotFound(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`
+        UPDATE users
+        SET password_hash = $2
+        WHERE id = $1
+    `)).
+		WithArgs(999, "new_hashed_password").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := repo.UpdatePassword(999, "new_hashed_password")
+	assert.Equal(t, ErrUserNotFound, err)
+}
+
+func TestDeleteUser_Success(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM profiles WHERE user_id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM push_tokens WHERE user_id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.DeleteUser(1)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUser_NotFoundRollsBack(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM profiles WHERE user_id = $1`)).
+		WithArgs(999).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM push_tokens WHERE user_id = $1`)).
+		WithArgs(999).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM users WHERE id = $1`)).
+		WithArgs(999).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.DeleteUser(999)
+	assert.Equal(t, ErrUserNotFound, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDeleteUser_ErrorRollsBack(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM profiles WHERE user_id = $1`)).
+		WithArgs(1).
+		WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
+
+	err := repo.DeleteUser(1)
+	assert.Error(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClaimInviteCode_UsedCodeIsNotFound(t *testing.T) {
+	db, mock, repo := setupMockDB(t)
+	defer db.Close()
+
+	query := regexp.QuoteMeta(`
+        UPDATE invite_codes
+        SET used_at = CURRENT_TIMESTAMP
+        WHERE code = $1 AND used_at IS NULL
+    `)
+	mock.ExpectExec(query).WithArgs("invite-1").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(query).WithArgs("invite-1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	assert.NoError(t, repo.ClaimInviteCode("invite-1"))
+	assert.ErrorIs(t, repo.ClaimInviteCode("invite-1"), ErrInviteCodeNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -27,6 +27,17 @@ type VerificationToken struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// PasswordResetToken is a single-use token for resetting a password, kept in the
+// password_resets table
+type PasswordResetToken struct {
+	ID        int        `json:"id"`
+	UserID    int        `json:"user_id"`
+	Token     string     `json:"token"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
+
 // Repository errors
 var (
 	ErrTokenNotFound = errors.New("token not found")
@@ -38,6 +49,8 @@ type Repository interface {
 	CreateToken(token *VerificationToken) error
 	GetTokenByValue(tokenValue string, tokenType TokenType) (*VerificationToken, error)
 	GetTokenByUserID(userID int, tokenType TokenType) (*VerificationToken, error)
+	CreatePasswordReset(reset *PasswordResetToken) error
+	ConsumePasswordReset(tokenValue string) (*PasswordResetToken, error)
 	DeleteToken(tokenID int) error
 	DeleteExpiredTokens() error
 	DeleteTokensByUserID(userID int, tokenType TokenType) error
@@ -139,6 +152,57 @@ func (r *PostgresRepository) GetTokenByUserID(userID int, tokenType TokenType) (
 	return &token, nil
 }
 
+// CreatePasswordReset saves a new password reset token. Unused reset tokens of the
+// user are dropped in the same statement, so only the latest link works.
+func (r *PostgresRepository) CreatePasswordReset(reset *PasswordResetToken) error {
+	query := `
+		WITH replaced AS (
+			DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL
+		)
+		INSERT INTO password_resets (user_id, token, expires_at)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`
+
+	return r.db.QueryRow(query, reset.UserID, reset.Token, reset.ExpiresAt).Scan(&reset.ID, &reset.CreatedAt)
+}
+
+// ConsumePasswordReset marks an unused password reset token as used and returns it.
+// The lookup and the update are one statement, so of concurrent calls with the same
+// token only one gets it. An expired token is used up as well and returned with
+// ErrTokenExpired.
+func (r *PostgresRepository) ConsumePasswordReset(tokenValue string) (*PasswordResetToken, error) {
+	query := `
+		UPDATE password_resets
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token = $1 AND used_at IS NULL
+		RETURNING id, user_id, token, expires_at, created_at, used_at
+	`
+
+	var reset PasswordResetToken
+	err := r.db.QueryRow(query, tokenValue).Scan(
+		&reset.ID,
+		&reset.UserID,
+		&reset.Token,
+		&reset.ExpiresAt,
+		&reset.CreatedAt,
+		&reset.UsedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrTokenNotFound
+		}
+		return nil, err
+	}
+
+	if time.Now().After(reset.ExpiresAt) {
+		return &reset, ErrTokenExpired
+	}
+
+	return &reset, nil
+}
+
 // DeleteToken deletes a token by ID
 func (r *PostgresRepository) DeleteToken(tokenID int) error {
 	query := `
@@ -1,12 +1,15 @@
 package auth
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
 	userrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/user"
@@ -14,12 +17,36 @@ import (
 
 type User = userrepo.User
 
+// Token types carried in the "type" claim, so that access and refresh tokens
+// can't be used in place of each other
+const (
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrInvalidTokenType    = errors.New("invalid token type")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrInvalidPassword     = errors.New("invalid password")
+	ErrIncorrectPassword   = errors.New("incorrect password")
+	ErrRegistrationClosed  = errors.New("registration requires an invite code")
+	ErrInvalidInviteCode   = errors.New("invalid or already used invite code")
+)
+
 type UserRepository interface {
 	GetUserByEmail(email string) (*User, error)
 	GetUserByID(id int) (*User, error)
 	CreateUser(user *User) error
 	UpdateEmailVerificationStatus(userID int, verified bool) error
 	UpdateUser(user *User) error
+	UpdatePassword(userID int, passwordHash string) error
+	RevokeToken(jti string, expiresAt time.Time) error
+	IsTokenRevoked(jti string) (bool, error)
+	DeleteUser(userID int) error
+	CreateInviteCode(code string, createdBy int) error
+	ClaimInviteCode(code string) error
+	ReleaseInviteCode(code string) error
 }
 
 type EmailVerificationService interface {
@@ -27,6 +54,8 @@ type EmailVerificationService interface {
 	VerifyEmail(token string) error
 	ResendVerificationEmail(userID int, ignoreCooldown bool) error
 	IsTokenExpiredForEmail(email string) (bool, error)
+	SendPasswordResetEmail(userID int, userEmail string) error
+	ConsumePasswordResetToken(token string) (int, error)
 }
 
 type AuthService struct {
@@ -35,6 +64,8 @@ type AuthService struct {
 	jwtSecret      []byte
 	tokenExpiry    time.Duration
 	refreshExpiry  time.Duration
+	inviteOnly     bool
+	now            func() time.Time
 }
 
 type AuthResponse struct {
@@ -43,13 +74,48 @@ type AuthResponse struct {
 	User         *User  `json:"user"`
 }
 
-func NewAuthService(userRepo UserRepository, emailService EmailVerificationService, jwtSecret string) *AuthService {
+// TokenInfo describes a valid access token
+type TokenInfo struct {
+	UserID    int
+	ExpiresAt time.Time
+	ExpiresIn time.Duration // Time left until the token expires
+}
+
+// Default token lifetimes used when not configured
+const (
+	DefaultAccessTokenTTL  = time.Hour * 1      // Token valid for 1 hour
+	DefaultRefreshTokenTTL = time.Hour * 24 * 7 // Refresh token valid for 7 days
+)
+
+// Config holds the configuration for the auth service
+type Config struct {
+	// AccessTokenTTL is the lifetime of access tokens. Zero uses DefaultAccessTokenTTL.
+	AccessTokenTTL time.Duration
+	// RefreshTokenTTL is the lifetime of refresh tokens of verified users.
+	// Zero uses DefaultRefreshTokenTTL.
+	RefreshTokenTTL time.Duration
+	// InviteOnly requires a single-use invite code to register
+	InviteOnly bool
+}
+
+func NewAuthService(userRepo UserRepository, emailService EmailVerificationService, jwtSecret string, config Config) *AuthService {
+	tokenExpiry := config.AccessTokenTTL
+	if tokenExpiry <= 0 {
+		tokenExpiry = DefaultAccessTokenTTL
+	}
+	refreshExpiry := config.RefreshTokenTTL
+	if refreshExpiry <= 0 {
+		refreshExpiry = DefaultRefreshTokenTTL
+	}
+
 	return &AuthService{
 		userRepository: userRepo,
 		emailService:   emailService,
 		jwtSecret:      []byte(jwtSecret),
-		tokenExpiry:    time.Hour * 1,      // Token valid for 1 hour
-		refreshExpiry:  time.Hour * 24 * 7, // Refresh token valid for 7 days
+		tokenExpiry:    tokenExpiry,
+		refreshExpiry:  refreshExpiry,
+		inviteOnly:     config.InviteOnly,
+		now:            time.Now,
 	}
 }
 
@@ -92,7 +158,11 @@ func (s *AuthService) Login(email, password string) (*AuthResponse, error) {
 	}, nil
 }
 
-func (s *AuthService) Register(email, password string) (*AuthResponse, error) {
+func (s *AuthService) Register(email, password, inviteCode string) (*AuthResponse, error) {
+	if s.inviteOnly && inviteCode == "" {
+		return nil, ErrRegistrationClosed
+	}
+
 	// Check if user already exists
 	existingUser, err := s.userRepository.GetUserByEmail(email)
 
@@ -106,20 +176,41 @@ func (s *AuthService) Register(email, password string) (*AuthResponse, error) {
 		return nil, errors.New("failed to process request")
 	}
 
+	// If the user exists and is already verified, registration is rejected
+	// before an invite code is spent
+	if existingUser != nil && existingUser.EmailVerified {
+		return nil, errors.New("email already registered")
+	}
+
+	// The code is claimed before the user is saved, so that concurrent registrations
+	// can't share it, and released again if saving fails
+	if s.inviteOnly {
+		if err := s.userRepository.ClaimInviteCode(inviteCode); err != nil {
+			if errors.Is(err, userrepo.ErrInviteCodeNotFound) {
+				return nil, ErrInvalidInviteCode
+			}
+			return nil, fmt.Errorf("failed to claim invite code: %w", err)
+		}
+	}
+	releaseInviteCode := func() {
+		if !s.inviteOnly {
+			return
+		}
+		if err := s.userRepository.ReleaseInviteCode(inviteCode); err != nil {
+			fmt.Printf("Failed to release invite code: %v\n", err)
+		}
+	}
+
 	var user *User
 
 	// Special handling for unverified emails
 	if existingUser != nil {
-		// If the user exists and is already verified, registration is rejected
-		if existingUser.EmailVerified {
-			return nil, errors.New("email already registered")
-		}
-
 		// If token is expired, update the existing user instead of creating a new one
 		existingUser.PasswordHash = string(hashedPassword)
 		existingUser.EmailVerified = false
 
 		if err := s.userRepository.UpdateUser(existingUser); err != nil {
+			releaseInviteCode()
 			return nil, errors.New("failed to update user")
 		}
 
@@ -134,6 +225,7 @@ func (s *AuthService) Register(email, password string) (*AuthResponse, error) {
 
 		// Save user to DB
 		if err := s.userRepository.CreateUser(newUser); err != nil {
+			releaseInviteCode()
 			return nil, errors.New("failed to create user")
 		}
 
@@ -171,6 +263,23 @@ func (s *AuthService) VerifyEmail(token string) error {
 	return s.emailService.VerifyEmail(token)
 }
 
+// inviteCodeBytes is the number of random bytes in an invite code
+const inviteCodeBytes = 8
+
+// CreateInviteCode mints a new single-use invite code
+func (s *AuthService) CreateInviteCode(createdBy int) (string, error) {
+	codeBytes := make([]byte, inviteCodeBytes)
+	if _, err := rand.Read(codeBytes); err != nil {
+		return "", fmt.Errorf("failed to generate invite code: %w", err)
+	}
+	code := hex.EncodeToString(codeBytes)
+
+	if err := s.userRepository.CreateInviteCode(code, createdBy); err != nil {
+		return "", fmt.Errorf("failed to store invite code: %w", err)
+	}
+	return code, nil
+}
+
 func (s *AuthService) RefreshToken(refreshToken string) (*AuthResponse, error) {
 	// Parse refresh token
 	claims := jwt.MapClaims{}
@@ -182,20 +291,34 @@ func (s *AuthService) RefreshToken(refreshToken string) (*AuthResponse, error) {
 	})
 
 	if err != nil || !token.Valid {
-		return nil, errors.New("invalid refresh token")
+		return nil, ErrInvalidRefreshToken
 	}
 
 	// Verify that it's a refresh token
 	tokenType, ok := claims["type"].(string)
-	if !ok || tokenType != "refresh" {
-		return nil, errors.New("invalid token type")
+	if !ok || tokenType != tokenTypeRefresh {
+		return nil, ErrInvalidTokenType
+	}
+
+	if err := s.checkNotRevoked(claims); err != nil {
+		return nil, err
+	}
+	// Rotation: the used refresh token can't be presented again
+	if err := s.revoke(claims); err != nil {
+		return nil, err
 	}
 
 	// Get user from database
-	userID := int(claims["user_id"].(float64))
-	user, err := s.userRepository.GetUserByID(userID)
+	rawUserID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, ErrInvalidRefreshToken
+	}
+	user, err := s.userRepository.GetUserByID(int(rawUserID))
+	if err == userrepo.ErrUserNotFound {
+		return nil, err
+	}
 	if err != nil {
-		return nil, errors.New("user not found")
+		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 
 	// Generate new tokens
@@ -224,8 +347,9 @@ func (s *AuthService) generateToken(user *User) (string, error) {
 		"user_id":        user.ID,
 		"email":          user.Email,
 		"email_verified": user.EmailVerified, // Include verification status in token
-		"exp":            time.Now().Add(s.tokenExpiry).UnixNano(),
-		"type":           "access",
+		"exp":            time.Now().Add(s.tokenExpiry).Unix(),
+		"type":           tokenTypeAccess,
+		"jti":            uuid.NewString(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
@@ -242,16 +366,176 @@ func (s *AuthService) generateRefreshToken(user *User) (string, error) {
 
 	claims := jwt.MapClaims{
 		"user_id": user.ID,
-		"exp":     expireAt.UnixNano(),
-		"type":    "refresh",
+		"exp":     expireAt.Unix(),
+		"type":    tokenTypeRefresh,
+		"jti":     uuid.NewString(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(s.jwtSecret)
 }
 
-// GetUserInfoFromToken extracts user information from JWT token
-func (s *AuthService) GetUserInfoFromToken(tokenString string) (*User, error) {
+// RequestPasswordReset sends a password reset link to the email. Unknown emails are
+// ignored without an error, so the result doesn't reveal whether an account exists.
+func (s *AuthService) RequestPasswordReset(email string) error {
+	user, err := s.userRepository.GetUserByEmail(email)
+	if err == userrepo.ErrUserNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return s.emailService.SendPasswordResetEmail(user.ID, user.Email)
+}
+
+// ConfirmPasswordReset consumes the reset token and sets the new password
+func (s *AuthService) ConfirmPasswordReset(token, newPassword string) error {
+	if newPassword == "" {
+		return ErrInvalidPassword
+	}
+
+	userID, err := s.emailService.ConsumePasswordResetToken(token)
+	if err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return errors.New("failed to process request")
+	}
+
+	if err := s.userRepository.UpdatePassword(userID, string(hashedPassword)); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	return nil
+}
+
+// Logout revokes the refresh token and, if given, the access token of the session,
+// so that neither can be used again before it expires
+func (s *AuthService) Logout(refreshToken, accessToken string) error {
+	claims, err := s.parseToken(refreshToken)
+	if err != nil {
+		return ErrInvalidRefreshToken
+	}
+	if tokenType, ok := claims["type"].(string); !ok || tokenType != tokenTypeRefresh {
+		return ErrInvalidTokenType
+	}
+	if err := s.revoke(claims); err != nil {
+		return err
+	}
+
+	if accessToken == "" {
+		return nil
+	}
+
+	// The access token is revoked on a best-effort basis: an invalid or expired one
+	// can't be used anyway
+	claims, err = s.parseToken(accessToken)
+	if err != nil {
+		return nil
+	}
+	if tokenType, ok := claims["type"].(string); !ok || tokenType != tokenTypeAccess {
+		return nil
+	}
+	return s.revoke(claims)
+}
+
+// DeleteAccount deletes the user after confirming the current password and revokes the
+// tokens of the session. Other refresh tokens of the user stop working as well, since
+// refreshing requires the user to exist.
+func (s *AuthService) DeleteAccount(userID int, password, accessToken, refreshToken string) error {
+	user, err := s.userRepository.GetUserByID(userID)
+	if err == userrepo.ErrUserNotFound {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return ErrIncorrectPassword
+	}
+
+	if err := s.userRepository.DeleteUser(userID); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	// Revoke the session tokens that belong to the deleted user
+	for _, tokenString := range []string{accessToken, refreshToken} {
+		if tokenString == "" {
+			continue
+		}
+		claims, err := s.parseToken(tokenString)
+		if err != nil {
+			continue
+		}
+		if tokenUserID, ok := claims["user_id"].(float64); !ok || int(tokenUserID) != userID {
+			continue
+		}
+		if err := s.revoke(claims); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// parseToken validates the token signature and expiry and returns its claims
+func (s *AuthService) parseToken(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return s.jwtSecret, nil
+	})
+	if err != nil || !token.Valid {
+		return nil, errors.New("invalid token")
+	}
+	return claims, nil
+}
+
+// revoke stores the token ID until the token expires. Tokens issued without an ID
+// can't be revoked and are skipped.
+func (s *AuthService) revoke(claims jwt.MapClaims) error {
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil
+	}
+
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return errors.New("invalid token")
+	}
+
+	if err := s.userRepository.RevokeToken(jti, exp.Time); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// checkNotRevoked returns ErrTokenRevoked if the token ID has been revoked
+func (s *AuthService) checkNotRevoked(claims jwt.MapClaims) error {
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return nil
+	}
+
+	revoked, err := s.userRepository.IsTokenRevoked(jti)
+	if err != nil {
+		return fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	if revoked {
+		return ErrTokenRevoked
+	}
+	return nil
+}
+
+// parseAccessToken validates an access token and returns its claims. Refresh tokens
+// and revoked tokens are rejected.
+func (s *AuthService) parseAccessToken(tokenString string) (jwt.MapClaims, error) {
 	// Extract JWT token
 	tokenString = strings.TrimPrefix(tokenString, "Bearer ")
 
@@ -271,11 +555,57 @@ func (s *AuthService) GetUserInfoFromToken(tokenString string) (*User, error) {
 		return nil, errors.New("invalid token")
 	}
 
-	userID := int(claims["user_id"].(float64))
-	email := claims["email"].(string)
-	emailVerified := claims["email_verified"].(bool)
+	// Refresh tokens must not be accepted in place of access tokens
+	if tokenType, ok := claims["type"].(string); !ok || tokenType != tokenTypeAccess {
+		return nil, ErrInvalidTokenType
+	}
+
+	if err := s.checkNotRevoked(claims); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+// GetUserInfoFromToken extracts user information from JWT token
+func (s *AuthService) GetUserInfoFromToken(tokenString string) (*User, error) {
+	claims, err := s.parseAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	email, _ := claims["email"].(string)
+	emailVerified, _ := claims["email_verified"].(bool)
+
+	return &userrepo.User{ID: int(userID), Email: email, EmailVerified: emailVerified}, nil
+}
+
+// ValidateToken checks an access token and reports when it expires, so that clients
+// can refresh it in time
+func (s *AuthService) ValidateToken(tokenString string) (*TokenInfo, error) {
+	claims, err := s.parseAccessToken(tokenString)
+	if err != nil {
+		return nil, err
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, errors.New("invalid token")
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil, errors.New("invalid token")
+	}
 
-	return &userrepo.User{ID: userID, Email: email, EmailVerified: emailVerified}, nil
+	return &TokenInfo{
+		UserID:    int(userID),
+		ExpiresAt: exp.Time,
+		ExpiresIn: max(exp.Sub(s.now()), 0),
+	}, nil
 }
 
 // IsUserVerified checks if a user's email is verified
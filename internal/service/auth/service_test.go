@@ -0,0 +1,508 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/bcrypt"
+
+	userrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/user"
+)
+
+const testJWTSecret = "test-secret"
+
+// MockUserRepository is a mock implementation of UserRepository
+type MockUserRepository struct {
+	mock.Mock
+}
+
+func (m *MockUserRepository) GetUserByEmail(email string) (*User, error) {
+	args := m.Called(email)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*User), args.Error(1)
+}
+
+func (m *MockUserRepository) GetUserByID(id int) (*User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*User), args.Error(1)
+}
+
+func (m *MockUserRepository) CreateUser(user *User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateEmailVerificationStatus(userID int, verified bool) error {
+	args := m.Called(userID, verified)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateUser(user *User) error {
+	args := m.Called(user)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePassword(userID int, passwordHash string) error {
+	args := m.Called(userID, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RevokeToken(jti string, expiresAt time.Time) error {
+	args := m.Called(jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) IsTokenRevoked(jti string) (bool, error) {
+	args := m.Called(jti)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) DeleteUser(userID int) error {
+	args := m.Called(userID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) CreateInviteCode(code string, createdBy int) error {
+	args := m.Called(code, createdBy)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ClaimInviteCode(code string) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) ReleaseInviteCode(code string) error {
+	args := m.Called(code)
+	return args.Error(0)
+}
+
+// MockEmailService is a mock implementation of EmailVerificationService
+type MockEmailService struct {
+	mock.Mock
+}
+
+func (m *MockEmailService) SendVerificationEmail(userID int, userEmail string) error {
+	args := m.Called(userID, userEmail)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) VerifyEmail(token string) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) ResendVerificationEmail(userID int, ignoreCooldown bool) error {
+	args := m.Called(userID, ignoreCooldown)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) IsTokenExpiredForEmail(email string) (bool, error) {
+	args := m.Called(email)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockEmailService) SendPasswordResetEmail(userID int, userEmail string) error {
+	args := m.Called(userID, userEmail)
+	return args.Error(0)
+}
+
+func (m *MockEmailService) ConsumePasswordResetToken(token string) (int, error) {
+	args := m.Called(token)
+	return args.Int(0), args.Error(1)
+}
+
+func setupService() (*AuthService, *MockUserRepository, *MockEmailService) {
+	userRepo := new(MockUserRepository)
+	emailService := new(MockEmailService)
+	return NewAuthService(userRepo, emailService, testJWTSecret, Config{}), userRepo, emailService
+}
+
+func signTestToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(testJWTSecret))
+	assert.NoError(t, err)
+	return token
+}
+
+func TestValidateToken_ReportsRemainingTTL(t *testing.T) {
+	service, userRepo, _ := setupService()
+	userRepo.On("IsTokenRevoked", "token-1").Return(false, nil)
+
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	service.now = func() time.Time { return expiresAt.Add(-90 * time.Second) }
+	token := signTestToken(t, jwt.MapClaims{
+		"user_id": 1,
+		"exp":     expiresAt.Unix(),
+		"type":    tokenTypeAccess,
+		"jti":     "token-1",
+	})
+
+	info, err := service.ValidateToken(token)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.UserID)
+	assert.True(t, expiresAt.Equal(info.ExpiresAt))
+	assert.Equal(t, 90*time.Second, info.ExpiresIn)
+}
+
+func TestValidateToken_RejectsRefreshToken(t *testing.T) {
+	service, _, _ := setupService()
+
+	refreshToken, err := service.generateRefreshToken(&User{ID: 1, EmailVerified: true})
+	assert.NoError(t, err)
+
+	info, err := service.ValidateToken(refreshToken)
+
+	assert.Nil(t, info)
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+}
+
+func TestRefreshToken_IssuesNewTokens(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	user := &User{ID: 1, Email: "test@example.com", EmailVerified: true}
+	userRepo.On("GetUserByID", 1).Return(&User{ID: 1, Email: "test@example.com", EmailVerified: true, PasswordHash: "hash"}, nil)
+	userRepo.On("IsTokenRevoked", mock.Anything).Return(false, nil)
+	userRepo.On("RevokeToken", mock.Anything, mock.Anything).Return(nil)
+
+	refreshToken, err := service.generateRefreshToken(user)
+	assert.NoError(t, err)
+
+	response, err := service.RefreshToken(refreshToken)
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, response.Token)
+	assert.NotEmpty(t, response.RefreshToken)
+	assert.Empty(t, response.User.PasswordHash)
+
+	// The new access token is usable, the new refresh token is not
+	info, err := service.GetUserInfoFromToken(response.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, info.ID)
+
+	_, err = service.GetUserInfoFromToken(response.RefreshToken)
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+
+	userRepo.AssertExpectations(t)
+}
+
+func TestRefreshToken_UsedTokenCantBeReused(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	user := &User{ID: 1, Email: "test@example.com", EmailVerified: true}
+	userRepo.On("GetUserByID", 1).Return(user, nil)
+
+	var revoked []string
+	userRepo.On("RevokeToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		revoked = append(revoked, args.String(0))
+	}).Return(nil)
+	userRepo.On("IsTokenRevoked", mock.Anything).Return(false, nil).Once()
+
+	refreshToken, err := service.generateRefreshToken(user)
+	assert.NoError(t, err)
+
+	response, err := service.RefreshToken(refreshToken)
+	assert.NoError(t, err)
+	if !assert.Len(t, revoked, 1) {
+		return
+	}
+
+	// Report the used token as revoked from now on
+	userRepo.On("IsTokenRevoked", revoked[0]).Return(true, nil)
+	userRepo.On("IsTokenRevoked", mock.Anything).Return(false, nil)
+
+	_, err = service.RefreshToken(refreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// The rotated refresh token keeps the session going
+	_, err = service.RefreshToken(response.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestRefreshToken_RejectsAccessToken(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	accessToken, err := service.generateToken(&User{ID: 1, Email: "test@example.com", EmailVerified: true})
+	assert.NoError(t, err)
+
+	response, err := service.RefreshToken(accessToken)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+	userRepo.AssertNotCalled(t, "GetUserByID", mock.Anything)
+}
+
+func TestRefreshToken_RejectsExpiredToken(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	refreshToken := signTestToken(t, jwt.MapClaims{
+		"user_id": 1,
+		"exp":     time.Now().Add(-time.Minute).Unix(),
+		"type":    tokenTypeRefresh,
+	})
+
+	response, err := service.RefreshToken(refreshToken)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	userRepo.AssertNotCalled(t, "GetUserByID", mock.Anything)
+}
+
+func TestRefreshToken_RejectsMalformedToken(t *testing.T) {
+	service, _, _ := setupService()
+
+	response, err := service.RefreshToken("not-a-jwt")
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshToken_RejectsWrongSignature(t *testing.T) {
+	service, _, _ := setupService()
+
+	refreshToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": 1,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+		"type":    tokenTypeRefresh,
+	}).SignedString([]byte("other-secret"))
+	assert.NoError(t, err)
+
+	response, err := service.RefreshToken(refreshToken)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+}
+
+func TestRefreshToken_RejectsDeletedUser(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	userRepo.On("GetUserByID", 1).Return(nil, userrepo.ErrUserNotFound)
+	userRepo.On("IsTokenRevoked", mock.Anything).Return(false, nil)
+	userRepo.On("RevokeToken", mock.Anything, mock.Anything).Return(nil)
+
+	refreshToken, err := service.generateRefreshToken(&User{ID: 1, EmailVerified: true})
+	assert.NoError(t, err)
+
+	response, err := service.RefreshToken(refreshToken)
+
+	assert.Nil(t, response)
+	assert.ErrorIs(t, err, userrepo.ErrUserNotFound)
+}
+
+func TestLogout_RevokesRefreshAndAccessTokens(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	user := &User{ID: 1, Email: "test@example.com", EmailVerified: true}
+	refreshToken, err := service.generateRefreshToken(user)
+	assert.NoError(t, err)
+	accessToken, err := service.generateToken(user)
+	assert.NoError(t, err)
+
+	// Record revoked token IDs and report them as revoked afterwards
+	var revoked []string
+	userRepo.On("RevokeToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		revoked = append(revoked, args.String(0))
+	}).Return(nil)
+
+	assert.NoError(t, service.Logout(refreshToken, accessToken))
+	assert.Len(t, revoked, 2)
+	for _, jti := range revoked {
+		userRepo.On("IsTokenRevoked", jti).Return(true, nil)
+	}
+
+	_, err = service.RefreshToken(refreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, err = service.GetUserInfoFromToken(accessToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	userRepo.AssertNotCalled(t, "GetUserByID", mock.Anything)
+}
+
+func TestLogout_RejectsAccessTokenInPlaceOfRefreshToken(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	accessToken, err := service.generateToken(&User{ID: 1, Email: "test@example.com"})
+	assert.NoError(t, err)
+
+	err = service.Logout(accessToken, "")
+
+	assert.ErrorIs(t, err, ErrInvalidTokenType)
+	userRepo.AssertNotCalled(t, "RevokeToken", mock.Anything, mock.Anything)
+}
+
+func TestLogout_RejectsInvalidRefreshToken(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	err := service.Logout("not-a-jwt", "")
+
+	assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	userRepo.AssertNotCalled(t, "RevokeToken", mock.Anything, mock.Anything)
+}
+
+func TestRequestPasswordReset_SendsResetEmail(t *testing.T) {
+	service, userRepo, emailService := setupService()
+
+	userRepo.On("GetUserByEmail", "test@example.com").Return(&User{ID: 1, Email: "test@example.com"}, nil)
+	emailService.On("SendPasswordResetEmail", 1, "test@example.com").Return(nil)
+
+	err := service.RequestPasswordReset("test@example.com")
+
+	assert.NoError(t, err)
+	emailService.AssertExpectations(t)
+}
+
+func TestRequestPasswordReset_UnknownEmailSucceedsSilently(t *testing.T) {
+	service, userRepo, emailService := setupService()
+
+	userRepo.On("GetUserByEmail", "missing@example.com").Return(nil, userrepo.ErrUserNotFound)
+
+	err := service.RequestPasswordReset("missing@example.com")
+
+	assert.NoError(t, err)
+	emailService.AssertNotCalled(t, "SendPasswordResetEmail", mock.Anything, mock.Anything)
+}
+
+func TestConfirmPasswordReset_UpdatesPasswordHash(t *testing.T) {
+	service, userRepo, emailService := setupService()
+
+	emailService.On("ConsumePasswordResetToken", "reset-token").Return(1, nil)
+	userRepo.On("UpdatePassword", 1, mock.MatchedBy(func(hash string) bool {
+		return bcrypt.CompareHashAndPassword([]byte(hash), []byte("new-password")) == nil
+	})).Return(nil)
+
+	err := service.ConfirmPasswordReset("reset-token", "new-password")
+
+	assert.NoError(t, err)
+	userRepo.AssertExpectations(t)
+}
+
+func TestConfirmPasswordReset_InvalidTokenKeepsPassword(t *testing.T) {
+	service, userRepo, emailService := setupService()
+
+	tokenErr := errors.New("invalid or expired password reset token")
+	emailService.On("ConsumePasswordResetToken", "used-token").Return(0, tokenErr)
+
+	err := service.ConfirmPasswordReset("used-token", "new-password")
+
+	assert.ErrorIs(t, err, tokenErr)
+	userRepo.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything)
+}
+
+func TestConfirmPasswordReset_RejectsEmptyPassword(t *testing.T) {
+	service, _, emailService := setupService()
+
+	err := service.ConfirmPasswordReset("reset-token", "")
+
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	emailService.AssertNotCalled(t, "ConsumePasswordResetToken", mock.Anything)
+}
+
+func TestNewAuthService_UsesConfiguredTTLs(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	service := NewAuthService(userRepo, nil, testJWTSecret, Config{
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: 30 * 24 * time.Hour,
+	})
+
+	user := &User{ID: 1, Email: "test@example.com", EmailVerified: true}
+	before := time.Now()
+
+	accessToken, err := service.generateToken(user)
+	assert.NoError(t, err)
+	refreshToken, err := service.generateRefreshToken(user)
+	assert.NoError(t, err)
+
+	accessClaims, err := service.parseToken(accessToken)
+	assert.NoError(t, err)
+	accessExp, err := accessClaims.GetExpirationTime()
+	assert.NoError(t, err)
+	assert.WithinDuration(t, before.Add(15*time.Minute), accessExp.Time, 2*time.Second)
+
+	refreshClaims, err := service.parseToken(refreshToken)
+	assert.NoError(t, err)
+	refreshExp, err := refreshClaims.GetExpirationTime()
+	assert.NoError(t, err)
+	assert.WithinDuration(t, before.Add(30*24*time.Hour), refreshExp.Time, 2*time.Second)
+}
+
+func TestDeleteAccount_DeletesUserAndRevokesSessionTokens(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &User{ID: 1, Email: "test@example.com", PasswordHash: string(hash), EmailVerified: true}
+	userRepo.On("GetUserByID", 1).Return(user, nil).Once()
+	userRepo.On("DeleteUser", 1).Return(nil)
+
+	accessToken, err := service.generateToken(user)
+	assert.NoError(t, err)
+	refreshToken, err := service.generateRefreshToken(user)
+	assert.NoError(t, err)
+	otherRefreshToken, err := service.generateRefreshToken(user)
+	assert.NoError(t, err)
+
+	var revoked []string
+	userRepo.On("RevokeToken", mock.Anything, mock.Anything).Run(func(args mock.Arguments) {
+		revoked = append(revoked, args.String(0))
+	}).Return(nil)
+
+	assert.NoError(t, service.DeleteAccount(1, "password", accessToken, refreshToken))
+	assert.Len(t, revoked, 2)
+	for _, jti := range revoked {
+		userRepo.On("IsTokenRevoked", jti).Return(true, nil)
+	}
+	userRepo.On("IsTokenRevoked", mock.Anything).Return(false, nil)
+	userRepo.On("GetUserByID", 1).Return(nil, userrepo.ErrUserNotFound)
+
+	_, err = service.GetUserInfoFromToken(accessToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	_, err = service.RefreshToken(refreshToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	// Refresh tokens issued to other sessions can't be used either
+	_, err = service.RefreshToken(otherRefreshToken)
+	assert.ErrorIs(t, err, userrepo.ErrUserNotFound)
+
+	userRepo.AssertExpectations(t)
+}
+
+func TestDeleteAccount_RejectsIncorrectPassword(t *testing.T) {
+	service, userRepo, _ := setupService()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	userRepo.On("GetUserByID", 1).Return(&User{ID: 1, PasswordHash: string(hash)}, nil)
+
+	err = service.DeleteAccount(1, "wrong-password", "", "")
+
+	assert.ErrorIs(t, err, ErrIncorrectPassword)
+	userRepo.AssertNotCalled(t, "DeleteUser", mock.Anything)
+}
+
+func TestRegister_ReleasesInviteCodeWhenUserIsNotSaved(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	service := NewAuthService(userRepo, new(MockEmailService), testJWTSecret, Config{InviteOnly: true})
+
+	userRepo.On("GetUserByEmail", "new@example.com").Return(nil, userrepo.ErrUserNotFound)
+	userRepo.On("ClaimInviteCode", "invite-1").Return(nil)
+	userRepo.On("CreateUser", mock.Anything).Return(errors.New("insert failed"))
+	userRepo.On("ReleaseInviteCode", "invite-1").Return(nil)
+
+	response, err := service.Register("new@example.com", "password", "invite-1")
+
+	assert.Nil(t, response)
+	assert.Error(t, err)
+	userRepo.AssertExpectations(t)
+}
@@ -0,0 +1,48 @@
+package block
+
+import (
+	"errors"
+
+	blockrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/block"
+)
+
+var (
+	ErrCannotBlockSelf = errors.New("cannot block yourself")
+	ErrUserNotFound    = blockrepo.ErrUserNotFound
+)
+
+// Service manages the users a user has blocked. Search and messaging read
+// blocks directly in their queries, so a block applies in both directions.
+type Service interface {
+	BlockUser(userID, blockedID int) error
+	UnblockUser(userID, blockedID int) error
+	GetBlockedUsers(userID int) ([]int, error)
+}
+
+// ServiceImpl implements Service
+type ServiceImpl struct {
+	repo blockrepo.Repository
+}
+
+// NewService creates a new block service
+func NewService(repo blockrepo.Repository) *ServiceImpl {
+	return &ServiceImpl{repo: repo}
+}
+
+// BlockUser blocks the user on behalf of userID
+func (s *ServiceImpl) BlockUser(userID, blockedID int) error {
+	if userID == blockedID {
+		return ErrCannotBlockSelf
+	}
+	return s.repo.Block(userID, blockedID)
+}
+
+// UnblockUser removes a block made by userID
+func (s *ServiceImpl) UnblockUser(userID, blockedID int) error {
+	return s.repo.Unblock(userID, blockedID)
+}
+
+// GetBlockedUsers returns the IDs of the users blocked by userID
+func (s *ServiceImpl) GetBlockedUsers(userID int) ([]int, error) {
+	return s.repo.GetBlockedUserIDs(userID)
+}
@@ -0,0 +1,50 @@
+package block
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// MockRepository is a mock implementation of the block repository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Block(blockerID, blockedID int) error {
+	args := m.Called(blockerID, blockedID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) Unblock(blockerID, blockedID int) error {
+	args := m.Called(blockerID, blockedID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetBlockedUserIDs(blockerID int) ([]int, error) {
+	args := m.Called(blockerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func TestBlockUser_RejectsSelf(t *testing.T) {
+	repo := new(MockRepository)
+	service := NewService(repo)
+
+	err := service.BlockUser(1, 1)
+
+	assert.ErrorIs(t, err, ErrCannotBlockSelf)
+	repo.AssertNotCalled(t, "Block", mock.Anything, mock.Anything)
+}
+
+func TestBlockUser_StoresBlock(t *testing.T) {
+	repo := new(MockRepository)
+	service := NewService(repo)
+	repo.On("Block", 1, 2).Return(nil)
+
+	assert.NoError(t, service.BlockUser(1, 2))
+	repo.AssertExpectations(t)
+}
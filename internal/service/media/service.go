@@ -1,19 +1,30 @@
 package media
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"log"
 	"mime/multipart"
+	"net/http"
 	"net/textproto"
-	"path/filepath"
+	"sort"
 	"strings"
+	"time"
+
+	mediarepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/media"
 )
 
 // Определение ошибок
 var (
 	ErrMediaNotFound   = errors.New("media not found")
+	ErrNotMediaOwner   = errors.New("media belongs to another user")
+	ErrUploadNotFound  = errors.New("uploaded file not found in storage")
 	ErrInvalidFileType = errors.New("invalid file type")
 	ErrFileTooBig      = errors.New("file too big")
+
+	ErrProfileMediaRequired = errors.New("profile must keep a minimum number of media items")
 )
 
 type Media struct {
@@ -22,48 +33,156 @@ type Media struct {
 	ThumbnailURL string `json:"thumbnail_url"`
 }
 
-// Константы для ограничений
-const (
-	MaxFileSize = 50 * 1024 * 1024 // 10 MB
-)
+// DefaultMaxBytes is the largest accepted file when not configured
+const DefaultMaxBytes = 50 << 20
+
+// DefaultAllowedContentTypes are the accepted content types when not configured
+var DefaultAllowedContentTypes = []string{
+	"image/jpeg",
+	"image/png",
+	"image/gif",
+	"image/webp",
+	"video/mp4",
+	"video/webm",
+}
+
+// PresignExpiry is how long a presigned upload URL stays valid
+const PresignExpiry = 15 * time.Minute
+
+// DefaultPendingUploadTTL is how long a direct upload may stay incomplete when not configured
+const DefaultPendingUploadTTL = 24 * time.Hour
+
+// sniffLen is the number of leading bytes used to detect the content type
+const sniffLen = 512
+
+// objectCleanupBatchSize is the number of queued objects retried per sweep
+const objectCleanupBatchSize = 100
+
+// Config holds the configuration for the media service
+type Config struct {
+	// MaxBytes limits the size of every uploaded file. Zero uses DefaultMaxBytes.
+	MaxBytes int64
+
+	// AllowedContentTypes lists the accepted content types, detected from the file
+	// contents rather than the client's headers. Empty uses DefaultAllowedContentTypes.
+	AllowedContentTypes []string
+
+	// MaxProfileMedia is the number of media items a profile may show. It is
+	// enforced by the profile service and only reported to clients here.
+	MaxProfileMedia int
+
+	// MinProfileMedia is the number of media items a profile must keep: media shown
+	// on the profile can't be deleted if fewer would be left. Zero disables the rule.
+	MinProfileMedia int
+
+	// PendingUploadTTL is how long a presigned upload may stay incomplete before its
+	// media is deleted. Zero uses DefaultPendingUploadTTL; it is never shorter than
+	// PresignExpiry, so a client's URL can't outlive its media.
+	PendingUploadTTL time.Duration
+}
+
+// Constraints describes what clients may upload
+type Constraints struct {
+	MaxBytes            int64    `json:"max_bytes"`
+	AllowedContentTypes []string `json:"allowed_content_types"`
+	MaxProfileMedia     int      `json:"max_profile_media"`
+	MinProfileMedia     int      `json:"min_profile_media"`
+}
 
 // Repository defines the interface for media database operations
 type MediaRepository interface {
 	CreateMedia(userID int, mediaType, mediaURL, thumbnailURL string) (int, error)
 	DeleteMedia(userID, mediaID int) error
+	DeleteMediaKeepingMinimum(userID, mediaID, minimum int) error
+	GetMediaByID(mediaID int) (*mediarepo.Media, error)
+	CreatePendingMedia(userID int, mediaType, mediaURL, objectKey string) (int, error)
+	MarkMediaReady(mediaID int) error
+	GetMediaByOwner(ownerID int, includePrivate bool, limit, offset int) ([]mediarepo.Media, int, error)
+	EnqueueObjectCleanup(objectKeys []string) error
+	TakeObjectCleanup(limit int) ([]string, error)
+	DeleteStalePendingMedia(olderThan time.Duration) ([]string, error)
 }
 
 // StorageProvider определяет интерфейс для загрузки и получения файлов
 type StorageProvider interface {
 	UploadFile(file multipart.File, fileName string) (string, error)
-	DeleteFile(fileName string) error
+	DeleteObject(objectKey string) error
 	GetFileURL(fileName string) string
+	ObjectKey(fileURL string) (string, bool)
+	PresignUpload(fileName string, expires time.Duration) (objectKey string, uploadURL string, err error)
+	HeadObject(objectKey string) (size int64, exists bool, err error)
+	ReadObjectHead(objectKey string, n int64) ([]byte, error)
 }
 
 // MediaServiceImpl представляет реализацию сервиса медиа
 type MediaServiceImpl struct {
 	mediaRepository MediaRepository
 	storageProvider StorageProvider
-	allowedTypes    map[string]bool // Разрешенные расширения
+	maxBytes        int64
+	allowedTypes    map[string]struct{} // Разрешенные типы содержимого
+	maxProfileMedia int
+	minProfileMedia int
+
+	pendingUploadTTL time.Duration
 }
 
 // NewMediaService создает новый экземпляр MediaServiceImpl
-func NewMediaService(mediaRepo MediaRepository, storageProvider StorageProvider) *MediaServiceImpl {
-	// Разрешенные типы файлов
-	allowedTypes := map[string]bool{
-		".jpg":  true,
-		".jpeg": true,
-		".png":  true,
-		".mp4":  true,
+func NewMediaService(mediaRepo MediaRepository, storageProvider StorageProvider, config Config) *MediaServiceImpl {
+	maxBytes := config.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+
+	contentTypes := config.AllowedContentTypes
+	if len(contentTypes) == 0 {
+		contentTypes = DefaultAllowedContentTypes
+	}
+	allowedTypes := make(map[string]struct{}, len(contentTypes))
+	for _, contentType := range contentTypes {
+		if contentType = strings.ToLower(strings.TrimSpace(contentType)); contentType != "" {
+			allowedTypes[contentType] = struct{}{}
+		}
+	}
+
+	pendingUploadTTL := config.PendingUploadTTL
+	if pendingUploadTTL <= 0 {
+		pendingUploadTTL = DefaultPendingUploadTTL
 	}
 
 	return &MediaServiceImpl{
-		mediaRepository: mediaRepo,
-		storageProvider: storageProvider,
-		allowedTypes:    allowedTypes,
+		mediaRepository:  mediaRepo,
+		storageProvider:  storageProvider,
+		maxBytes:         maxBytes,
+		allowedTypes:     allowedTypes,
+		maxProfileMedia:  config.MaxProfileMedia,
+		minProfileMedia:  config.MinProfileMedia,
+		pendingUploadTTL: max(pendingUploadTTL, PresignExpiry),
+	}
+}
+
+// Constraints returns the configured upload limits
+func (s *MediaServiceImpl) Constraints() Constraints {
+	contentTypes := make([]string, 0, len(s.allowedTypes))
+	for contentType := range s.allowedTypes {
+		contentTypes = append(contentTypes, contentType)
+	}
+	sort.Strings(contentTypes)
+
+	return Constraints{
+		MaxBytes:            s.maxBytes,
+		AllowedContentTypes: contentTypes,
+		MaxProfileMedia:     s.maxProfileMedia,
+		MinProfileMedia:     s.minProfileMedia,
 	}
 }
 
+// PresignedUpload is a pending media record and where the client uploads its file
+type PresignedUpload struct {
+	MediaID   int       `json:"media_id"`
+	UploadURL string    `json:"upload_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
 type FileHeaderWrapper struct {
 	*multipart.FileHeader
 }
@@ -94,12 +213,12 @@ type UploadedFile interface {
 // UploadMedia uploads a new media file and its thumbnail
 func (s *MediaServiceImpl) UploadMedia(userID int, fileHeader, thumbnailHeader UploadedFile) (*Media, error) {
 	// Проверяем размер основного файла
-	if fileHeader.GetSize() > MaxFileSize {
+	if fileHeader.GetSize() > s.maxBytes {
 		return nil, ErrFileTooBig
 	}
 
 	// Проверяем размер thumbnail файла, если он предоставлен
-	if thumbnailHeader.GetSize() > MaxFileSize {
+	if thumbnailHeader.GetSize() > s.maxBytes {
 		return nil, ErrFileTooBig
 	}
 
@@ -110,19 +229,14 @@ func (s *MediaServiceImpl) UploadMedia(userID int, fileHeader, thumbnailHeader U
 	}
 	defer file.Close()
 
-	ext := strings.ToLower(filepath.Ext(fileHeader.GetFilename()))
-	if _, allowed := s.allowedTypes[ext]; !allowed {
-		return nil, ErrInvalidFileType
+	contentType, err := s.detectContentType(file)
+	if err != nil {
+		return nil, err
 	}
 
-	// Определяем тип медиа по расширению
-	var mediaType string
-	switch ext {
-	case ".jpg", ".jpeg", ".png", ".gif", ".webp":
-		mediaType = "image"
-	case ".mp4", ".webm":
-		mediaType = "video"
-	default:
+	// Определяем тип медиа по содержимому
+	mediaType := mediaTypeOf(contentType)
+	if mediaType == "" {
 		return nil, ErrInvalidFileType
 	}
 
@@ -140,9 +254,8 @@ func (s *MediaServiceImpl) UploadMedia(userID int, fileHeader, thumbnailHeader U
 	}
 	defer thumbFile.Close()
 
-	thumbExt := strings.ToLower(filepath.Ext(thumbnailHeader.GetFilename()))
-	if _, allowed := s.allowedTypes[thumbExt]; !allowed {
-		return nil, ErrInvalidFileType
+	if _, err := s.detectContentType(thumbFile); err != nil {
+		return nil, err
 	}
 
 	thumbnailURL, err = s.storageProvider.UploadFile(thumbFile, thumbnailHeader.GetFilename())
@@ -162,3 +275,289 @@ func (s *MediaServiceImpl) UploadMedia(userID int, fileHeader, thumbnailHeader U
 		ThumbnailURL: thumbnailURL,
 	}, nil
 }
+
+// mediaTypeOf returns the media type stored for a content type, or an empty string
+// if it is neither an image nor a video
+func mediaTypeOf(contentType string) string {
+	switch {
+	case strings.HasPrefix(contentType, "image/"):
+		return "image"
+	case strings.HasPrefix(contentType, "video/"):
+		return "video"
+	default:
+		return ""
+	}
+}
+
+// detectContentType detects the content type from the leading bytes of the file and
+// rewinds it. It returns ErrInvalidFileType for content types that are not allowed.
+func (s *MediaServiceImpl) detectContentType(file multipart.File) (string, error) {
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return "", fmt.Errorf("failed to read file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("failed to rewind file: %w", err)
+	}
+
+	contentType, _, _ := strings.Cut(http.DetectContentType(buf[:n]), ";")
+	if _, allowed := s.allowedTypes[contentType]; !allowed {
+		return "", ErrInvalidFileType
+	}
+	return contentType, nil
+}
+
+// GetUserMedia returns a page of media uploaded by the given user and the total count.
+// Profile media comes first in gallery order, then the remaining uploads newest first.
+// Private (not attached to the profile) media is included only when includePrivate is true.
+func (s *MediaServiceImpl) GetUserMedia(ownerID int, includePrivate bool, limit, offset int) ([]Media, int, error) {
+	items, total, err := s.mediaRepository.GetMediaByOwner(ownerID, includePrivate, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]Media, 0, len(items))
+	for _, item := range items {
+		result = append(result, Media{
+			ID:           item.ID,
+			URL:          item.URL,
+			ThumbnailURL: item.ThumbnailURL,
+		})
+	}
+	return result, total, nil
+}
+
+// assertOwner returns the media if it belongs to the user. Every endpoint that reads
+// or changes a single media item goes through it.
+func (s *MediaServiceImpl) assertOwner(mediaID, userID int) (*mediarepo.Media, error) {
+	item, err := s.mediaRepository.GetMediaByID(mediaID)
+	if err != nil {
+		if errors.Is(err, mediarepo.ErrMediaNotFound) {
+			return nil, ErrMediaNotFound
+		}
+		return nil, err
+	}
+
+	if item.UserID != userID {
+		return nil, ErrNotMediaOwner
+	}
+	return item, nil
+}
+
+// GetMedia returns a media item of the user
+func (s *MediaServiceImpl) GetMedia(mediaID, userID int) (*Media, error) {
+	item, err := s.assertOwner(mediaID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Media{
+		ID:           item.ID,
+		URL:          item.URL,
+		ThumbnailURL: item.ThumbnailURL,
+	}, nil
+}
+
+// DeleteMedia deletes a media item of the user together with its files in storage.
+// Files that could not be deleted are queued for cleanup, the media stays deleted.
+// Returns ErrProfileMediaRequired if the profile would be left below MinProfileMedia.
+func (s *MediaServiceImpl) DeleteMedia(mediaID, userID int) error {
+	item, err := s.assertOwner(mediaID, userID)
+	if err != nil {
+		return err
+	}
+
+	if s.minProfileMedia > 0 {
+		err = s.mediaRepository.DeleteMediaKeepingMinimum(userID, mediaID, s.minProfileMedia)
+	} else {
+		err = s.mediaRepository.DeleteMedia(userID, mediaID)
+	}
+	if err != nil {
+		if errors.Is(err, mediarepo.ErrProfileMediaMinimum) {
+			return ErrProfileMediaRequired
+		}
+		return err
+	}
+
+	s.removeObjects(s.objectKeys(item))
+	return nil
+}
+
+// removeObjects deletes files from storage and queues the ones that could not be
+// deleted for cleanup
+func (s *MediaServiceImpl) removeObjects(keys []string) {
+	var leftovers []string
+	for _, key := range keys {
+		if err := s.storageProvider.DeleteObject(key); err != nil {
+			log.Printf("Error deleting object %s: %v", key, err)
+			leftovers = append(leftovers, key)
+		}
+	}
+
+	if err := s.mediaRepository.EnqueueObjectCleanup(leftovers); err != nil {
+		log.Printf("Error queueing objects %v for cleanup: %v", leftovers, err)
+	}
+}
+
+// objectKeys returns the keys of every stored file of the media: the original and
+// its thumbnail
+func (s *MediaServiceImpl) objectKeys(item *mediarepo.Media) []string {
+	var keys []string
+	add := func(key string) {
+		for _, existing := range keys {
+			if existing == key {
+				return
+			}
+		}
+		keys = append(keys, key)
+	}
+
+	if item.ObjectKey != "" {
+		add(item.ObjectKey)
+	} else if key, ok := s.storageProvider.ObjectKey(item.URL); ok {
+		add(key)
+	}
+	if key, ok := s.storageProvider.ObjectKey(item.ThumbnailURL); ok {
+		add(key)
+	}
+	return keys
+}
+
+// PresignUpload creates a pending media record and a URL the client uploads the file to
+// directly. The content type declared by the client only picks the media type, the
+// uploaded contents are checked by CompleteUpload. Uploads that are never completed
+// are deleted after PendingUploadTTL.
+func (s *MediaServiceImpl) PresignUpload(userID int, fileName, contentType string) (*PresignedUpload, error) {
+	contentType, _, _ = strings.Cut(strings.ToLower(contentType), ";")
+	contentType = strings.TrimSpace(contentType)
+	if _, allowed := s.allowedTypes[contentType]; !allowed {
+		return nil, ErrInvalidFileType
+	}
+
+	mediaType := mediaTypeOf(contentType)
+	if mediaType == "" {
+		return nil, ErrInvalidFileType
+	}
+
+	objectKey, uploadURL, err := s.storageProvider.PresignUpload(fileName, PresignExpiry)
+	if err != nil {
+		return nil, err
+	}
+	expiresAt := time.Now().Add(PresignExpiry)
+
+	mediaID, err := s.mediaRepository.CreatePendingMedia(userID, mediaType, s.storageProvider.GetFileURL(objectKey), objectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PresignedUpload{
+		MediaID:   mediaID,
+		UploadURL: uploadURL,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+// CompleteUpload marks a presigned upload of the user as ready once its file is in storage.
+// Completing an upload that is already ready is a no-op.
+func (s *MediaServiceImpl) CompleteUpload(mediaID, userID int) (*Media, error) {
+	item, err := s.assertOwner(mediaID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if item.Status != mediarepo.StatusReady {
+		if err := s.verifyUpload(item); err != nil {
+			return nil, err
+		}
+
+		if err := s.mediaRepository.MarkMediaReady(mediaID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Media{
+		ID:           item.ID,
+		URL:          item.URL,
+		ThumbnailURL: item.ThumbnailURL,
+	}, nil
+}
+
+// verifyUpload checks the object of a direct upload like UploadMedia checks a file: it
+// must fit the size limit and its contents must be of an allowed content type matching
+// the declared media type. A rejected object is removed from storage; the media stays
+// pending, so the client may upload again while the URL is valid.
+func (s *MediaServiceImpl) verifyUpload(item *mediarepo.Media) error {
+	size, exists, err := s.storageProvider.HeadObject(item.ObjectKey)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return ErrUploadNotFound
+	}
+	if size > s.maxBytes {
+		s.removeObjects([]string{item.ObjectKey})
+		return ErrFileTooBig
+	}
+
+	head, err := s.storageProvider.ReadObjectHead(item.ObjectKey, sniffLen)
+	if err != nil {
+		return err
+	}
+	contentType, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	if _, allowed := s.allowedTypes[contentType]; !allowed || mediaTypeOf(contentType) != item.Role {
+		s.removeObjects([]string{item.ObjectKey})
+		return ErrInvalidFileType
+	}
+	return nil
+}
+
+// RunPendingUploadSweeper periodically deletes media whose direct uploads were never
+// completed and retries queued object cleanup until the context is cancelled. It
+// returns immediately when the interval is not positive.
+func (s *MediaServiceImpl) RunPendingUploadSweeper(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.ExpirePendingUploads(); err != nil {
+				log.Printf("Error expiring pending uploads: %v", err)
+			}
+			if err := s.RetryObjectCleanup(); err != nil {
+				log.Printf("Error retrying object cleanup: %v", err)
+			}
+		}
+	}
+}
+
+// ExpirePendingUploads deletes media whose uploads stayed incomplete for longer than
+// PendingUploadTTL together with anything uploaded for them
+func (s *MediaServiceImpl) ExpirePendingUploads() error {
+	keys, err := s.mediaRepository.DeleteStalePendingMedia(s.pendingUploadTTL)
+	if err != nil {
+		return err
+	}
+
+	s.removeObjects(keys)
+	return nil
+}
+
+// RetryObjectCleanup deletes a batch of storage objects queued by earlier failed
+// deletions. Objects that still can't be deleted go back to the end of the queue.
+func (s *MediaServiceImpl) RetryObjectCleanup() error {
+	keys, err := s.mediaRepository.TakeObjectCleanup(objectCleanupBatchSize)
+	if err != nil {
+		return err
+	}
+
+	s.removeObjects(keys)
+	return nil
+}
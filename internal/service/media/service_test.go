@@ -0,0 +1,483 @@
+package media
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mediarepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/media"
+)
+
+// MockMediaRepository is a mock implementation of MediaRepository
+type MockMediaRepository struct {
+	mock.Mock
+}
+
+func (m *MockMediaRepository) CreateMedia(userID int, mediaType, mediaURL, thumbnailURL string) (int, error) {
+	args := m.Called(userID, mediaType, mediaURL, thumbnailURL)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockMediaRepository) DeleteMedia(userID, mediaID int) error {
+	args := m.Called(userID, mediaID)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) DeleteMediaKeepingMinimum(userID, mediaID, minimum int) error {
+	args := m.Called(userID, mediaID, minimum)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) DeleteStalePendingMedia(olderThan time.Duration) ([]string, error) {
+	args := m.Called(olderThan)
+	keys, _ := args.Get(0).([]string)
+	return keys, args.Error(1)
+}
+
+func (m *MockMediaRepository) GetMediaByID(mediaID int) (*mediarepo.Media, error) {
+	args := m.Called(mediaID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*mediarepo.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) CreatePendingMedia(userID int, mediaType, mediaURL, objectKey string) (int, error) {
+	args := m.Called(userID, mediaType, mediaURL, objectKey)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockMediaRepository) MarkMediaReady(mediaID int) error {
+	args := m.Called(mediaID)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) GetMediaByOwner(ownerID int, includePrivate bool, limit, offset int) ([]mediarepo.Media, int, error) {
+	args := m.Called(ownerID, includePrivate, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]mediarepo.Media), args.Int(1), args.Error(2)
+}
+
+func (m *MockMediaRepository) EnqueueObjectCleanup(objectKeys []string) error {
+	args := m.Called(objectKeys)
+	return args.Error(0)
+}
+
+func (m *MockMediaRepository) TakeObjectCleanup(limit int) ([]string, error) {
+	args := m.Called(limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// MockStorageProvider records the contents of uploaded files
+type MockStorageProvider struct {
+	uploaded   map[string][]byte
+	objects    map[string]int64  // Sizes of objects uploaded directly
+	contents   map[string][]byte // Leading bytes of objects uploaded directly
+	deleted    []string
+	failDelete map[string]bool // Keys whose deletion fails
+}
+
+func (m *MockStorageProvider) UploadFile(file multipart.File, fileName string) (string, error) {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
+	}
+	if m.uploaded == nil {
+		m.uploaded = make(map[string][]byte)
+	}
+	m.uploaded[fileName] = data
+	return "https://cdn.example.com/" + fileName, nil
+}
+
+func (m *MockStorageProvider) DeleteObject(objectKey string) error {
+	m.deleted = append(m.deleted, objectKey)
+	if m.failDelete[objectKey] {
+		return errors.New("storage unavailable")
+	}
+	return nil
+}
+
+func (m *MockStorageProvider) ObjectKey(fileURL string) (string, bool) {
+	key, ok := strings.CutPrefix(fileURL, "https://cdn.example.com/")
+	return key, ok && key != ""
+}
+
+func (m *MockStorageProvider) GetFileURL(fileName string) string {
+	return "https://cdn.example.com/" + fileName
+}
+
+func (m *MockStorageProvider) PresignUpload(fileName string, expires time.Duration) (string, string, error) {
+	key := "media/key-" + fileName
+	return key, "https://s3.example.com/" + key + "?signature", nil
+}
+
+func (m *MockStorageProvider) HeadObject(objectKey string) (int64, bool, error) {
+	size, ok := m.objects[objectKey]
+	return size, ok, nil
+}
+
+func (m *MockStorageProvider) ReadObjectHead(objectKey string, n int64) ([]byte, error) {
+	data := m.contents[objectKey]
+	return data[:min(int64(len(data)), n)], nil
+}
+
+// memoryFile is an in-memory multipart.File
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (f memoryFile) Close() error {
+	return nil
+}
+
+// testUpload is an UploadedFile with the given name and contents
+type testUpload struct {
+	name string
+	data []byte
+}
+
+func (u testUpload) Open() (multipart.File, error) {
+	return memoryFile{bytes.NewReader(u.data)}, nil
+}
+
+func (u testUpload) GetFilename() string {
+	return u.name
+}
+
+func (u testUpload) GetSize() int64 {
+	return int64(len(u.data))
+}
+
+func (u testUpload) GetHeader() textproto.MIMEHeader {
+	// The client's claim is ignored in favour of the detected type
+	return textproto.MIMEHeader{"Content-Type": {"image/jpeg"}}
+}
+
+var (
+	pngData  = []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR")
+	mp4Data  = []byte("\x00\x00\x00\x18ftypmp42\x00\x00\x00\x00mp42isom")
+	jpegData = []byte{0xFF, 0xD8, 0xFF, 0xE0, 0x00, 0x10, 'J', 'F', 'I', 'F', 0x00}
+)
+
+func TestUploadMedia_DetectsTypeFromContents(t *testing.T) {
+	repo := new(MockMediaRepository)
+	storage := &MockStorageProvider{}
+	service := NewMediaService(repo, storage, Config{})
+
+	repo.On("CreateMedia", 1, "image", "https://cdn.example.com/clip.mp4", "https://cdn.example.com/thumb.jpg").Return(5, nil)
+
+	uploaded, err := service.UploadMedia(1, testUpload{"clip.mp4", pngData}, testUpload{"thumb.jpg", jpegData})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, uploaded.ID)
+	// Sniffing must not consume the stored contents
+	assert.Equal(t, pngData, storage.uploaded["clip.mp4"])
+	assert.Equal(t, jpegData, storage.uploaded["thumb.jpg"])
+	repo.AssertExpectations(t)
+}
+
+func TestUploadMedia_RejectsDisallowedContents(t *testing.T) {
+	repo := new(MockMediaRepository)
+	storage := &MockStorageProvider{}
+	service := NewMediaService(repo, storage, Config{})
+
+	_, err := service.UploadMedia(1, testUpload{"photo.jpg", []byte("#!/bin/sh\necho hi\n")}, testUpload{"thumb.jpg", jpegData})
+
+	assert.ErrorIs(t, err, ErrInvalidFileType)
+	assert.Empty(t, storage.uploaded)
+	repo.AssertNotCalled(t, "CreateMedia")
+}
+
+func TestUploadMedia_AppliesConfiguredLimits(t *testing.T) {
+	repo := new(MockMediaRepository)
+	storage := &MockStorageProvider{}
+
+	// Larger than the limit
+	service := NewMediaService(repo, storage, Config{MaxBytes: 8})
+	_, err := service.UploadMedia(1, testUpload{"photo.png", pngData}, testUpload{"thumb.jpg", jpegData})
+	assert.ErrorIs(t, err, ErrFileTooBig)
+
+	// Not in the configured allowlist
+	service = NewMediaService(repo, storage, Config{AllowedContentTypes: []string{"image/jpeg"}})
+	_, err = service.UploadMedia(1, testUpload{"photo.png", pngData}, testUpload{"thumb.jpg", jpegData})
+	assert.ErrorIs(t, err, ErrInvalidFileType)
+
+	assert.Empty(t, storage.uploaded)
+}
+
+func TestConstraints_ReportsConfiguredLimits(t *testing.T) {
+	service := NewMediaService(new(MockMediaRepository), &MockStorageProvider{}, Config{
+		MaxBytes:            1 << 20,
+		AllowedContentTypes: []string{"video/mp4", " Image/JPEG "},
+		MaxProfileMedia:     6,
+	})
+
+	assert.Equal(t, Constraints{
+		MaxBytes:            1 << 20,
+		AllowedContentTypes: []string{"image/jpeg", "video/mp4"},
+		MaxProfileMedia:     6,
+	}, service.Constraints())
+}
+
+func TestGetUserMedia_PagesKeepRepositoryOrderAndTotal(t *testing.T) {
+	repo := new(MockMediaRepository)
+	service := NewMediaService(repo, nil, Config{})
+
+	// Gallery media first, then unattached uploads
+	repo.On("GetMediaByOwner", 42, true, 2, 0).Return([]mediarepo.Media{{ID: 5}, {ID: 3}}, 3, nil)
+	repo.On("GetMediaByOwner", 42, true, 2, 2).Return([]mediarepo.Media{{ID: 9}}, 3, nil)
+
+	first, total, err := service.GetUserMedia(42, true, 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	second, total, err := service.GetUserMedia(42, true, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, total)
+
+	var ids []int
+	for _, item := range append(first, second...) {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []int{5, 3, 9}, ids)
+	repo.AssertExpectations(t)
+}
+
+func TestAssertOwner(t *testing.T) {
+	repo := new(MockMediaRepository)
+	service := NewMediaService(repo, nil, Config{})
+
+	repo.On("GetMediaByID", 1).Return(&mediarepo.Media{ID: 1, UserID: 42}, nil)
+	repo.On("GetMediaByID", 2).Return(nil, mediarepo.ErrMediaNotFound)
+
+	item, err := service.assertOwner(1, 42)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, item.ID)
+
+	_, err = service.assertOwner(1, 7)
+	assert.ErrorIs(t, err, ErrNotMediaOwner)
+
+	_, err = service.assertOwner(2, 42)
+	assert.ErrorIs(t, err, ErrMediaNotFound)
+}
+
+func TestDeleteMedia_ChecksOwnerFirst(t *testing.T) {
+	repo := new(MockMediaRepository)
+	service := NewMediaService(repo, &MockStorageProvider{}, Config{})
+
+	repo.On("GetMediaByID", 1).Return(&mediarepo.Media{ID: 1, UserID: 42}, nil)
+	repo.On("DeleteMedia", 42, 1).Return(nil).Once()
+	repo.On("EnqueueObjectCleanup", []string(nil)).Return(nil)
+
+	assert.ErrorIs(t, service.DeleteMedia(1, 7), ErrNotMediaOwner)
+	assert.NoError(t, service.DeleteMedia(1, 42))
+
+	repo.AssertExpectations(t)
+}
+
+func TestDeleteMedia_KeepsProfileMinimum(t *testing.T) {
+	repo := new(MockMediaRepository)
+	service := NewMediaService(repo, &MockStorageProvider{}, Config{MinProfileMedia: 1})
+
+	repo.On("GetMediaByID", 1).Return(&mediarepo.Media{ID: 1, UserID: 42}, nil)
+	repo.On("GetMediaByID", 2).Return(&mediarepo.Media{ID: 2, UserID: 42}, nil)
+	repo.On("DeleteMediaKeepingMinimum", 42, 1, 1).Return(nil)
+	repo.On("DeleteMediaKeepingMinimum", 42, 2, 1).Return(mediarepo.ErrProfileMediaMinimum)
+	repo.On("EnqueueObjectCleanup", []string(nil)).Return(nil).Once()
+
+	// Another item is left on the profile
+	assert.NoError(t, service.DeleteMedia(1, 42))
+	// The last item the profile must keep
+	assert.ErrorIs(t, service.DeleteMedia(2, 42), ErrProfileMediaRequired)
+
+	repo.AssertNotCalled(t, "DeleteMedia", mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+}
+
+func TestDeleteMedia_DeletesEveryStoredObject(t *testing.T) {
+	tests := []struct {
+		name string
+		item *mediarepo.Media
+		keys []string
+	}{
+		{
+			name: "Uploaded through the server",
+			item: &mediarepo.Media{ID: 1, UserID: 42, URL: "https://cdn.example.com/media/a.mp4", ThumbnailURL: "https://cdn.example.com/media/a.jpg"},
+			keys: []string{"media/a.mp4", "media/a.jpg"},
+		},
+		{
+			name: "Uploaded directly",
+			item: &mediarepo.Media{ID: 1, UserID: 42, URL: "https://cdn.example.com/media/b.mp4", ObjectKey: "media/b.mp4"},
+			keys: []string{"media/b.mp4"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(MockMediaRepository)
+			storage := &MockStorageProvider{}
+			service := NewMediaService(repo, storage, Config{})
+
+			repo.On("GetMediaByID", 1).Return(tc.item, nil)
+			repo.On("DeleteMedia", 42, 1).Return(nil)
+			repo.On("EnqueueObjectCleanup", []string(nil)).Return(nil)
+
+			assert.NoError(t, service.DeleteMedia(1, 42))
+			assert.Equal(t, tc.keys, storage.deleted)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestDeleteMedia_QueuesObjectsThatFailedToDelete(t *testing.T) {
+	repo := new(MockMediaRepository)
+	storage := &MockStorageProvider{failDelete: map[string]bool{"media/a.jpg": true}}
+	service := NewMediaService(repo, storage, Config{})
+
+	item := &mediarepo.Media{ID: 1, UserID: 42, URL: "https://cdn.example.com/media/a.mp4", ThumbnailURL: "https://cdn.example.com/media/a.jpg"}
+	repo.On("GetMediaByID", 1).Return(item, nil)
+	repo.On("DeleteMedia", 42, 1).Return(nil)
+	repo.On("EnqueueObjectCleanup", []string{"media/a.jpg"}).Return(nil)
+
+	// The media is gone even though one of its files is still in storage
+	assert.NoError(t, service.DeleteMedia(1, 42))
+	assert.Equal(t, []string{"media/a.mp4", "media/a.jpg"}, storage.deleted)
+	repo.AssertExpectations(t)
+}
+
+func TestDeleteMedia_KeepsObjectsWhenRecordIsNotDeleted(t *testing.T) {
+	repo := new(MockMediaRepository)
+	storage := &MockStorageProvider{}
+	service := NewMediaService(repo, storage, Config{})
+
+	item := &mediarepo.Media{ID: 1, UserID: 42, URL: "https://cdn.example.com/media/a.mp4"}
+	repo.On("GetMediaByID", 1).Return(item, nil)
+	repo.On("DeleteMedia", 42, 1).Return(errors.New("db error"))
+
+	assert.Error(t, service.DeleteMedia(1, 42))
+	assert.Empty(t, storage.deleted)
+}
+
+func TestPresignUpload_CreatesPendingMedia(t *testing.T) {
+	repo := new(MockMediaRepository)
+	service := NewMediaService(repo, &MockStorageProvider{}, Config{})
+
+	repo.On("CreatePendingMedia", 42, "video", "https://cdn.example.com/media/key-clip.mp4", "media/key-clip.mp4").Return(9, nil)
+
+	upload, err := service.PresignUpload(42, "clip.mp4", "video/mp4")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 9, upload.MediaID)
+	assert.Equal(t, "https://s3.example.com/media/key-clip.mp4?signature", upload.UploadURL)
+	assert.WithinDuration(t, time.Now().Add(PresignExpiry), upload.ExpiresAt, time.Minute)
+
+	_, err = service.PresignUpload(42, "notes.txt", "text/plain")
+	assert.ErrorIs(t, err, ErrInvalidFileType)
+	repo.AssertExpectations(t)
+}
+
+func TestCompleteUpload_RequiresObjectInStorage(t *testing.T) {
+	repo := new(MockMediaRepository)
+	storage := &MockStorageProvider{objects: map[string]int64{}, contents: map[string][]byte{"media/key-clip.mp4": mp4Data}}
+	service := NewMediaService(repo, storage, Config{MaxBytes: 100})
+
+	pending := &mediarepo.Media{ID: 9, UserID: 42, Role: "video", Status: mediarepo.StatusPending, ObjectKey: "media/key-clip.mp4", URL: "https://cdn.example.com/media/key-clip.mp4"}
+	repo.On("GetMediaByID", 9).Return(pending, nil)
+	repo.On("MarkMediaReady", 9).Return(nil).Once()
+	repo.On("EnqueueObjectCleanup", []string(nil)).Return(nil)
+
+	// Nothing has been uploaded yet
+	_, err := service.CompleteUpload(9, 42)
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+
+	// The uploaded object is over the limit and is removed from storage
+	storage.objects["media/key-clip.mp4"] = 101
+	_, err = service.CompleteUpload(9, 42)
+	assert.ErrorIs(t, err, ErrFileTooBig)
+	assert.Equal(t, []string{"media/key-clip.mp4"}, storage.deleted)
+
+	storage.objects["media/key-clip.mp4"] = 100
+	item, err := service.CompleteUpload(9, 42)
+	assert.NoError(t, err)
+	assert.Equal(t, pending.URL, item.URL)
+
+	// Only the owner can complete the upload
+	_, err = service.CompleteUpload(9, 7)
+	assert.ErrorIs(t, err, ErrNotMediaOwner)
+
+	repo.AssertExpectations(t)
+}
+
+func TestCompleteUpload_ChecksStoredContents(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		contents []byte
+	}{
+		{name: "Not an allowed type", role: "video", contents: []byte("#!/bin/sh\necho hello\n")},
+		{name: "Not the declared media type", role: "video", contents: pngData},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := new(MockMediaRepository)
+			storage := &MockStorageProvider{
+				objects:    map[string]int64{"media/key-clip.mp4": int64(len(tc.contents))},
+				contents:   map[string][]byte{"media/key-clip.mp4": tc.contents},
+				failDelete: map[string]bool{"media/key-clip.mp4": true},
+			}
+			service := NewMediaService(repo, storage, Config{})
+
+			pending := &mediarepo.Media{ID: 9, UserID: 42, Role: tc.role, Status: mediarepo.StatusPending, ObjectKey: "media/key-clip.mp4"}
+			repo.On("GetMediaByID", 9).Return(pending, nil)
+			// Storage is unavailable, so the rejected object is queued for cleanup
+			repo.On("EnqueueObjectCleanup", []string{"media/key-clip.mp4"}).Return(nil)
+
+			_, err := service.CompleteUpload(9, 42)
+
+			assert.ErrorIs(t, err, ErrInvalidFileType)
+			repo.AssertNotCalled(t, "MarkMediaReady", mock.Anything)
+			repo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExpirePendingUploads_RemovesStaleUploads(t *testing.T) {
+	repo := new(MockMediaRepository)
+	storage := &MockStorageProvider{}
+	// A TTL shorter than the presigned URL's lifetime is raised to it
+	service := NewMediaService(repo, storage, Config{PendingUploadTTL: time.Minute})
+
+	repo.On("DeleteStalePendingMedia", PresignExpiry).Return([]string{"media/a.mp4", "media/b.jpg"}, nil)
+	repo.On("EnqueueObjectCleanup", []string(nil)).Return(nil)
+
+	assert.NoError(t, service.ExpirePendingUploads())
+	assert.Equal(t, []string{"media/a.mp4", "media/b.jpg"}, storage.deleted)
+	repo.AssertExpectations(t)
+}
+
+func TestRetryObjectCleanup_RequeuesObjectsThatStillFail(t *testing.T) {
+	repo := new(MockMediaRepository)
+	storage := &MockStorageProvider{failDelete: map[string]bool{"media/b.jpg": true}}
+	service := NewMediaService(repo, storage, Config{})
+
+	repo.On("TakeObjectCleanup", objectCleanupBatchSize).Return([]string{"media/a.jpg", "media/b.jpg"}, nil)
+	repo.On("EnqueueObjectCleanup", []string{"media/b.jpg"}).Return(nil)
+
+	assert.NoError(t, service.RetryObjectCleanup())
+	assert.Equal(t, []string{"media/a.jpg", "media/b.jpg"}, storage.deleted)
+	repo.AssertExpectations(t)
+}
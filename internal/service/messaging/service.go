@@ -2,9 +2,12 @@ package messaging
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"log"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/repository/messaging"
@@ -12,26 +15,74 @@ import (
 )
 
 type Chat = messaging.Chat
+type UserReaction = messaging.UserReaction
+type SentMessage = messaging.SentMessage
+type ChatOverview = messaging.ChatOverview
+type ExpiredMessage = messaging.ExpiredMessage
+type ChatMessage = messaging.ChatMessage
+type ReadPosition = messaging.ReadPosition
+type ReactionSummary = messaging.ReactionSummary
+
+// ErrUserNotInChat is returned when a non-member accesses a chat. Its message is
+// apierrors.ErrorUserNotInChat, so callers comparing messages keep working.
+var ErrUserNotInChat = errors.New(apierrors.ErrorUserNotInChat)
+
+// ErrUsersBlocked is returned when a chat would include users who have blocked one another
+var ErrUsersBlocked = errors.New(apierrors.ErrorUsersBlocked)
+
+// ErrNotChatAdmin is returned when a member attempts something only chat admins may do
+var ErrNotChatAdmin = errors.New(apierrors.ErrorNotChatAdmin)
+
+// Roles of chat participants
+const (
+	RoleAdmin  = messaging.RoleAdmin
+	RoleMember = messaging.RoleMember
+)
+
+// maxChatNameLength matches the chats.chat_name column
+const maxChatNameLength = 255
+
+// maxSearchQueryLength bounds the text searched for in chat messages, in characters
+const maxSearchQueryLength = 200
+
+// MaxMessageExpiry is the longest a disappearing message may be kept
+const MaxMessageExpiry = 7 * 24 * time.Hour
 
 // Service interface defines the messaging service operations
 type Service interface {
 	GetUserChats(userID int) ([]messaging.Chat, error)
+	GetUserTeams(userID int) ([]messaging.Chat, error)
 	GetChat(chatID string, userID int) (*messaging.Chat, error)
-	CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int) error
-	AddMessage(messageID string, chatID string, senderID int, content string) (time.Time, error)
+	CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int) (string, error)
+	AddMessage(messageID string, chatID string, senderID int, content string, expiresIn time.Duration) (time.Time, error)
 	GetChatParticipants(chatID string) ([]int, error)
 	IsUserInChat(userID int, chatID string) (bool, error)
-	AddParticipant(chatID string, userID int) error
-	RemoveParticipant(chatID string, userID int) error
+	AddParticipant(chatID string, actorID int, userID int) error
+	RemoveParticipant(chatID string, actorID int, userID int) error
+	SetParticipantRole(chatID string, actorID int, userID int, role string) error
+	RenameChat(chatID string, actorID int, chatName string) error
 	AddReaction(reactionID string, messageID string, userID int, reactionCode string) error
-	RemoveReaction(messageID string, userID int, reactionCode string) error
+	RemoveReaction(chatID string, messageID string, userID int, reactionCode string) error
+	GetReactionCatalog() ([]messaging.ReactionCatalogItem, error)
+	GetReactions(messageID string, userID int, limit, offset int) ([]messaging.MessageReaction, error)
 	GetChatIDForMessage(messageID string) (string, error)
 	GetChatMessages(chatID string, userID int, limit, offset int) ([]messaging.ChatMessage, error)
+	GetMessage(chatID string, messageID string, userID int) (*messaging.ChatMessage, error)
+	GetChatMessagesBefore(chatID string, userID int, before string, limit int) (*MessagePage, error)
+	SearchMessages(chatID string, userID int, query string, limit, offset int) ([]messaging.ChatMessage, error)
+	EditMessage(chatID string, messageID string, userID int, content string) (*messaging.ChatMessage, error)
+	DeleteMessage(chatID string, messageID string, userID int) (time.Time, error)
+	DeleteExpiredMessages() ([]messaging.ExpiredMessage, error)
 	StoreTypingIndicator(userID int, chatID string) error
 	StoreReadReceipt(userID int, chatID string, messageID string) error
 	GetUserChatRooms(userID int) (map[string]struct{}, error)
 	GetChatParticipantsForBroadcast(chatID string) ([]int, error)
 	GetOrCreateDirectChat(ctx context.Context, userID1 int, userID2 int) (string, error)
+	GetUserReactions(userID int, limit, offset int) ([]messaging.UserReaction, error)
+	GetSentMessages(userID int, limit, offset int) ([]messaging.SentMessage, error)
+	GetChatOverviews(userID int, limit, offset int) ([]messaging.ChatOverview, error)
+	GetReadPositions(chatID string, userID int) ([]messaging.ReadPosition, error)
+	AttachMessageDetails(chatID string, userID int, messages []messaging.ChatMessage, include MessageInclude) error
 }
 
 type ProfileRepository interface {
@@ -74,6 +125,24 @@ func (s *ServiceImpl) GetUserChats(userID int) ([]messaging.Chat, error) {
 	return chats, nil
 }
 
+// GetUserTeams retrieves the teams a user belongs to. Group chats double as
+// improv teams, so direct chats are left out.
+func (s *ServiceImpl) GetUserTeams(userID int) ([]messaging.Chat, error) {
+	chats, err := s.messagingRepo.GetUserChats(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	teams := []messaging.Chat{}
+	for _, chat := range chats {
+		if chat.IsGroup {
+			teams = append(teams, chat)
+		}
+	}
+
+	return teams, nil
+}
+
 func (s *ServiceImpl) setChatName(chat *messaging.Chat, userID int) (*messaging.Chat, error) {
 	if chat == nil || chat.IsGroup {
 		return chat, nil
@@ -102,13 +171,62 @@ func (s *ServiceImpl) GetChat(chatID string, userID int) (*messaging.Chat, error
 	return s.setChatName(chat, userID)
 }
 
-// CreateChat creates a new chat with the specified participants
-func (s *ServiceImpl) CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int) error {
-	return s.messagingRepo.CreateChat(ctx, chatID, creatorID, chatName, participants)
+// CreateChat creates a new chat with the specified participants and returns its ID.
+// Users who have blocked one another can't be put in the same chat. A chat of
+// exactly two people is their direct chat: the existing one is returned if there
+// is one, otherwise it is created under a new ID. A larger chat is a group.
+func (s *ServiceImpl) CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int) (string, error) {
+	members := chatMembers(creatorID, participants)
+	if err := s.checkNotBlocked(members); err != nil {
+		return "", err
+	}
+
+	switch len(members) {
+	case 1:
+		return "", errors.New(apierrors.ErrorCannotCreateChatWithSelf)
+	case 2:
+		return s.messagingRepo.GetOrCreateDirectChat(ctx, creatorID, members[1])
+	}
+
+	if err := s.messagingRepo.CreateChat(ctx, chatID, creatorID, chatName, members[1:], true); err != nil {
+		return "", err
+	}
+	return chatID, nil
 }
 
-// AddMessage adds a new message to a chat
-func (s *ServiceImpl) AddMessage(messageID string, chatID string, senderID int, content string) (time.Time, error) {
+// chatMembers returns the creator followed by the other participants, without duplicates
+func chatMembers(creatorID int, participants []int) []int {
+	members := []int{creatorID}
+	seen := map[int]bool{creatorID: true}
+	for _, id := range participants {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		members = append(members, id)
+	}
+	return members
+}
+
+// checkNotBlocked returns ErrUsersBlocked if any of the users has blocked another one
+func (s *ServiceImpl) checkNotBlocked(userIDs []int) error {
+	blocked, err := s.messagingRepo.HasBlockBetween(userIDs)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrUsersBlocked
+	}
+	return nil
+}
+
+// AddMessage adds a new message to a chat. A positive expiresIn sends a disappearing
+// message that is removed once the duration has passed.
+func (s *ServiceImpl) AddMessage(messageID string, chatID string, senderID int, content string, expiresIn time.Duration) (time.Time, error) {
+	if expiresIn < 0 || expiresIn > MaxMessageExpiry {
+		return time.Time{}, errors.New(apierrors.ErrorInvalidMessageExpiry)
+	}
+
 	// Check if user can send messages to this chat
 	inChat, err := s.IsUserInChat(senderID, chatID)
 	if err != nil {
@@ -116,10 +234,10 @@ func (s *ServiceImpl) AddMessage(messageID string, chatID string, senderID int,
 	}
 
 	if !inChat {
-		return time.Time{}, errors.New(apierrors.ErrorUserNotInChat)
+		return time.Time{}, ErrUserNotInChat
 	}
 
-	return s.messagingRepo.AddMessage(messageID, chatID, senderID, content)
+	return s.messagingRepo.AddMessage(messageID, chatID, senderID, content, expiresIn)
 }
 
 // GetChatParticipants retrieves all participants in a chat
@@ -132,25 +250,91 @@ func (s *ServiceImpl) IsUserInChat(userID int, chatID string) (bool, error) {
 	return s.messagingRepo.IsUserInChat(userID, chatID)
 }
 
-// AddParticipant adds a user to a chat
-func (s *ServiceImpl) AddParticipant(chatID string, userID int) error {
-	return s.messagingRepo.AddParticipant(chatID, userID)
+// requireChatAdmin returns ErrUserNotInChat if the user isn't a participant
+// and ErrNotChatAdmin if they are not an admin of the chat
+func (s *ServiceImpl) requireChatAdmin(chatID string, userID int) error {
+	role, err := s.messagingRepo.GetParticipantRole(chatID, userID)
+	if err != nil {
+		return err
+	}
+	switch role {
+	case "":
+		return ErrUserNotInChat
+	case RoleAdmin:
+		return nil
+	default:
+		return ErrNotChatAdmin
+	}
+}
+
+// AddParticipant lets a chat admin add a user to a group chat unless they and a
+// participant have blocked one another
+func (s *ServiceImpl) AddParticipant(chatID string, actorID int, userID int) error {
+	if err := s.requireChatAdmin(chatID, actorID); err != nil {
+		return err
+	}
+
+	err := s.messagingRepo.AddParticipant(chatID, userID)
+	if err != nil && err.Error() == apierrors.ErrorUsersBlocked {
+		return ErrUsersBlocked
+	}
+	return err
 }
 
-// RemoveParticipant removes a user from a chat
-func (s *ServiceImpl) RemoveParticipant(chatID string, userID int) error {
+// RemoveParticipant removes a user from a chat. Any participant may leave;
+// only admins may remove others.
+func (s *ServiceImpl) RemoveParticipant(chatID string, actorID int, userID int) error {
+	if actorID == userID {
+		inChat, err := s.messagingRepo.IsUserInChat(actorID, chatID)
+		if err != nil {
+			return err
+		}
+		if !inChat {
+			return ErrUserNotInChat
+		}
+	} else if err := s.requireChatAdmin(chatID, actorID); err != nil {
+		return err
+	}
+
 	return s.messagingRepo.RemoveParticipant(chatID, userID)
 }
 
+// SetParticipantRole lets a chat admin promote a participant to admin or demote them to member
+func (s *ServiceImpl) SetParticipantRole(chatID string, actorID int, userID int, role string) error {
+	if role != RoleAdmin && role != RoleMember {
+		return errors.New(apierrors.ErrorInvalidChatRole)
+	}
+	if err := s.requireChatAdmin(chatID, actorID); err != nil {
+		return err
+	}
+	return s.messagingRepo.SetParticipantRole(chatID, userID, role)
+}
+
+// RenameChat lets a chat admin change the name of a group chat
+func (s *ServiceImpl) RenameChat(chatID string, actorID int, chatName string) error {
+	chatName = strings.TrimSpace(chatName)
+	if chatName == "" || utf8.RuneCountInString(chatName) > maxChatNameLength {
+		return errors.New(apierrors.ErrorInvalidChatName)
+	}
+	if err := s.requireChatAdmin(chatID, actorID); err != nil {
+		return err
+	}
+	return s.messagingRepo.RenameChat(chatID, chatName)
+}
+
 // AddReaction adds a reaction to a message
 func (s *ServiceImpl) AddReaction(reactionID string, messageID string, userID int, reactionCode string) error {
 	// Business logic moved from repository to service
 	chatID, err := s.GetChatIDForMessage(messageID)
 	if err != nil {
+		// An unknown message is reported like a foreign one to avoid revealing which IDs exist
+		if errors.Is(err, sql.ErrNoRows) {
+			return errors.New(apierrors.ErrorNotAuthorizedToReact)
+		}
 		return err
 	}
 
-	// Check if user is in the chat
+	// Only participants of the message's chat may react, checked before anything is stored
 	inChat, err := s.IsUserInChat(userID, chatID)
 	if err != nil {
 		return err
@@ -160,12 +344,50 @@ func (s *ServiceImpl) AddReaction(reactionID string, messageID string, userID in
 		return errors.New(apierrors.ErrorNotAuthorizedToReact)
 	}
 
-	return s.messagingRepo.AddReaction(reactionID, messageID, userID, reactionCode)
+	// Only reactions from the catalog may be stored
+	allowed, err := s.messagingRepo.ReactionCodeExists(reactionCode)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return errors.New(apierrors.ErrorInvalidReactionCode)
+	}
+
+	return s.messagingRepo.AddReaction(reactionID, chatID, messageID, userID, reactionCode)
 }
 
-// RemoveReaction removes a reaction from a message
-func (s *ServiceImpl) RemoveReaction(messageID string, userID int, reactionCode string) error {
-	return s.messagingRepo.RemoveReaction(messageID, userID, reactionCode)
+// RemoveReaction removes a reaction from a message in the given chat
+func (s *ServiceImpl) RemoveReaction(chatID string, messageID string, userID int, reactionCode string) error {
+	return s.messagingRepo.RemoveReaction(chatID, messageID, userID, reactionCode)
+}
+
+// GetReactions retrieves a page of the reactions on a message. Users outside the
+// message's chat get ErrUserNotInChat, as do unknown messages.
+func (s *ServiceImpl) GetReactions(messageID string, userID int, limit, offset int) ([]messaging.MessageReaction, error) {
+	chatID, err := s.GetChatIDForMessage(messageID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrUserNotInChat
+		}
+		return nil, err
+	}
+
+	inChat, err := s.IsUserInChat(userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inChat {
+		return nil, ErrUserNotInChat
+	}
+
+	return s.messagingRepo.GetMessageReactions(chatID, messageID, limit, offset)
+}
+
+// GetReactionCatalog retrieves the reactions clients may put on messages
+func (s *ServiceImpl) GetReactionCatalog() ([]messaging.ReactionCatalogItem, error) {
+	return s.messagingRepo.GetReactionCatalog()
 }
 
 // GetChatIDForMessage retrieves the chat ID for a message
@@ -175,19 +397,166 @@ func (s *ServiceImpl) GetChatIDForMessage(messageID string) (string, error) {
 
 // GetChatMessages retrieves messages for a chat with pagination
 func (s *ServiceImpl) GetChatMessages(chatID string, userID int, limit, offset int) ([]messaging.ChatMessage, error) {
-	// Check if user is in chat
+	// The repository reads any chat, membership must be checked before the query
 	inChat, err := s.IsUserInChat(userID, chatID)
 	if err != nil {
 		return nil, err
 	}
 
 	if !inChat {
-		return nil, errors.New(apierrors.ErrorUserNotInChat)
+		return nil, ErrUserNotInChat
 	}
 
 	return s.messagingRepo.GetChatMessages(chatID, userID, limit, offset)
 }
 
+// GetMessage retrieves a single message of a chat the user belongs to
+func (s *ServiceImpl) GetMessage(chatID string, messageID string, userID int) (*messaging.ChatMessage, error) {
+	inChat, err := s.IsUserInChat(userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inChat {
+		return nil, ErrUserNotInChat
+	}
+
+	return s.messagingRepo.GetMessage(chatID, messageID)
+}
+
+// MessagePage is a page of chat messages, newest first
+type MessagePage struct {
+	Messages   []messaging.ChatMessage `json:"messages"`
+	NextCursor string                  `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// GetChatMessagesBefore retrieves a page of messages sent before the cursor, which is the
+// ID of the oldest message of the previous page. Unlike offsets, the cursor is not shifted
+// by messages arriving between pages.
+func (s *ServiceImpl) GetChatMessagesBefore(chatID string, userID int, before string, limit int) (*MessagePage, error) {
+	inChat, err := s.IsUserInChat(userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inChat {
+		return nil, ErrUserNotInChat
+	}
+
+	messages, err := s.messagingRepo.GetChatMessagesBefore(chatID, before, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &MessagePage{Messages: messages}
+	// A full page may be followed by older messages
+	if len(messages) == limit {
+		page.NextCursor = messages[len(messages)-1].MessageID
+	}
+	return page, nil
+}
+
+// MessageInclude selects the details attached to a page of messages
+type MessageInclude struct {
+	Reactions bool // Reaction counts per code
+	Reads     bool // Participants who have read each message
+}
+
+// AttachMessageDetails fills in the requested details of a page of chat messages in
+// place. Each detail is fetched with one query over the whole page.
+func (s *ServiceImpl) AttachMessageDetails(chatID string, userID int, messages []messaging.ChatMessage, include MessageInclude) error {
+	if len(messages) == 0 || (!include.Reactions && !include.Reads) {
+		return nil
+	}
+
+	inChat, err := s.IsUserInChat(userID, chatID)
+	if err != nil {
+		return err
+	}
+	if !inChat {
+		return ErrUserNotInChat
+	}
+
+	messageIDs := make([]string, len(messages))
+	for i, message := range messages {
+		messageIDs[i] = message.MessageID
+	}
+
+	if include.Reactions {
+		summaries, err := s.messagingRepo.GetReactionSummaries(chatID, messageIDs, userID)
+		if err != nil {
+			return err
+		}
+		for i := range messages {
+			messages[i].Reactions = summaries[messages[i].MessageID]
+		}
+	}
+
+	if include.Reads {
+		readers, err := s.messagingRepo.GetMessageReaders(chatID, messageIDs)
+		if err != nil {
+			return err
+		}
+		for i := range messages {
+			messages[i].ReadBy = readers[messages[i].MessageID]
+		}
+	}
+
+	return nil
+}
+
+// SearchMessages finds messages of a chat containing the query, newest first.
+// Only participants can search a chat.
+func (s *ServiceImpl) SearchMessages(chatID string, userID int, query string, limit, offset int) ([]messaging.ChatMessage, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || utf8.RuneCountInString(query) > maxSearchQueryLength {
+		return nil, errors.New(apierrors.ErrorInvalidSearchQuery)
+	}
+
+	inChat, err := s.IsUserInChat(userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+	if !inChat {
+		return nil, ErrUserNotInChat
+	}
+
+	return s.messagingRepo.SearchMessages(chatID, query, limit, offset)
+}
+
+// EditMessage updates the content of a message. Only the original sender may edit it.
+func (s *ServiceImpl) EditMessage(chatID string, messageID string, userID int, content string) (*messaging.ChatMessage, error) {
+	inChat, err := s.IsUserInChat(userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inChat {
+		return nil, ErrUserNotInChat
+	}
+
+	return s.messagingRepo.EditMessage(chatID, messageID, userID, content)
+}
+
+// DeleteMessage marks a message as deleted. Only the original sender may delete it.
+func (s *ServiceImpl) DeleteMessage(chatID string, messageID string, userID int) (time.Time, error) {
+	inChat, err := s.IsUserInChat(userID, chatID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if !inChat {
+		return time.Time{}, ErrUserNotInChat
+	}
+
+	return s.messagingRepo.DeleteMessage(chatID, messageID, userID)
+}
+
+// DeleteExpiredMessages removes disappearing messages whose expiry has passed
+func (s *ServiceImpl) DeleteExpiredMessages() ([]messaging.ExpiredMessage, error) {
+	return s.messagingRepo.DeleteExpiredMessages()
+}
+
 // StoreTypingIndicator records that a user is typing in a chat
 func (s *ServiceImpl) StoreTypingIndicator(userID int, chatID string) error {
 	return s.messagingRepo.StoreTypingIndicator(userID, chatID)
@@ -215,5 +584,57 @@ func (s *ServiceImpl) GetOrCreateDirectChat(ctx context.Context, userID1 int, us
 		return "", errors.New(apierrors.ErrorCannotCreateChatWithSelf)
 	}
 
+	if err := s.checkNotBlocked([]int{userID1, userID2}); err != nil {
+		return "", err
+	}
+
 	return s.messagingRepo.GetOrCreateDirectChat(ctx, userID1, userID2)
 }
+
+// GetUserReactions retrieves the user's recent reactions in chats they still belong to
+func (s *ServiceImpl) GetUserReactions(userID int, limit, offset int) ([]messaging.UserReaction, error) {
+	return s.messagingRepo.GetUserReactions(userID, limit, offset)
+}
+
+// GetSentMessages retrieves the user's recent messages in chats they still belong to
+func (s *ServiceImpl) GetSentMessages(userID int, limit, offset int) ([]messaging.SentMessage, error) {
+	return s.messagingRepo.GetSentMessages(userID, limit, offset)
+}
+
+// GetChatOverviews retrieves the user's chat list with latest messages and unread counts.
+// Direct chats are named after the other participant without extra lookups.
+func (s *ServiceImpl) GetChatOverviews(userID int, limit, offset int) ([]messaging.ChatOverview, error) {
+	overviews, err := s.messagingRepo.GetChatOverviews(userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range overviews {
+		if overviews[i].IsGroup {
+			continue
+		}
+		for _, participant := range overviews[i].Participants {
+			if participant.UserID != userID {
+				name := participant.FullName
+				overviews[i].ChatName = &name
+			}
+		}
+	}
+
+	return overviews, nil
+}
+
+// GetReadPositions returns how far each participant of the chat has read. Only
+// participants may see the read positions of a chat.
+func (s *ServiceImpl) GetReadPositions(chatID string, userID int) ([]messaging.ReadPosition, error) {
+	inChat, err := s.IsUserInChat(userID, chatID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !inChat {
+		return nil, ErrUserNotInChat
+	}
+
+	return s.messagingRepo.GetReadPositions(chatID)
+}
@@ -0,0 +1,880 @@
+package messaging
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	apierrors "github.com/bulatminnakhmetov/brigadka-backend/internal/errors"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/repository/messaging"
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
+)
+
+// MockRepository is a mock implementation of messaging.MessagingRepository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) GetUserChats(userID int) ([]messaging.Chat, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.Chat), args.Error(1)
+}
+
+func (m *MockRepository) GetChat(chatID string, userID int) (*messaging.Chat, error) {
+	args := m.Called(chatID, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*messaging.Chat), args.Error(1)
+}
+
+func (m *MockRepository) CreateChat(ctx context.Context, chatID string, creatorID int, chatName string, participants []int, isGroup bool) error {
+	args := m.Called(ctx, chatID, creatorID, chatName, participants, isGroup)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AddMessage(messageID string, chatID string, senderID int, content string, expiresIn time.Duration) (time.Time, error) {
+	args := m.Called(messageID, chatID, senderID, content, expiresIn)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockRepository) GetChatParticipants(chatID string) ([]int, error) {
+	args := m.Called(chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockRepository) IsUserInChat(userID int, chatID string) (bool, error) {
+	args := m.Called(userID, chatID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) AddParticipant(chatID string, userID int) error {
+	args := m.Called(chatID, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RemoveParticipant(chatID string, userID int) error {
+	args := m.Called(chatID, userID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetParticipantRole(chatID string, userID int) (string, error) {
+	args := m.Called(chatID, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRepository) SetParticipantRole(chatID string, userID int, role string) error {
+	args := m.Called(chatID, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RenameChat(chatID string, chatName string) error {
+	args := m.Called(chatID, chatName)
+	return args.Error(0)
+}
+
+func (m *MockRepository) AddReaction(reactionID string, chatID string, messageID string, userID int, reactionCode string) error {
+	args := m.Called(reactionID, chatID, messageID, userID, reactionCode)
+	return args.Error(0)
+}
+
+func (m *MockRepository) RemoveReaction(chatID string, messageID string, userID int, reactionCode string) error {
+	args := m.Called(chatID, messageID, userID, reactionCode)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetChatIDForMessage(messageID string) (string, error) {
+	args := m.Called(messageID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRepository) GetChatMessagesBefore(chatID string, beforeMessageID string, limit int) ([]messaging.ChatMessage, error) {
+	args := m.Called(chatID, beforeMessageID, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.ChatMessage), args.Error(1)
+}
+
+func (m *MockRepository) SearchMessages(chatID string, query string, limit, offset int) ([]messaging.ChatMessage, error) {
+	args := m.Called(chatID, query, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.ChatMessage), args.Error(1)
+}
+
+func (m *MockRepository) EditMessage(chatID string, messageID string, userID int, content string) (*messaging.ChatMessage, error) {
+	args := m.Called(chatID, messageID, userID, content)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*messaging.ChatMessage), args.Error(1)
+}
+
+func (m *MockRepository) DeleteMessage(chatID string, messageID string, userID int) (time.Time, error) {
+	args := m.Called(chatID, messageID, userID)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockRepository) DeleteExpiredMessages() ([]messaging.ExpiredMessage, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.ExpiredMessage), args.Error(1)
+}
+
+func (m *MockRepository) GetChatMessages(chatID string, userID int, limit, offset int) ([]messaging.ChatMessage, error) {
+	args := m.Called(chatID, userID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.ChatMessage), args.Error(1)
+}
+
+func (m *MockRepository) GetMessage(chatID string, messageID string) (*messaging.ChatMessage, error) {
+	args := m.Called(chatID, messageID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*messaging.ChatMessage), args.Error(1)
+}
+
+func (m *MockRepository) StoreTypingIndicator(userID int, chatID string) error {
+	args := m.Called(userID, chatID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) StoreReadReceipt(userID int, chatID string, messageID string) error {
+	args := m.Called(userID, chatID, messageID)
+	return args.Error(0)
+}
+
+func (m *MockRepository) GetUserChatRooms(userID int) (map[string]struct{}, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string]struct{}), args.Error(1)
+}
+
+func (m *MockRepository) GetChatParticipantsForBroadcast(chatID string) ([]int, error) {
+	args := m.Called(chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockRepository) GetOrCreateDirectChat(ctx context.Context, userID1 int, userID2 int) (string, error) {
+	args := m.Called(ctx, userID1, userID2)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRepository) GetUserReactions(userID int, limit, offset int) ([]messaging.UserReaction, error) {
+	args := m.Called(userID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.UserReaction), args.Error(1)
+}
+
+func (m *MockRepository) GetSentMessages(senderID int, limit, offset int) ([]messaging.SentMessage, error) {
+	args := m.Called(senderID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.SentMessage), args.Error(1)
+}
+
+func (m *MockRepository) GetChatOverviews(userID int, limit, offset int) ([]messaging.ChatOverview, error) {
+	args := m.Called(userID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.ChatOverview), args.Error(1)
+}
+
+func (m *MockRepository) GetMessageReactions(chatID string, messageID string, limit, offset int) ([]messaging.MessageReaction, error) {
+	args := m.Called(chatID, messageID, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.MessageReaction), args.Error(1)
+}
+
+func (m *MockRepository) ReactionCodeExists(reactionCode string) (bool, error) {
+	args := m.Called(reactionCode)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) GetReactionCatalog() ([]messaging.ReactionCatalogItem, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.ReactionCatalogItem), args.Error(1)
+}
+
+func (m *MockRepository) GetReadPositions(chatID string) ([]messaging.ReadPosition, error) {
+	args := m.Called(chatID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]messaging.ReadPosition), args.Error(1)
+}
+
+func (m *MockRepository) GetReactionSummaries(chatID string, messageIDs []string, userID int) (map[string][]messaging.ReactionSummary, error) {
+	args := m.Called(chatID, messageIDs, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string][]messaging.ReactionSummary), args.Error(1)
+}
+
+func (m *MockRepository) GetMessageReaders(chatID string, messageIDs []string) (map[string][]int, error) {
+	args := m.Called(chatID, messageIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(map[string][]int), args.Error(1)
+}
+
+func (m *MockRepository) HasBlockBetween(userIDs []int) (bool, error) {
+	args := m.Called(userIDs)
+	return args.Bool(0), args.Error(1)
+}
+
+// MockProfileRepository is a mock implementation of ProfileRepository
+type MockProfileRepository struct {
+	mock.Mock
+}
+
+func (m *MockProfileRepository) GetProfile(userID int) (*profile.ProfileModel, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profile.ProfileModel), args.Error(1)
+}
+
+func setupService() (*ServiceImpl, *MockRepository, *MockProfileRepository) {
+	repo := new(MockRepository)
+	profileRepo := new(MockProfileRepository)
+	return NewService(repo, profileRepo), repo, profileRepo
+}
+
+func TestGetUserReactions_ReturnsCallerReactionsInRecencyOrder(t *testing.T) {
+	service, repo, _ := setupService()
+
+	now := time.Now()
+	reactions := []messaging.UserReaction{
+		{ReactionID: "r2", ReactionCode: "like", ReactedAt: now, MessageID: "m2", ChatID: "chat-1"},
+		{ReactionID: "r1", ReactionCode: "heart", ReactedAt: now.Add(-time.Hour), MessageID: "m1", ChatID: "chat-2"},
+	}
+	repo.On("GetUserReactions", 1, 20, 0).Return(reactions, nil)
+
+	result, err := service.GetUserReactions(1, 20, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"r2", "r1"}, []string{result[0].ReactionID, result[1].ReactionID})
+	assert.True(t, result[0].ReactedAt.After(result[1].ReactedAt))
+	repo.AssertExpectations(t)
+}
+
+func TestGetSentMessages_ReturnsCallerMessagesInRecencyOrder(t *testing.T) {
+	service, repo, _ := setupService()
+
+	now := time.Now()
+	messages := []messaging.SentMessage{
+		{MessageID: "m2", Content: "Later", SentAt: now, ChatID: "chat-1"},
+		{MessageID: "m1", Content: "Earlier", SentAt: now.Add(-time.Hour), ChatID: "chat-2"},
+	}
+	repo.On("GetSentMessages", 1, 20, 0).Return(messages, nil)
+
+	result, err := service.GetSentMessages(1, 20, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m2", "m1"}, []string{result[0].MessageID, result[1].MessageID})
+	assert.True(t, result[0].SentAt.After(result[1].SentAt))
+	repo.AssertExpectations(t)
+}
+
+func TestAddMessage_MessageIDUniquePerChat(t *testing.T) {
+	service, repo, _ := setupService()
+
+	sentAt := time.Now()
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	repo.On("IsUserInChat", 1, "chat-2").Return(true, nil)
+	repo.On("AddMessage", "msg-1", "chat-1", 1, "Hello", time.Duration(0)).Return(sentAt, nil).Once()
+	repo.On("AddMessage", "msg-1", "chat-2", 1, "Hello", time.Duration(0)).Return(sentAt, nil).Once()
+	repo.On("AddMessage", "msg-1", "chat-1", 1, "Hello", time.Duration(0)).Return(time.Time{}, errors.New(apierrors.ErrorMessageAlreadyExists)).Once()
+
+	// The same ID is accepted in two different chats
+	_, err := service.AddMessage("msg-1", "chat-1", 1, "Hello", 0)
+	assert.NoError(t, err)
+
+	_, err = service.AddMessage("msg-1", "chat-2", 1, "Hello", 0)
+	assert.NoError(t, err)
+
+	// A duplicate within one chat is rejected
+	_, err = service.AddMessage("msg-1", "chat-1", 1, "Hello", 0)
+	assert.EqualError(t, err, apierrors.ErrorMessageAlreadyExists)
+
+	repo.AssertExpectations(t)
+}
+
+func TestAddMessage_RejectsInvalidExpiry(t *testing.T) {
+	service, repo, _ := setupService()
+
+	for _, expiresIn := range []time.Duration{-time.Second, MaxMessageExpiry + time.Second} {
+		_, err := service.AddMessage("msg-1", "chat-1", 1, "Hello", expiresIn)
+		assert.EqualError(t, err, apierrors.ErrorInvalidMessageExpiry)
+	}
+
+	repo.AssertNotCalled(t, "AddMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetChatOverviews_CombinedPayloadInConstantQueries(t *testing.T) {
+	for _, chatCount := range []int{1, 25} {
+		service, repo, profileRepo := setupService()
+
+		overviews := make([]messaging.ChatOverview, chatCount)
+		for i := range overviews {
+			overviews[i] = messaging.ChatOverview{
+				ChatID:  fmt.Sprintf("chat-%d", i),
+				IsGroup: false,
+				Participants: []messaging.ParticipantSummary{
+					{UserID: 1, FullName: "Me"},
+					{UserID: 100 + i, FullName: fmt.Sprintf("Friend %d", i)},
+				},
+				LastMessage: &messaging.ChatMessage{MessageID: fmt.Sprintf("msg-%d", i), Content: "Hi"},
+				UnreadCount: i,
+			}
+		}
+		repo.On("GetChatOverviews", 1, 50, 0).Return(overviews, nil)
+
+		result, err := service.GetChatOverviews(1, 50, 0)
+
+		assert.NoError(t, err)
+		assert.Len(t, result, chatCount)
+		for i, overview := range result {
+			assert.Equal(t, fmt.Sprintf("Friend %d", i), *overview.ChatName)
+			assert.Equal(t, i, overview.UnreadCount)
+			assert.Equal(t, fmt.Sprintf("msg-%d", i), overview.LastMessage.MessageID)
+		}
+
+		// A single repository call regardless of the number of chats
+		repo.AssertNumberOfCalls(t, "GetChatOverviews", 1)
+		assert.Len(t, repo.Calls, 1)
+		assert.Empty(t, profileRepo.Calls)
+	}
+}
+
+func TestAddReaction_NonMemberRejectedBeforeInsert(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetChatIDForMessage", "msg-1").Return("chat-1", nil)
+	repo.On("IsUserInChat", 2, "chat-1").Return(false, nil)
+
+	err := service.AddReaction("reaction-1", "msg-1", 2, "like")
+
+	assert.EqualError(t, err, apierrors.ErrorNotAuthorizedToReact)
+	repo.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+}
+
+func TestAddReaction_UnknownMessageRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetChatIDForMessage", "missing").Return("", sql.ErrNoRows)
+
+	err := service.AddReaction("reaction-1", "missing", 2, "like")
+
+	assert.EqualError(t, err, apierrors.ErrorNotAuthorizedToReact)
+	repo.AssertNotCalled(t, "IsUserInChat", mock.Anything, mock.Anything)
+	repo.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddReaction_MemberStoresReaction(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetChatIDForMessage", "msg-1").Return("chat-1", nil)
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	repo.On("ReactionCodeExists", "like").Return(true, nil)
+	repo.On("AddReaction", "reaction-1", "chat-1", "msg-1", 1, "like").Return(nil)
+
+	err := service.AddReaction("reaction-1", "msg-1", 1, "like")
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+func TestGetReactions_NonMemberRejectedBeforeQuery(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetChatIDForMessage", "msg-1").Return("chat-1", nil)
+	repo.On("IsUserInChat", 3, "chat-1").Return(false, nil)
+
+	_, err := service.GetReactions("msg-1", 3, 50, 0)
+
+	assert.ErrorIs(t, err, ErrUserNotInChat)
+	repo.AssertNotCalled(t, "GetMessageReactions", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetReactions_MemberGetsPage(t *testing.T) {
+	service, repo, _ := setupService()
+
+	reactions := []messaging.MessageReaction{{UserID: 2, ReactionCode: "like"}}
+	repo.On("GetChatIDForMessage", "msg-1").Return("chat-1", nil)
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	repo.On("GetMessageReactions", "chat-1", "msg-1", 50, 0).Return(reactions, nil)
+
+	result, err := service.GetReactions("msg-1", 1, 50, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, reactions, result)
+}
+
+func TestAddReaction_CodeOutsideCatalogRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetChatIDForMessage", "msg-1").Return("chat-1", nil)
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	repo.On("ReactionCodeExists", "<script>").Return(false, nil)
+
+	err := service.AddReaction("reaction-1", "msg-1", 1, "<script>")
+
+	assert.EqualError(t, err, apierrors.ErrorInvalidReactionCode)
+	repo.AssertNotCalled(t, "AddReaction", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetChatMessages_NonMemberRejectedBeforeQuery(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("IsUserInChat", 3, "chat-1").Return(false, nil)
+
+	messages, err := service.GetChatMessages("chat-1", 3, 50, 0)
+
+	assert.Nil(t, messages)
+	assert.ErrorIs(t, err, ErrUserNotInChat)
+	repo.AssertNotCalled(t, "GetChatMessages", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetChatMessages_MemberReadsMessages(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	repo.On("GetChatMessages", "chat-1", 1, 50, 0).Return([]messaging.ChatMessage{{MessageID: "msg-1", ChatID: "chat-1"}}, nil)
+
+	messages, err := service.GetChatMessages("chat-1", 1, 50, 0)
+
+	assert.NoError(t, err)
+	assert.Len(t, messages, 1)
+	repo.AssertExpectations(t)
+}
+
+func TestAttachMessageDetails_FillsInReturnedPageOnly(t *testing.T) {
+	service, repo, _ := setupService()
+
+	page := []messaging.ChatMessage{
+		{MessageID: "msg-2", ChatID: "chat-1", SenderID: 1},
+		{MessageID: "msg-1", ChatID: "chat-1", SenderID: 2},
+	}
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	// Only the page is queried, whatever else the repository knows about is ignored
+	repo.On("GetReactionSummaries", "chat-1", []string{"msg-2", "msg-1"}, 1).Return(map[string][]messaging.ReactionSummary{
+		"msg-1": {{ReactionCode: "like", Count: 2, Reacted: true}, {ReactionCode: "laugh", Count: 1}},
+		"msg-3": {{ReactionCode: "like", Count: 5}},
+	}, nil)
+	repo.On("GetMessageReaders", "chat-1", []string{"msg-2", "msg-1"}).Return(map[string][]int{
+		"msg-2": {2, 3},
+		"msg-1": {3},
+	}, nil)
+
+	err := service.AttachMessageDetails("chat-1", 1, page, MessageInclude{Reactions: true, Reads: true})
+
+	assert.NoError(t, err)
+	assert.Nil(t, page[0].Reactions)
+	assert.Equal(t, []ReactionSummary{{ReactionCode: "like", Count: 2, Reacted: true}, {ReactionCode: "laugh", Count: 1}}, page[1].Reactions)
+	assert.Equal(t, []int{2, 3}, page[0].ReadBy)
+	assert.Equal(t, []int{3}, page[1].ReadBy)
+	repo.AssertExpectations(t)
+}
+
+func TestAttachMessageDetails_OnlyRequestedDetails(t *testing.T) {
+	service, repo, _ := setupService()
+
+	page := []messaging.ChatMessage{{MessageID: "msg-1", ChatID: "chat-1"}}
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	repo.On("GetMessageReaders", "chat-1", []string{"msg-1"}).Return(map[string][]int{}, nil)
+
+	err := service.AttachMessageDetails("chat-1", 1, page, MessageInclude{Reads: true})
+
+	assert.NoError(t, err)
+	assert.Nil(t, page[0].ReadBy)
+	repo.AssertNotCalled(t, "GetReactionSummaries", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetChatMessagesBefore_ReturnsCursorOfOldestMessage(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	repo.On("GetChatMessagesBefore", "chat-1", "msg-9", 2).Return([]messaging.ChatMessage{
+		{MessageID: "msg-8", ChatID: "chat-1"},
+		{MessageID: "msg-7", ChatID: "chat-1"},
+	}, nil)
+	repo.On("GetChatMessagesBefore", "chat-1", "msg-7", 2).Return([]messaging.ChatMessage{
+		{MessageID: "msg-6", ChatID: "chat-1"},
+	}, nil)
+
+	page, err := service.GetChatMessagesBefore("chat-1", 1, "msg-9", 2)
+	assert.NoError(t, err)
+	assert.Len(t, page.Messages, 2)
+	assert.Equal(t, "msg-7", page.NextCursor)
+
+	// A short page is the last one
+	page, err = service.GetChatMessagesBefore("chat-1", 1, page.NextCursor, 2)
+	assert.NoError(t, err)
+	assert.Len(t, page.Messages, 1)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestGetChatMessagesBefore_NonMemberRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("IsUserInChat", 1, "chat-1").Return(false, nil)
+
+	_, err := service.GetChatMessagesBefore("chat-1", 1, "", 50)
+
+	assert.EqualError(t, err, apierrors.ErrorUserNotInChat)
+	repo.AssertNotCalled(t, "GetChatMessagesBefore", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestEditMessage_NonMemberRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("IsUserInChat", 2, "chat-1").Return(false, nil)
+
+	_, err := service.EditMessage("chat-1", "msg-1", 2, "Edited")
+
+	assert.EqualError(t, err, apierrors.ErrorUserNotInChat)
+	repo.AssertNotCalled(t, "EditMessage", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetMessage_NonMemberRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("IsUserInChat", 2, "chat-1").Return(false, nil)
+
+	_, err := service.GetMessage("chat-1", "msg-1", 2)
+
+	assert.EqualError(t, err, apierrors.ErrorUserNotInChat)
+	repo.AssertNotCalled(t, "GetMessage", mock.Anything, mock.Anything)
+}
+
+func TestGetReadPositions_ReflectsStoredReceipts(t *testing.T) {
+	service, repo, _ := setupService()
+
+	readAt := time.Now()
+	msg := "msg-7"
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	repo.On("GetReadPositions", "chat-1").Return([]messaging.ReadPosition{
+		{UserID: 1, LastReadMessageID: &msg, ReadAt: &readAt},
+		{UserID: 2},
+	}, nil)
+
+	positions, err := service.GetReadPositions("chat-1", 1)
+
+	assert.NoError(t, err)
+	assert.Len(t, positions, 2)
+	assert.Equal(t, "msg-7", *positions[0].LastReadMessageID)
+	assert.Equal(t, readAt, *positions[0].ReadAt)
+	// A participant without a receipt has no position yet
+	assert.Nil(t, positions[1].LastReadMessageID)
+	assert.Nil(t, positions[1].ReadAt)
+	repo.AssertExpectations(t)
+}
+
+func TestGetReadPositions_NonMemberRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("IsUserInChat", 3, "chat-1").Return(false, nil)
+
+	_, err := service.GetReadPositions("chat-1", 3)
+
+	assert.EqualError(t, err, apierrors.ErrorUserNotInChat)
+	repo.AssertNotCalled(t, "GetReadPositions", mock.Anything)
+}
+
+func TestGetUserTeams_ReturnsOnlyGroupChats(t *testing.T) {
+	service, repo, profileRepo := setupService()
+
+	teamName := "Impro Crew"
+	repo.On("GetUserChats", 1).Return([]messaging.Chat{
+		{ChatID: "direct-1", IsGroup: false, Participants: []int{1, 2}},
+		{ChatID: "team-1", ChatName: &teamName, IsGroup: true, Participants: []int{1, 2, 3}},
+	}, nil)
+
+	teams, err := service.GetUserTeams(1)
+
+	assert.NoError(t, err)
+	assert.Len(t, teams, 1)
+	assert.Equal(t, "team-1", teams[0].ChatID)
+	assert.Equal(t, &teamName, teams[0].ChatName)
+	profileRepo.AssertNotCalled(t, "GetProfile", mock.Anything)
+}
+
+func TestCreateChat_BlockedPairRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("HasBlockBetween", []int{1, 2, 3}).Return(true, nil)
+
+	_, err := service.CreateChat(context.Background(), "chat-1", 1, "Team", []int{2, 3})
+
+	assert.ErrorIs(t, err, ErrUsersBlocked)
+	repo.AssertNotCalled(t, "CreateChat", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateChat_TwoPeopleIsDirectChat(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("HasBlockBetween", []int{1, 2}).Return(false, nil)
+	repo.On("GetOrCreateDirectChat", mock.Anything, 1, 2).Return("direct-1", nil)
+
+	// The creator listed among the participants doesn't make it a group, and the
+	// pair's direct chat is reused instead of creating another one
+	chatID, err := service.CreateChat(context.Background(), "chat-1", 1, "Pair", []int{1, 2, 2})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "direct-1", chatID)
+	repo.AssertExpectations(t)
+	repo.AssertNotCalled(t, "CreateChat", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateChat_OnlyCreatorRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("HasBlockBetween", []int{1}).Return(false, nil)
+
+	_, err := service.CreateChat(context.Background(), "chat-1", 1, "Solo", []int{1})
+
+	assert.EqualError(t, err, apierrors.ErrorCannotCreateChatWithSelf)
+	repo.AssertNotCalled(t, "CreateChat", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestCreateChat_ThreePeopleIsGroupChat(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("HasBlockBetween", []int{1, 2, 3}).Return(false, nil)
+	repo.On("CreateChat", mock.Anything, "chat-1", 1, "Team", []int{2, 3}, true).Return(nil)
+
+	chatID, err := service.CreateChat(context.Background(), "chat-1", 1, "Team", []int{2, 3})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "chat-1", chatID)
+	repo.AssertExpectations(t)
+}
+
+func TestAddParticipant_DirectChatRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetParticipantRole", "chat-1", 1).Return(RoleAdmin, nil)
+	repo.On("AddParticipant", "chat-1", 5).Return(errors.New(apierrors.ErrorDirectChatParticipants))
+
+	err := service.AddParticipant("chat-1", 1, 5)
+
+	assert.EqualError(t, err, apierrors.ErrorDirectChatParticipants)
+}
+
+func TestAddParticipant_BlockedByParticipantRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetParticipantRole", "chat-1", 1).Return(RoleAdmin, nil)
+	repo.On("AddParticipant", "chat-1", 5).Return(errors.New(apierrors.ErrorUsersBlocked))
+
+	err := service.AddParticipant("chat-1", 1, 5)
+
+	assert.ErrorIs(t, err, ErrUsersBlocked)
+}
+
+func TestGetOrCreateDirectChat_BlockedPairRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("HasBlockBetween", []int{1, 2}).Return(true, nil)
+
+	_, err := service.GetOrCreateDirectChat(context.Background(), 1, 2)
+
+	assert.EqualError(t, err, apierrors.ErrorUsersBlocked)
+	repo.AssertNotCalled(t, "GetOrCreateDirectChat", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAddParticipant_UnblockedUserAdded(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetParticipantRole", "chat-1", 1).Return(RoleAdmin, nil)
+	repo.On("AddParticipant", "chat-1", 5).Return(nil)
+
+	assert.NoError(t, service.AddParticipant("chat-1", 1, 5))
+	repo.AssertExpectations(t)
+}
+
+func TestAddParticipant_MemberRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetParticipantRole", "chat-1", 2).Return(RoleMember, nil)
+
+	err := service.AddParticipant("chat-1", 2, 5)
+
+	assert.ErrorIs(t, err, ErrNotChatAdmin)
+	repo.AssertNotCalled(t, "AddParticipant", mock.Anything, mock.Anything)
+}
+
+func TestAddParticipant_NonParticipantRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetParticipantRole", "chat-1", 9).Return("", nil)
+
+	err := service.AddParticipant("chat-1", 9, 5)
+
+	assert.ErrorIs(t, err, ErrUserNotInChat)
+	repo.AssertNotCalled(t, "AddParticipant", mock.Anything, mock.Anything)
+}
+
+func TestRemoveParticipant_MemberCanLeave(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("IsUserInChat", 2, "chat-1").Return(true, nil)
+	repo.On("RemoveParticipant", "chat-1", 2).Return(nil)
+
+	assert.NoError(t, service.RemoveParticipant("chat-1", 2, 2))
+	repo.AssertNotCalled(t, "GetParticipantRole", mock.Anything, mock.Anything)
+	repo.AssertExpectations(t)
+}
+
+func TestRemoveParticipant_MemberCannotRemoveOthers(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetParticipantRole", "chat-1", 2).Return(RoleMember, nil)
+
+	err := service.RemoveParticipant("chat-1", 2, 3)
+
+	assert.ErrorIs(t, err, ErrNotChatAdmin)
+	repo.AssertNotCalled(t, "RemoveParticipant", mock.Anything, mock.Anything)
+}
+
+func TestRemoveParticipant_AdminRemovesOthers(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("GetParticipantRole", "chat-1", 1).Return(RoleAdmin, nil)
+	repo.On("RemoveParticipant", "chat-1", 3).Return(nil)
+
+	assert.NoError(t, service.RemoveParticipant("chat-1", 1, 3))
+	repo.AssertExpectations(t)
+}
+
+func TestSetParticipantRole(t *testing.T) {
+	t.Run("admin promotes member", func(t *testing.T) {
+		service, repo, _ := setupService()
+		repo.On("GetParticipantRole", "chat-1", 1).Return(RoleAdmin, nil)
+		repo.On("SetParticipantRole", "chat-1", 2, RoleAdmin).Return(nil)
+
+		assert.NoError(t, service.SetParticipantRole("chat-1", 1, 2, RoleAdmin))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("member cannot promote", func(t *testing.T) {
+		service, repo, _ := setupService()
+		repo.On("GetParticipantRole", "chat-1", 2).Return(RoleMember, nil)
+
+		err := service.SetParticipantRole("chat-1", 2, 2, RoleAdmin)
+
+		assert.ErrorIs(t, err, ErrNotChatAdmin)
+		repo.AssertNotCalled(t, "SetParticipantRole", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		service, repo, _ := setupService()
+
+		err := service.SetParticipantRole("chat-1", 1, 2, "owner")
+
+		assert.EqualError(t, err, apierrors.ErrorInvalidChatRole)
+		repo.AssertNotCalled(t, "GetParticipantRole", mock.Anything, mock.Anything)
+	})
+}
+
+func TestRenameChat(t *testing.T) {
+	t.Run("admin renames", func(t *testing.T) {
+		service, repo, _ := setupService()
+		repo.On("GetParticipantRole", "chat-1", 1).Return(RoleAdmin, nil)
+		repo.On("RenameChat", "chat-1", "Impro crew").Return(nil)
+
+		assert.NoError(t, service.RenameChat("chat-1", 1, "  Impro crew "))
+		repo.AssertExpectations(t)
+	})
+
+	t.Run("member cannot rename", func(t *testing.T) {
+		service, repo, _ := setupService()
+		repo.On("GetParticipantRole", "chat-1", 2).Return(RoleMember, nil)
+
+		err := service.RenameChat("chat-1", 2, "Impro crew")
+
+		assert.ErrorIs(t, err, ErrNotChatAdmin)
+		repo.AssertNotCalled(t, "RenameChat", mock.Anything, mock.Anything)
+	})
+
+	t.Run("empty name", func(t *testing.T) {
+		service, repo, _ := setupService()
+
+		err := service.RenameChat("chat-1", 1, "   ")
+
+		assert.EqualError(t, err, apierrors.ErrorInvalidChatName)
+		repo.AssertNotCalled(t, "GetParticipantRole", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSearchMessages_NonParticipantRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	repo.On("IsUserInChat", 9, "chat-1").Return(false, nil)
+
+	_, err := service.SearchMessages("chat-1", 9, "jam", 20, 0)
+
+	assert.ErrorIs(t, err, ErrUserNotInChat)
+	repo.AssertNotCalled(t, "SearchMessages", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSearchMessages_TrimsQuery(t *testing.T) {
+	service, repo, _ := setupService()
+
+	found := []messaging.ChatMessage{{MessageID: "msg-2", Content: "Jam on Friday?"}}
+	repo.On("IsUserInChat", 1, "chat-1").Return(true, nil)
+	repo.On("SearchMessages", "chat-1", "jam", 20, 0).Return(found, nil)
+
+	messages, err := service.SearchMessages("chat-1", 1, "  jam ", 20, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, found, messages)
+}
+
+func TestSearchMessages_EmptyQueryRejected(t *testing.T) {
+	service, repo, _ := setupService()
+
+	_, err := service.SearchMessages("chat-1", 1, "   ", 20, 0)
+
+	assert.EqualError(t, err, apierrors.ErrorInvalidSearchQuery)
+	repo.AssertNotCalled(t, "IsUserInChat", mock.Anything, mock.Anything)
+}
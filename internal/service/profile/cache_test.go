@@ -0,0 +1,44 @@
+package profile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTLCache_ExpiresEntries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newTTLCache[string, int](time.Minute, 2, func() time.Time { return now })
+
+	cache.put("a", 1)
+	value, ok := cache.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	now = now.Add(time.Minute)
+	_, ok = cache.get("a")
+	assert.False(t, ok)
+}
+
+func TestTTLCache_BoundsEntries(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cache := newTTLCache[int, string](time.Minute, 2, func() time.Time { return now })
+
+	cache.put(1, "one")
+	now = now.Add(time.Minute)
+	cache.put(2, "two")
+
+	// The expired entry makes room
+	cache.put(3, "three")
+	assert.Len(t, cache.entries, 2)
+	_, ok := cache.get(2)
+	assert.True(t, ok)
+
+	// Only fresh entries are left, the cache starts over
+	cache.put(4, "four")
+	assert.Len(t, cache.entries, 1)
+	value, ok := cache.get(4)
+	assert.True(t, ok)
+	assert.Equal(t, "four", value)
+}
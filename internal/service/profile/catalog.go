@@ -0,0 +1,60 @@
+package profile
+
+import "sync"
+
+// Catalogs holds every catalog a client needs on start, keyed by catalog name
+type Catalogs struct {
+	ImprovStyles []TranslatedItem `json:"improv_styles"`
+	ImprovGoals  []TranslatedItem `json:"improv_goals"`
+	Genders      []TranslatedItem `json:"genders"`
+	Cities       []City           `json:"cities"`
+}
+
+// GetAllCatalogs returns all catalogs in the given language. The catalogs are
+// fetched concurrently; if any of them fails, the first error is returned.
+func (s *ProfileServiceImpl) GetAllCatalogs(lang string) (*Catalogs, error) {
+	lang, err := resolveLanguage(lang)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		catalogs Catalogs
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	fetch := func(load func() error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := load(); err != nil {
+				errOnce.Do(func() { firstErr = err })
+			}
+		}()
+	}
+
+	fetch(func() (err error) {
+		catalogs.ImprovStyles, err = s.GetImprovStyles(lang)
+		return err
+	})
+	fetch(func() (err error) {
+		catalogs.ImprovGoals, err = s.GetImprovGoals(lang)
+		return err
+	})
+	fetch(func() (err error) {
+		catalogs.Genders, err = s.GetGenders(lang)
+		return err
+	})
+	fetch(func() (err error) {
+		catalogs.Cities, err = s.GetCities()
+		return err
+	})
+
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return &catalogs, nil
+}
@@ -0,0 +1,68 @@
+package profile
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	profilerepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
+)
+
+func TestGetAllCatalogs_ReturnsEveryCatalog(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	profileRepo.On("GetImprovStylesCatalog", "en").Return([]profilerepo.TranslatedItem{{Code: "shortform", Label: "Short form"}}, nil)
+	profileRepo.On("GetImprovGoalsCatalog", "en").Return([]profilerepo.TranslatedItem{{Code: "hobby", Label: "Hobby"}}, nil)
+	profileRepo.On("GetGendersCatalog", "en").Return([]profilerepo.TranslatedItem{{Code: "female", Label: "Female"}}, nil)
+	profileRepo.On("GetCities").Return([]struct {
+		ID   int
+		Name string
+	}{{ID: 1, Name: "Moscow"}}, nil)
+
+	catalogs, err := service.GetAllCatalogs("en")
+
+	assert.NoError(t, err)
+	assert.Equal(t, &Catalogs{
+		ImprovStyles: []TranslatedItem{{Code: "shortform", Label: "Short form"}},
+		ImprovGoals:  []TranslatedItem{{Code: "hobby", Label: "Hobby"}},
+		Genders:      []TranslatedItem{{Code: "female", Label: "Female"}},
+		Cities:       []City{{ID: 1, Name: "Moscow"}},
+	}, catalogs)
+	profileRepo.AssertExpectations(t)
+}
+
+func TestGetAllCatalogs_FailsIfAnyCatalogFails(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	profileRepo.On("GetImprovStylesCatalog", "ru").Return([]profilerepo.TranslatedItem{}, nil)
+	profileRepo.On("GetImprovGoalsCatalog", "ru").Return(nil, errors.New("db down"))
+	profileRepo.On("GetGendersCatalog", "ru").Return([]profilerepo.TranslatedItem{}, nil)
+	profileRepo.On("GetCities").Return([]struct {
+		ID   int
+		Name string
+	}{}, nil)
+
+	catalogs, err := service.GetAllCatalogs("ru")
+
+	assert.Nil(t, catalogs)
+	assert.EqualError(t, err, "db down")
+}
+
+func TestGetImprovStyles_ResolvesLanguage(t *testing.T) {
+	service, profileRepo, _ := setupService()
+	profileRepo.On("GetImprovStylesCatalog", "ru").Return([]profilerepo.TranslatedItem{}, nil).Once()
+	profileRepo.On("GetImprovStylesCatalog", "en").Return([]profilerepo.TranslatedItem{}, nil).Once()
+
+	// Empty defaults to DefaultLanguage, codes are case-insensitive
+	_, err := service.GetImprovStyles("")
+	assert.NoError(t, err)
+	_, err = service.GetImprovStyles(" EN ")
+	assert.NoError(t, err)
+
+	_, err = service.GetImprovStyles("de")
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+	_, err = service.GetAllCatalogs("de")
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+	profileRepo.AssertExpectations(t)
+}
@@ -0,0 +1,102 @@
+package profile
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	profilerepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
+)
+
+// DefaultFeedCacheTTL is how long a feed page is served from the cache when not configured
+const DefaultFeedCacheTTL = 30 * time.Second
+
+// maxFeedCacheEntries bounds the number of cached feed pages
+const maxFeedCacheEntries = 256
+
+var ErrInvalidFeedCursor = errors.New("invalid feed cursor")
+
+// FeedResult is a page of the new profiles feed
+type FeedResult struct {
+	Profiles   []Profile `json:"profiles"`
+	NextCursor string    `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// GetNewProfiles returns a page of recently created profiles, newest first, as seen by
+// userID: users they have blocked or were blocked by are left out. Pages are cached
+// for a short time, so new profiles may appear with a delay.
+// The returned result is shared with the cache and must not be modified.
+func (s *ProfileServiceImpl) GetNewProfiles(userID int, cursor string, limit int) (*FeedResult, error) {
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+
+	var after *profilerepo.FeedPosition
+	if cursor != "" {
+		position, err := decodeFeedCursor(cursor)
+		if err != nil {
+			return nil, err
+		}
+		after = position
+	}
+
+	key := fmt.Sprintf("%d|%s|%d", userID, cursor, limit)
+	if result, ok := s.feedCache.get(key); ok {
+		return result, nil
+	}
+
+	profiles, err := s.profileRepo.GetNewProfiles(userID, after, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &FeedResult{Profiles: make([]Profile, 0, len(profiles))}
+	for _, p := range profiles {
+		expanded, err := s.ExpandProfile(p)
+		if err != nil {
+			log.Printf("Error expanding profile %d: %v", p.UserID, err)
+			continue
+		}
+		result.Profiles = append(result.Profiles, *expanded)
+	}
+
+	// A full page may be followed by more profiles
+	if len(profiles) == limit {
+		last := profiles[len(profiles)-1]
+		result.NextCursor = encodeFeedCursor(profilerepo.FeedPosition{CreatedAt: last.CreatedAt, UserID: last.UserID})
+	}
+
+	s.feedCache.put(key, result)
+	return result, nil
+}
+
+// encodeFeedCursor encodes a feed position into an opaque cursor
+func encodeFeedCursor(position profilerepo.FeedPosition) string {
+	raw := fmt.Sprintf("%d:%d", position.CreatedAt.UnixNano(), position.UserID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeFeedCursor decodes a cursor produced by encodeFeedCursor
+func decodeFeedCursor(cursor string) (*profilerepo.FeedPosition, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidFeedCursor
+	}
+
+	var createdAt int64
+	var userID int
+	if _, err := fmt.Sscanf(string(raw), "%d:%d", &createdAt, &userID); err != nil {
+		return nil, ErrInvalidFeedCursor
+	}
+
+	return &profilerepo.FeedPosition{CreatedAt: time.Unix(0, createdAt).UTC(), UserID: userID}, nil
+}
+
+// feedCache keeps feed pages for a short time
+type feedCache = ttlCache[string, *FeedResult]
+
+func newFeedCache(ttl time.Duration, now func() time.Time) *feedCache {
+	return newTTLCache[string, *FeedResult](ttl, maxFeedCacheEntries, now)
+}
@@ -0,0 +1,104 @@
+package profile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mediarepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/media"
+	profilerepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
+)
+
+// setupFeedService returns a service whose feed cache uses the given clock
+func setupFeedService(now func() time.Time) (*ProfileServiceImpl, *MockProfileRepository) {
+	service, profileRepo, mediaRepo := setupService()
+	service.feedCache = newFeedCache(time.Minute, now)
+
+	profileRepo.On("GetImprovStyles", mock.Anything).Return([]string{}, nil)
+	mediaRepo.On("GetMediaByIDs", mock.Anything).Return([]mediarepo.Media{}, nil)
+	return service, profileRepo
+}
+
+func feedProfiles(createdAt time.Time, userIDs ...int) []*profilerepo.ProfileModel {
+	profiles := make([]*profilerepo.ProfileModel, len(userIDs))
+	for i, userID := range userIDs {
+		profiles[i] = &profilerepo.ProfileModel{UserID: userID, CreatedAt: createdAt.Add(-time.Duration(i) * time.Minute)}
+	}
+	return profiles
+}
+
+func TestGetNewProfiles_CursorContinuesAfterLastProfile(t *testing.T) {
+	service, profileRepo := setupFeedService(time.Now)
+
+	createdAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	firstPage := feedProfiles(createdAt, 5, 4)
+	profileRepo.On("GetNewProfiles", 1, (*profilerepo.FeedPosition)(nil), 2).Return(firstPage, nil)
+
+	result, err := service.GetNewProfiles(1, "", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5, 4}, []int{result.Profiles[0].UserID, result.Profiles[1].UserID})
+	assert.NotEmpty(t, result.NextCursor)
+
+	// The next page starts after the last profile of the previous one
+	last := firstPage[1]
+	profileRepo.On("GetNewProfiles", 1, &profilerepo.FeedPosition{CreatedAt: last.CreatedAt, UserID: 4}, 2).
+		Return(feedProfiles(createdAt.Add(-time.Hour), 2), nil)
+
+	next, err := service.GetNewProfiles(1, result.NextCursor, 2)
+	assert.NoError(t, err)
+	assert.Len(t, next.Profiles, 1)
+	assert.Equal(t, 2, next.Profiles[0].UserID)
+	assert.Empty(t, next.NextCursor)
+
+	profileRepo.AssertExpectations(t)
+}
+
+func TestGetNewProfiles_CacheHitSkipsRepository(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	service, profileRepo := setupFeedService(func() time.Time { return now })
+
+	profileRepo.On("GetNewProfiles", 1, (*profilerepo.FeedPosition)(nil), 20).Return(feedProfiles(now, 1), nil)
+
+	_, err := service.GetNewProfiles(1, "", 0)
+	assert.NoError(t, err)
+	result, err := service.GetNewProfiles(1, "", 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Profiles[0].UserID)
+	profileRepo.AssertNumberOfCalls(t, "GetNewProfiles", 1)
+
+	// The page is loaded again once the entry expires
+	now = now.Add(time.Minute)
+	_, err = service.GetNewProfiles(1, "", 20)
+	assert.NoError(t, err)
+	profileRepo.AssertNumberOfCalls(t, "GetNewProfiles", 2)
+}
+
+func TestGetNewProfiles_RejectsInvalidCursor(t *testing.T) {
+	service, profileRepo := setupFeedService(time.Now)
+
+	_, err := service.GetNewProfiles(1, "not a cursor", 20)
+
+	assert.ErrorIs(t, err, ErrInvalidFeedCursor)
+	profileRepo.AssertNotCalled(t, "GetNewProfiles", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetNewProfiles_CachesPagesPerUser(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	service, profileRepo := setupFeedService(func() time.Time { return now })
+
+	// User 2 has blocked user 5, so their feeds differ
+	profileRepo.On("GetNewProfiles", 1, (*profilerepo.FeedPosition)(nil), 20).Return(feedProfiles(now, 5, 4), nil)
+	profileRepo.On("GetNewProfiles", 2, (*profilerepo.FeedPosition)(nil), 20).Return(feedProfiles(now, 4), nil)
+
+	first, err := service.GetNewProfiles(1, "", 20)
+	assert.NoError(t, err)
+	second, err := service.GetNewProfiles(2, "", 20)
+	assert.NoError(t, err)
+
+	assert.Len(t, first.Profiles, 2)
+	assert.Len(t, second.Profiles, 1)
+	assert.Equal(t, 4, second.Profiles[0].UserID)
+	profileRepo.AssertExpectations(t)
+}
@@ -3,8 +3,24 @@ package profile
 import (
 	"log"
 	"time"
+
+	profilerepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
+)
+
+// Search result orderings
+const (
+	SortCreatedAtDesc = profilerepo.SortCreatedAtDesc
+	SortCreatedAtAsc  = profilerepo.SortCreatedAtAsc
+	SortAgeAsc        = profilerepo.SortAgeAsc
+	SortAgeDesc       = profilerepo.SortAgeDesc
+	SortRelevance     = profilerepo.SortRelevance
+	SortDistance      = profilerepo.SortDistance
+	SortLastSeenDesc  = profilerepo.SortLastSeenDesc
 )
 
+// SortOrders lists the supported search orderings, the default first
+var SortOrders = profilerepo.SortOrders
+
 // SearchFilter defines the filters for profile searches
 type SearchFilter struct {
 	FullName       *string    `json:"full_name,omitempty"`
@@ -13,11 +29,17 @@ type SearchFilter struct {
 	ImprovStyles   []string   `json:"improv_styles,omitempty"`
 	AgeMin         *int       `json:"age_min,omitempty"`
 	AgeMax         *int       `json:"age_max,omitempty"`
+	WithUnknownAge bool       `json:"include_unknown_age,omitempty"` // Keep profiles without a birthday in an age range search
 	Genders        []string   `json:"genders,omitempty"`
 	CityID         *int       `json:"city_id,omitempty"`
+	NearLat        *float64   `json:"near_lat,omitempty"` // NearLat, NearLng and RadiusKm are given together
+	NearLng        *float64   `json:"near_lng,omitempty"`
+	RadiusKm       *float64   `json:"radius_km,omitempty"`
 	HasAvatar      *bool      `json:"has_avatar,omitempty"`
 	HasVideo       *bool      `json:"has_video,omitempty"`
+	HasMedia       *bool      `json:"has_media,omitempty"` // At least one avatar or video
 	CreatedAfter   *time.Time `json:"created_after,omitempty"`
+	SortBy         string     `json:"sort_by,omitempty"` // Defaults to newest first
 	Page           int        `json:"page"`
 	PageSize       int        `json:"page_size"`
 }
@@ -28,10 +50,27 @@ type SearchResult struct {
 	TotalCount int       `json:"total_count"`
 	Page       int       `json:"page"`
 	PageSize   int       `json:"page_size"`
+	TotalPages int       `json:"total_pages"`
 }
 
-// Search searches for profiles with the given filters and sorts results by improv style matches
+// Search searches for profiles with the given filters in the requested order
 func (s *ProfileServiceImpl) Search(userID int, filter SearchFilter) (*SearchResult, error) {
+	if filter.SortBy == "" {
+		filter.SortBy = SortCreatedAtDesc
+	}
+	if !profilerepo.IsValidSort(filter.SortBy) {
+		return nil, ErrInvalidSort
+	}
+
+	near, err := nearFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+	// Without a point there is no distance to sort by
+	if near == nil && filter.SortBy == SortDistance {
+		return nil, ErrInvalidSort
+	}
+
 	// Set defaults for pagination
 	if filter.Page <= 0 {
 		filter.Page = 1
@@ -40,6 +79,11 @@ func (s *ProfileServiceImpl) Search(userID int, filter SearchFilter) (*SearchRes
 		filter.PageSize = 20
 	}
 
+	// Normalize catalog codes
+	filter.Goals = normalizeCatalogCodes(filter.Goals)
+	filter.ImprovStyles = normalizeCatalogCodes(filter.ImprovStyles)
+	filter.Genders = normalizeCatalogCodes(filter.Genders)
+
 	// Convert ages to birthdate bounds if provided
 	var birthDateMin, birthDateMax *time.Time
 	if filter.AgeMin != nil {
@@ -60,11 +104,15 @@ func (s *ProfileServiceImpl) Search(userID int, filter SearchFilter) (*SearchRes
 		filter.ImprovStyles,
 		birthDateMin,
 		birthDateMax,
+		filter.WithUnknownAge,
 		filter.Genders,
 		filter.CityID,
+		near,
 		filter.HasAvatar,
 		filter.HasVideo,
+		filter.HasMedia,
 		filter.CreatedAfter,
+		filter.SortBy,
 		filter.Page,
 		filter.PageSize,
 	)
@@ -79,6 +127,7 @@ func (s *ProfileServiceImpl) Search(userID int, filter SearchFilter) (*SearchRes
 		TotalCount: totalCount,
 		Page:       filter.Page,
 		PageSize:   filter.PageSize,
+		TotalPages: totalPages(totalCount, filter.PageSize),
 	}
 
 	for _, p := range profiles {
@@ -92,3 +141,25 @@ func (s *ProfileServiceImpl) Search(userID int, filter SearchFilter) (*SearchRes
 
 	return result, nil
 }
+
+// nearFilter returns the proximity filter of a search, or nil if none was requested.
+// The point and the radius must be given together.
+func nearFilter(filter SearchFilter) (*profilerepo.NearFilter, error) {
+	if filter.NearLat == nil && filter.NearLng == nil && filter.RadiusKm == nil {
+		return nil, nil
+	}
+	if filter.NearLat == nil || filter.NearLng == nil || filter.RadiusKm == nil {
+		return nil, ErrInvalidProximity
+	}
+
+	lat, lng, radius := *filter.NearLat, *filter.NearLng, *filter.RadiusKm
+	if lat < -90 || lat > 90 || lng < -180 || lng > 180 || radius <= 0 {
+		return nil, ErrInvalidProximity
+	}
+	return &profilerepo.NearFilter{Lat: lat, Lng: lng, RadiusKm: radius}, nil
+}
+
+// totalPages returns the number of pages needed for the results, counting a partial last page
+func totalPages(totalCount, pageSize int) int {
+	return (totalCount + pageSize - 1) / pageSize
+}
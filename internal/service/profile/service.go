@@ -4,8 +4,12 @@ import (
 	"database/sql"
 	"errors"
 	"log"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/lib/pq"
+
 	mediarepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/media"
 	"github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
 	profilerepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
@@ -20,14 +24,51 @@ var (
 	ErrInvalidImprovGoal    = errors.New("invalid improv goal")
 	ErrInvalidGender        = errors.New("invalid gender")
 	ErrInvalidCity          = errors.New("invalid city")
+	ErrInvalidCatalog       = errors.New("invalid catalog type")
+	ErrTooManyImprovStyles  = errors.New("too many improv styles")
+	ErrTooManyVideos        = errors.New("too many videos")
+	ErrInvalidMedia         = errors.New("media not found or owned by another user")
+	ErrInvalidSort          = errors.New("invalid sort order")
+	ErrInvalidMediaOrder    = errors.New("media order must list every profile video exactly once")
+	ErrInvalidProximity     = errors.New("near_lat, near_lng and radius_km must be valid and given together")
+	ErrProfileNotDeleted    = errors.New("profile is not deleted")
+	ErrRestoreExpired       = errors.New("profile can no longer be restored")
+	ErrImprovStylesRequired = errors.New("at least one improv style is required")
+	ErrUnsupportedLanguage  = errors.New("unsupported language")
 )
 
+// SupportedLanguages lists the languages catalogs are expected to be translated into
+var SupportedLanguages = []string{"ru", "en"}
+
+// DefaultLanguage is used for catalog labels when no language is requested
+const DefaultLanguage = "ru"
+
+// resolveLanguage returns the catalog language for a requested one: empty means
+// DefaultLanguage, a language outside SupportedLanguages is rejected
+func resolveLanguage(lang string) (string, error) {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if lang == "" {
+		return DefaultLanguage, nil
+	}
+	if !slices.Contains(SupportedLanguages, lang) {
+		return "", ErrUnsupportedLanguage
+	}
+	return lang, nil
+}
+
 // TranslatedItem represents a catalog item with translations
 type TranslatedItem struct {
 	Code  string `json:"code"`
 	Label string `json:"label"`
 }
 
+// CatalogItemTranslations represents a catalog item with labels in all supported languages
+type CatalogItemTranslations struct {
+	Code             string            `json:"code"`
+	Labels           map[string]string `json:"labels"`
+	MissingLanguages []string          `json:"missing_languages"`
+}
+
 // City represents a city
 type City struct {
 	ID   int    `json:"id"`
@@ -42,18 +83,21 @@ type Media struct {
 
 // Profile represents profile data for response
 type Profile struct {
-	UserID         int       `json:"user_id"`
-	FullName       string    `json:"full_name"`
-	Birthday       time.Time `json:"birthday,omitempty"`
-	Gender         string    `json:"gender,omitempty"`
-	CityID         int       `json:"city_id,omitempty"`
-	Bio            string    `json:"bio,omitempty"`
-	Goal           string    `json:"goal,omitempty"`
-	LookingForTeam bool      `json:"looking_for_team"`
-	ImprovStyles   []string  `json:"improv_styles,omitempty"`
-	CreatedAt      time.Time `json:"created_at"`
-	Avatar         *Media    `json:"avatar,omitempty"`
-	Videos         []Media   `json:"videos,omitempty"`
+	UserID         int        `json:"user_id"`
+	FullName       string     `json:"full_name"`
+	Birthday       time.Time  `json:"birthday,omitempty"`
+	Gender         string     `json:"gender,omitempty"`
+	CityID         int        `json:"city_id,omitempty"`
+	Bio            string     `json:"bio,omitempty"`
+	Goal           string     `json:"goal,omitempty"`
+	LookingForTeam bool       `json:"looking_for_team"`
+	ImprovStyles   []string   `json:"improv_styles,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	LastSeenAt     *time.Time `json:"last_seen_at,omitempty"`
+	Avatar         *Media     `json:"avatar,omitempty"`
+	Videos         []Media    `json:"videos,omitempty"`
+	DistanceKm     *float64   `json:"distance_km,omitempty"` // Set in searches near a point
 }
 
 // ProfileCreateRequest represents data needed to create a profile
@@ -85,6 +129,13 @@ type ProfileUpdateRequest struct {
 	Videos         []int      `json:"videos,omitempty"`
 }
 
+// ImprovProfilePatch lists the improv fields to change; nil fields are left as they are
+type ImprovProfilePatch struct {
+	Goal           *string
+	ImprovStyles   *[]string
+	LookingForTeam *bool
+}
+
 type MediaRepository interface {
 	GetMediaByIDs(mediaIDs []int) ([]mediarepo.Media, error)
 	GetMediaByID(mediaID int) (*mediarepo.Media, error)
@@ -105,10 +156,14 @@ type ProfileRepository interface {
 
 	GetProfileVideos(userID int) ([]int, error)
 	SetProfileVideos(tx *sql.Tx, userID int, videos []int) error
+	SetProfileVideoPositions(tx *sql.Tx, userID int, videos []int) error
 
 	ValidateMediaRole(role string) (bool, error)
 	GetImprovStyles(userID int) ([]string, error)
 	UpdateProfile(tx *sql.Tx, profile *profile.UpdateProfileModel) error
+	SetLookingForTeam(userID int, lookingForTeam bool) error
+	SoftDeleteProfile(userID int, at time.Time) error
+	RestoreProfile(userID int, deletedAfter time.Time) error
 	ClearImprovStyles(tx *sql.Tx, userID int) error
 	ClearProfileMedia(tx *sql.Tx, userID int, role string) error
 	ValidateImprovGoal(goal string) (bool, error)
@@ -118,6 +173,7 @@ type ProfileRepository interface {
 	GetImprovStylesCatalog(lang string) ([]profile.TranslatedItem, error)
 	GetImprovGoalsCatalog(lang string) ([]profile.TranslatedItem, error)
 	GetGendersCatalog(lang string) ([]profile.TranslatedItem, error)
+	GetCatalogTranslations(catalogType string, langs []string) ([]profile.CatalogTranslations, error)
 	GetCities() ([]struct {
 		ID   int
 		Name string
@@ -130,27 +186,100 @@ type ProfileRepository interface {
 		improvStyles []string,
 		birthDateMin *time.Time,
 		birthDateMax *time.Time,
+		includeUnknownAge bool,
 		genders []string,
 		cityID *int,
+		near *profilerepo.NearFilter,
 		hasAvatar *bool,
 		hasVideo *bool,
+		hasMedia *bool,
 		createdAfter *time.Time,
+		sortBy string,
 		page int,
 		pageSize int,
 	) ([]*profilerepo.ProfileModel, int, error)
+	GetNewProfiles(userID int, after *profilerepo.FeedPosition, limit int) ([]*profilerepo.ProfileModel, error)
+	GetImprovStyleCounts(userID int, since time.Time) ([]profilerepo.StyleCount, error)
+}
+
+// DefaultMaxImprovStyles is the number of improv styles a profile may list when not configured
+const DefaultMaxImprovStyles = 20
+
+// DefaultMaxVideos is the number of videos a profile may show when not configured
+const DefaultMaxVideos = 10
+
+// DefaultRestoreGracePeriod is how long a deleted profile can be restored when not configured
+const DefaultRestoreGracePeriod = 30 * 24 * time.Hour
+
+// Config holds the configuration for the profile service
+type Config struct {
+	// MaxImprovStyles limits the number of improv styles per profile.
+	// Zero uses DefaultMaxImprovStyles.
+	MaxImprovStyles int
+
+	// MaxVideos limits the number of videos per profile.
+	// Zero uses DefaultMaxVideos.
+	MaxVideos int
+
+	// FeedCacheTTL is how long pages of the new profiles feed are cached.
+	// Zero uses DefaultFeedCacheTTL.
+	FeedCacheTTL time.Duration
+
+	// TrendingCacheTTL is how long trending statistics are cached.
+	// Zero uses DefaultTrendingCacheTTL.
+	TrendingCacheTTL time.Duration
+
+	// RestoreGracePeriod is how long after deletion the owner may restore a profile.
+	// Zero uses DefaultRestoreGracePeriod.
+	RestoreGracePeriod time.Duration
 }
 
 // ProfileServiceImpl реализует интерфейс ProfileService
 type ProfileServiceImpl struct {
-	profileRepo ProfileRepository
-	mediaRepo   MediaRepository
+	profileRepo     ProfileRepository
+	mediaRepo       MediaRepository
+	maxImprovStyles int
+	maxVideos       int
+	feedCache       *feedCache
+	trendingCache   *trendingCache
+
+	restoreGracePeriod time.Duration
+	now                func() time.Time
 }
 
 // NewProfileService создает новый экземпляр сервиса профилей
-func NewProfileService(profileRepo ProfileRepository, mediaRepo MediaRepository) *ProfileServiceImpl {
+func NewProfileService(profileRepo ProfileRepository, mediaRepo MediaRepository, config Config) *ProfileServiceImpl {
+	maxImprovStyles := config.MaxImprovStyles
+	if maxImprovStyles <= 0 {
+		maxImprovStyles = DefaultMaxImprovStyles
+	}
+	maxVideos := config.MaxVideos
+	if maxVideos <= 0 {
+		maxVideos = DefaultMaxVideos
+	}
+	feedCacheTTL := config.FeedCacheTTL
+	if feedCacheTTL <= 0 {
+		feedCacheTTL = DefaultFeedCacheTTL
+	}
+	trendingCacheTTL := config.TrendingCacheTTL
+	if trendingCacheTTL <= 0 {
+		trendingCacheTTL = DefaultTrendingCacheTTL
+	}
+	restoreGracePeriod := config.RestoreGracePeriod
+	if restoreGracePeriod <= 0 {
+		restoreGracePeriod = DefaultRestoreGracePeriod
+	}
+
 	return &ProfileServiceImpl{
-		profileRepo: profileRepo,
-		mediaRepo:   mediaRepo,
+		profileRepo:     profileRepo,
+		mediaRepo:       mediaRepo,
+		maxImprovStyles: maxImprovStyles,
+		maxVideos:       maxVideos,
+		feedCache:       newFeedCache(feedCacheTTL, time.Now),
+		trendingCache:   newTrendingCache(trendingCacheTTL, time.Now),
+
+		restoreGracePeriod: restoreGracePeriod,
+		now:                time.Now,
 	}
 }
 
@@ -186,13 +315,67 @@ func convertToProfile(profile *profilerepo.ProfileModel, styles []string, avatar
 		LookingForTeam: profile.LookingForTeam,
 		ImprovStyles:   styles,
 		CreatedAt:      profile.CreatedAt,
+		UpdatedAt:      profile.UpdatedAt,
 		Avatar:         convertMedia(avatar),
 		Videos:         convertMediaList(videos),
+		DistanceKm:     profile.DistanceKm,
+		LastSeenAt:     profile.LastSeenAt,
 	}
 }
 
+// normalizeCatalogCode brings a client-supplied catalog code to its canonical form.
+// Catalog codes are stored in lower case.
+func normalizeCatalogCode(code string) string {
+	return strings.ToLower(strings.TrimSpace(code))
+}
+
+// normalizeCatalogCodes normalizes every code in the list
+func normalizeCatalogCodes(codes []string) []string {
+	if codes == nil {
+		return nil
+	}
+	normalized := make([]string, len(codes))
+	for i, code := range codes {
+		normalized[i] = normalizeCatalogCode(code)
+	}
+	return normalized
+}
+
+// validateMediaOwnership checks that the avatar and videos exist and were uploaded by the user
+func (s *ProfileServiceImpl) validateMediaOwnership(userID int, avatar *int, videos []int) error {
+	mediaIDs := videos
+	if avatar != nil {
+		mediaIDs = append([]int{*avatar}, videos...)
+	}
+
+	for _, mediaID := range mediaIDs {
+		media, err := s.mediaRepo.GetMediaByID(mediaID)
+		if errors.Is(err, mediarepo.ErrMediaNotFound) {
+			return ErrInvalidMedia
+		}
+		if err != nil {
+			return err
+		}
+		if media.UserID != userID {
+			return ErrInvalidMedia
+		}
+	}
+	return nil
+}
+
 // CreateProfile creates a new profile
 func (s *ProfileServiceImpl) CreateProfile(req ProfileCreateRequest) (*Profile, error) {
+	req.Gender = normalizeCatalogCode(req.Gender)
+	req.Goal = normalizeCatalogCode(req.Goal)
+	req.ImprovStyles = normalizeCatalogCodes(req.ImprovStyles)
+
+	if len(req.ImprovStyles) > s.maxImprovStyles {
+		return nil, ErrTooManyImprovStyles
+	}
+	if len(req.Videos) > s.maxVideos {
+		return nil, ErrTooManyVideos
+	}
+
 	// Check user exists
 	exists, err := s.profileRepo.CheckUserExists(req.UserID)
 	if err != nil {
@@ -247,6 +430,10 @@ func (s *ProfileServiceImpl) CreateProfile(req ProfileCreateRequest) (*Profile,
 		}
 	}
 
+	if err := s.validateMediaOwnership(req.UserID, req.Avatar, req.Videos); err != nil {
+		return nil, err
+	}
+
 	// Start transaction
 	tx, err := s.profileRepo.BeginTx()
 	if err != nil {
@@ -273,6 +460,10 @@ func (s *ProfileServiceImpl) CreateProfile(req ProfileCreateRequest) (*Profile,
 
 	_, err = s.profileRepo.CreateProfile(tx, profileModel)
 	if err != nil {
+		// A concurrent request created the profile after the existence check
+		if isUniqueViolation(err) {
+			return nil, ErrProfileAlreadyExists
+		}
 		return nil, err
 	}
 
@@ -285,14 +476,14 @@ func (s *ProfileServiceImpl) CreateProfile(req ProfileCreateRequest) (*Profile,
 	}
 
 	if req.Avatar != nil {
-		err := s.profileRepo.SetProfileAvatar(tx, req.UserID, *req.Avatar)
+		err = s.profileRepo.SetProfileAvatar(tx, req.UserID, *req.Avatar)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	if req.Videos != nil {
-		err := s.profileRepo.SetProfileVideos(tx, req.UserID, req.Videos)
+		err = s.profileRepo.SetProfileVideos(tx, req.UserID, req.Videos)
 		if err != nil {
 			return nil, err
 		}
@@ -356,6 +547,9 @@ func (s *ProfileServiceImpl) GetProfile(userID int) (*Profile, error) {
 		}
 		return nil, err
 	}
+	if profile.DeletedAt != nil {
+		return nil, ErrProfileNotFound
+	}
 
 	return s.ExpandProfile(profile)
 }
@@ -370,6 +564,27 @@ func (s *ProfileServiceImpl) UpdateProfile(userID int, req ProfileUpdateRequest)
 		}
 		return nil, err
 	}
+	if profile.DeletedAt != nil {
+		return nil, ErrProfileNotFound
+	}
+
+	// Normalize catalog codes
+	if req.Gender != nil {
+		gender := normalizeCatalogCode(*req.Gender)
+		req.Gender = &gender
+	}
+	if req.Goal != nil {
+		goal := normalizeCatalogCode(*req.Goal)
+		req.Goal = &goal
+	}
+	req.ImprovStyles = normalizeCatalogCodes(req.ImprovStyles)
+
+	if len(req.ImprovStyles) > s.maxImprovStyles {
+		return nil, ErrTooManyImprovStyles
+	}
+	if len(req.Videos) > s.maxVideos {
+		return nil, ErrTooManyVideos
+	}
 
 	// Validate fields
 	if req.Gender != nil {
@@ -413,6 +628,10 @@ func (s *ProfileServiceImpl) UpdateProfile(userID int, req ProfileUpdateRequest)
 		}
 	}
 
+	if err := s.validateMediaOwnership(userID, req.Avatar, req.Videos); err != nil {
+		return nil, err
+	}
+
 	// Start transaction
 	tx, err := s.profileRepo.BeginTx()
 	if err != nil {
@@ -442,28 +661,30 @@ func (s *ProfileServiceImpl) UpdateProfile(userID int, req ProfileUpdateRequest)
 		return nil, err
 	}
 
-	// Clear and re-add styles
-	err = s.profileRepo.ClearImprovStyles(tx, userID)
-	if err != nil {
-		return nil, err
-	}
-
-	if len(req.ImprovStyles) > 0 {
-		err = s.profileRepo.AddImprovStyles(tx, userID, req.ImprovStyles)
+	// Styles are only replaced when given, an empty list clears them
+	if req.ImprovStyles != nil {
+		err = s.profileRepo.ClearImprovStyles(tx, userID)
 		if err != nil {
 			return nil, err
 		}
+
+		if len(req.ImprovStyles) > 0 {
+			err = s.profileRepo.AddImprovStyles(tx, userID, req.ImprovStyles)
+			if err != nil {
+				return nil, err
+			}
+		}
 	}
 
 	if req.Avatar != nil {
-		err := s.profileRepo.SetProfileAvatar(tx, userID, *req.Avatar)
+		err = s.profileRepo.SetProfileAvatar(tx, userID, *req.Avatar)
 		if err != nil {
 			return nil, err
 		}
 	}
 
 	if req.Videos != nil {
-		err := s.profileRepo.SetProfileVideos(tx, userID, req.Videos)
+		err = s.profileRepo.SetProfileVideos(tx, userID, req.Videos)
 		if err != nil {
 			return nil, err
 		}
@@ -477,8 +698,141 @@ func (s *ProfileServiceImpl) UpdateProfile(userID int, req ProfileUpdateRequest)
 	return s.GetProfile(userID)
 }
 
+// PatchImprovProfile changes only the given improv fields of a profile. Unlike a
+// general update, the styles may not be cleared: a given list must not be empty.
+func (s *ProfileServiceImpl) PatchImprovProfile(userID int, patch ImprovProfilePatch) (*Profile, error) {
+	req := ProfileUpdateRequest{
+		Goal:           patch.Goal,
+		LookingForTeam: patch.LookingForTeam,
+	}
+	if patch.ImprovStyles != nil {
+		if len(*patch.ImprovStyles) == 0 {
+			return nil, ErrImprovStylesRequired
+		}
+		req.ImprovStyles = *patch.ImprovStyles
+	}
+
+	return s.UpdateProfile(userID, req)
+}
+
+// SetLookingForTeam toggles whether the user is looking for a team without touching
+// the rest of the profile
+func (s *ProfileServiceImpl) SetLookingForTeam(userID int, lookingForTeam bool) (*Profile, error) {
+	if err := s.profileRepo.SetLookingForTeam(userID, lookingForTeam); err != nil {
+		if errors.Is(err, profilerepo.ErrProfileNotExists) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, err
+	}
+
+	return s.GetProfile(userID)
+}
+
+// ReorderMedia sets the gallery order of the profile videos. The IDs must be exactly
+// the current videos of the profile, each listed once.
+func (s *ProfileServiceImpl) ReorderMedia(userID int, mediaIDs []int) (*Profile, error) {
+	exists, err := s.profileRepo.CheckProfileExists(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrProfileNotFound
+	}
+
+	videos, err := s.profileRepo.GetProfileVideos(userID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameMediaSet(videos, mediaIDs) {
+		return nil, ErrInvalidMediaOrder
+	}
+
+	tx, err := s.profileRepo.BeginTx()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.profileRepo.SetProfileVideoPositions(tx, userID, mediaIDs); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	// An update without fields only bumps updated_at
+	if err := s.profileRepo.UpdateProfile(tx, &profilerepo.UpdateProfileModel{UserID: userID}); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return s.GetProfile(userID)
+}
+
+// DeleteProfile hides the profile from other users. Its data is kept, so the owner
+// can restore it within the grace period.
+func (s *ProfileServiceImpl) DeleteProfile(userID int) error {
+	if err := s.profileRepo.SoftDeleteProfile(userID, s.now()); err != nil {
+		if errors.Is(err, profilerepo.ErrProfileNotExists) {
+			return ErrProfileNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// RestoreProfile undoes DeleteProfile if the grace period has not passed yet
+func (s *ProfileServiceImpl) RestoreProfile(userID int) (*Profile, error) {
+	profile, err := s.profileRepo.GetProfileByUserID(userID)
+	if err != nil {
+		if errors.Is(err, profilerepo.ErrProfileNotExists) {
+			return nil, ErrProfileNotFound
+		}
+		return nil, err
+	}
+	if profile.DeletedAt == nil {
+		return nil, ErrProfileNotDeleted
+	}
+
+	deletedAfter := s.now().Add(-s.restoreGracePeriod)
+	if !profile.DeletedAt.After(deletedAfter) {
+		return nil, ErrRestoreExpired
+	}
+	if err := s.profileRepo.RestoreProfile(userID, deletedAfter); err != nil {
+		// The grace period ended or the profile was restored since it was read
+		if errors.Is(err, profilerepo.ErrProfileNotExists) {
+			return nil, ErrRestoreExpired
+		}
+		return nil, err
+	}
+
+	return s.GetProfile(userID)
+}
+
+// sameMediaSet reports whether order lists every ID of current exactly once
+func sameMediaSet(current, order []int) bool {
+	if len(current) != len(order) {
+		return false
+	}
+
+	remaining := make(map[int]bool, len(current))
+	for _, id := range current {
+		remaining[id] = true
+	}
+	for _, id := range order {
+		if !remaining[id] {
+			return false
+		}
+		delete(remaining, id)
+	}
+	return true
+}
+
 // GetImprovStyles returns improv styles catalog with translations
 func (s *ProfileServiceImpl) GetImprovStyles(lang string) ([]TranslatedItem, error) {
+	lang, err := resolveLanguage(lang)
+	if err != nil {
+		return nil, err
+	}
+
 	repoItems, err := s.profileRepo.GetImprovStylesCatalog(lang)
 	if err != nil {
 		return nil, err
@@ -496,6 +850,11 @@ func (s *ProfileServiceImpl) GetImprovStyles(lang string) ([]TranslatedItem, err
 
 // GetImprovGoals returns improv goals catalog with translations
 func (s *ProfileServiceImpl) GetImprovGoals(lang string) ([]TranslatedItem, error) {
+	lang, err := resolveLanguage(lang)
+	if err != nil {
+		return nil, err
+	}
+
 	repoItems, err := s.profileRepo.GetImprovGoalsCatalog(lang)
 	if err != nil {
 		return nil, err
@@ -513,6 +872,11 @@ func (s *ProfileServiceImpl) GetImprovGoals(lang string) ([]TranslatedItem, erro
 
 // GetGenders returns gender catalog with translations
 func (s *ProfileServiceImpl) GetGenders(lang string) ([]TranslatedItem, error) {
+	lang, err := resolveLanguage(lang)
+	if err != nil {
+		return nil, err
+	}
+
 	repoItems, err := s.profileRepo.GetGendersCatalog(lang)
 	if err != nil {
 		return nil, err
@@ -528,6 +892,35 @@ func (s *ProfileServiceImpl) GetGenders(lang string) ([]TranslatedItem, error) {
 	return items, nil
 }
 
+// GetCatalogTranslations returns catalog items with labels in all supported languages,
+// flagging the languages each item is missing a translation for
+func (s *ProfileServiceImpl) GetCatalogTranslations(catalogType string) ([]CatalogItemTranslations, error) {
+	repoItems, err := s.profileRepo.GetCatalogTranslations(catalogType, SupportedLanguages)
+	if err != nil {
+		if errors.Is(err, profilerepo.ErrInvalidCatalog) {
+			return nil, ErrInvalidCatalog
+		}
+		return nil, err
+	}
+
+	items := make([]CatalogItemTranslations, len(repoItems))
+	for i, item := range repoItems {
+		missing := []string{}
+		for _, lang := range SupportedLanguages {
+			if _, ok := item.Labels[lang]; !ok {
+				missing = append(missing, lang)
+			}
+		}
+
+		items[i] = CatalogItemTranslations{
+			Code:             item.Code,
+			Labels:           item.Labels,
+			MissingLanguages: missing,
+		}
+	}
+	return items, nil
+}
+
 // GetCities returns available cities
 func (s *ProfileServiceImpl) GetCities() ([]City, error) {
 	repoCities, err := s.profileRepo.GetCities()
@@ -544,3 +937,9 @@ func (s *ProfileServiceImpl) GetCities() ([]City, error) {
 	}
 	return cities, nil
 }
+
+// isUniqueViolation checks if the error is a PostgreSQL unique constraint violation
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
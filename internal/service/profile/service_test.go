@@ -0,0 +1,849 @@
+package profile
+
+import (
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mediarepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/media"
+	profilerepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
+)
+
+// MockProfileRepository is a mock implementation of ProfileRepository
+type MockProfileRepository struct {
+	mock.Mock
+}
+
+func (m *MockProfileRepository) BeginTx() (*sql.Tx, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*sql.Tx), args.Error(1)
+}
+
+func (m *MockProfileRepository) CheckUserExists(userID int) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProfileRepository) CheckProfileExists(userID int) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProfileRepository) CreateProfile(tx *sql.Tx, profile *profilerepo.ProfileModel) (time.Time, error) {
+	args := m.Called(txArg{tx}, profile)
+	return args.Get(0).(time.Time), args.Error(1)
+}
+
+func (m *MockProfileRepository) AddImprovStyles(tx *sql.Tx, userID int, styles []string) error {
+	args := m.Called(txArg{tx}, userID, styles)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) GetProfile(userID int) (*profilerepo.ProfileModel, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profilerepo.ProfileModel), args.Error(1)
+}
+
+func (m *MockProfileRepository) GetProfileByUserID(userID int) (*profilerepo.ProfileModel, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*profilerepo.ProfileModel), args.Error(1)
+}
+
+func (m *MockProfileRepository) GetProfileAvatar(userID int) (*int, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*int), args.Error(1)
+}
+
+func (m *MockProfileRepository) SetProfileAvatar(tx *sql.Tx, userID int, mediaID int) error {
+	args := m.Called(txArg{tx}, userID, mediaID)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) RemoveAvatar(tx *sql.Tx, userID int) error {
+	args := m.Called(txArg{tx}, userID)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) GetProfileVideos(userID int) ([]int, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]int), args.Error(1)
+}
+
+func (m *MockProfileRepository) SetProfileVideos(tx *sql.Tx, userID int, videos []int) error {
+	args := m.Called(txArg{tx}, userID, videos)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) SetProfileVideoPositions(tx *sql.Tx, userID int, videos []int) error {
+	args := m.Called(txArg{tx}, userID, videos)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) ValidateMediaRole(role string) (bool, error) {
+	args := m.Called(role)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProfileRepository) GetImprovStyles(userID int) ([]string, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *MockProfileRepository) UpdateProfile(tx *sql.Tx, profile *profilerepo.UpdateProfileModel) error {
+	args := m.Called(txArg{tx}, profile)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) SetLookingForTeam(userID int, lookingForTeam bool) error {
+	args := m.Called(userID, lookingForTeam)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) SoftDeleteProfile(userID int, at time.Time) error {
+	args := m.Called(userID, at)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) RestoreProfile(userID int, deletedAfter time.Time) error {
+	args := m.Called(userID, deletedAfter)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) ClearImprovStyles(tx *sql.Tx, userID int) error {
+	args := m.Called(txArg{tx}, userID)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) ClearProfileMedia(tx *sql.Tx, userID int, role string) error {
+	args := m.Called(txArg{tx}, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockProfileRepository) ValidateImprovGoal(goal string) (bool, error) {
+	args := m.Called(goal)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProfileRepository) ValidateImprovStyle(style string) (bool, error) {
+	args := m.Called(style)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProfileRepository) ValidateGender(gender string) (bool, error) {
+	args := m.Called(gender)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProfileRepository) ValidateCity(cityID int) (bool, error) {
+	args := m.Called(cityID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockProfileRepository) GetImprovStylesCatalog(lang string) ([]profilerepo.TranslatedItem, error) {
+	args := m.Called(lang)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profilerepo.TranslatedItem), args.Error(1)
+}
+
+func (m *MockProfileRepository) GetImprovGoalsCatalog(lang string) ([]profilerepo.TranslatedItem, error) {
+	args := m.Called(lang)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profilerepo.TranslatedItem), args.Error(1)
+}
+
+func (m *MockProfileRepository) GetGendersCatalog(lang string) ([]profilerepo.TranslatedItem, error) {
+	args := m.Called(lang)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profilerepo.TranslatedItem), args.Error(1)
+}
+
+func (m *MockProfileRepository) GetCatalogTranslations(catalogType string, langs []string) ([]profilerepo.CatalogTranslations, error) {
+	args := m.Called(catalogType, langs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profilerepo.CatalogTranslations), args.Error(1)
+}
+
+func (m *MockProfileRepository) GetCities() ([]struct {
+	ID   int
+	Name string
+}, error) {
+	args := m.Called()
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]struct {
+		ID   int
+		Name string
+	}), args.Error(1)
+}
+
+func (m *MockProfileRepository) SearchProfiles(
+	currentUserID int,
+	fullName *string,
+	lookingForTeam *bool,
+	goals []string,
+	improvStyles []string,
+	birthDateMin *time.Time,
+	birthDateMax *time.Time,
+	includeUnknownAge bool,
+	genders []string,
+	cityID *int,
+	near *profilerepo.NearFilter,
+	hasAvatar *bool,
+	hasVideo *bool,
+	hasMedia *bool,
+	createdAfter *time.Time,
+	sortBy string,
+	page int,
+	pageSize int,
+) ([]*profilerepo.ProfileModel, int, error) {
+	args := m.Called(currentUserID, fullName, lookingForTeam, goals, improvStyles, birthDateMin, birthDateMax, includeUnknownAge,
+		genders, cityID, near, hasAvatar, hasVideo, hasMedia, createdAfter, sortBy, page, pageSize)
+	if args.Get(0) == nil {
+		return nil, args.Int(1), args.Error(2)
+	}
+	return args.Get(0).([]*profilerepo.ProfileModel), args.Int(1), args.Error(2)
+}
+
+func (m *MockProfileRepository) GetNewProfiles(userID int, after *profilerepo.FeedPosition, limit int) ([]*profilerepo.ProfileModel, error) {
+	args := m.Called(userID, after, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*profilerepo.ProfileModel), args.Error(1)
+}
+
+func (m *MockProfileRepository) GetImprovStyleCounts(userID int, since time.Time) ([]profilerepo.StyleCount, error) {
+	args := m.Called(userID, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]profilerepo.StyleCount), args.Error(1)
+}
+
+// MockMediaRepository is a mock implementation of MediaRepository
+type MockMediaRepository struct {
+	mock.Mock
+}
+
+func (m *MockMediaRepository) GetMediaByIDs(mediaIDs []int) ([]mediarepo.Media, error) {
+	args := m.Called(mediaIDs)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]mediarepo.Media), args.Error(1)
+}
+
+func (m *MockMediaRepository) GetMediaByID(mediaID int) (*mediarepo.Media, error) {
+	args := m.Called(mediaID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*mediarepo.Media), args.Error(1)
+}
+
+func setupService() (*ProfileServiceImpl, *MockProfileRepository, *MockMediaRepository) {
+	profileRepo := new(MockProfileRepository)
+	mediaRepo := new(MockMediaRepository)
+	return NewProfileService(profileRepo, mediaRepo, Config{}), profileRepo, mediaRepo
+}
+
+func TestGetCatalogTranslations_FlagsMissingLanguages(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	profileRepo.On("GetCatalogTranslations", "genders", SupportedLanguages).Return([]profilerepo.CatalogTranslations{
+		{Code: "female", Labels: map[string]string{"ru": "Женщина", "en": "Female"}},
+		{Code: "male", Labels: map[string]string{"ru": "Мужчина"}},
+	}, nil)
+
+	items, err := service.GetCatalogTranslations("genders")
+
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.Equal(t, "female", items[0].Code)
+	assert.Empty(t, items[0].MissingLanguages)
+	assert.Equal(t, "male", items[1].Code)
+	assert.Equal(t, []string{"en"}, items[1].MissingLanguages)
+	assert.Equal(t, "Мужчина", items[1].Labels["ru"])
+	profileRepo.AssertExpectations(t)
+}
+
+func TestGetCatalogTranslations_UnknownCatalog(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	profileRepo.On("GetCatalogTranslations", "unknown", SupportedLanguages).Return(nil, profilerepo.ErrInvalidCatalog)
+
+	items, err := service.GetCatalogTranslations("unknown")
+
+	assert.Nil(t, items)
+	assert.ErrorIs(t, err, ErrInvalidCatalog)
+}
+
+// txArg stands in for the transaction in recorded mock calls. testify formats
+// call arguments with %v, and printing a live *sql.Tx races with the goroutine
+// database/sql runs for it.
+type txArg struct{ tx *sql.Tx }
+
+func (txArg) String() string { return "*sql.Tx" }
+
+// anyTx matches the transaction passed to repository methods.
+var anyTx = mock.AnythingOfType("profile.txArg")
+
+// beginTestTx returns a transaction backed by sqlmock. The caller sets up
+// the expected commit or rollback.
+func beginTestTx(t *testing.T) (*sql.Tx, sqlmock.Sqlmock) {
+	t.Helper()
+	db, dbMock, err := sqlmock.New()
+	assert.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	dbMock.ExpectBegin()
+	tx, err := db.Begin()
+	assert.NoError(t, err)
+	return tx, dbMock
+}
+
+func TestCreateProfile_AcceptsMixedCaseCatalogCodes(t *testing.T) {
+	service, profileRepo, mediaRepo := setupService()
+	tx, dbMock := beginTestTx(t)
+	dbMock.ExpectCommit()
+
+	profileRepo.On("CheckUserExists", 1).Return(true, nil)
+	profileRepo.On("CheckProfileExists", 1).Return(false, nil)
+	profileRepo.On("ValidateGender", "male").Return(true, nil)
+	profileRepo.On("ValidateCity", 1).Return(true, nil)
+	profileRepo.On("ValidateImprovGoal", "hobby").Return(true, nil)
+	profileRepo.On("ValidateImprovStyle", "shortform").Return(true, nil)
+	profileRepo.On("ValidateImprovStyle", "longform").Return(true, nil)
+	profileRepo.On("BeginTx").Return(tx, nil)
+	profileRepo.On("CreateProfile", anyTx, mock.MatchedBy(func(p *profilerepo.ProfileModel) bool {
+		return p.Gender == "male" && p.Goal == "hobby"
+	})).Return(time.Now(), nil)
+	profileRepo.On("AddImprovStyles", anyTx, 1, []string{"shortform", "longform"}).Return(nil)
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1, Gender: "male", Goal: "hobby"}, nil)
+	profileRepo.On("GetImprovStyles", 1).Return([]string{"shortform", "longform"}, nil)
+	mediaRepo.On("GetMediaByIDs", mock.Anything).Return([]mediarepo.Media{}, nil)
+
+	result, err := service.CreateProfile(ProfileCreateRequest{
+		UserID:       1,
+		FullName:     "Test User",
+		Gender:       "Male",
+		CityID:       1,
+		Goal:         " Hobby ",
+		ImprovStyles: []string{"ShortForm", "LONGFORM"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hobby", result.Goal)
+	profileRepo.AssertExpectations(t)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestCreateProfile_ConcurrentInsertIsConflict(t *testing.T) {
+	service, profileRepo, _ := setupService()
+	tx, dbMock := beginTestTx(t)
+	dbMock.ExpectRollback()
+
+	// Both requests pass the existence check, the second insert hits the primary key
+	profileRepo.On("CheckUserExists", 1).Return(true, nil)
+	profileRepo.On("CheckProfileExists", 1).Return(false, nil)
+	profileRepo.On("ValidateGender", "male").Return(true, nil)
+	profileRepo.On("ValidateCity", 1).Return(true, nil)
+	profileRepo.On("ValidateImprovGoal", "hobby").Return(true, nil)
+	profileRepo.On("BeginTx").Return(tx, nil)
+	profileRepo.On("CreateProfile", anyTx, mock.Anything).
+		Return(time.Time{}, &pq.Error{Code: "23505", Constraint: "profiles_pkey"})
+
+	result, err := service.CreateProfile(ProfileCreateRequest{
+		UserID:   1,
+		FullName: "Test User",
+		Gender:   "male",
+		CityID:   1,
+		Goal:     "hobby",
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrProfileAlreadyExists)
+	profileRepo.AssertExpectations(t)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestCreateProfile_RejectsInvalidGoalRegardlessOfCase(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	profileRepo.On("CheckUserExists", 1).Return(true, nil)
+	profileRepo.On("CheckProfileExists", 1).Return(false, nil)
+	profileRepo.On("ValidateGender", "male").Return(true, nil)
+	profileRepo.On("ValidateCity", 1).Return(true, nil)
+	profileRepo.On("ValidateImprovGoal", "fame").Return(false, nil)
+
+	result, err := service.CreateProfile(ProfileCreateRequest{
+		UserID:   1,
+		FullName: "Test User",
+		Gender:   "MALE",
+		CityID:   1,
+		Goal:     "Fame",
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrInvalidImprovGoal)
+	profileRepo.AssertNotCalled(t, "BeginTx")
+}
+
+func TestUpdateProfile_NormalizesCatalogCodes(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	goal := "CAREER"
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1}, nil)
+	profileRepo.On("ValidateImprovGoal", "career").Return(true, nil)
+	profileRepo.On("ValidateImprovStyle", "rap").Return(true, nil)
+	profileRepo.On("ValidateImprovStyle", "jazz").Return(false, nil)
+
+	result, err := service.UpdateProfile(1, ProfileUpdateRequest{
+		Goal:         &goal,
+		ImprovStyles: []string{"Rap", "Jazz"},
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrInvalidImprovStyle)
+	profileRepo.AssertExpectations(t)
+}
+
+func TestUpdateProfile_StyleInsertFailureRollsBack(t *testing.T) {
+	service, profileRepo, _ := setupService()
+	tx, dbMock := beginTestTx(t)
+	dbMock.ExpectRollback()
+
+	styles := []string{"shortform", "longform"}
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1}, nil)
+	profileRepo.On("ValidateImprovStyle", mock.Anything).Return(true, nil)
+	profileRepo.On("BeginTx").Return(tx, nil)
+	profileRepo.On("UpdateProfile", anyTx, mock.Anything).Return(nil)
+	profileRepo.On("ClearImprovStyles", anyTx, 1).Return(nil)
+	profileRepo.On("AddImprovStyles", anyTx, 1, styles).Return(errors.New("insert failed"))
+
+	result, err := service.UpdateProfile(1, ProfileUpdateRequest{ImprovStyles: styles})
+
+	assert.Nil(t, result)
+	assert.Error(t, err)
+	profileRepo.AssertExpectations(t)
+	// The cleared styles are restored by the rollback, nothing is committed
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestUpdateProfile_MediaFailureRollsBack(t *testing.T) {
+	service, profileRepo, mediaRepo := setupService()
+	tx, dbMock := beginTestTx(t)
+	dbMock.ExpectRollback()
+
+	videos := []int{10, 11}
+	for _, mediaID := range videos {
+		mediaRepo.On("GetMediaByID", mediaID).Return(&mediarepo.Media{ID: mediaID, UserID: 1}, nil)
+	}
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1}, nil)
+	profileRepo.On("BeginTx").Return(tx, nil)
+	profileRepo.On("UpdateProfile", anyTx, mock.Anything).Return(nil)
+	profileRepo.On("ClearImprovStyles", anyTx, 1).Return(nil)
+	profileRepo.On("SetProfileVideos", anyTx, 1, videos).Return(errors.New("insert failed"))
+
+	result, err := service.UpdateProfile(1, ProfileUpdateRequest{Videos: videos})
+
+	assert.Nil(t, result)
+	assert.Error(t, err)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestUpdateProfile_OmittedStylesAreKept(t *testing.T) {
+	service, profileRepo, _ := setupService()
+	tx, dbMock := beginTestTx(t)
+	dbMock.ExpectCommit()
+
+	bio := "New bio"
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1}, nil)
+	profileRepo.On("BeginTx").Return(tx, nil)
+	profileRepo.On("UpdateProfile", anyTx, &profilerepo.UpdateProfileModel{UserID: 1, Bio: &bio}).Return(nil)
+	profileRepo.On("CheckUserExists", 1).Return(false, nil)
+
+	// The updated profile is read back after the commit
+	_, err := service.UpdateProfile(1, ProfileUpdateRequest{Bio: &bio})
+
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	profileRepo.AssertNotCalled(t, "ClearImprovStyles", mock.Anything, mock.Anything)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestPatchImprovProfile_RejectsEmptyStyles(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	result, err := service.PatchImprovProfile(1, ImprovProfilePatch{ImprovStyles: &[]string{}})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrImprovStylesRequired)
+	profileRepo.AssertNotCalled(t, "GetProfileByUserID", mock.Anything)
+}
+
+func TestCreateProfile_RejectsTooManyImprovStyles(t *testing.T) {
+	profileRepo := new(MockProfileRepository)
+	service := NewProfileService(profileRepo, new(MockMediaRepository), Config{MaxImprovStyles: 2})
+
+	result, err := service.CreateProfile(ProfileCreateRequest{
+		UserID:       1,
+		FullName:     "Test User",
+		ImprovStyles: []string{"shortform", "longform", "rap"},
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrTooManyImprovStyles)
+	profileRepo.AssertNotCalled(t, "CheckUserExists", mock.Anything)
+}
+
+func TestUpdateProfile_RejectsTooManyImprovStyles(t *testing.T) {
+	profileRepo := new(MockProfileRepository)
+	service := NewProfileService(profileRepo, new(MockMediaRepository), Config{MaxImprovStyles: 2})
+
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1}, nil)
+
+	result, err := service.UpdateProfile(1, ProfileUpdateRequest{
+		ImprovStyles: []string{"shortform", "longform", "rap"},
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrTooManyImprovStyles)
+	profileRepo.AssertNotCalled(t, "BeginTx")
+}
+
+func TestUpdateProfile_RejectsTooManyVideos(t *testing.T) {
+	profileRepo := new(MockProfileRepository)
+	service := NewProfileService(profileRepo, new(MockMediaRepository), Config{MaxVideos: 2})
+
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1}, nil)
+
+	result, err := service.UpdateProfile(1, ProfileUpdateRequest{
+		Videos: []int{10, 11, 12},
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrTooManyVideos)
+	profileRepo.AssertNotCalled(t, "BeginTx")
+}
+
+func TestNewProfileService_DefaultImprovStylesCap(t *testing.T) {
+	service, _, _ := setupService()
+	assert.Equal(t, DefaultMaxImprovStyles, service.maxImprovStyles)
+}
+
+func TestCreateProfile_RejectsAvatarOfAnotherUser(t *testing.T) {
+	service, profileRepo, mediaRepo := setupService()
+
+	profileRepo.On("CheckUserExists", 1).Return(true, nil)
+	profileRepo.On("CheckProfileExists", 1).Return(false, nil)
+	profileRepo.On("ValidateGender", "male").Return(true, nil)
+	profileRepo.On("ValidateCity", 1).Return(true, nil)
+	profileRepo.On("ValidateImprovGoal", "hobby").Return(true, nil)
+	mediaRepo.On("GetMediaByID", 7).Return(&mediarepo.Media{ID: 7, UserID: 2}, nil)
+
+	avatar := 7
+	result, err := service.CreateProfile(ProfileCreateRequest{
+		UserID: 1,
+		Gender: "male",
+		CityID: 1,
+		Goal:   "hobby",
+		Avatar: &avatar,
+	})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrInvalidMedia)
+	profileRepo.AssertNotCalled(t, "BeginTx")
+}
+
+func TestUpdateProfile_RejectsUnknownMedia(t *testing.T) {
+	service, profileRepo, mediaRepo := setupService()
+
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1}, nil)
+	mediaRepo.On("GetMediaByID", 7).Return(&mediarepo.Media{ID: 7, UserID: 1}, nil)
+	mediaRepo.On("GetMediaByID", 8).Return(nil, mediarepo.ErrMediaNotFound)
+
+	avatar := 7
+	result, err := service.UpdateProfile(1, ProfileUpdateRequest{Avatar: &avatar, Videos: []int{8}})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrInvalidMedia)
+	profileRepo.AssertNotCalled(t, "BeginTx")
+}
+
+func TestSearch_DefaultsToNewestFirst(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	profileRepo.On("SearchProfiles", 1, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, SortCreatedAtDesc, 1, 20).
+		Return([]*profilerepo.ProfileModel{}, 0, nil)
+
+	result, err := service.Search(1, SearchFilter{})
+
+	assert.NoError(t, err)
+	assert.Empty(t, result.Profiles)
+	profileRepo.AssertExpectations(t)
+}
+
+func TestSearch_AgeRangeHandlesUnknownAge(t *testing.T) {
+	ageMin, ageMax := 20, 30
+
+	tests := []struct {
+		name           string
+		withUnknownAge bool
+	}{
+		{"unknown age excluded by default", false},
+		{"unknown age included on request", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, profileRepo, mediaRepo := setupService()
+
+			// The repository returns a profile without a birthday only when asked to
+			var profiles []*profilerepo.ProfileModel
+			if tt.withUnknownAge {
+				profiles = []*profilerepo.ProfileModel{{UserID: 2}}
+			}
+			profileRepo.On("SearchProfiles", 1, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+				mock.AnythingOfType("*time.Time"), mock.AnythingOfType("*time.Time"), tt.withUnknownAge,
+				mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, SortCreatedAtDesc, 1, 20).
+				Return(profiles, len(profiles), nil)
+			profileRepo.On("GetImprovStyles", 2).Return([]string{}, nil).Maybe()
+			mediaRepo.On("GetMediaByIDs", mock.Anything).Return([]mediarepo.Media{}, nil).Maybe()
+
+			result, err := service.Search(1, SearchFilter{AgeMin: &ageMin, AgeMax: &ageMax, WithUnknownAge: tt.withUnknownAge})
+
+			assert.NoError(t, err)
+			if tt.withUnknownAge {
+				if assert.Len(t, result.Profiles, 1) {
+					assert.True(t, result.Profiles[0].Birthday.IsZero())
+				}
+			} else {
+				assert.Empty(t, result.Profiles)
+			}
+			profileRepo.AssertExpectations(t)
+		})
+	}
+}
+
+func TestSearch_RejectsUnknownSort(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	result, err := service.Search(1, SearchFilter{SortBy: "name_asc"})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrInvalidSort)
+	profileRepo.AssertNotCalled(t, "SearchProfiles")
+}
+
+func TestSearch_ProximityParamsGivenTogether(t *testing.T) {
+	lat, lng, radius, zero, tooFar := 55.75, 37.62, 10.0, 0.0, 91.0
+
+	tests := []struct {
+		name   string
+		filter SearchFilter
+	}{
+		{"point without radius", SearchFilter{NearLat: &lat, NearLng: &lng}},
+		{"radius without point", SearchFilter{RadiusKm: &radius}},
+		{"latitude only", SearchFilter{NearLat: &lat}},
+		{"zero radius", SearchFilter{NearLat: &lat, NearLng: &lng, RadiusKm: &zero}},
+		{"latitude out of range", SearchFilter{NearLat: &tooFar, NearLng: &lng, RadiusKm: &radius}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, profileRepo, _ := setupService()
+
+			result, err := service.Search(1, tt.filter)
+
+			assert.Nil(t, result)
+			assert.ErrorIs(t, err, ErrInvalidProximity)
+			profileRepo.AssertNotCalled(t, "SearchProfiles")
+		})
+	}
+}
+
+func TestSearch_NearPointPassesFilterAndDistance(t *testing.T) {
+	service, profileRepo, mediaRepo := setupService()
+	lat, lng, radius := 55.75, 37.62, 10.0
+	distance := 3.2
+
+	profileRepo.On("SearchProfiles", 1, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+		mock.Anything, mock.Anything, &profilerepo.NearFilter{Lat: lat, Lng: lng, RadiusKm: radius},
+		mock.Anything, mock.Anything, mock.Anything, mock.Anything, SortDistance, 1, 20).
+		Return([]*profilerepo.ProfileModel{{UserID: 2, DistanceKm: &distance}}, 1, nil)
+	profileRepo.On("GetImprovStyles", 2).Return([]string{}, nil)
+	mediaRepo.On("GetMediaByIDs", mock.Anything).Return([]mediarepo.Media{}, nil)
+
+	result, err := service.Search(1, SearchFilter{NearLat: &lat, NearLng: &lng, RadiusKm: &radius, SortBy: SortDistance})
+
+	assert.NoError(t, err)
+	if assert.Len(t, result.Profiles, 1) {
+		assert.Equal(t, &distance, result.Profiles[0].DistanceKm)
+	}
+}
+
+func TestSearch_DistanceSortRequiresPoint(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	result, err := service.Search(1, SearchFilter{SortBy: SortDistance})
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrInvalidSort)
+	profileRepo.AssertNotCalled(t, "SearchProfiles")
+}
+
+func TestSearch_TotalPagesCountsPartialLastPage(t *testing.T) {
+	tests := []struct {
+		totalCount int
+		pageSize   int
+		expected   int
+	}{
+		{totalCount: 0, pageSize: 20, expected: 0},
+		{totalCount: 20, pageSize: 20, expected: 1},
+		{totalCount: 21, pageSize: 20, expected: 2},
+		{totalCount: 45, pageSize: 10, expected: 5},
+	}
+
+	for _, tc := range tests {
+		service, profileRepo, _ := setupService()
+		profileRepo.On("SearchProfiles", 1, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+			mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, SortCreatedAtDesc, 3, tc.pageSize).
+			Return([]*profilerepo.ProfileModel{}, tc.totalCount, nil)
+
+		result, err := service.Search(1, SearchFilter{Page: 3, PageSize: tc.pageSize})
+
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, result.TotalPages, "total %d, page size %d", tc.totalCount, tc.pageSize)
+		assert.Equal(t, 3, result.Page)
+	}
+}
+
+func TestReorderMedia_RewritesPositionsInTransaction(t *testing.T) {
+	service, profileRepo, mediaRepo := setupService()
+	tx, dbMock := beginTestTx(t)
+	dbMock.ExpectCommit()
+
+	order := []int{12, 10, 11}
+	profileRepo.On("CheckProfileExists", 1).Return(true, nil)
+	profileRepo.On("GetProfileVideos", 1).Return([]int{10, 11, 12}, nil)
+	profileRepo.On("BeginTx").Return(tx, nil)
+	profileRepo.On("SetProfileVideoPositions", anyTx, 1, order).Return(nil)
+	profileRepo.On("UpdateProfile", anyTx, &profilerepo.UpdateProfileModel{UserID: 1}).Return(nil)
+	profileRepo.On("CheckUserExists", 1).Return(true, nil)
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1, Videos: order}, nil)
+	profileRepo.On("GetImprovStyles", 1).Return([]string{}, nil)
+	mediaRepo.On("GetMediaByIDs", order).Return([]mediarepo.Media{{ID: 12}, {ID: 10}, {ID: 11}}, nil)
+
+	result, err := service.ReorderMedia(1, order)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []int{12, 10, 11}, []int{result.Videos[0].ID, result.Videos[1].ID, result.Videos[2].ID})
+	profileRepo.AssertExpectations(t)
+	assert.NoError(t, dbMock.ExpectationsWereMet())
+}
+
+func TestReorderMedia_RequiresExactlyTheCurrentVideos(t *testing.T) {
+	tests := []struct {
+		name  string
+		order []int
+	}{
+		{name: "Missing video", order: []int{11, 10}},
+		{name: "Foreign video", order: []int{12, 10, 99}},
+		{name: "Duplicate video", order: []int{10, 10, 11}},
+		{name: "Extra video", order: []int{10, 11, 12, 13}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			service, profileRepo, _ := setupService()
+			profileRepo.On("CheckProfileExists", 1).Return(true, nil)
+			profileRepo.On("GetProfileVideos", 1).Return([]int{10, 11, 12}, nil)
+
+			result, err := service.ReorderMedia(1, tc.order)
+
+			assert.Nil(t, result)
+			assert.ErrorIs(t, err, ErrInvalidMediaOrder)
+			profileRepo.AssertNotCalled(t, "BeginTx")
+		})
+	}
+}
+
+func TestGetProfile_DeletedProfileIsNotFound(t *testing.T) {
+	service, profileRepo, _ := setupService()
+	deletedAt := time.Now()
+	profileRepo.On("CheckUserExists", 1).Return(true, nil)
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1, DeletedAt: &deletedAt}, nil)
+
+	result, err := service.GetProfile(1)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+}
+
+func TestRestoreProfile_WithinGracePeriod(t *testing.T) {
+	service, profileRepo, mediaRepo := setupService()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	deletedAt := now.Add(-24 * time.Hour)
+
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1, DeletedAt: &deletedAt}, nil).Once()
+	profileRepo.On("RestoreProfile", 1, now.Add(-DefaultRestoreGracePeriod)).Return(nil)
+	profileRepo.On("CheckUserExists", 1).Return(true, nil)
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1}, nil).Once()
+	profileRepo.On("GetImprovStyles", 1).Return([]string{}, nil)
+	mediaRepo.On("GetMediaByIDs", mock.Anything).Return([]mediarepo.Media{}, nil)
+
+	result, err := service.RestoreProfile(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.UserID)
+	profileRepo.AssertExpectations(t)
+}
+
+func TestRestoreProfile_AfterGracePeriod(t *testing.T) {
+	service, profileRepo, _ := setupService()
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	service.now = func() time.Time { return now }
+	deletedAt := now.Add(-DefaultRestoreGracePeriod - time.Minute)
+	profileRepo.On("GetProfileByUserID", 1).Return(&profilerepo.ProfileModel{UserID: 1, DeletedAt: &deletedAt}, nil)
+
+	result, err := service.RestoreProfile(1)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrRestoreExpired)
+	profileRepo.AssertNotCalled(t, "RestoreProfile", mock.Anything, mock.Anything)
+}
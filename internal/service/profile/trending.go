@@ -0,0 +1,72 @@
+package profile
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultTrendingCacheTTL is how long trending statistics are served from the cache when not configured
+const DefaultTrendingCacheTTL = 5 * time.Minute
+
+// TrendingWindow is how far back profiles are counted for trending statistics
+const TrendingWindow = 7 * 24 * time.Hour
+
+// maxTrendingItems is the number of entries in trending statistics
+const maxTrendingItems = 10
+
+// maxTrendingCacheEntries bounds the number of users whose trending statistics are cached
+const maxTrendingCacheEntries = 256
+
+// TrendingItem is a catalog code with the number of recent profiles using it
+type TrendingItem struct {
+	Code  string `json:"code"`
+	Count int    `json:"count"`
+}
+
+// TrendingResult is the most used improv styles among recent profiles, most used first
+type TrendingResult struct {
+	Since time.Time      `json:"since"`
+	Items []TrendingItem `json:"items"`
+}
+
+// GetTrendingImprovStyles ranks the improv styles of profiles created within the
+// trending window, leaving out users that userID has blocked or was blocked by.
+// Results are cached for a short time and must not be modified.
+func (s *ProfileServiceImpl) GetTrendingImprovStyles(userID int) (*TrendingResult, error) {
+	if result, ok := s.trendingCache.get(userID); ok {
+		return result, nil
+	}
+
+	since := s.trendingCache.now().Add(-TrendingWindow)
+	counts, err := s.profileRepo.GetImprovStyleCounts(userID, since)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]TrendingItem, 0, len(counts))
+	for _, count := range counts {
+		items = append(items, TrendingItem{Code: count.Style, Count: count.Count})
+	}
+
+	// Ties are broken by code so that the ranking is stable
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Code < items[j].Code
+	})
+	if len(items) > maxTrendingItems {
+		items = items[:maxTrendingItems]
+	}
+
+	result := &TrendingResult{Since: since, Items: items}
+	s.trendingCache.put(userID, result)
+	return result, nil
+}
+
+// trendingCache keeps the latest trending statistics of each user for a short time
+type trendingCache = ttlCache[int, *TrendingResult]
+
+func newTrendingCache(ttl time.Duration, now func() time.Time) *trendingCache {
+	return newTTLCache[int, *TrendingResult](ttl, maxTrendingCacheEntries, now)
+}
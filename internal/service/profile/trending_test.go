@@ -0,0 +1,77 @@
+package profile
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	profilerepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/profile"
+)
+
+func TestGetTrendingImprovStyles_RanksByCount(t *testing.T) {
+	now := time.Date(2024, 5, 8, 12, 0, 0, 0, time.UTC)
+	service, profileRepo, _ := setupService()
+	service.trendingCache = newTrendingCache(time.Minute, func() time.Time { return now })
+
+	since := now.Add(-TrendingWindow)
+	profileRepo.On("GetImprovStyleCounts", 1, since).Return([]profilerepo.StyleCount{
+		{Style: "longform", Count: 3},
+		{Style: "shortform", Count: 7},
+		{Style: "musical", Count: 3},
+		{Style: "playback", Count: 1},
+	}, nil).Once()
+
+	result, err := service.GetTrendingImprovStyles(1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, since, result.Since)
+	assert.Equal(t, []TrendingItem{
+		{Code: "shortform", Count: 7},
+		{Code: "longform", Count: 3},
+		{Code: "musical", Count: 3},
+		{Code: "playback", Count: 1},
+	}, result.Items)
+
+	// Served from the cache until it expires
+	cached, err := service.GetTrendingImprovStyles(1)
+	assert.NoError(t, err)
+	assert.Same(t, result, cached)
+	profileRepo.AssertExpectations(t)
+}
+
+func TestGetTrendingImprovStyles_KeepsTopItems(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	counts := make([]profilerepo.StyleCount, 0, maxTrendingItems+5)
+	for i := 0; i < maxTrendingItems+5; i++ {
+		counts = append(counts, profilerepo.StyleCount{Style: string(rune('a' + i)), Count: i})
+	}
+	profileRepo.On("GetImprovStyleCounts", 1, mock.AnythingOfType("time.Time")).Return(counts, nil)
+
+	result, err := service.GetTrendingImprovStyles(1)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Items, maxTrendingItems)
+	assert.Equal(t, maxTrendingItems+4, result.Items[0].Count)
+}
+
+func TestGetTrendingImprovStyles_CachesPerUser(t *testing.T) {
+	service, profileRepo, _ := setupService()
+
+	// Profiles of blocked users are not counted, so each user gets their own result
+	profileRepo.On("GetImprovStyleCounts", 1, mock.AnythingOfType("time.Time")).
+		Return([]profilerepo.StyleCount{{Style: "longform", Count: 2}}, nil).Once()
+	profileRepo.On("GetImprovStyleCounts", 2, mock.AnythingOfType("time.Time")).
+		Return([]profilerepo.StyleCount{{Style: "longform", Count: 1}}, nil).Once()
+
+	first, err := service.GetTrendingImprovStyles(1)
+	assert.NoError(t, err)
+	second, err := service.GetTrendingImprovStyles(2)
+	assert.NoError(t, err)
+
+	assert.Equal(t, 2, first.Items[0].Count)
+	assert.Equal(t, 1, second.Items[0].Count)
+	profileRepo.AssertExpectations(t)
+}
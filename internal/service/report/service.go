@@ -0,0 +1,78 @@
+package report
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	reportrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/report"
+)
+
+// MaxReasonLength is the longest reason a report may have, in characters
+const MaxReasonLength = 1000
+
+var (
+	ErrInvalidTargetType = errors.New("invalid report target type")
+	ErrInvalidReason     = errors.New("invalid report reason")
+	ErrTargetNotFound    = errors.New("report target not found")
+)
+
+// Report is a complaint about a profile, message or user
+type Report = reportrepo.Report
+
+// Service files reports about abusive content and lets admins read them
+type Service interface {
+	CreateReport(reporterID int, targetType, targetID, reason string) (*Report, error)
+	GetReports(limit, offset int) ([]Report, error)
+	IsAdmin(userID int) (bool, error)
+}
+
+// ServiceImpl implements Service
+type ServiceImpl struct {
+	repo reportrepo.Repository
+}
+
+// NewService creates a new report service
+func NewService(repo reportrepo.Repository) *ServiceImpl {
+	return &ServiceImpl{repo: repo}
+}
+
+// CreateReport files a report on behalf of reporterID. Reporting the same
+// target again replaces the earlier reason instead of adding a report.
+func (s *ServiceImpl) CreateReport(reporterID int, targetType, targetID, reason string) (*Report, error) {
+	switch targetType {
+	case reportrepo.TargetProfile, reportrepo.TargetMessage, reportrepo.TargetUser:
+	default:
+		return nil, ErrInvalidTargetType
+	}
+
+	reason = strings.TrimSpace(reason)
+	if reason == "" || utf8.RuneCountInString(reason) > MaxReasonLength {
+		return nil, ErrInvalidReason
+	}
+
+	targetID = strings.TrimSpace(targetID)
+	if targetID == "" {
+		return nil, ErrTargetNotFound
+	}
+	exists, err := s.repo.TargetExists(targetType, targetID)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrTargetNotFound
+	}
+
+	return s.repo.Upsert(reporterID, targetType, targetID, reason)
+}
+
+// GetReports returns a page of reports, most recent first. Access is
+// restricted to admins at the routing layer.
+func (s *ServiceImpl) GetReports(limit, offset int) ([]Report, error) {
+	return s.repo.List(limit, offset)
+}
+
+// IsAdmin reports whether the user is flagged as an admin (users.is_admin)
+func (s *ServiceImpl) IsAdmin(userID int) (bool, error) {
+	return s.repo.IsAdmin(userID)
+}
@@ -0,0 +1,105 @@
+package report
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	reportrepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/report"
+)
+
+// MockRepository is a mock implementation of the report repository
+type MockRepository struct {
+	mock.Mock
+}
+
+func (m *MockRepository) Upsert(reporterID int, targetType, targetID, reason string) (*Report, error) {
+	args := m.Called(reporterID, targetType, targetID, reason)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Report), args.Error(1)
+}
+
+func (m *MockRepository) List(limit, offset int) ([]Report, error) {
+	args := m.Called(limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]Report), args.Error(1)
+}
+
+func (m *MockRepository) TargetExists(targetType, targetID string) (bool, error) {
+	args := m.Called(targetType, targetID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRepository) IsAdmin(userID int) (bool, error) {
+	args := m.Called(userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func TestCreateReport_StoresTrimmedReason(t *testing.T) {
+	repo := new(MockRepository)
+	service := NewService(repo)
+	report := &Report{ID: 1, ReporterID: 1, TargetType: reportrepo.TargetProfile, TargetID: "2", Reason: "fake profile"}
+	repo.On("TargetExists", reportrepo.TargetProfile, "2").Return(true, nil)
+	repo.On("Upsert", 1, reportrepo.TargetProfile, "2", "fake profile").Return(report, nil)
+
+	result, err := service.CreateReport(1, reportrepo.TargetProfile, "2", "  fake profile \n")
+
+	assert.NoError(t, err)
+	assert.Equal(t, report, result)
+	repo.AssertExpectations(t)
+}
+
+func TestCreateReport_Validation(t *testing.T) {
+	tests := []struct {
+		name       string
+		targetType string
+		reason     string
+		want       error
+	}{
+		{"unknown target type", "chat", "spam", ErrInvalidTargetType},
+		{"empty reason", reportrepo.TargetUser, "   ", ErrInvalidReason},
+		{"reason too long", reportrepo.TargetUser, strings.Repeat("я", MaxReasonLength+1), ErrInvalidReason},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(MockRepository)
+			service := NewService(repo)
+
+			_, err := service.CreateReport(1, tt.targetType, "2", tt.reason)
+
+			assert.ErrorIs(t, err, tt.want)
+			repo.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestCreateReport_UnknownTarget(t *testing.T) {
+	repo := new(MockRepository)
+	service := NewService(repo)
+	repo.On("TargetExists", reportrepo.TargetMessage, "missing").Return(false, nil)
+
+	_, err := service.CreateReport(1, reportrepo.TargetMessage, "missing", "abuse")
+
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+	repo.AssertNotCalled(t, "Upsert", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestGetReports_ListsPage(t *testing.T) {
+	repo := new(MockRepository)
+	service := NewService(repo)
+	reports := []Report{{ID: 2}, {ID: 1}}
+	repo.On("List", 50, 0).Return(reports, nil)
+
+	result, err := service.GetReports(50, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, reports, result)
+	repo.AssertExpectations(t)
+}
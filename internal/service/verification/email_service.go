@@ -19,8 +19,14 @@ const (
 	// TokenExpirationHours is how long a token is valid for
 	TokenExpirationHours = 24
 
+	// PasswordResetExpirationMinutes is how long a password reset token is valid for
+	PasswordResetExpirationMinutes = 30
+
 	// TestToken is a predefined token used in test environments
 	TestToken = "test-verification-token-%d"
+
+	// TestPasswordResetToken is a predefined password reset token used in test environments
+	TestPasswordResetToken = "test-password-reset-token-%d"
 )
 
 // Error constants
@@ -35,6 +41,7 @@ var (
 	ErrGetUser                   = errors.New("failed to get user")
 	ErrEmailAlreadyVerified      = errors.New("email already verified")
 	ErrEmailRecentlySent         = errors.New("verification email already sent recently, please wait before resending")
+	ErrInvalidResetToken         = errors.New("invalid or expired password reset token")
 )
 
 type EmailProviderClient interface {
@@ -63,6 +70,8 @@ type VerificationRepository interface {
 	CreateToken(token *verificationRepo.VerificationToken) error
 	GetTokenByValue(tokenValue string, tokenType verificationRepo.TokenType) (*verificationRepo.VerificationToken, error)
 	GetTokenByUserID(userID int, tokenType verificationRepo.TokenType) (*verificationRepo.VerificationToken, error)
+	CreatePasswordReset(reset *verificationRepo.PasswordResetToken) error
+	ConsumePasswordReset(tokenValue string) (*verificationRepo.PasswordResetToken, error)
 	DeleteToken(tokenID int) error
 	DeleteExpiredTokens() error
 }
@@ -86,9 +95,14 @@ func NewEmailVerificationService(
 
 // GenerateVerificationToken creates a new token for email verification
 func (s *EmailVerificationService) generateVerificationToken(userID int) (string, error) {
+	return s.generateToken(TestToken, userID)
+}
+
+// generateToken creates a random token, or a predefined one in test environments
+func (s *EmailVerificationService) generateToken(testToken string, userID int) (string, error) {
 	// If in test environment, use predefined token
 	if s.environment == config.EnvTypeTest {
-		return fmt.Sprintf(TestToken, userID), nil
+		return fmt.Sprintf(testToken, userID), nil
 	}
 
 	// Generate random token
@@ -182,6 +196,47 @@ func (s *EmailVerificationService) ResendVerificationEmail(userID int, ignoreCoo
 	return s.SendVerificationEmail(user.ID, user.Email)
 }
 
+// SendPasswordResetEmail creates a single-use password reset token and sends a reset link
+func (s *EmailVerificationService) SendPasswordResetEmail(userID int, userEmail string) error {
+	token, err := s.generateToken(TestPasswordResetToken, userID)
+	if err != nil {
+		return err
+	}
+
+	// Create and save token to database, replacing any previous reset token of the user
+	resetToken := &verificationRepo.PasswordResetToken{
+		UserID:    userID,
+		Token:     token,
+		ExpiresAt: time.Now().Add(PasswordResetExpirationMinutes * time.Minute),
+	}
+
+	if err := s.verificationRepo.CreatePasswordReset(resetToken); err != nil {
+		return fmt.Errorf("%w: %v", ErrSaveToken, err)
+	}
+
+	resetLink := fmt.Sprintf("%s/reset-password?token=%s", s.frontendURL, token)
+
+	s.emailProviderClient.SendVerificationEmail(userEmail, "Brigadka: Password Reset", resetLink)
+
+	return nil
+}
+
+// ConsumePasswordResetToken validates a password reset token, marks it used so it can't
+// be used again and returns the ID of the user it was issued for
+func (s *EmailVerificationService) ConsumePasswordResetToken(token string) (int, error) {
+	// The token is used up as it is read, before the password is changed, so
+	// concurrent requests with the same token can't both use it
+	resetToken, err := s.verificationRepo.ConsumePasswordReset(token)
+	if err != nil {
+		if err == verificationRepo.ErrTokenNotFound || err == verificationRepo.ErrTokenExpired {
+			return 0, ErrInvalidResetToken
+		}
+		return 0, fmt.Errorf("%w: %v", ErrVerifyToken, err)
+	}
+
+	return resetToken.UserID, nil
+}
+
 // IsTokenExpiredForEmail checks if a user has an expired token
 func (s *EmailVerificationService) IsTokenExpiredForEmail(email string) (bool, error) {
 	user, err := s.userRepo.GetUserByEmail(email)
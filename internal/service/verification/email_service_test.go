@@ -0,0 +1,122 @@
+package verification
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	"github.com/bulatminnakhmetov/brigadka-backend/internal/config"
+	verificationRepo "github.com/bulatminnakhmetov/brigadka-backend/internal/repository/verification"
+)
+
+// MockVerificationRepository is a mock implementation of VerificationRepository
+type MockVerificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockVerificationRepository) CreateToken(token *verificationRepo.VerificationToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *MockVerificationRepository) GetTokenByValue(tokenValue string, tokenType verificationRepo.TokenType) (*verificationRepo.VerificationToken, error) {
+	args := m.Called(tokenValue, tokenType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*verificationRepo.VerificationToken), args.Error(1)
+}
+
+func (m *MockVerificationRepository) GetTokenByUserID(userID int, tokenType verificationRepo.TokenType) (*verificationRepo.VerificationToken, error) {
+	args := m.Called(userID, tokenType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*verificationRepo.VerificationToken), args.Error(1)
+}
+
+func (m *MockVerificationRepository) CreatePasswordReset(reset *verificationRepo.PasswordResetToken) error {
+	args := m.Called(reset)
+	return args.Error(0)
+}
+
+func (m *MockVerificationRepository) ConsumePasswordReset(tokenValue string) (*verificationRepo.PasswordResetToken, error) {
+	args := m.Called(tokenValue)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*verificationRepo.PasswordResetToken), args.Error(1)
+}
+
+func (m *MockVerificationRepository) DeleteToken(tokenID int) error {
+	args := m.Called(tokenID)
+	return args.Error(0)
+}
+
+func (m *MockVerificationRepository) DeleteExpiredTokens() error {
+	args := m.Called()
+	return args.Error(0)
+}
+
+// MockEmailProviderClient is a mock implementation of EmailProviderClient
+type MockEmailProviderClient struct {
+	mock.Mock
+}
+
+func (m *MockEmailProviderClient) SendVerificationEmail(to string, subject string, body string) error {
+	args := m.Called(to, subject, body)
+	return args.Error(0)
+}
+
+func setupService() (*EmailVerificationService, *MockVerificationRepository, *MockEmailProviderClient) {
+	repo := new(MockVerificationRepository)
+	emailClient := new(MockEmailProviderClient)
+	return NewEmailVerificationService(nil, emailClient, repo, "https://example.com", config.EnvTypeTest), repo, emailClient
+}
+
+func TestSendPasswordResetEmail_StoresShortLivedToken(t *testing.T) {
+	service, repo, emailClient := setupService()
+
+	repo.On("CreatePasswordReset", mock.MatchedBy(func(reset *verificationRepo.PasswordResetToken) bool {
+		return reset.UserID == 1 && reset.Token == "test-password-reset-token-1"
+	})).Return(nil)
+	emailClient.On("SendVerificationEmail", "test@example.com", mock.Anything,
+		"https://example.com/reset-password?token=test-password-reset-token-1").Return(nil)
+
+	err := service.SendPasswordResetEmail(1, "test@example.com")
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+	emailClient.AssertExpectations(t)
+}
+
+func TestConsumePasswordResetToken_IsSingleUse(t *testing.T) {
+	service, repo, _ := setupService()
+
+	token := &verificationRepo.PasswordResetToken{ID: 5, UserID: 1, Token: "reset-token"}
+	repo.On("ConsumePasswordReset", "reset-token").Return(token, nil).Once()
+	repo.On("ConsumePasswordReset", "reset-token").Return(nil, verificationRepo.ErrTokenNotFound).Once()
+
+	userID, err := service.ConsumePasswordResetToken("reset-token")
+	assert.NoError(t, err)
+	assert.Equal(t, 1, userID)
+
+	// The token is used up after the first use
+	_, err = service.ConsumePasswordResetToken("reset-token")
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+
+	repo.AssertExpectations(t)
+}
+
+func TestConsumePasswordResetToken_RejectsExpiredToken(t *testing.T) {
+	service, repo, _ := setupService()
+
+	token := &verificationRepo.PasswordResetToken{ID: 5, UserID: 1, Token: "reset-token"}
+	repo.On("ConsumePasswordReset", "reset-token").Return(token, verificationRepo.ErrTokenExpired)
+
+	_, err := service.ConsumePasswordResetToken("reset-token")
+
+	assert.ErrorIs(t, err, ErrInvalidResetToken)
+	repo.AssertExpectations(t)
+}
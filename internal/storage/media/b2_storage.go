@@ -5,7 +5,11 @@ import (
 	"fmt"
 	"io"
 	"mime/multipart"
+	"net/http"
+	"net/url"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/minio/minio-go/v7"
@@ -17,6 +21,9 @@ type MinioClient interface {
 	BucketExists(ctx context.Context, bucketName string) (bool, error)
 	PutObject(ctx context.Context, bucketName string, objectName string, reader io.Reader, objectSize int64, opts minio.PutObjectOptions) (info minio.UploadInfo, err error)
 	RemoveObject(ctx context.Context, bucketName string, objectName string, opts minio.RemoveObjectOptions) error
+	PresignedPutObject(ctx context.Context, bucketName string, objectName string, expires time.Duration) (*url.URL, error)
+	StatObject(ctx context.Context, bucketName string, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error)
+	GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*minio.Object, error)
 }
 
 // S3StorageProvider представляет провайдер хранилища для S3-совместимых сервисов (включая Backblaze B2)
@@ -80,7 +87,7 @@ func (s *S3StorageProvider) UploadFile(file multipart.File, fileName string) (st
 
 	// Генерируем уникальное имя файла, используя UUID
 	extension := filepath.Ext(fileName)
-	uniqueFileName := fmt.Sprintf("%s/%s%s", s.uploadPath, uuid.New().String(), extension)
+	uniqueFileName := s.objectKey(fileName)
 
 	// Определяем тип контента
 	contentType := ""
@@ -106,16 +113,88 @@ func (s *S3StorageProvider) UploadFile(file multipart.File, fileName string) (st
 	return s.GetFileURL(uniqueFileName), nil
 }
 
-// DeleteFile удаляет файл из хранилища
-func (s *S3StorageProvider) DeleteFile(fileName string) error {
+// objectKey generates a unique key for a file in the upload path
+func (s *S3StorageProvider) objectKey(fileName string) string {
+	return fmt.Sprintf("%s/%s%s", s.uploadPath, uuid.New().String(), filepath.Ext(fileName))
+}
+
+// PresignUpload returns a new object key and a URL the client can PUT the file to
+// directly until it expires
+func (s *S3StorageProvider) PresignUpload(fileName string, expires time.Duration) (string, string, error) {
+	key := s.objectKey(fileName)
+
+	uploadURL, err := s.client.PresignedPutObject(context.Background(), s.bucketName, key, expires)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	return key, uploadURL.String(), nil
+}
+
+// HeadObject reports whether the object exists and its size
+func (s *S3StorageProvider) HeadObject(objectKey string) (int64, bool, error) {
+	info, err := s.client.StatObject(context.Background(), s.bucketName, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+			return 0, false, nil
+		}
+		return 0, false, fmt.Errorf("failed to check object: %w", err)
+	}
+
+	return info.Size, true, nil
+}
+
+// ReadObjectHead returns up to n leading bytes of the object
+func (s *S3StorageProvider) ReadObjectHead(objectKey string, n int64) ([]byte, error) {
+	opts := minio.GetObjectOptions{}
+	if err := opts.SetRange(0, n-1); err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+
+	object, err := s.client.GetObject(context.Background(), s.bucketName, objectKey, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	defer object.Close()
+
+	head, err := io.ReadAll(io.LimitReader(object, n))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read object: %w", err)
+	}
+	return head, nil
+}
+
+// DeleteObject удаляет объект из хранилища
+func (s *S3StorageProvider) DeleteObject(objectKey string) error {
 	ctx := context.Background()
 
 	// Удаляем объект из бакета
-	err := s.client.RemoveObject(ctx, s.bucketName, fileName, minio.RemoveObjectOptions{})
+	err := s.client.RemoveObject(ctx, s.bucketName, objectKey, minio.RemoveObjectOptions{})
 	if err != nil {
-		return fmt.Errorf("failed to delete file: %w", err)
+		return fmt.Errorf("failed to delete object: %w", err)
+	}
+
+	return nil
+}
+
+// ObjectKey возвращает ключ объекта по URL, полученному от GetFileURL
+func (s *S3StorageProvider) ObjectKey(fileURL string) (string, bool) {
+	key, ok := strings.CutPrefix(fileURL, s.GetFileURL(""))
+	if !ok || key == "" {
+		return "", false
 	}
+	return key, true
+}
 
+// Ping проверяет доступность бакета. Вызывающий ограничивает время проверки через ctx
+func (s *S3StorageProvider) Ping(ctx context.Context) error {
+	exists, err := s.client.BucketExists(ctx, s.bucketName)
+	if err != nil {
+		return fmt.Errorf("failed to reach bucket: %w", err)
+	}
+	if !exists {
+		return fmt.Errorf("bucket '%s' does not exist", s.bucketName)
+	}
 	return nil
 }
 
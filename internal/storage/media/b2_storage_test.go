@@ -7,6 +7,7 @@ import (
 	"mime/multipart"
 	"net/url"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -43,6 +44,25 @@ func (m *MockMinioClient) PresignedGetObject(ctx context.Context, bucketName str
 	return args.Get(0).(*url.URL), args.Error(1)
 }
 
+func (m *MockMinioClient) PresignedPutObject(ctx context.Context, bucketName string, objectName string, expires time.Duration) (*url.URL, error) {
+	args := m.Called(ctx, bucketName, objectName, expires)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*url.URL), args.Error(1)
+}
+
+func (m *MockMinioClient) StatObject(ctx context.Context, bucketName string, objectName string, opts minio.StatObjectOptions) (minio.ObjectInfo, error) {
+	args := m.Called(ctx, bucketName, objectName, opts)
+	return args.Get(0).(minio.ObjectInfo), args.Error(1)
+}
+
+func (m *MockMinioClient) GetObject(ctx context.Context, bucketName string, objectName string, opts minio.GetObjectOptions) (*minio.Object, error) {
+	args := m.Called(ctx, bucketName, objectName, opts)
+	object, _ := args.Get(0).(*minio.Object)
+	return object, args.Error(1)
+}
+
 type mockMultipartFile struct {
 	*bytes.Reader
 	io.Closer
@@ -180,8 +200,8 @@ func TestUploadFile(t *testing.T) {
 	})
 }
 
-// TestDeleteFile проверяет функцию удаления файла
-func TestDeleteFile(t *testing.T) {
+// TestDeleteObject проверяет функцию удаления объекта
+func TestDeleteObject(t *testing.T) {
 	t.Run("successful delete", func(t *testing.T) {
 		mockClient := new(MockMinioClient)
 
@@ -199,7 +219,7 @@ func TestDeleteFile(t *testing.T) {
 			fileName,
 			mock.Anything).Return(nil)
 
-		err := provider.DeleteFile(fileName)
+		err := provider.DeleteObject(fileName)
 
 		// Проверяем результаты
 		assert.NoError(t, err)
@@ -225,17 +245,56 @@ func TestDeleteFile(t *testing.T) {
 			fileName,
 			mock.Anything).Return(os.ErrNotExist)
 
-		err := provider.DeleteFile(fileName)
+		err := provider.DeleteObject(fileName)
 
 		// Проверяем результаты
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to delete file")
+		assert.Contains(t, err.Error(), "failed to delete object")
 
 		// Проверяем, что мок был вызван
 		mockClient.AssertExpectations(t)
 	})
 }
 
+// TestObjectKey проверяет получение ключа объекта по URL
+func TestObjectKey(t *testing.T) {
+	t.Run("with CDN domain", func(t *testing.T) {
+		provider := &S3StorageProvider{
+			cdnDomain:  "cdn.example.com",
+			bucketName: "test-bucket",
+			endpoint:   "s3.example.com",
+		}
+
+		key, ok := provider.ObjectKey("https://cdn.example.com/media/test.jpg")
+		assert.True(t, ok)
+		assert.Equal(t, "media/test.jpg", key)
+	})
+
+	t.Run("without CDN domain", func(t *testing.T) {
+		provider := &S3StorageProvider{
+			bucketName: "test-bucket",
+			endpoint:   "s3.example.com",
+		}
+
+		key, ok := provider.ObjectKey("https://s3.example.com/test-bucket/media/test.jpg")
+		assert.True(t, ok)
+		assert.Equal(t, "media/test.jpg", key)
+	})
+
+	t.Run("foreign URL", func(t *testing.T) {
+		provider := &S3StorageProvider{
+			cdnDomain:  "cdn.example.com",
+			bucketName: "test-bucket",
+		}
+
+		_, ok := provider.ObjectKey("https://elsewhere.example.com/media/test.jpg")
+		assert.False(t, ok)
+
+		_, ok = provider.ObjectKey("")
+		assert.False(t, ok)
+	})
+}
+
 // TestGetFileURL проверяет функцию получения URL файла
 func TestGetFileURL(t *testing.T) {
 	t.Run("with CDN domain", func(t *testing.T) {
@@ -264,3 +323,90 @@ func TestGetFileURL(t *testing.T) {
 		assert.Equal(t, "https://s3.example.com/test-bucket/media/test-file.jpg", url)
 	})
 }
+
+// TestPresignUpload проверяет выдачу ссылки для прямой загрузки
+func TestPresignUpload(t *testing.T) {
+	mockClient := new(MockMinioClient)
+
+	provider := &S3StorageProvider{
+		client:     mockClient,
+		bucketName: "test-bucket",
+		uploadPath: "media",
+	}
+
+	presigned, _ := url.Parse("https://s3.example.com/test-bucket/media/key.mp4?X-Amz-Signature=abc")
+	mockClient.On("PresignedPutObject",
+		mock.Anything,
+		"test-bucket",
+		mock.AnythingOfType("string"),
+		15*time.Minute).Return(presigned, nil)
+
+	key, uploadURL, err := provider.PresignUpload("clip.mp4", 15*time.Minute)
+
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key, "media/"))
+	assert.True(t, strings.HasSuffix(key, ".mp4"))
+	assert.Equal(t, presigned.String(), uploadURL)
+	mockClient.AssertExpectations(t)
+}
+
+// TestHeadObject проверяет проверку наличия объекта в бакете
+func TestHeadObject(t *testing.T) {
+	t.Run("object exists", func(t *testing.T) {
+		mockClient := new(MockMinioClient)
+		provider := &S3StorageProvider{client: mockClient, bucketName: "test-bucket"}
+
+		mockClient.On("StatObject", mock.Anything, "test-bucket", "media/key.mp4", mock.Anything).
+			Return(minio.ObjectInfo{Key: "media/key.mp4", Size: 1024}, nil)
+
+		size, exists, err := provider.HeadObject("media/key.mp4")
+
+		assert.NoError(t, err)
+		assert.True(t, exists)
+		assert.Equal(t, int64(1024), size)
+	})
+
+	t.Run("object missing", func(t *testing.T) {
+		mockClient := new(MockMinioClient)
+		provider := &S3StorageProvider{client: mockClient, bucketName: "test-bucket"}
+
+		mockClient.On("StatObject", mock.Anything, "test-bucket", "media/key.mp4", mock.Anything).
+			Return(minio.ObjectInfo{}, minio.ErrorResponse{StatusCode: 404, Code: "NoSuchKey"})
+
+		_, exists, err := provider.HeadObject("media/key.mp4")
+
+		assert.NoError(t, err)
+		assert.False(t, exists)
+	})
+}
+
+// TestPing проверяет проверку доступности бакета
+func TestPing(t *testing.T) {
+	t.Run("bucket reachable", func(t *testing.T) {
+		mockClient := new(MockMinioClient)
+		provider := &S3StorageProvider{client: mockClient, bucketName: "test-bucket"}
+
+		mockClient.On("BucketExists", mock.Anything, "test-bucket").Return(true, nil)
+
+		assert.NoError(t, provider.Ping(context.Background()))
+	})
+
+	t.Run("bucket missing", func(t *testing.T) {
+		mockClient := new(MockMinioClient)
+		provider := &S3StorageProvider{client: mockClient, bucketName: "test-bucket"}
+
+		mockClient.On("BucketExists", mock.Anything, "test-bucket").Return(false, nil)
+
+		assert.Error(t, provider.Ping(context.Background()))
+	})
+
+	t.Run("endpoint unreachable", func(t *testing.T) {
+		mockClient := new(MockMinioClient)
+		provider := &S3StorageProvider{client: mockClient, bucketName: "test-bucket"}
+
+		mockClient.On("BucketExists", mock.Anything, "test-bucket").Return(false, context.DeadlineExceeded)
+
+		err := provider.Ping(context.Background())
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}